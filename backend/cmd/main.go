@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	echomiddleware "github.com/labstack/echo/v4/middleware"
@@ -10,41 +15,121 @@ import (
 	"todo-app-backend/internal/config"
 	"todo-app-backend/internal/handlers"
 	authmiddleware "todo-app-backend/internal/middleware"
+	"todo-app-backend/internal/notify"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/services"
 )
 
+// ブラウザからの API・WebSocket の接続を許可するオリジン
+var allowedOrigins = []string{"http://localhost:3000", "http://localhost:3001"}
+
 func main() {
 	// 設定を読み込み
 	cfg := config.Load()
 
+	// migrate サブコマンド
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	// データベースに接続
+	db, err := repository.Open(cfg)
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
+	defer db.Close()
+
+	// スキーマを確認・更新
+	prepareSchema(cfg, db)
+
+	// シグナル受信時にバックグラウンド処理とサーバーを停止する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Echoインスタンスを作成
 	e := echo.New()
 
 	// ミドルウェアを設定
 	e.Use(echomiddleware.Logger())
 	e.Use(echomiddleware.Recover())
-	
+
 	// CORS設定
 	e.Use(echomiddleware.CORSWithConfig(echomiddleware.CORSConfig{
-		AllowOrigins: []string{"http://localhost:3000", "http://localhost:3001"},
-		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.DELETE, echo.OPTIONS},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
+		AllowOrigins: allowedOrigins,
+		AllowMethods: []string{echo.GET, echo.POST, echo.PUT, echo.PATCH, echo.DELETE, echo.OPTIONS},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, "Last-Event-ID", "If-Match", "If-None-Match"},
+		// タスクの版数を If-Match で送り返せるよう、ブラウザから ETag を読めるようにする
+		ExposeHeaders: []string{"ETag"},
 	}))
 
+	// リポジトリを初期化
+	repos := repository.NewRepositories(db)
+
+	// サービスを初期化
+	jwtService := services.NewJWTService(cfg, repos.RefreshTokens)
+	jwtService.StartRefreshTokenCleanup(ctx, time.Duration(cfg.TokenCleanupIntervalMinutes)*time.Minute)
+
+	// 保持期間を過ぎたゴミ箱のタスクを削除する
+	trashPurger := services.NewTrashPurger(repos.Tasks, time.Duration(cfg.TrashRetentionDays)*24*time.Hour)
+	trashPurger.Start(ctx, time.Duration(cfg.TrashPurgeIntervalMinutes)*time.Minute)
+
+	// リマインダーの通知チャネルとスケジューラー
+	notifiers, err := notify.NewNotifiers(cfg)
+	if err != nil {
+		log.Fatal("Failed to configure notification channels:", err)
+	}
+	reminderScheduler := services.NewReminderScheduler(repos.Reminders, notifiers, time.Duration(cfg.ReminderGraceMinutes)*time.Minute)
+	reminderScheduler.Start(ctx, time.Duration(cfg.ReminderIntervalSeconds)*time.Second)
+
+	// タスクの変更イベントを Webhook に送信する
+	webhookDispatcher := services.NewWebhookDispatcher(repos.Webhooks)
+	webhookDispatcher.Start(ctx, time.Duration(cfg.WebhookPollIntervalSeconds)*time.Second)
+
+	// タスクの変更イベントを SSE の接続に配信する
+	eventBroker := services.NewEventBroker(cfg.EventLogSize)
+	// WebSocket の接続のプレゼンスを管理し、購読中のプロジェクト・タスクの変更を配信する
+	collabHub := services.NewCollabHub()
+
 	// ハンドラーを初期化
-	authHandler := handlers.NewAuthHandler(cfg)
-	taskHandler := handlers.NewTaskHandler()
+	authHandler := handlers.NewAuthHandler(repos.Users, repos.Projects, jwtService)
+	taskHandler := handlers.NewTaskHandler(repos.Tasks, repos.TaskHistory, handlers.TaskEventPublishers{webhookDispatcher, eventBroker, collabHub})
+	projectHandler := handlers.NewProjectHandler(repos.Projects)
+	viewHandler := handlers.NewViewHandler(repos.Views, repos.Tasks)
+	tagHandler := handlers.NewTagHandler(repos.Tags)
+	reminderHandler := handlers.NewReminderHandler(repos.Tasks, repos.Reminders)
+	webhookHandler := handlers.NewWebhookHandler(repos.Webhooks, webhookDispatcher)
+	eventHandler := handlers.NewEventHandler(eventBroker)
+	collabHandler := handlers.NewCollabHandler(collabHub, taskHandler, repos.Projects, repos.Users, allowedOrigins)
 
 	// ルートを設定
-	setupRoutes(e, authHandler, taskHandler, cfg)
+	setupRoutes(e, authHandler, taskHandler, projectHandler, viewHandler, tagHandler, reminderHandler, webhookHandler, eventHandler, collabHandler, jwtService)
 
 	// サーバーを起動
-	log.Printf("Server starting on port %s", cfg.Port)
-	if err := e.Start(":" + cfg.Port); err != nil && err != http.ErrServerClosed {
-		log.Fatal("Failed to start server:", err)
+	go func() {
+		log.Printf("Server starting on port %s", cfg.Port)
+		if err := e.Start(":" + cfg.Port); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down server...")
+
+	// SSE・WebSocket の接続はクライアントが切断するまで終わらないため、先に閉じる
+	eventBroker.Close()
+	collabHub.Close()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down server gracefully: %v", err)
 	}
 }
 
-func setupRoutes(e *echo.Echo, authHandler *handlers.AuthHandler, taskHandler *handlers.TaskHandler, cfg *config.Config) {
+func setupRoutes(e *echo.Echo, authHandler *handlers.AuthHandler, taskHandler *handlers.TaskHandler, projectHandler *handlers.ProjectHandler, viewHandler *handlers.ViewHandler, tagHandler *handlers.TagHandler, reminderHandler *handlers.ReminderHandler, webhookHandler *handlers.WebhookHandler, eventHandler *handlers.EventHandler, collabHandler *handlers.CollabHandler, jwtService *services.JWTService) {
 	// 認証不要のルート
 	e.POST("/api/auth/register", authHandler.Register)
 	e.POST("/api/auth/login", authHandler.Login)
@@ -53,13 +138,72 @@ func setupRoutes(e *echo.Echo, authHandler *handlers.AuthHandler, taskHandler *h
 
 	// 認証が必要なルート
 	api := e.Group("/api")
-	api.Use(authmiddleware.JWTAuth(cfg))
+	api.Use(authmiddleware.JWTAuth(jwtService))
 
 	// タスク関連のルート
 	api.GET("/tasks", taskHandler.GetTasks)
 	api.POST("/tasks", taskHandler.CreateTask)
+	api.POST("/tasks/batch", taskHandler.BatchTasks)
+	api.GET("/tasks/search", taskHandler.SearchTasks)
+	api.GET("/tasks/:id", taskHandler.GetTask)
 	api.PUT("/tasks/:id", taskHandler.UpdateTask)
+	api.PATCH("/tasks/:id", taskHandler.PatchTask)
 	api.DELETE("/tasks/:id", taskHandler.DeleteTask)
+	api.GET("/tasks/:id/children", taskHandler.GetTaskChildren)
+	api.GET("/tasks/:id/tree", taskHandler.GetTaskTree)
+	api.POST("/tasks/:id/move", taskHandler.MoveTask)
+	api.POST("/tasks/:id/skip", taskHandler.SkipOccurrence)
+	api.POST("/tasks/:id/end-series", taskHandler.EndTaskSeries)
+	api.POST("/tasks/:id/restore", taskHandler.RestoreTask)
+	api.GET("/tasks/:id/history", taskHandler.GetTaskHistory)
+	api.POST("/tasks/:id/history/:version/revert", taskHandler.RevertTask)
+
+	// ゴミ箱関連のルート
+	api.GET("/trash", taskHandler.GetTrash)
+	api.DELETE("/trash/:id", taskHandler.PurgeTask)
+
+	// リマインダー関連のルート
+	api.GET("/tasks/:id/reminders", reminderHandler.GetReminders)
+	api.POST("/tasks/:id/reminders", reminderHandler.CreateReminder)
+	api.DELETE("/tasks/:id/reminders/:reminder_id", reminderHandler.DeleteReminder)
+	api.GET("/tasks/:id/reminders/:reminder_id/deliveries", reminderHandler.GetReminderDeliveries)
+
+	// プロジェクト関連のルート
+	api.GET("/projects", projectHandler.GetProjects)
+	api.POST("/projects", projectHandler.CreateProject)
+	api.PUT("/projects/order", projectHandler.ReorderProjects)
+	api.PUT("/projects/:id", projectHandler.UpdateProject)
+	api.DELETE("/projects/:id", projectHandler.DeleteProject)
+
+	// 保存したビュー関連のルート
+	api.GET("/views", viewHandler.GetViews)
+	api.POST("/views", viewHandler.CreateView)
+	api.GET("/views/:id", viewHandler.GetView)
+	api.PUT("/views/:id", viewHandler.UpdateView)
+	api.DELETE("/views/:id", viewHandler.DeleteView)
+	api.GET("/views/:id/tasks", viewHandler.GetViewTasks)
+
+	// タグ関連のルート
+	api.GET("/tags", tagHandler.GetTags)
+	api.POST("/tags", tagHandler.CreateTag)
+	api.PUT("/tags/:id", tagHandler.UpdateTag)
+	api.DELETE("/tags/:id", tagHandler.DeleteTag)
+
+	// Webhook関連のルート
+	api.GET("/webhooks", webhookHandler.GetWebhooks)
+	api.POST("/webhooks", webhookHandler.CreateWebhook)
+	api.GET("/webhooks/:id", webhookHandler.GetWebhook)
+	api.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+	api.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+	api.GET("/webhooks/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+	api.GET("/webhooks/:id/deliveries/:delivery_id", webhookHandler.GetWebhookDelivery)
+	api.POST("/webhooks/:id/deliveries/:delivery_id/replay", webhookHandler.ReplayWebhookDelivery)
+
+	// タスクの変更の配信（Server-Sent Events）
+	api.GET("/events", eventHandler.StreamEvents)
+
+	// 共同編集（WebSocket）
+	api.GET("/collab", collabHandler.Connect)
 
 	// ヘルスチェック
 	e.GET("/health", func(c echo.Context) error {
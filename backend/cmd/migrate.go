@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"todo-app-backend/internal/config"
+	"todo-app-backend/internal/repository"
+)
+
+const migrateUsage = `Usage: main migrate <command> [options]
+
+Commands:
+  up            未適用のマイグレーションをすべて適用する
+  down [-n N]   適用済みのマイグレーションを新しい順に N 件（デフォルト 1）ロールバックする
+  status        マイグレーションの適用状況を表示する
+
+Options:
+  -dry-run      実行せずに適用・ロールバックされるSQLを表示する
+`
+
+// runMigrate migrate サブコマンドを実行し、終了コードを返す
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, migrateUsage) }
+	dryRun := flags.Bool("dry-run", false, "print the SQL without executing it")
+	steps := flags.Int("n", 1, "number of migrations to roll back")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	db, err := repository.Open(cfg)
+	if err != nil {
+		log.Printf("Failed to open database: %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		log.Printf("Failed to load migrations: %v", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(*dryRun)
+		if err != nil {
+			log.Printf("Migration failed: %v", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			log.Println("Database schema is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			log.Println("-n must be a positive integer")
+			return 2
+		}
+		if _, err := migrator.Down(*steps, *dryRun); err != nil {
+			log.Printf("Rollback failed: %v", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Printf("Failed to get migration status: %v", err)
+			return 1
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied at " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, applied)
+		}
+		if err := migrator.Check(); err != nil {
+			fmt.Println(err)
+		}
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+// prepareSchema 起動時にスキーマを確認し、設定に応じてマイグレーションを適用する
+// DBのスキーマがバイナリより新しい場合は起動しない
+func prepareSchema(cfg *config.Config, db *repository.DB) {
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	if cfg.AutoMigrate {
+		if _, err := migrator.Up(false); err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		return
+	}
+
+	if err := migrator.Check(); err != nil {
+		log.Fatal("Database schema check failed (run `migrate up` or set DB_AUTO_MIGRATE=true): ", err)
+	}
+}
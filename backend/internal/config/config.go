@@ -3,26 +3,66 @@ package config
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Port           string
-	JWTSecret      string
-	JWTExpiryHours int
-	DatabasePath   string
-	Environment    string
+	Port                        string
+	JWTSecret                   string
+	JWTExpiryHours              int
+	TokenCleanupIntervalMinutes int
+	DatabaseDriver              string
+	DatabasePath                string
+	DatabaseURL                 string
+	AutoMigrate                 bool
+	Environment                 string
+	// リマインダー
+	ReminderIntervalSeconds int
+	ReminderGraceMinutes    int      // 停止中に通知時刻を過ぎたリマインダーを遅れて配信する猶予
+	NotifyChannels          []string // log / smtp / webhook
+	SMTPHost                string
+	SMTPPort                int
+	SMTPUsername            string
+	SMTPPassword            string
+	SMTPFrom                string
+	ReminderWebhookURL      string
+	// Webhook の送信待ちの配信を確認する間隔（新しい配信はすぐに送信される）
+	WebhookPollIntervalSeconds int
+	// SSE の再接続で再送できるよう保持する直近のイベント数
+	EventLogSize int
+	// ゴミ箱のタスクを完全に削除するまでの日数（0 以下なら自動では削除しない）と、確認する間隔
+	TrashRetentionDays        int
+	TrashPurgeIntervalMinutes int
 }
 
 func Load() *Config {
 	config := &Config{
-		Port:           getEnv("PORT", "8080"),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		JWTExpiryHours: getEnvAsInt("JWT_EXPIRY_HOURS", 24),
-		DatabasePath:   getEnv("DATABASE_PATH", "./todo.db"),
-		Environment:    getEnv("ENVIRONMENT", "development"),
+		Port:                        getEnv("PORT", "8080"),
+		JWTSecret:                   getEnv("JWT_SECRET", ""),
+		JWTExpiryHours:              getEnvAsInt("JWT_EXPIRY_HOURS", 24),
+		TokenCleanupIntervalMinutes: getEnvAsInt("TOKEN_CLEANUP_INTERVAL_MINUTES", 60),
+		DatabaseDriver:              getEnv("DATABASE_DRIVER", "sqlite"),
+		DatabasePath:                getEnv("DATABASE_PATH", "./todo.db"),
+		DatabaseURL:                 getEnv("DATABASE_URL", ""),
+		AutoMigrate:                 getEnvAsBool("DB_AUTO_MIGRATE", true),
+		Environment:                 getEnv("ENVIRONMENT", "development"),
+		ReminderIntervalSeconds:     getEnvAsInt("REMINDER_INTERVAL_SECONDS", 60),
+		ReminderGraceMinutes:        getEnvAsInt("REMINDER_GRACE_MINUTES", 24*60),
+		NotifyChannels:              getEnvAsList("NOTIFY_CHANNELS", []string{"log"}),
+		SMTPHost:                    getEnv("SMTP_HOST", ""),
+		SMTPPort:                    getEnvAsInt("SMTP_PORT", 25),
+		SMTPUsername:                getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:                getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:                    getEnv("SMTP_FROM", "todo@localhost"),
+		ReminderWebhookURL:          getEnv("REMINDER_WEBHOOK_URL", ""),
+		WebhookPollIntervalSeconds:  getEnvAsInt("WEBHOOK_POLL_INTERVAL_SECONDS", 5),
+		EventLogSize:                getEnvAsInt("EVENT_LOG_SIZE", 1000),
+		TrashRetentionDays:          getEnvAsInt("TRASH_RETENTION_DAYS", 30),
+		TrashPurgeIntervalMinutes:   getEnvAsInt("TRASH_PURGE_INTERVAL_MINUTES", 60),
 	}
 
 	// JWTシークレットが設定されていない場合は生成
@@ -36,6 +76,25 @@ func Load() *Config {
 	return config
 }
 
+// Validate 設定値を検証する（定期実行の間隔は time.NewTicker が 0 以下で panic するため正の値に限る）
+func (c *Config) Validate() error {
+	intervals := []struct {
+		name  string
+		value int
+	}{
+		{"TOKEN_CLEANUP_INTERVAL_MINUTES", c.TokenCleanupIntervalMinutes},
+		{"REMINDER_INTERVAL_SECONDS", c.ReminderIntervalSeconds},
+		{"WEBHOOK_POLL_INTERVAL_SECONDS", c.WebhookPollIntervalSeconds},
+		{"TRASH_PURGE_INTERVAL_MINUTES", c.TrashPurgeIntervalMinutes},
+	}
+	for _, interval := range intervals {
+		if interval.value <= 0 {
+			return fmt.Errorf("%s must be greater than 0, got %d", interval.name, interval.value)
+		}
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -52,6 +111,30 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList カンマ区切りの値を空白を除いて分割する
+func getEnvAsList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func generateRandomSecret() string {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
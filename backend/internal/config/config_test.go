@@ -0,0 +1,24 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateIntervals(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	if err := Load().Validate(); err != nil {
+		t.Fatalf("Validate with the defaults = %v, want nil", err)
+	}
+
+	for _, key := range []string{"TOKEN_CLEANUP_INTERVAL_MINUTES", "REMINDER_INTERVAL_SECONDS", "WEBHOOK_POLL_INTERVAL_SECONDS", "TRASH_PURGE_INTERVAL_MINUTES"} {
+		for _, value := range []string{"0", "-1"} {
+			t.Run(key+"="+value, func(t *testing.T) {
+				t.Setenv(key, value)
+				if err := Load().Validate(); err == nil || !strings.Contains(err.Error(), key) {
+					t.Errorf("Validate = %v, want an error for %s", err, key)
+				}
+			})
+		}
+	}
+}
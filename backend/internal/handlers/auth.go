@@ -1,13 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
 
-	"todo-app-backend/internal/config"
 	"todo-app-backend/internal/models"
 	"todo-app-backend/internal/repository"
 	"todo-app-backend/internal/services"
@@ -15,14 +15,16 @@ import (
 )
 
 type AuthHandler struct {
-	userRepo  *repository.UserRepository
-	jwtService *services.JWTService
+	userRepo    repository.UserRepository
+	projectRepo repository.ProjectRepository
+	jwtService  *services.JWTService
 }
 
-func NewAuthHandler(cfg *config.Config) *AuthHandler {
+func NewAuthHandler(userRepo repository.UserRepository, projectRepo repository.ProjectRepository, jwtService *services.JWTService) *AuthHandler {
 	return &AuthHandler{
-		userRepo:   repository.NewUserRepository(),
-		jwtService: services.NewJWTService(cfg),
+		userRepo:    userRepo,
+		projectRepo: projectRepo,
+		jwtService:  jwtService,
 	}
 }
 
@@ -72,6 +74,13 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		})
 	}
 
+	// 既定のプロジェクト（Inbox）を作成
+	if _, err := h.projectRepo.EnsureInbox(user.ID, user.CreatedAt); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create inbox project",
+		})
+	}
+
 	// JWTトークンを生成
 	accessToken, refreshToken, err := h.jwtService.GenerateTokens(user.ID)
 	if err != nil {
@@ -149,11 +158,16 @@ func (h *AuthHandler) RefreshToken(c echo.Context) error {
 
 	// リフレッシュトークンを使用して新しいトークンを生成
 	newAccessToken, newRefreshToken, err := h.jwtService.RefreshAccessToken(req.RefreshToken)
-	if err != nil {
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
 			"error": "Invalid refresh token",
 		})
 	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to refresh token",
+		})
+	}
 
 	response := map[string]interface{}{
 		"access_token":  newAccessToken,
@@ -179,7 +193,13 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 	}
 
 	// リフレッシュトークンを無効化
-	if err := h.jwtService.RevokeRefreshToken(req.RefreshToken); err != nil {
+	err := h.jwtService.RevokeRefreshToken(req.RefreshToken)
+	if errors.Is(err, services.ErrInvalidRefreshToken) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid refresh token",
+		})
+	}
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to revoke token",
 		})
@@ -190,10 +210,3 @@ func (h *AuthHandler) Logout(c echo.Context) error {
 		"message": "Logged out successfully",
 	})
 }
-
-
-
-
-
-
-
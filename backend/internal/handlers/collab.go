@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/services"
+)
+
+// CollabProtocol WebSocket のサブプロトコル
+// ブラウザは WebSocket の接続にヘッダーを付けられないため、アクセストークンは "bearer.<トークン>" のサブプロトコルとして送る
+const CollabProtocol = "todo.collab.v1"
+
+const (
+	// クライアントから受信するメッセージの最大サイズ
+	collabMaxMessageSize = 64 * 1024
+	// この時間内に何も受信しなければ切断する（ping を送って pong を待つ）
+	collabPongWait   = 60 * time.Second
+	collabPingPeriod = collabPongWait * 9 / 10
+	collabWriteWait  = 10 * time.Second
+)
+
+type CollabHandler struct {
+	hub         *services.CollabHub
+	tasks       *TaskHandler
+	projectRepo repository.ProjectRepository
+	userRepo    repository.UserRepository
+	upgrader    websocket.Upgrader
+}
+
+// NewCollabHandler allowedOrigins は接続を許可するブラウザのオリジン（Origin ヘッダーのない接続は許可する）
+func NewCollabHandler(hub *services.CollabHub, tasks *TaskHandler, projectRepo repository.ProjectRepository, userRepo repository.UserRepository, allowedOrigins []string) *CollabHandler {
+	origins := map[string]bool{}
+	for _, origin := range allowedOrigins {
+		origins[origin] = true
+	}
+	return &CollabHandler{
+		hub:         hub,
+		tasks:       tasks,
+		projectRepo: projectRepo,
+		userRepo:    userRepo,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{CollabProtocol},
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				return origin == "" || origins[origin]
+			},
+		},
+	}
+}
+
+// Connect WebSocket で接続し、プロジェクト・タスクの購読、プレゼンス、タスクの更新を受け付ける
+func (h *CollabHandler) Connect(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	user, err := h.userRepo.GetUserByID(userID)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "User not found",
+		})
+	}
+
+	// 失敗した場合は Upgrade がエラーのレスポンスを返している
+	conn, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+
+	session := h.hub.Connect(user.ID, user.Name)
+	defer h.hub.Disconnect(session)
+
+	done := make(chan struct{})
+	defer close(done)
+	go h.writeLoop(conn, session, done)
+	h.hub.Reply(session, &models.CollabServerMessage{Type: models.CollabHello, SessionID: session.ID})
+
+	conn.SetReadLimit(collabMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		conn.SetReadDeadline(time.Now().Add(collabPongWait))
+
+		var message models.CollabClientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			h.hub.Reply(session, collabError("", http.StatusBadRequest, "Invalid message"))
+			continue
+		}
+		h.hub.Reply(session, h.handleMessage(session, &message))
+	}
+}
+
+// writeLoop 送信待ちのメッセージと ping を送る
+// ハブが接続を閉じた（停止・送信の遅延）場合は close フレームを送って切断する
+func (h *CollabHandler) writeLoop(conn *websocket.Conn, session *services.CollabSession, done <-chan struct{}) {
+	ticker := time.NewTicker(collabPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case data, ok := <-session.Send():
+			conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				conn.Close()
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				conn.Close()
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// handleMessage クライアントのメッセージを処理し、応答を返す
+func (h *CollabHandler) handleMessage(session *services.CollabSession, message *models.CollabClientMessage) *models.CollabServerMessage {
+	switch message.Type {
+	case models.CollabSubscribe:
+		if status, msg := h.authorizeTopic(session.UserID, message.Topic); status != 0 {
+			return collabError(message.Ref, status, msg)
+		}
+		if err := h.hub.Join(session, message.Topic); err != nil {
+			return collabError(message.Ref, http.StatusBadRequest, err.Error())
+		}
+		return collabAck(message.Ref, message.Topic, nil)
+
+	case models.CollabUnsubscribe:
+		if err := h.hub.Leave(session, message.Topic); err != nil {
+			return collabError(message.Ref, http.StatusBadRequest, err.Error())
+		}
+		return collabAck(message.Ref, message.Topic, nil)
+
+	case models.CollabPresence:
+		if !models.IsPresenceState(message.State) {
+			return collabError(message.Ref, http.StatusBadRequest, "state must be one of viewing, editing")
+		}
+		if err := h.hub.SetState(session, message.Topic, message.State); err != nil {
+			return collabError(message.Ref, http.StatusBadRequest, err.Error())
+		}
+		return collabAck(message.Ref, message.Topic, nil)
+
+	case models.CollabUpdate:
+		if message.Changes == nil {
+			return collabError(message.Ref, http.StatusBadRequest, "changes is required")
+		}
+		// PUT /api/tasks/:id と同じ所有者の確認と検証を行う
+		task, status, msg := h.tasks.ownedTask(session.UserID, message.TaskID)
+		if task == nil {
+			return collabError(message.Ref, status, msg)
+		}
+		if message.Version == nil {
+			return collabError(message.Ref, http.StatusPreconditionRequired, "version is required")
+		}
+		// 版数が変わっていれば、マージし直せるよう現在のタスクを付けて 412 を返す
+		if *message.Version != task.Version {
+			return collabConflict(message.Ref, task)
+		}
+		result, status, msg := h.tasks.applyTaskUpdate(session.UserID, task, message.Changes)
+		if status == http.StatusPreconditionFailed {
+			current, err := h.tasks.taskRepo.GetTaskByID(task.ID)
+			if err != nil {
+				return collabError(message.Ref, http.StatusNotFound, "Task not found")
+			}
+			return collabConflict(message.Ref, current)
+		}
+		if result == nil {
+			return collabError(message.Ref, status, msg)
+		}
+		ack := collabAck(message.Ref, "", result.Task)
+		ack.NextOccurrence = result.NextOccurrence
+		return ack
+
+	case models.CollabPing:
+		return &models.CollabServerMessage{Type: models.CollabPong, Ref: message.Ref}
+
+	default:
+		return collabError(message.Ref, http.StatusBadRequest, "Unknown message type")
+	}
+}
+
+// authorizeTopic トピックのプロジェクト・タスクがユーザーのものか確認する（問題なければステータスコード 0 を返す）
+func (h *CollabHandler) authorizeTopic(userID, topic string) (int, string) {
+	kind, id, err := models.ParseCollabTopic(topic)
+	if err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+
+	switch kind {
+	case models.CollabTopicProject:
+		project, err := h.projectRepo.GetProjectByID(id)
+		if err != nil {
+			return http.StatusNotFound, "Project not found"
+		}
+		if project.UserID != userID {
+			return http.StatusForbidden, "Access denied"
+		}
+	case models.CollabTopicTask:
+		if task, status, message := h.tasks.ownedTask(userID, id); task == nil {
+			return status, message
+		}
+	default:
+		return http.StatusBadRequest, "Unknown topic"
+	}
+	return 0, ""
+}
+
+func collabAck(ref, topic string, data interface{}) *models.CollabServerMessage {
+	return &models.CollabServerMessage{
+		Type:  models.CollabAck,
+		Ref:   ref,
+		Topic: topic,
+		Data:  data,
+	}
+}
+
+func collabError(ref string, status int, message string) *models.CollabServerMessage {
+	return &models.CollabServerMessage{
+		Type:   models.CollabError,
+		Ref:    ref,
+		Status: status,
+		Error:  message,
+	}
+}
+
+// collabConflict 他の変更で更新されていた場合の 412 のエラー（現在のタスクを付ける）
+func collabConflict(ref string, current *models.Task) *models.CollabServerMessage {
+	message := collabError(ref, http.StatusPreconditionFailed, taskModifiedMessage)
+	message.Data = current
+	return message
+}
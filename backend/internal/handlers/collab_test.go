@@ -0,0 +1,182 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/handlers"
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/services"
+)
+
+// collabClient WebSocket の接続
+type collabClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+	// sessionID hello で受け取った接続のID
+	sessionID string
+}
+
+// newCollabServer u1 のプロジェクト p1 とタスク t1、u2 を用意して /api/collab を提供する
+func newCollabServer(t *testing.T) string {
+	t.Helper()
+
+	repos := repository.NewMemoryRepositories()
+	now := time.Now()
+	for _, user := range []*models.User{
+		{ID: "u1", Email: "u1@example.com", Password: "hash", Name: "Alice", CreatedAt: now, UpdatedAt: now},
+		{ID: "u2", Email: "u2@example.com", Password: "hash", Name: "Bob", CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := repos.Users.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+	}
+	if err := repos.Projects.CreateProject(&models.Project{ID: "p1", UserID: "u1", Name: "Work", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	task := &models.Task{ID: "t1", UserID: "u1", ProjectID: "p1", Title: "draft", Status: "pending", Priority: "low", Version: 1, CreatedAt: now, UpdatedAt: now}
+	if err := repos.Tasks.CreateTask(task); err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+
+	hub := services.NewCollabHub()
+	tasks := handlers.NewTaskHandler(repos.Tasks, repos.TaskHistory, hub)
+	h := handlers.NewCollabHandler(hub, tasks, repos.Projects, repos.Users, nil)
+	e := echo.New()
+	e.GET("/api/collab", func(c echo.Context) error {
+		c.Set("user_id", c.Request().Header.Get("X-Test-User"))
+		return h.Connect(c)
+	})
+	server := httptest.NewServer(e)
+	t.Cleanup(func() {
+		hub.Close()
+		server.Close()
+	})
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/api/collab"
+}
+
+// dialCollab userID として接続し、hello を受け取る
+func dialCollab(t *testing.T, url, userID string) *collabClient {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"X-Test-User": {userID}})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := &collabClient{t: t, conn: conn}
+	hello := c.read()
+	if hello.Type != models.CollabHello || hello.SessionID == "" {
+		t.Fatalf("first message = %+v, want hello", hello)
+	}
+	c.sessionID = hello.SessionID
+	return c
+}
+
+func (c *collabClient) send(message string) {
+	c.t.Helper()
+
+	if err := c.conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		c.t.Fatalf("write: %v", err)
+	}
+}
+
+// read 次のメッセージを読む
+func (c *collabClient) read() *models.CollabServerMessage {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message models.CollabServerMessage
+	if err := c.conn.ReadJSON(&message); err != nil {
+		c.t.Fatalf("read: %v", err)
+	}
+	return &message
+}
+
+// readType type のメッセージまで読み進める（プレゼンスと応答の順序は決まっていないため）
+func (c *collabClient) readType(messageType string) *models.CollabServerMessage {
+	c.t.Helper()
+
+	for {
+		if message := c.read(); message.Type == messageType {
+			return message
+		}
+	}
+}
+
+func TestCollabRejectsOtherUsersTopics(t *testing.T) {
+	url := newCollabServer(t)
+	bob := dialCollab(t, url, "u2")
+
+	for _, topic := range []string{"project:p1", "task:t1"} {
+		bob.send(`{"type":"subscribe","ref":"1","topic":"` + topic + `"}`)
+		if reply := bob.read(); reply.Type != models.CollabError || reply.Status != http.StatusForbidden || reply.Ref != "1" {
+			t.Errorf("subscribe %s as another user = %+v, want 403", topic, reply)
+		}
+	}
+	bob.send(`{"type":"update","ref":"2","task_id":"t1","version":1,"changes":{"title":"stolen"}}`)
+	if reply := bob.read(); reply.Type != models.CollabError || reply.Status != http.StatusForbidden {
+		t.Errorf("update as another user = %+v, want 403", reply)
+	}
+}
+
+func TestCollabJoinBroadcastAndLeave(t *testing.T) {
+	url := newCollabServer(t)
+	alice := dialCollab(t, url, "u1")
+	tablet := dialCollab(t, url, "u1")
+
+	alice.send(`{"type":"subscribe","ref":"1","topic":"project:p1"}`)
+	if ack := alice.readType(models.CollabAck); ack.Ref != "1" || ack.Topic != "project:p1" {
+		t.Fatalf("subscribe = %+v, want ack", ack)
+	}
+	tablet.send(`{"type":"subscribe","ref":"1","topic":"project:p1"}`)
+	tablet.readType(models.CollabAck)
+
+	// 参加すると他の参加者にプレゼンスが届く
+	for {
+		presence := alice.readType(models.CollabPresence)
+		if len(presence.Members) == 2 {
+			if presence.Members[1].SessionID != tablet.sessionID || presence.Members[1].State != models.PresenceViewing {
+				t.Errorf("presence = %+v, want the tablet viewing", presence.Members)
+			}
+			break
+		}
+	}
+
+	// 一方の接続での更新は、同じプロジェクトを購読している他の接続に配信される
+	tablet.send(`{"type":"update","ref":"2","task_id":"t1","version":1,"changes":{"title":"final"}}`)
+	if ack := tablet.readType(models.CollabAck); ack.Ref != "2" {
+		t.Fatalf("update = %+v, want ack", ack)
+	}
+	event := alice.readType(models.EventTaskUpdated)
+	if task, ok := event.Data.(map[string]interface{}); !ok || event.Topic != "project:p1" || task["title"] != "final" {
+		t.Errorf("event = %+v, want task.updated of t1 on project:p1", event)
+	}
+
+	// 抜けると残った参加者にプレゼンスが届く
+	tablet.send(`{"type":"unsubscribe","ref":"3","topic":"project:p1"}`)
+	tablet.readType(models.CollabAck)
+	if presence := alice.readType(models.CollabPresence); len(presence.Members) != 1 || presence.Members[0].SessionID != alice.sessionID {
+		t.Errorf("presence after leaving = %+v, want only alice", presence.Members)
+	}
+	tablet.send(`{"type":"unsubscribe","ref":"4","topic":"project:p1"}`)
+	if reply := tablet.readType(models.CollabError); reply.Ref != "4" || reply.Status != http.StatusBadRequest {
+		t.Errorf("unsubscribe again = %+v, want 400", reply)
+	}
+
+	// 切断しても残った参加者にプレゼンスが届く
+	tablet.send(`{"type":"subscribe","ref":"5","topic":"project:p1"}`)
+	tablet.readType(models.CollabAck)
+	alice.readType(models.CollabPresence)
+	tablet.conn.Close()
+	if presence := alice.readType(models.CollabPresence); len(presence.Members) != 1 {
+		t.Errorf("presence after disconnecting = %+v, want only alice", presence.Members)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/services"
+)
+
+const (
+	// 切断時にクライアントが再接続するまでの間隔（ミリ秒）
+	eventStreamRetryMillis = 3000
+	// プロキシに接続を切られないよう、イベントがなくても送るコメントの間隔
+	eventStreamHeartbeat = 25 * time.Second
+)
+
+type EventHandler struct {
+	broker *services.EventBroker
+}
+
+func NewEventHandler(broker *services.EventBroker) *EventHandler {
+	return &EventHandler{
+		broker: broker,
+	}
+}
+
+// StreamEvents ユーザーのタスクの変更を Server-Sent Events で配信する
+// Last-Event-ID ヘッダーを付けて再接続すると、取りこぼしたイベントから再開する
+// 再開できない場合は reset イベントを送るので、クライアントはタスクを取得し直す
+func (h *EventHandler) StreamEvents(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	lastEventID := c.Request().Header.Get("Last-Event-ID")
+	sub, resume := h.broker.Subscribe(userID, lastEventID)
+	defer h.broker.Unsubscribe(sub)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	// nginx などのバッファリングを無効にする
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(res, "retry: %d\n\n", eventStreamRetryMillis)
+	switch {
+	case resume.Reset:
+		writeEvent(res, resume.LastID, "reset", []byte("{}"))
+	case lastEventID == "":
+		// 以降の再接続で使うイベントIDをクライアントに伝える
+		writeEvent(res, resume.LastID, "ready", []byte("{}"))
+	}
+	for _, event := range resume.Events {
+		writeEvent(res, event.ID, event.Type, event.Data)
+	}
+	res.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case event, ok := <-sub.C:
+			if !ok {
+				return nil
+			}
+			writeEvent(res, event.ID, event.Type, event.Data)
+			res.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(res, ": ping\n\n")
+			res.Flush()
+		}
+	}
+}
+
+// writeEvent SSE の1イベントを書き込む（data は改行を含まないJSON）
+func writeEvent(w io.Writer, id, event string, data []byte) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
@@ -0,0 +1,148 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/handlers"
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/services"
+)
+
+// eventStream SSE の接続（Server-Sent Events の1イベントずつ読む）
+type eventStream struct {
+	t      *testing.T
+	cancel context.CancelFunc
+	events chan map[string]string
+}
+
+// openEventStream userID として /api/events に接続する（lastEventID が空でなければ Last-Event-ID を送る）
+func openEventStream(t *testing.T, url, userID, lastEventID string) *eventStream {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/api/events", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("X-Test-User", userID)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/events: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get(echo.HeaderContentType) != "text/event-stream" {
+		t.Fatalf("GET /api/events = %d %s", resp.StatusCode, resp.Header.Get(echo.HeaderContentType))
+	}
+
+	s := &eventStream{t: t, cancel: cancel, events: make(chan map[string]string, 16)}
+	t.Cleanup(cancel)
+	go func() {
+		defer resp.Body.Close()
+		defer close(s.events)
+		scanner := bufio.NewScanner(resp.Body)
+		event := map[string]string{}
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line == "" {
+				if event["event"] != "" {
+					s.events <- event
+				}
+				event = map[string]string{}
+				continue
+			}
+			if field, value, ok := strings.Cut(line, ": "); ok {
+				event[field] = value
+			}
+		}
+	}()
+	return s
+}
+
+// next 次のイベントを待つ
+func (s *eventStream) next() map[string]string {
+	s.t.Helper()
+
+	select {
+	case event, ok := <-s.events:
+		if !ok {
+			s.t.Fatal("event stream closed")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		s.t.Fatal("no event within 5s")
+	}
+	return nil
+}
+
+// none イベントが届いていないことを確認する
+func (s *eventStream) none() {
+	s.t.Helper()
+
+	select {
+	case event := <-s.events:
+		s.t.Errorf("unexpected event %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	broker := services.NewEventBroker(100)
+	h := handlers.NewEventHandler(broker)
+	finished := make(chan string, 4)
+
+	e := echo.New()
+	e.GET("/api/events", func(c echo.Context) error {
+		userID := c.Request().Header.Get("X-Test-User")
+		c.Set("user_id", userID)
+		err := h.StreamEvents(c)
+		finished <- userID
+		return err
+	})
+	server := httptest.NewServer(e)
+	// 接続を閉じた後に停止する（Cleanup は登録と逆の順に実行される）
+	t.Cleanup(server.Close)
+
+	u1 := openEventStream(t, server.URL, "u1", "")
+	u2 := openEventStream(t, server.URL, "u2", "")
+	ready := u1.next()
+	if ready["event"] != "ready" || ready["id"] == "" {
+		t.Fatalf("first event = %v, want ready with an id", ready)
+	}
+	u2.next()
+
+	// 変更はそのタスクのユーザーの接続にだけ届く
+	broker.PublishTaskEvent(&models.TaskEvent{ID: "e1", Type: models.EventTaskCreated, UserID: "u1", Task: &models.Task{ID: "t1"}, OccurredAt: time.Now()})
+	event := u1.next()
+	if event["event"] != models.EventTaskCreated || !strings.Contains(event["data"], `"id":"t1"`) || event["id"] == "" || event["id"] == ready["id"] {
+		t.Errorf("event = %v, want task.created of t1 with a new id", event)
+	}
+	u2.none()
+
+	// 切断するとハンドラーが終わり、購読をやめる
+	u1.cancel()
+	select {
+	case userID := <-finished:
+		if userID != "u1" {
+			t.Errorf("finished stream of %s, want u1", userID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not return after the client disconnected")
+	}
+
+	// 再接続すると Last-Event-ID の後のイベントから再開する
+	broker.PublishTaskEvent(&models.TaskEvent{ID: "e2", Type: models.EventTaskUpdated, UserID: "u1", Task: &models.Task{ID: "t1"}, OccurredAt: time.Now()})
+	resumed := openEventStream(t, server.URL, "u1", event["id"])
+	if missed := resumed.next(); missed["event"] != models.EventTaskUpdated || missed["id"] == event["id"] {
+		t.Errorf("first event after reconnecting = %v, want the missed task.updated", missed)
+	}
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/utils"
+)
+
+type ProjectHandler struct {
+	projectRepo repository.ProjectRepository
+}
+
+func NewProjectHandler(projectRepo repository.ProjectRepository) *ProjectHandler {
+	return &ProjectHandler{
+		projectRepo: projectRepo,
+	}
+}
+
+// GetProjects プロジェクトを並び順に返す（include_archived=true でアーカイブ済みも含める）
+func (h *ProjectHandler) GetProjects(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	includeArchived := c.QueryParam("include_archived") == "true"
+	projects, err := h.projectRepo.GetProjectsByUserID(userID, includeArchived)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get projects",
+		})
+	}
+	if projects == nil {
+		projects = []models.Project{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    projects,
+	})
+}
+
+func (h *ProjectHandler) CreateProject(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateProjectRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	project := models.Project{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		Name:      req.Name,
+		Color:     models.DefaultProjectColor,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if req.Color != nil {
+		project.Color = *req.Color
+	}
+	if err := project.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.projectRepo.CreateProject(&project); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create project",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    project,
+	})
+}
+
+// UpdateProject 名前・色・アーカイブ状態を変更する（Inbox はアーカイブできない）
+func (h *ProjectHandler) UpdateProject(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	project, status, message := h.ownedProject(c.Param("id"), userID)
+	if project == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	var req models.UpdateProjectRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Name != nil {
+		project.Name = *req.Name
+	}
+	if req.Color != nil {
+		project.Color = *req.Color
+	}
+	if req.Archived != nil {
+		project.Archived = *req.Archived
+	}
+	if err := project.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	project.UpdatedAt = time.Now()
+
+	if err := h.projectRepo.UpdateProject(project); err != nil {
+		if errors.Is(err, repository.ErrInboxProject) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "The Inbox project cannot be archived",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update project",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    project,
+	})
+}
+
+// ReorderProjects project_ids の順にプロジェクトを並べ替える
+// 指定しなかったプロジェクトは元の順序のまま後ろに並ぶ
+func (h *ProjectHandler) ReorderProjects(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.ReorderProjectsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.projectRepo.ReorderProjects(userID, req.ProjectIDs, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrProjectNotFound) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Project not found",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to reorder projects",
+		})
+	}
+
+	projects, err := h.projectRepo.GetProjectsByUserID(userID, true)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get projects",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    projects,
+	})
+}
+
+// DeleteProject プロジェクトを削除する
+// mode=cascade はタスクも削除し、mode=reassign は target（省略時は Inbox）にタスクを移す
+func (h *ProjectHandler) DeleteProject(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	project, status, message := h.ownedProject(c.Param("id"), userID)
+	if project == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	if project.IsInbox {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "The Inbox project cannot be deleted",
+		})
+	}
+
+	var reassignTo *string
+	switch c.QueryParam("mode") {
+	case "cascade":
+	case "reassign":
+		target := c.QueryParam("target")
+		if target == "" {
+			inbox, err := h.projectRepo.EnsureInbox(userID, time.Now())
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to delete project",
+				})
+			}
+			target = inbox.ID
+		}
+		reassignTo = &target
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "mode must be either 'cascade' or 'reassign'",
+		})
+	}
+
+	if err := h.projectRepo.DeleteProject(project.ID, reassignTo, time.Now()); err != nil {
+		if status, message, ok := taskProjectError(err); ok {
+			return c.JSON(status, map[string]string{
+				"error": message,
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete project",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Project deleted successfully",
+	})
+}
+
+// ownedProject ユーザーのプロジェクトを取得する（取得できない場合はステータスとメッセージを返す）
+func (h *ProjectHandler) ownedProject(projectID, userID string) (*models.Project, int, string) {
+	project, err := h.projectRepo.GetProjectByID(projectID)
+	if err != nil {
+		return nil, http.StatusNotFound, "Project not found"
+	}
+
+	// プロジェクトがユーザーのものかチェック
+	if project.UserID != userID {
+		return nil, http.StatusForbidden, "Access denied"
+	}
+	return project, 0, ""
+}
+
+// taskProjectError タスクの移動先のプロジェクトに関するエラーをレスポンスに変換する
+func taskProjectError(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, repository.ErrProjectNotFound):
+		return http.StatusBadRequest, "Project not found", true
+	case errors.Is(err, repository.ErrProjectArchived):
+		return http.StatusConflict, "Tasks cannot be added to an archived project", true
+	case errors.Is(err, repository.ErrInboxProject):
+		return http.StatusConflict, "The Inbox project cannot be deleted", true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/utils"
+)
+
+type ReminderHandler struct {
+	taskRepo     repository.TaskRepository
+	reminderRepo repository.ReminderRepository
+}
+
+func NewReminderHandler(taskRepo repository.TaskRepository, reminderRepo repository.ReminderRepository) *ReminderHandler {
+	return &ReminderHandler{
+		taskRepo:     taskRepo,
+		reminderRepo: reminderRepo,
+	}
+}
+
+// GetReminders タスクのリマインダーを取得する
+func (h *ReminderHandler) GetReminders(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	task, status, message := h.ownedTask(userID, c.Param("id"))
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	reminders, err := h.reminderRepo.GetRemindersByTaskID(task.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get reminders",
+		})
+	}
+	if reminders == nil {
+		reminders = []models.Reminder{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    reminders,
+	})
+}
+
+// CreateReminder タスクにリマインダーを追加する
+func (h *ReminderHandler) CreateReminder(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	task, status, message := h.ownedTask(userID, c.Param("id"))
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	var req models.CreateReminderRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	reminder := models.Reminder{
+		ID:            utils.GenerateID(),
+		TaskID:        task.ID,
+		Kind:          req.Kind,
+		OffsetMinutes: req.OffsetMinutes,
+		TimeOfDay:     req.TimeOfDay,
+		Timezone:      req.Timezone,
+		CreatedAt:     time.Now(),
+	}
+	if err := reminder.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.reminderRepo.CreateReminder(&reminder); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create reminder",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    reminder,
+	})
+}
+
+// DeleteReminder リマインダーを配信記録ごと削除する
+func (h *ReminderHandler) DeleteReminder(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	reminder, status, message := h.ownedReminder(userID, c.Param("id"), c.Param("reminder_id"))
+	if reminder == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	if err := h.reminderRepo.DeleteReminder(reminder.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete reminder",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Reminder deleted successfully",
+	})
+}
+
+// GetReminderDeliveries リマインダーの配信記録を取得する
+func (h *ReminderHandler) GetReminderDeliveries(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	reminder, status, message := h.ownedReminder(userID, c.Param("id"), c.Param("reminder_id"))
+	if reminder == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	deliveries, err := h.reminderRepo.GetReminderDeliveries(reminder.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get reminder deliveries",
+		})
+	}
+	if deliveries == nil {
+		deliveries = []models.ReminderDelivery{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    deliveries,
+	})
+}
+
+// ownedTask ユーザーのタスクを取得する（取得できなければステータスコードとエラーメッセージを返す）
+func (h *ReminderHandler) ownedTask(userID, taskID string) (*models.Task, int, string) {
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, http.StatusNotFound, "Task not found"
+	}
+
+	// タスクがユーザーのものかチェック
+	if task.UserID != userID {
+		return nil, http.StatusForbidden, "Access denied"
+	}
+	return task, 0, ""
+}
+
+// ownedReminder ユーザーのタスクに属するリマインダーを取得する
+func (h *ReminderHandler) ownedReminder(userID, taskID, reminderID string) (*models.Reminder, int, string) {
+	task, status, message := h.ownedTask(userID, taskID)
+	if task == nil {
+		return nil, status, message
+	}
+
+	reminder, err := h.reminderRepo.GetReminderByID(reminderID)
+	if err != nil || reminder.TaskID != task.ID {
+		return nil, http.StatusNotFound, "Reminder not found"
+	}
+	return reminder, 0, ""
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/utils"
+)
+
+type TagHandler struct {
+	tagRepo repository.TagRepository
+}
+
+func NewTagHandler(tagRepo repository.TagRepository) *TagHandler {
+	return &TagHandler{
+		tagRepo: tagRepo,
+	}
+}
+
+func (h *TagHandler) GetTags(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	tags, err := h.tagRepo.GetTagsByUserID(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get tags",
+		})
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tags,
+	})
+}
+
+func (h *TagHandler) CreateTag(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateTagRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	name, err := models.NormalizeTagName(req.Name)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	tag := models.Tag{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		Name:      name,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if err := h.tagRepo.CreateTag(&tag); err != nil {
+		if errors.Is(err, repository.ErrTagExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Tag already exists",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create tag",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    tag,
+	})
+}
+
+// UpdateTag タグ名を変更する（タグが付いているすべてのタスクに反映される）
+func (h *TagHandler) UpdateTag(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	tagID := c.Param("id")
+	tag, err := h.tagRepo.GetTagByID(tagID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Tag not found",
+		})
+	}
+
+	// タグがユーザーのものかチェック
+	if tag.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+
+	var req models.UpdateTagRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	name, err := models.NormalizeTagName(req.Name)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	tag.Name = name
+	tag.UpdatedAt = time.Now()
+	if err := h.tagRepo.RenameTag(tag.ID, tag.Name, tag.UpdatedAt); err != nil {
+		if errors.Is(err, repository.ErrTagExists) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Tag already exists",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update tag",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tag,
+	})
+}
+
+func (h *TagHandler) DeleteTag(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	tagID := c.Param("id")
+	tag, err := h.tagRepo.GetTagByID(tagID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Tag not found",
+		})
+	}
+
+	// タグがユーザーのものかチェック
+	if tag.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+
+	if err := h.tagRepo.DeleteTag(tagID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete tag",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Tag deleted successfully",
+	})
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
@@ -11,13 +14,31 @@ import (
 	"todo-app-backend/internal/utils"
 )
 
+// TaskEventPublisher タスクの変更イベントの通知先（Webhook の配信、SSE の配信など）
+type TaskEventPublisher interface {
+	PublishTaskEvent(event *models.TaskEvent)
+}
+
+// TaskEventPublishers 複数の通知先に順に通知する
+type TaskEventPublishers []TaskEventPublisher
+
+func (p TaskEventPublishers) PublishTaskEvent(event *models.TaskEvent) {
+	for _, publisher := range p {
+		publisher.PublishTaskEvent(event)
+	}
+}
+
 type TaskHandler struct {
-	taskRepo *repository.TaskRepository
+	taskRepo    repository.TaskRepository
+	historyRepo repository.TaskHistoryRepository
+	events      TaskEventPublisher
 }
 
-func NewTaskHandler() *TaskHandler {
+func NewTaskHandler(taskRepo repository.TaskRepository, historyRepo repository.TaskHistoryRepository, events TaskEventPublisher) *TaskHandler {
 	return &TaskHandler{
-		taskRepo: repository.NewTaskRepository(),
+		taskRepo:    taskRepo,
+		historyRepo: historyRepo,
+		events:      events,
 	}
 }
 
@@ -29,8 +50,42 @@ func (h *TaskHandler) GetTasks(c echo.Context) error {
 		})
 	}
 
+	// クエリパラメータからフィルター・ソート条件を取得
+	var filters models.TaskFilters
+	if err := c.Bind(&filters); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid query parameters",
+		})
+	}
+	return listTasks(c, h.taskRepo, userID, &filters)
+}
+
+// listTasks フィルターに一致するユーザーのタスクを1ページ分返す（ページングはクエリパラメータの limit / cursor）
+// GET /api/tasks と保存したビューのタスク一覧（GET /api/views/:id/tasks）で共通の処理
+func listTasks(c echo.Context, taskRepo repository.TaskRepository, userID string, filters *models.TaskFilters) error {
+	if err := filters.Validate(); err != nil {
+		return invalidTaskFilters(c, err)
+	}
+
+	var pagination models.TaskPagination
+	if err := c.Bind(&pagination); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid query parameters",
+		})
+	}
+	if err := pagination.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
 	// ユーザーのタスクを取得
-	userTasks, err := h.taskRepo.GetTasksByUserID(userID)
+	page, err := taskRepo.GetTasksByUserID(userID, filters, &pagination)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid cursor",
+		})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to get tasks",
@@ -38,8 +93,24 @@ func (h *TaskHandler) GetTasks(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"success": true,
-		"data":    userTasks,
+		"success":     true,
+		"data":        page.Tasks,
+		"next_cursor": page.NextCursor,
+		"has_more":    page.HasMore,
+	})
+}
+
+// invalidTaskFilters フィルターの検証エラーを返す（フィルター式の構文エラーは位置を query_error に含める）
+func invalidTaskFilters(c echo.Context, err error) error {
+	var queryErr *models.TaskQueryError
+	if errors.As(err, &queryErr) {
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":       err.Error(),
+			"query_error": queryErr,
+		})
+	}
+	return c.JSON(http.StatusBadRequest, map[string]string{
+		"error": err.Error(),
 	})
 }
 
@@ -58,32 +129,100 @@ func (h *TaskHandler) CreateTask(c echo.Context) error {
 		})
 	}
 
+	task, status, message := h.createTask(userID, &req)
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    task,
+	})
+}
+
+// createTask ユーザーのタスクを作成し、変更履歴の記録と変更イベントの通知を行う
+// 一括操作でも同じ検証を行うため、失敗した場合はステータスコードとエラーメッセージを返す
+func (h *TaskHandler) createTask(userID string, req *models.CreateTaskRequest) (*models.Task, int, string) {
+	tags, err := models.NormalizeTagNames(req.Tags)
+	if err != nil {
+		return nil, http.StatusBadRequest, err.Error()
+	}
+
 	// タスクを作成
-	task := models.Task{
+	task := &models.Task{
 		ID:          utils.GenerateID(),
 		UserID:      userID,
+		ProjectID:   projectIDOf(req.ProjectID),
 		Title:       req.Title,
 		Description: req.Description,
 		Deadline:    req.Deadline,
 		Priority:    req.Priority,
+		ParentID:    parentIDOf(req.ParentID),
 		Status:      "pending",
+		Tags:        tags,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if req.RRule != nil {
+		if err := setTaskRecurrence(task, *req.RRule, req.Timezone); err != nil {
+			return nil, http.StatusBadRequest, err.Error()
+		}
+	}
 
-	// データベースにタスクを保存
-	if err := h.taskRepo.CreateTask(&task); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to create task",
+	// データベースにタスクを保存し、同じトランザクションで変更履歴を記録
+	err = h.inTx(func(tx *TaskHandler) error {
+		if err := tx.taskRepo.CreateTask(task); err != nil {
+			if status, message, ok := taskHierarchyError(err); ok {
+				return newTaskError(status, message)
+			}
+			if status, message, ok := taskProjectError(err); ok {
+				return newTaskError(status, message)
+			}
+			return err
+		}
+		if err := tx.recordHistory(models.TaskHistoryCreated, userID, nil, task); err != nil {
+			return err
+		}
+		tx.publish(models.EventTaskCreated, task)
+		return nil
+	})
+	if err != nil {
+		status, message := taskErrorOf(err, "Failed to create task")
+		return nil, status, message
+	}
+	return task, 0, ""
+}
+
+// GetTask タスクを取得する（ETag に版数を返し、If-None-Match が一致すれば 304）
+func (h *TaskHandler) GetTask(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
 		})
 	}
 
-	return c.JSON(http.StatusCreated, map[string]interface{}{
+	task, status, message := h.ownedTask(userID, c.Param("id"))
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	c.Response().Header().Set(headerETag, taskETag(task))
+	if header := c.Request().Header.Get(headerIfNoneMatch); header != "" && matchTaskETag(strings.ReplaceAll(header, "W/", ""), task) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data":    task,
 	})
 }
 
+// UpdateTask タスクを更新する（If-Match に GET / PUT で返した ETag が必要）
 func (h *TaskHandler) UpdateTask(c echo.Context) error {
 	userID := getUserIDFromContext(c)
 	if userID == "" {
@@ -92,18 +231,17 @@ func (h *TaskHandler) UpdateTask(c echo.Context) error {
 		})
 	}
 
-	taskID := c.Param("id")
-	task, err := h.taskRepo.GetTaskByID(taskID)
-	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "Task not found",
+	task, status, message := h.ownedTask(userID, c.Param("id"))
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
 		})
 	}
-
-	// タスクがユーザーのものかチェック
-	if task.UserID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{
-			"error": "Access denied",
+	if status, message := checkTaskPrecondition(c, task); status == http.StatusPreconditionFailed {
+		return taskPreconditionFailed(c, task)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
 		})
 	}
 
@@ -114,15 +252,91 @@ func (h *TaskHandler) UpdateTask(c echo.Context) error {
 		})
 	}
 
+	result, status, message := h.applyTaskUpdate(userID, task, &req)
+	if status == http.StatusPreconditionFailed {
+		return h.reloadPreconditionFailed(c, task.ID)
+	}
+	if result == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	c.Response().Header().Set(headerETag, taskETag(result.Task))
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    result.Task,
+	}
+	if result.NextOccurrence != nil {
+		response["next_occurrence"] = result.NextOccurrence
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// taskUpdateResult タスクの更新結果
+type taskUpdateResult struct {
+	Task *models.Task
+	// NextOccurrence 繰り返しタスクを完了にした場合に作成した次の回
+	NextOccurrence *models.Task
+}
+
+// applyTaskUpdate ユーザーのタスクに変更を適用して保存し、変更履歴の記録と変更イベントの通知を行う
+// REST の更新と WebSocket からの更新で同じ検証を行うため、失敗した場合はステータスコードとエラーメッセージを返す
+// 系列の変更・サブタスクの一括完了・次の回の作成は、タスクの更新と同じトランザクションで行う（失敗すればすべて取り消す）
+func (h *TaskHandler) applyTaskUpdate(actorID string, task *models.Task, req *models.UpdateTaskRequest) (*taskUpdateResult, int, string) {
+	var result *taskUpdateResult
+	err := h.inTx(func(tx *TaskHandler) error {
+		var err error
+		result, err = tx.updateTask(actorID, task, req)
+		return err
+	})
+	if err != nil {
+		status, message := taskErrorOf(err, "Failed to update task")
+		return nil, status, message
+	}
+	return result, 0, ""
+}
+
+// updateTask applyTaskUpdate の本体（トランザクション内で呼び出す。失敗した場合は taskError を返す）
+func (h *TaskHandler) updateTask(actorID string, task *models.Task, req *models.UpdateTaskRequest) (*taskUpdateResult, error) {
+	// 繰り返しタスクは scope=series の場合のみ系列（以降の回）も変更する
+	var series *models.TaskSeries
+	switch req.Scope {
+	case "", "this":
+		if task.RRule != nil && (req.RRule != nil || req.Timezone != nil) {
+			return nil, newTaskError(http.StatusBadRequest, "The recurrence rule can only be changed with scope=series")
+		}
+	case "series":
+		if task.RRule == nil {
+			return nil, newTaskError(http.StatusBadRequest, "scope=series requires a task in an active recurring series")
+		}
+		var err error
+		if series, err = h.taskRepo.GetTaskSeries(*task.SeriesID); err != nil {
+			return nil, newTaskError(http.StatusInternalServerError, "Failed to get series")
+		}
+	default:
+		return nil, newTaskError(http.StatusBadRequest, "scope must be one of this, series")
+	}
+	wasCompleted := task.Status == "completed"
+	before := *task
+
 	// タスクを更新
 	if req.Title != nil {
 		task.Title = *req.Title
 	}
 	if req.Description != nil {
 		task.Description = req.Description
+	} else if req.ClearDescription {
+		task.Description = nil
 	}
 	if req.Deadline != nil {
 		task.Deadline = req.Deadline
+	} else if req.ClearDeadline {
+		// 繰り返しは期限を基準に次の回を計算するため、期限を消せない
+		if task.RRule != nil {
+			return nil, newTaskError(http.StatusBadRequest, "deadline is required for recurring tasks")
+		}
+		task.Deadline = nil
 	}
 	if req.Priority != nil {
 		task.Priority = *req.Priority
@@ -130,22 +344,291 @@ func (h *TaskHandler) UpdateTask(c echo.Context) error {
 	if req.Status != nil {
 		task.Status = *req.Status
 	}
+	if req.ParentID != nil {
+		task.ParentID = parentIDOf(req.ParentID)
+	}
+	if req.Tags != nil {
+		tags, err := models.NormalizeTagNames(*req.Tags)
+		if err != nil {
+			return nil, newTaskError(http.StatusBadRequest, err.Error())
+		}
+		task.Tags = tags
+	}
+	if req.RRule != nil || req.Timezone != nil {
+		rule := ""
+		if req.RRule != nil {
+			rule = *req.RRule
+		} else if task.RRule != nil {
+			rule = *task.RRule
+		}
+		if err := setTaskRecurrence(task, rule, req.Timezone); err != nil {
+			return nil, newTaskError(http.StatusBadRequest, err.Error())
+		}
+	}
 	task.UpdatedAt = time.Now()
 
-	// データベースでタスクを更新
+	if series != nil {
+		if req.Title != nil {
+			series.Title = task.Title
+		}
+		if req.Description != nil || req.ClearDescription {
+			series.Description = task.Description
+		}
+		if req.Priority != nil {
+			series.Priority = task.Priority
+		}
+		series.RRule, series.Timezone = *task.RRule, *task.Timezone
+		// 期限の変更はこの回から系列を組み直す
+		if req.Deadline != nil {
+			occurrenceAt := task.Deadline.UTC()
+			series.DTStart = occurrenceAt
+			task.OccurrenceAt = &occurrenceAt
+		}
+		series.UpdatedAt = task.UpdatedAt
+		if err := h.taskRepo.UpdateTaskSeries(series); err != nil {
+			return nil, newTaskError(http.StatusInternalServerError, "Failed to update series")
+		}
+	}
+
+	// データベースでタスクを更新（読み込んでから他の変更で更新されていれば 412）
 	if err := h.taskRepo.UpdateTask(task); err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to update task",
+		if status, message, ok := taskHierarchyError(err); ok {
+			return nil, newTaskError(status, message)
+		}
+		if errors.Is(err, repository.ErrTaskVersionConflict) {
+			return nil, newTaskError(http.StatusPreconditionFailed, taskModifiedMessage)
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newTaskError(http.StatusNotFound, "Task not found")
+		}
+		return nil, newTaskError(http.StatusInternalServerError, "Failed to update task")
+	}
+
+	// 指定があればサブタスクもまとめて完了にする
+	var subtasks []models.Task
+	subtasksBefore := map[string]models.Task{}
+	if req.Cascade && task.Status == "completed" {
+		subtree, err := h.taskRepo.GetTaskSubtree(task.ID)
+		if err != nil {
+			return nil, newTaskError(http.StatusInternalServerError, "Failed to complete subtasks")
+		}
+		for _, subtask := range subtree {
+			subtasksBefore[subtask.ID] = subtask
+		}
+		if subtasks, err = h.taskRepo.CompleteSubtasks(task.ID, task.UpdatedAt); err != nil {
+			return nil, newTaskError(http.StatusInternalServerError, "Failed to complete subtasks")
+		}
+	}
+	var err error
+	if req.RevertedFrom != nil {
+		err = h.recordRevert(actorID, &before, task, *req.RevertedFrom)
+	} else {
+		err = h.recordHistory(models.TaskHistoryUpdated, actorID, &before, task)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.publish(models.EventTaskUpdated, task)
+
+	// まとめて完了にしたサブタスクも、個別に完了にした場合と同じく履歴を記録してイベントを通知する
+	for i := range subtasks {
+		subtask := &subtasks[i]
+		subtaskBefore := subtasksBefore[subtask.ID]
+		if err := h.recordHistory(models.TaskHistoryUpdated, actorID, &subtaskBefore, subtask); err != nil {
+			return nil, err
+		}
+		h.publish(models.EventTaskUpdated, subtask)
+		h.publish(models.EventTaskCompleted, subtask)
+	}
+
+	result := &taskUpdateResult{Task: task}
+
+	// 繰り返しタスクを完了にしたら次の回を作成する
+	if !wasCompleted && task.Status == "completed" {
+		next, err := h.completeOccurrence(actorID, task, task.UpdatedAt)
+		if err != nil {
+			return nil, newTaskError(http.StatusInternalServerError, "Failed to create the next occurrence")
+		}
+		h.publish(models.EventTaskCompleted, task)
+		if next != nil {
+			result.NextOccurrence = next
+			if err := h.recordHistory(models.TaskHistoryCreated, actorID, nil, next); err != nil {
+				return nil, err
+			}
+			h.publish(models.EventTaskCreated, next)
+		}
+	}
+
+	return result, nil
+}
+
+// DeleteTask タスクを子孫ごとゴミ箱に移す（If-Match が必要。POST /api/tasks/:id/restore で元に戻せる）
+func (h *TaskHandler) DeleteTask(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	taskID := c.Param("id")
+
+	// タスクが存在するかチェック
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Task not found",
+		})
+	}
+
+	// タスクがユーザーのものかチェック
+	if task.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+	if status, message := checkTaskPrecondition(c, task); status == http.StatusPreconditionFailed {
+		return taskPreconditionFailed(c, task)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	// タスクを子孫ごとゴミ箱に移す（読み込んでから他の変更で更新されていれば 412）
+	if status, message := h.deleteTask(userID, task); status == http.StatusPreconditionFailed {
+		return h.reloadPreconditionFailed(c, taskID)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Task moved to trash",
+	})
+}
+
+// deleteTask ユーザーのタスクを子孫ごとゴミ箱に移し、変更履歴の記録と変更イベントの通知を行う
+// 読み込んでから他の変更で更新されていれば 412 を返す（問題なければステータスコード 0 を返す）
+func (h *TaskHandler) deleteTask(userID string, task *models.Task) (int, string) {
+	deletedAt := time.Now()
+	err := h.inTx(func(tx *TaskHandler) error {
+		if err := tx.taskRepo.DeleteTask(task.ID, task.Version, deletedAt); err != nil {
+			if errors.Is(err, repository.ErrTaskVersionConflict) {
+				return newTaskError(http.StatusPreconditionFailed, taskModifiedMessage)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return newTaskError(http.StatusNotFound, "Task not found")
+			}
+			return err
+		}
+		deleted := *task
+		deleted.DeletedAt = &deletedAt
+		deleted.Version++
+		if err := tx.recordHistory(models.TaskHistoryDeleted, userID, task, &deleted); err != nil {
+			return err
+		}
+		tx.publish(models.EventTaskDeleted, &deleted)
+		return nil
+	})
+	if err != nil {
+		return taskErrorOf(err, "Failed to delete task")
+	}
+	return 0, ""
+}
+
+// MoveTask タスクをサブタスクごと別のプロジェクトに移動する（If-Match が必要）
+// 親タスクが別のプロジェクトに残る場合、移動したタスクは親から切り離される
+func (h *TaskHandler) MoveTask(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	taskID := c.Param("id")
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Task not found",
 		})
 	}
 
+	// タスクがユーザーのものかチェック
+	if task.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+	if status, message := checkTaskPrecondition(c, task); status == http.StatusPreconditionFailed {
+		return taskPreconditionFailed(c, task)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	var req models.MoveTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	if req.ProjectID == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "project_id is required",
+		})
+	}
+
+	// 読み込んでから他の変更で更新されていれば 412
+	var moved *models.Task
+	err = h.inTx(func(tx *TaskHandler) error {
+		if err := tx.taskRepo.MoveTask(taskID, task.Version, req.ProjectID, time.Now()); err != nil {
+			if errors.Is(err, repository.ErrTaskVersionConflict) {
+				return newTaskError(http.StatusPreconditionFailed, taskModifiedMessage)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return newTaskError(http.StatusNotFound, "Task not found")
+			}
+			if status, message, ok := taskProjectError(err); ok {
+				return newTaskError(status, message)
+			}
+			return err
+		}
+		var err error
+		if moved, err = tx.taskRepo.GetTaskByID(taskID); err != nil {
+			return err
+		}
+		if err := tx.recordHistory(models.TaskHistoryUpdated, userID, task, moved); err != nil {
+			return err
+		}
+		tx.publish(models.EventTaskUpdated, moved)
+		return nil
+	})
+	if err != nil {
+		status, message := taskErrorOf(err, "Failed to move task")
+		if status == http.StatusPreconditionFailed {
+			return h.reloadPreconditionFailed(c, taskID)
+		}
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	task = moved
+	c.Response().Header().Set(headerETag, taskETag(task))
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
 		"data":    task,
 	})
 }
 
-func (h *TaskHandler) DeleteTask(c echo.Context) error {
+// GetTaskChildren 直下のサブタスクを取得する
+func (h *TaskHandler) GetTaskChildren(c echo.Context) error {
 	userID := getUserIDFromContext(c)
 	if userID == "" {
 		return c.JSON(http.StatusUnauthorized, map[string]string{
@@ -154,8 +637,6 @@ func (h *TaskHandler) DeleteTask(c echo.Context) error {
 	}
 
 	taskID := c.Param("id")
-	
-	// タスクが存在するかチェック
 	task, err := h.taskRepo.GetTaskByID(taskID)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{
@@ -170,20 +651,117 @@ func (h *TaskHandler) DeleteTask(c echo.Context) error {
 		})
 	}
 
-	// データベースからタスクを削除
-	if err := h.taskRepo.DeleteTask(taskID); err != nil {
+	children, err := h.taskRepo.GetChildTasks(taskID)
+	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Failed to delete task",
+			"error": "Failed to get subtasks",
 		})
 	}
+	if children == nil {
+		children = []models.Task{}
+	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"success": true,
-		"message": "Task deleted successfully",
+		"data":    children,
+	})
+}
+
+// GetTaskTree タスクと子孫のサブタスクを木構造で取得する（各タスクに完了率を付与する）
+func (h *TaskHandler) GetTaskTree(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	taskID := c.Param("id")
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Task not found",
+		})
+	}
+
+	// タスクがユーザーのものかチェック
+	if task.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+
+	tasks, err := h.taskRepo.GetTaskSubtree(taskID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get task tree",
+		})
+	}
+	tree := models.BuildTaskTree(taskID, tasks)
+	if tree == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Task not found",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tree,
 	})
 }
 
 // ヘルパー関数
+// ownedTask ユーザーのタスクを取得する（取得できなければステータスコードとエラーメッセージを返す）
+func (h *TaskHandler) ownedTask(userID, taskID string) (*models.Task, int, string) {
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return nil, http.StatusNotFound, "Task not found"
+	}
+
+	// タスクがユーザーのものかチェック
+	if task.UserID != userID {
+		return nil, http.StatusForbidden, "Access denied"
+	}
+	return task, 0, ""
+}
+
+// recordHistory タスクの変更を履歴に記録する（作成した場合の before は nil）
+// 変更と同じトランザクションで記録するため、失敗した場合は変更も取り消すようエラーを返す
+func (h *TaskHandler) recordHistory(action, actorID string, before, after *models.Task) error {
+	return h.saveHistory(action, actorID, before, after, nil)
+}
+
+// recordRevert 以前の版の内容に戻した変更を履歴に記録する
+func (h *TaskHandler) recordRevert(actorID string, before, after *models.Task, revertedFrom int64) error {
+	return h.saveHistory(models.TaskHistoryReverted, actorID, before, after, &revertedFrom)
+}
+
+func (h *TaskHandler) saveHistory(action, actorID string, before, after *models.Task, revertedFrom *int64) error {
+	if h.historyRepo == nil {
+		return nil
+	}
+	entry, err := models.NewTaskHistoryEntry(utils.GenerateID(), action, actorID, before, after, time.Now())
+	if err != nil {
+		return err
+	}
+	entry.RevertedFrom = revertedFrom
+	return h.historyRepo.CreateTaskHistory(entry)
+}
+
+// publish タスクの変更イベントを通知する
+func (h *TaskHandler) publish(eventType string, task *models.Task) {
+	if h.events == nil {
+		return
+	}
+	h.events.PublishTaskEvent(&models.TaskEvent{
+		ID:         utils.GenerateID(),
+		Type:       eventType,
+		UserID:     task.UserID,
+		Task:       task,
+		OccurredAt: time.Now().UTC(),
+	})
+}
+
 func getUserIDFromContext(c echo.Context) string {
 	// JWTからユーザーIDを取得
 	userID := c.Get("user_id")
@@ -193,3 +771,29 @@ func getUserIDFromContext(c echo.Context) string {
 	return userID.(string)
 }
 
+// parentIDOf リクエストの parent_id を保存する値に変換する（空文字列は親なし）
+func parentIDOf(parentID *string) *string {
+	if parentID == nil || *parentID == "" {
+		return nil
+	}
+	return parentID
+}
+
+// projectIDOf リクエストの project_id を保存する値に変換する（空文字列は Inbox）
+func projectIDOf(projectID *string) string {
+	if projectID == nil {
+		return ""
+	}
+	return *projectID
+}
+
+// taskHierarchyError 親タスクの指定に関するエラーをレスポンスに変換する
+func taskHierarchyError(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, repository.ErrParentNotFound):
+		return http.StatusBadRequest, "Parent task not found", true
+	case errors.Is(err, repository.ErrTaskCycle):
+		return http.StatusConflict, "A task cannot be moved under itself or its subtasks", true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+)
+
+// errTaskBatchOperationFailed 一括操作の1件が失敗した（その操作の変更を取り消す）
+var errTaskBatchOperationFailed = errors.New("task batch operation failed")
+
+// BatchTasks 複数のタスクの作成・更新・完了・削除を1つのトランザクションで行う
+// 各操作は単独の API と同じ所有者の確認と検証を行い、操作ごとの結果を返す
+// atomic=true なら1つでも失敗すればすべて取り消し、409（サーバーのエラーなら 500）と失敗した操作を返す
+func (h *TaskHandler) BatchTasks(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.TaskBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	if len(req.Operations) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "operations is required",
+		})
+	}
+	if len(req.Operations) > models.MaxTaskBatchOperations {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "operations must contain at most " + strconv.Itoa(models.MaxTaskBatchOperations) + " items",
+		})
+	}
+
+	// 変更履歴の記録と変更イベントの通知は、コミットしてから反映した操作の分のみ行う
+	results := make([]models.TaskBatchResult, len(req.Operations))
+	failed := -1
+	err := h.inTx(func(tx *TaskHandler) error {
+		for i := range req.Operations {
+			operation := &req.Operations[i]
+
+			// 操作ごとにセーブポイントを置き、失敗した操作の変更のみを取り消す
+			err := tx.inTx(func(tx *TaskHandler) error {
+				results[i] = tx.applyBatchOperation(userID, operation)
+				if results[i].Error != "" {
+					return errTaskBatchOperationFailed
+				}
+				return nil
+			})
+			results[i].Index, results[i].Op = i, operation.Op
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, errTaskBatchOperationFailed) {
+				return err
+			}
+			if req.Atomic {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	if failed >= 0 {
+		return taskBatchRolledBack(c, req.Operations, results, failed)
+	}
+	if err != nil {
+		log.Printf("Failed to apply task batch: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to apply batch",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    results,
+	})
+}
+
+// applyBatchOperation 一括操作の1件を単独の API と同じ手順で実行する
+func (h *TaskHandler) applyBatchOperation(userID string, operation *models.TaskBatchOperation) models.TaskBatchResult {
+	if operation.Op == models.TaskBatchCreate {
+		if operation.Task == nil {
+			return taskBatchError("", http.StatusBadRequest, "task is required")
+		}
+		task, status, message := h.createTask(userID, operation.Task)
+		if task == nil {
+			return taskBatchError("", status, message)
+		}
+		return models.TaskBatchResult{ID: task.ID, Status: http.StatusCreated, Data: task}
+	}
+
+	var req *models.UpdateTaskRequest
+	switch operation.Op {
+	case models.TaskBatchUpdate:
+		if operation.Changes == nil {
+			return taskBatchError(operation.ID, http.StatusBadRequest, "changes is required")
+		}
+		req = operation.Changes
+	case models.TaskBatchComplete:
+		status := "completed"
+		req = &models.UpdateTaskRequest{Status: &status, Cascade: operation.Cascade}
+	case models.TaskBatchDelete:
+	default:
+		return taskBatchError(operation.ID, http.StatusBadRequest, "op must be one of create, update, complete, delete")
+	}
+
+	// PUT / DELETE /api/tasks/:id と同じ所有者の確認を行い、version を If-Match として扱う
+	task, status, message := h.ownedTask(userID, operation.ID)
+	if task == nil {
+		return taskBatchError(operation.ID, status, message)
+	}
+	if operation.Version == nil {
+		return taskBatchError(operation.ID, http.StatusPreconditionRequired, "version is required")
+	}
+	if *operation.Version != task.Version {
+		return h.taskBatchConflict(task.ID, task)
+	}
+
+	if operation.Op == models.TaskBatchDelete {
+		if status, message := h.deleteTask(userID, task); status == http.StatusPreconditionFailed {
+			return h.taskBatchConflict(task.ID, nil)
+		} else if status != 0 {
+			return taskBatchError(task.ID, status, message)
+		}
+		return models.TaskBatchResult{ID: task.ID, Status: http.StatusOK}
+	}
+
+	result, status, message := h.applyTaskUpdate(userID, task, req)
+	if status == http.StatusPreconditionFailed {
+		return h.taskBatchConflict(task.ID, nil)
+	}
+	if result == nil {
+		return taskBatchError(task.ID, status, message)
+	}
+	return models.TaskBatchResult{ID: task.ID, Status: http.StatusOK, Data: result.Task, NextOccurrence: result.NextOccurrence}
+}
+
+func taskBatchError(taskID string, status int, message string) models.TaskBatchResult {
+	return models.TaskBatchResult{ID: taskID, Status: status, Error: message}
+}
+
+// taskBatchConflict 412 と、クライアントがマージし直すための現在のタスクを返す（current が nil なら取得し直す）
+func (h *TaskHandler) taskBatchConflict(taskID string, current *models.Task) models.TaskBatchResult {
+	if current == nil {
+		var err error
+		if current, err = h.taskRepo.GetTaskByID(taskID); err != nil {
+			return taskBatchError(taskID, http.StatusNotFound, "Task not found")
+		}
+	}
+	result := taskBatchError(taskID, http.StatusPreconditionFailed, taskModifiedMessage)
+	result.Data = current
+	return result
+}
+
+// taskBatchRolledBack atomic の一括操作で操作が失敗し、すべて取り消した場合のレスポンス
+// 失敗した操作以外は、取り消した・実行しなかったことを 424 で示す（作成を取り消したタスクのIDは返さない）
+func taskBatchRolledBack(c echo.Context, operations []models.TaskBatchOperation, results []models.TaskBatchResult, failed int) error {
+	reason := "Operation " + strconv.Itoa(failed) + " failed"
+	for i, operation := range operations {
+		if i == failed {
+			continue
+		}
+		results[i] = models.TaskBatchResult{
+			Index:  i,
+			Op:     operation.Op,
+			ID:     operation.ID,
+			Status: http.StatusFailedDependency,
+			Error:  reason,
+		}
+	}
+
+	status := http.StatusConflict
+	if results[failed].Status >= http.StatusInternalServerError {
+		status = http.StatusInternalServerError
+	}
+	return c.JSON(status, map[string]interface{}{
+		"error":        reason + ": " + results[failed].Error,
+		"failed_index": failed,
+		"data":         results,
+	})
+}
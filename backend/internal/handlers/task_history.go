@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+)
+
+// GetTaskHistory タスクの変更履歴を新しい順に取得する（項目ごとの変更前後の値と、変更したユーザーを含む）
+func (h *TaskHandler) GetTaskHistory(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	task, status, message := h.ownedTask(userID, c.Param("id"))
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	entries, err := h.historyRepo.GetTaskHistory(task.ID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get task history",
+		})
+	}
+	if entries == nil {
+		entries = []models.TaskHistoryEntry{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    entries,
+	})
+}
+
+// RevertTask タスクの内容を履歴の版（:version）の時点に戻す（If-Match が必要）
+// 戻す変更も新しい版として履歴に記録する。繰り返しのルールとプロジェクトは戻さない
+func (h *TaskHandler) RevertTask(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	task, status, message := h.ownedTask(userID, c.Param("id"))
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	if status, message := checkTaskPrecondition(c, task); status == http.StatusPreconditionFailed {
+		return taskPreconditionFailed(c, task)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil || version < 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "version must be a positive integer",
+		})
+	}
+	entry, err := h.historyRepo.GetTaskRevision(task.ID, version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Revision not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get revision",
+		})
+	}
+
+	req, err := models.TaskRevertChanges(task, entry)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to revert task",
+		})
+	}
+	// 既にその版と同じ内容であれば、版数を増やさずに現在のタスクを返す
+	if *req == (models.UpdateTaskRequest{}) {
+		c.Response().Header().Set(headerETag, taskETag(task))
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"success": true,
+			"data":    task,
+		})
+	}
+	req.RevertedFrom = &version
+
+	result, status, message := h.applyTaskUpdate(userID, task, req)
+	if status == http.StatusPreconditionFailed {
+		return h.reloadPreconditionFailed(c, task.ID)
+	}
+	if result == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	c.Response().Header().Set(headerETag, taskETag(result.Task))
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    result.Task,
+	}
+	if result.NextOccurrence != nil {
+		response["next_occurrence"] = result.NextOccurrence
+	}
+	return c.JSON(http.StatusOK, response)
+}
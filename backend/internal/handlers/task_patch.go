@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/jsonpatch"
+	"todo-app-backend/internal/models"
+)
+
+// パッチ文書の最大サイズ
+const maxTaskPatchSize = 1 << 20
+
+// acceptPatch PATCH で受け付ける Content-Type（Accept-Patch ヘッダーで伝える）
+var acceptPatch = models.MergePatchContentType + ", " + models.JSONPatchContentType
+
+// PatchTask タスクを JSON Merge Patch または JSON Patch で部分更新する（If-Match が必要）
+// PUT と違い、null で説明・期限・親タスクを消せる。繰り返しの範囲は ?scope=、子孫の一括完了は ?cascade=true で指定する
+func (h *TaskHandler) PatchTask(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	mediaType, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if err != nil || (mediaType != models.MergePatchContentType && mediaType != models.JSONPatchContentType) {
+		c.Response().Header().Set("Accept-Patch", acceptPatch)
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{
+			"error": "Content-Type must be " + models.MergePatchContentType + " or " + models.JSONPatchContentType,
+		})
+	}
+
+	task, status, message := h.ownedTask(userID, c.Param("id"))
+	if task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	if status, message := checkTaskPrecondition(c, task); status == http.StatusPreconditionFailed {
+		return taskPreconditionFailed(c, task)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	patch, err := io.ReadAll(io.LimitReader(c.Request().Body, maxTaskPatchSize+1))
+	if err != nil || len(patch) > maxTaskPatchSize {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// パッチはタスクの文書にまとめて適用し、途中の操作が失敗した場合は何も変更しない
+	document, err := json.Marshal(models.NewTaskPatchDocument(task))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to patch task",
+		})
+	}
+	var patched []byte
+	if mediaType == models.MergePatchContentType {
+		patched, err = jsonpatch.MergePatch(document, patch)
+	} else {
+		patched, err = jsonpatch.Apply(document, patch)
+	}
+	if err != nil {
+		status, message := taskPatchError(err)
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	req, err := models.TaskPatchChanges(task, patched)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	req.Scope = c.QueryParam("scope")
+	req.Cascade = c.QueryParam("cascade") == "true"
+
+	result, status, message := h.applyTaskUpdate(userID, task, req)
+	if status == http.StatusPreconditionFailed {
+		return h.reloadPreconditionFailed(c, task.ID)
+	}
+	if result == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	c.Response().Header().Set(headerETag, taskETag(result.Task))
+
+	response := map[string]interface{}{
+		"success": true,
+		"data":    result.Task,
+	}
+	if result.NextOccurrence != nil {
+		response["next_occurrence"] = result.NextOccurrence
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// taskPatchError パッチを適用できなかった場合のステータスコードとメッセージ
+// パッチ文書が不正なら 400、test の不一致は 409、存在しないパスなど適用できない操作は 422
+func taskPatchError(err error) (int, string) {
+	var opErr *jsonpatch.OperationError
+	switch {
+	case errors.Is(err, jsonpatch.ErrInvalidPatch):
+		return http.StatusBadRequest, "Invalid patch document"
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		return http.StatusConflict, err.Error()
+	case errors.As(err, &opErr):
+		return http.StatusUnprocessableEntity, err.Error()
+	default:
+		return http.StatusInternalServerError, "Failed to patch task"
+	}
+}
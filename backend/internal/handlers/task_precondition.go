@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfMatch     = "If-Match"
+	headerIfNoneMatch = "If-None-Match"
+)
+
+// 他の変更で更新されていたタスクを更新・削除しようとした場合のエラーメッセージ
+const taskModifiedMessage = "Task has been modified"
+
+// taskETag タスクの版数から ETag を作る
+func taskETag(task *models.Task) string {
+	return `"` + strconv.FormatInt(task.Version, 10) + `"`
+}
+
+// matchTaskETag If-Match / If-None-Match の ETag の一覧にタスクの現在の版が含まれるか
+// If-Match は強い比較のため、弱い ETag（W/ 付き）は一致しない
+func matchTaskETag(header string, task *models.Task) bool {
+	current := taskETag(task)
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.TrimSpace(etag)
+		if etag == "*" || etag == current {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTaskPrecondition 更新・削除の If-Match ヘッダーを確認する（問題なければステータスコード 0 を返す）
+// 他の変更を上書きしないよう、If-Match は必須とする
+func checkTaskPrecondition(c echo.Context, task *models.Task) (int, string) {
+	header := c.Request().Header.Get(headerIfMatch)
+	if header == "" {
+		return http.StatusPreconditionRequired, "If-Match header is required"
+	}
+	if !matchTaskETag(header, task) {
+		return http.StatusPreconditionFailed, taskModifiedMessage
+	}
+	return 0, ""
+}
+
+// taskPreconditionFailed 412 と、クライアントがマージし直すための現在のタスクを返す
+func taskPreconditionFailed(c echo.Context, current *models.Task) error {
+	c.Response().Header().Set(headerETag, taskETag(current))
+	return c.JSON(http.StatusPreconditionFailed, map[string]interface{}{
+		"error": taskModifiedMessage,
+		"data":  current,
+	})
+}
+
+// reloadPreconditionFailed 更新・削除の時点で版数が変わっていた場合に、現在のタスクを取得し直して 412 を返す
+func (h *TaskHandler) reloadPreconditionFailed(c echo.Context, taskID string) error {
+	current, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Task not found",
+		})
+	}
+	return taskPreconditionFailed(c, current)
+}
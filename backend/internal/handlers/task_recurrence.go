@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/utils"
+)
+
+// SkipOccurrence 繰り返しタスクのこの回をスキップし、次の回に置き換える（If-Match が必要）
+// 系列の最後の回だった場合は系列を終了し、data は null になる
+func (h *TaskHandler) SkipOccurrence(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	taskID := c.Param("id")
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Task not found",
+		})
+	}
+
+	// タスクがユーザーのものかチェック
+	if task.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+	if status, message := checkTaskPrecondition(c, task); status == http.StatusPreconditionFailed {
+		return taskPreconditionFailed(c, task)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	if task.RRule == nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Task is not part of an active recurring series",
+		})
+	}
+	if task.Status == "completed" {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Completed occurrences cannot be skipped",
+		})
+	}
+
+	// 次の回の作成と系列の終了は、スキップと同じトランザクションで行う（読み込んでから他の変更で更新されていれば 412）
+	now := time.Now()
+	var next *models.Task
+	err = h.inTx(func(tx *TaskHandler) error {
+		var err error
+		if next, err = tx.nextOccurrence(task, now); err != nil {
+			return newTaskError(http.StatusInternalServerError, "Failed to compute the next occurrence")
+		}
+		if err := tx.taskRepo.SkipOccurrence(task.ID, task.Version, next, now); err != nil {
+			if errors.Is(err, repository.ErrTaskVersionConflict) {
+				return newTaskError(http.StatusPreconditionFailed, taskModifiedMessage)
+			}
+			if errors.Is(err, sql.ErrNoRows) {
+				return newTaskError(http.StatusNotFound, "Task not found")
+			}
+			return err
+		}
+
+		// スキップした回は削除と同じくゴミ箱に移る
+		skipped := *task
+		skipped.DeletedAt = &now
+		skipped.Version++
+		if err := tx.recordHistory(models.TaskHistoryDeleted, userID, task, &skipped); err != nil {
+			return err
+		}
+		tx.publish(models.EventTaskDeleted, &skipped)
+		if next == nil {
+			// 最後の回は、スキップしてから系列を終了する（先に終了するとこの回の版数が増え、版数の確認が失敗する）
+			return tx.endSeries(userID, &skipped, now)
+		}
+		if err := tx.recordHistory(models.TaskHistoryCreated, userID, nil, next); err != nil {
+			return err
+		}
+		tx.publish(models.EventTaskCreated, next)
+		return nil
+	})
+	if err != nil {
+		status, message := taskErrorOf(err, "Failed to skip occurrence")
+		if status == http.StatusPreconditionFailed {
+			return h.reloadPreconditionFailed(c, task.ID)
+		}
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    next,
+	})
+}
+
+// EndTaskSeries 繰り返しを終了する（このタスクは残り、以降の回は作成されない。If-Match が必要）
+func (h *TaskHandler) EndTaskSeries(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	taskID := c.Param("id")
+	task, err := h.taskRepo.GetTaskByID(taskID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"error": "Task not found",
+		})
+	}
+
+	// タスクがユーザーのものかチェック
+	if task.UserID != userID {
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Access denied",
+		})
+	}
+	if status, message := checkTaskPrecondition(c, task); status == http.StatusPreconditionFailed {
+		return taskPreconditionFailed(c, task)
+	} else if status != 0 {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	if task.RRule == nil {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Task is not part of an active recurring series",
+		})
+	}
+
+	// 系列の終了は系列のすべての回の版数を1つ増やすため、このタスクが読み込んだ版から
+	// ちょうど1つ増えていなければ、他の変更で更新されていたとして取り消して 412 を返す
+	ended := *task
+	err = h.inTx(func(tx *TaskHandler) error {
+		if err := tx.endSeries(userID, &ended, time.Now()); err != nil {
+			return err
+		}
+		if ended.Version != task.Version+1 {
+			return newTaskError(http.StatusPreconditionFailed, taskModifiedMessage)
+		}
+		return nil
+	})
+	if err != nil {
+		status, message := taskErrorOf(err, "Failed to end series")
+		if status == http.StatusPreconditionFailed {
+			return h.reloadPreconditionFailed(c, task.ID)
+		}
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	task = &ended
+	c.Response().Header().Set(headerETag, taskETag(task))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    task,
+	})
+}
+
+// setTaskRecurrence 繰り返しのルールを検証してタスクに設定する
+// 系列のないタスク（終了した系列を含む）は、期限を最初の回とする新しい系列になる
+func setTaskRecurrence(task *models.Task, rule string, timezone *string) error {
+	if task.Deadline == nil {
+		return errors.New("deadline is required for recurring tasks")
+	}
+
+	tz := ""
+	if timezone != nil {
+		tz = *timezone
+	} else if task.Timezone != nil {
+		tz = *task.Timezone
+	}
+	rule, tz, err := models.NormalizeRecurrence(rule, tz)
+	if err != nil {
+		return err
+	}
+
+	if task.RRule == nil {
+		occurrenceAt := task.Deadline.UTC()
+		task.SeriesID = nil
+		task.OccurrenceAt = &occurrenceAt
+	}
+	task.RRule, task.Timezone = &rule, &tz
+	return nil
+}
+
+// completeOccurrence 完了にした繰り返しタスクの次の回を作成する
+// 同じ回が既にある場合（完了の取り消し後に再度完了にした場合など）は nil を返す
+// 系列の最後の回だった場合は系列を終了し、task を終了後の版にする
+func (h *TaskHandler) completeOccurrence(actorID string, task *models.Task, now time.Time) (*models.Task, error) {
+	next, err := h.nextOccurrence(task, now)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, h.endSeries(actorID, task, now)
+	}
+	created, err := h.taskRepo.CreateOccurrence(next)
+	if err != nil || !created {
+		return nil, err
+	}
+	return next, nil
+}
+
+// nextOccurrence 繰り返しタスクの次の回を組み立てる（系列の最後の回だった場合や、繰り返しでない場合は nil）
+// 系列の終了はタスクの変更を保存してから endSeries で行う
+func (h *TaskHandler) nextOccurrence(task *models.Task, now time.Time) (*models.Task, error) {
+	if task.RRule == nil {
+		return nil, nil
+	}
+	series, err := h.taskRepo.GetTaskSeries(*task.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 今回のみ期限をずらしていても、本来の日時から次の回を計算する
+	after := task.OccurrenceAt
+	if after == nil {
+		after = task.Deadline
+	}
+	occurrenceAt, err := series.NextOccurrence(*after)
+	if err != nil || occurrenceAt == nil {
+		return nil, err
+	}
+
+	next := series.NewOccurrence(utils.GenerateID(), task, *occurrenceAt, now)
+	next.RRule, next.Timezone = &series.RRule, &series.Timezone
+	return next, nil
+}
+
+// endSeries task の系列を終了する
+// 系列のゴミ箱にない回は繰り返しのルールがなくなり版数が増えるため、回ごとに履歴を記録して変更イベントを通知する
+// task がゴミ箱になければ task も終了後の版にする
+func (h *TaskHandler) endSeries(actorID string, task *models.Task, now time.Time) error {
+	if task.RRule == nil {
+		return nil
+	}
+	ended, err := h.taskRepo.EndTaskSeries(*task.SeriesID, now)
+	if err != nil {
+		return err
+	}
+	for i := range ended {
+		occurrence := &ended[i]
+		// 終了による変更はルールの削除と版数のみ
+		before := *occurrence
+		before.RRule, before.Timezone = task.RRule, task.Timezone
+		before.Version--
+		if err := h.recordHistory(models.TaskHistoryUpdated, actorID, &before, occurrence); err != nil {
+			return err
+		}
+		h.publish(models.EventTaskUpdated, occurrence)
+		if occurrence.ID == task.ID {
+			*task = *occurrence
+		}
+	}
+	return nil
+}
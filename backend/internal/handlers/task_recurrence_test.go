@@ -0,0 +1,124 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"todo-app-backend/internal/models"
+)
+
+// lastOccurrence 1回だけの系列（COUNT=1）のタスク
+const lastOccurrence = `{"title":"once","priority":"low","deadline":"2026-01-01T09:00:00Z","rrule":"FREQ=DAILY;COUNT=1","timezone":"UTC"}`
+
+func TestSkipLastOccurrence(t *testing.T) {
+	api := newTaskAPI(t)
+	task := api.createTask(lastOccurrence)
+
+	rec := api.do(http.MethodPost, "/api/tasks/"+task.ID+"/skip", "", "If-Match", `"1"`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("skip = %d %s, want 200", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data *models.Task `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Data != nil {
+		t.Errorf("skip body = %s, want data null", rec.Body)
+	}
+
+	if _, err := api.repos.Tasks.GetTaskByID(task.ID); err == nil {
+		t.Error("skipped occurrence is not in the trash")
+	}
+	series, err := api.repos.Tasks.GetTaskSeries(*task.SeriesID)
+	if err != nil || series.EndedAt == nil {
+		t.Errorf("series after skipping the last occurrence = %+v, %v; want ended", series, err)
+	}
+}
+
+func TestCompleteLastOccurrence(t *testing.T) {
+	api := newTaskAPI(t)
+	task := api.createTask(lastOccurrence)
+	path := "/api/tasks/" + task.ID
+
+	// 完了にすると系列が終了してルールがなくなるため、レスポンスは終了後の版を返す
+	rec := api.do(http.MethodPut, path, `{"status":"completed"}`, "If-Match", `"1"`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("complete = %d %s, want 200", rec.Code, rec.Body)
+	}
+	completed := decodeTask(t, rec)
+	stored := api.storedTask(task.ID)
+	if completed.Version != stored.Version || rec.Header().Get("ETag") != `"3"` || stored.Version != 3 {
+		t.Errorf("response version %d, ETag %s; stored version %d, want 3", completed.Version, rec.Header().Get("ETag"), stored.Version)
+	}
+	if completed.RRule != nil || stored.RRule != nil {
+		t.Errorf("rrule after completing the last occurrence = %v, %v; want none", completed.RRule, stored.RRule)
+	}
+	entries, err := api.repos.TaskHistory.GetTaskHistory(task.ID)
+	if err != nil || len(entries) != 3 || entries[0].Version != stored.Version {
+		t.Errorf("history = %+v, %v; want the latest entry at version %d", entries, err, stored.Version)
+	}
+
+	// 返した ETag でそのまま次の変更ができる
+	rec = api.do(http.MethodPut, path, `{"title":"renamed"}`, "If-Match", rec.Header().Get("ETag"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("update with the returned ETag = %d %s, want 200", rec.Code, rec.Body)
+	}
+}
+
+func TestBatchCompleteLastOccurrence(t *testing.T) {
+	api := newTaskAPI(t)
+	task := api.createTask(lastOccurrence)
+
+	rec := api.do(http.MethodPost, "/api/tasks/batch", `{"operations":[{"op":"complete","id":"`+task.ID+`","version":1}]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch = %d %s, want 200", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data []struct {
+			Status int          `json:"status"`
+			Data   *models.Task `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Data) != 1 || body.Data[0].Data == nil {
+		t.Fatalf("batch body = %s, %v", rec.Body, err)
+	}
+	if got, want := body.Data[0].Data.Version, api.storedTask(task.ID).Version; got != want {
+		t.Errorf("batch result version = %d, want the stored version %d", got, want)
+	}
+}
+
+func TestEndTaskSeriesUpdatesEveryOccurrence(t *testing.T) {
+	api := newTaskAPI(t)
+	first := api.createTask(`{"title":"daily","priority":"low","deadline":"2026-01-01T09:00:00Z","rrule":"FREQ=DAILY","timezone":"UTC"}`)
+
+	rec := api.do(http.MethodPut, "/api/tasks/"+first.ID, `{"status":"completed"}`, "If-Match", `"1"`)
+	var completed struct {
+		NextOccurrence *models.Task `json:"next_occurrence"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &completed); rec.Code != http.StatusOK || err != nil || completed.NextOccurrence == nil {
+		t.Fatalf("complete = %d %s, want 200 with the next occurrence", rec.Code, rec.Body)
+	}
+	next := completed.NextOccurrence
+	api.events.events = nil
+
+	rec = api.do(http.MethodPost, "/api/tasks/"+next.ID+"/end-series", "", "If-Match", `"1"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("end-series = %d %s, ETag %s; want 200 and \"2\"", rec.Code, rec.Body, rec.Header().Get("ETag"))
+	}
+
+	// 版数が増えた他の回も、履歴と変更イベントで理由がわかる
+	want := []string{models.EventTaskUpdated + ":" + first.ID, models.EventTaskUpdated + ":" + next.ID}
+	if got := api.events.types(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	for _, id := range []string{first.ID, next.ID} {
+		stored := api.storedTask(id)
+		entries, err := api.repos.TaskHistory.GetTaskHistory(id)
+		if err != nil || len(entries) == 0 || entries[0].Version != stored.Version {
+			t.Fatalf("history of %s = %+v, %v; want the latest entry at version %d", id, entries, err, stored.Version)
+		}
+		if changes := entries[0].Changes; len(changes) != 2 || changes[0].Field != "rrule" || string(changes[0].New) != "null" {
+			t.Errorf("changes of %s = %+v, want rrule and timezone removed", id, changes)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+)
+
+// SearchTasks タスクのタイトル・説明を全文検索する（?q= の語をすべて含むタスクを一致の度合いの順に返す）
+// "..." で囲んだ語はフレーズ、末尾の * は前方一致。ゴミ箱のタスクは含めない
+func (h *TaskHandler) SearchTasks(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	query, err := models.ParseTaskSearchQuery(c.QueryParam("q"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	limit := models.DefaultTaskSearchLimit
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "limit must be a positive integer",
+			})
+		}
+		if limit > models.MaxTaskSearchLimit {
+			limit = models.MaxTaskSearchLimit
+		}
+	}
+
+	results, err := h.taskRepo.SearchTasks(userID, query, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to search tasks",
+		})
+	}
+	if results == nil {
+		results = []models.TaskSearchResult{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    results,
+	})
+}
@@ -0,0 +1,179 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/handlers"
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+)
+
+// testUserID テストのリクエストを送るユーザー（JWT の検証の代わりにミドルウェアで設定する）
+const testUserID = "u1"
+
+// taskAPI インメモリのリポジトリを使うタスクの API
+type taskAPI struct {
+	t      *testing.T
+	e      *echo.Echo
+	repos  *repository.Repositories
+	events *recordedEvents
+}
+
+// recordedEvents 通知された変更イベントを記録する
+type recordedEvents struct {
+	events []*models.TaskEvent
+}
+
+func (r *recordedEvents) PublishTaskEvent(event *models.TaskEvent) {
+	r.events = append(r.events, event)
+}
+
+// types 通知されたイベントの種類とタスクのIDを "type:id" の形で返す
+func (r *recordedEvents) types() []string {
+	types := make([]string, len(r.events))
+	for i, event := range r.events {
+		types[i] = event.Type + ":" + event.Task.ID
+	}
+	return types
+}
+
+func newTaskAPI(t *testing.T) *taskAPI {
+	t.Helper()
+
+	repos := repository.NewMemoryRepositories()
+	now := time.Now()
+	user := &models.User{ID: testUserID, Email: "u1@example.com", Password: "hash", Name: "User", CreatedAt: now, UpdatedAt: now}
+	if err := repos.Users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	events := &recordedEvents{}
+	h := handlers.NewTaskHandler(repos.Tasks, repos.TaskHistory, events)
+	e := echo.New()
+	api := e.Group("/api", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user_id", testUserID)
+			return next(c)
+		}
+	})
+	api.POST("/tasks", h.CreateTask)
+	api.POST("/tasks/batch", h.BatchTasks)
+	api.GET("/tasks/:id", h.GetTask)
+	api.PUT("/tasks/:id", h.UpdateTask)
+	api.PATCH("/tasks/:id", h.PatchTask)
+	api.DELETE("/tasks/:id", h.DeleteTask)
+	api.POST("/tasks/:id/skip", h.SkipOccurrence)
+	api.POST("/tasks/:id/end-series", h.EndTaskSeries)
+	api.GET("/tasks/:id/history", h.GetTaskHistory)
+	return &taskAPI{t: t, e: e, repos: repos, events: events}
+}
+
+// do リクエストを送り、レスポンスを返す（header は名前と値を交互に並べる）
+func (a *taskAPI) do(method, path, body string, header ...string) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	a.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// createTask タスクを作成し、作成したタスクを返す
+func (a *taskAPI) createTask(body string) *models.Task {
+	a.t.Helper()
+
+	rec := a.do(http.MethodPost, "/api/tasks", body)
+	if rec.Code != http.StatusCreated {
+		a.t.Fatalf("POST /api/tasks = %d %s", rec.Code, rec.Body)
+	}
+	return decodeTask(a.t, rec)
+}
+
+// storedTask 保存されているタスクを取得する
+func (a *taskAPI) storedTask(id string) *models.Task {
+	a.t.Helper()
+
+	task, err := a.repos.Tasks.GetTaskByID(id)
+	if err != nil {
+		a.t.Fatalf("GetTaskByID(%s): %v", id, err)
+	}
+	return task
+}
+
+// decodeTask レスポンスの data のタスクを返す
+func decodeTask(t *testing.T, rec *httptest.ResponseRecorder) *models.Task {
+	t.Helper()
+
+	var body struct {
+		Data *models.Task `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Data == nil {
+		t.Fatalf("decode task from %s: %v", rec.Body, err)
+	}
+	return body.Data
+}
+
+func TestUpdateTaskPrecondition(t *testing.T) {
+	api := newTaskAPI(t)
+	task := api.createTask(`{"title":"before","priority":"low"}`)
+	path := "/api/tasks/" + task.ID
+
+	rec := api.do(http.MethodGet, path, "")
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"1"` {
+		t.Fatalf("GET = %d, ETag %s; want 200 and \"1\"", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	// If-Match がなければ 428、古い版なら 412 と現在のタスク
+	if rec := api.do(http.MethodPut, path, `{"title":"after"}`); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("PUT without If-Match = %d, want 428", rec.Code)
+	}
+	rec = api.do(http.MethodPut, path, `{"title":"after"}`, "If-Match", `"2"`)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("PUT with a stale If-Match = %d, want 412", rec.Code)
+	}
+	if current := decodeTask(t, rec); current.Title != "before" || current.Version != 1 {
+		t.Errorf("412 body = %+v, want the current task", current)
+	}
+
+	rec = api.do(http.MethodPut, path, `{"title":"after"}`, "If-Match", `"1"`)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"2"` {
+		t.Fatalf("PUT = %d, ETag %s; want 200 and \"2\"", rec.Code, rec.Header().Get("ETag"))
+	}
+	if updated := api.storedTask(task.ID); updated.Title != "after" || updated.Version != 2 {
+		t.Errorf("stored task = %+v, want title after and version 2", updated)
+	}
+
+	entries, err := api.repos.TaskHistory.GetTaskHistory(task.ID)
+	if err != nil || len(entries) != 2 || entries[0].Action != models.TaskHistoryUpdated || entries[0].Version != 2 {
+		t.Errorf("history = %+v, %v; want updated v2 and created v1", entries, err)
+	}
+	want := []string{models.EventTaskCreated + ":" + task.ID, models.EventTaskUpdated + ":" + task.ID}
+	if got := api.events.types(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestPatchTaskJSONPatchNull(t *testing.T) {
+	api := newTaskAPI(t)
+	task := api.createTask(`{"title":"t","description":"d","deadline":"2026-01-01T09:00:00Z","priority":"low"}`)
+
+	patch := `[{"op":"test","path":"/parent_id","value":null},{"op":"replace","path":"/description","value":null},{"op":"replace","path":"/deadline","value":null}]`
+	rec := api.do(http.MethodPatch, "/api/tasks/"+task.ID, patch, "If-Match", `"1"`, echo.HeaderContentType, "application/json-patch+json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH = %d %s, want 200", rec.Code, rec.Body)
+	}
+	if stored := api.storedTask(task.ID); stored.Description != nil || stored.Deadline != nil {
+		t.Errorf("stored task = %+v, want description and deadline cleared", stored)
+	}
+}
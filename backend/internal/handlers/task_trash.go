@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+)
+
+// GetTrash ゴミ箱のタスクを削除日時の新しい順に取得する（親と一緒に削除したサブタスクは含めない）
+func (h *TaskHandler) GetTrash(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	tasks, err := h.taskRepo.GetDeletedTasks(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get trash",
+		})
+	}
+	if tasks == nil {
+		tasks = []models.Task{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    tasks,
+	})
+}
+
+// RestoreTask ゴミ箱のタスクを、一緒に削除したサブタスクとともに元に戻す
+// 親タスクがゴミ箱にある場合はトップレベルのタスクとして戻す
+func (h *TaskHandler) RestoreTask(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	taskID := c.Param("id")
+	deleted, status, message := h.deletedTask(userID, taskID)
+	if deleted == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	var task *models.Task
+	err := h.inTx(func(tx *TaskHandler) error {
+		if err := tx.taskRepo.RestoreTask(taskID, time.Now()); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return newTaskError(http.StatusConflict, "Task is not in the trash")
+			}
+			return err
+		}
+		var err error
+		if task, err = tx.taskRepo.GetTaskByID(taskID); err != nil {
+			return err
+		}
+		if err := tx.recordHistory(models.TaskHistoryRestored, userID, deleted, task); err != nil {
+			return err
+		}
+		tx.publish(models.EventTaskRestored, task)
+		return nil
+	})
+	if err != nil {
+		status, message := taskErrorOf(err, "Failed to restore task")
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	c.Response().Header().Set(headerETag, taskETag(task))
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    task,
+	})
+}
+
+// PurgeTask ゴミ箱のタスクをサブタスクごと完全に削除する（元に戻せない）
+func (h *TaskHandler) PurgeTask(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	taskID := c.Param("id")
+	if task, status, message := h.deletedTask(userID, taskID); task == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	if err := h.taskRepo.PurgeTask(taskID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Task is not in the trash",
+			})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to purge task",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Task purged successfully",
+	})
+}
+
+// deletedTask ゴミ箱にあるユーザーのタスクを取得する
+// 取得できない場合はステータスコードとメッセージを返す（ゴミ箱にないタスクは 409、存在しないタスクは 404）
+func (h *TaskHandler) deletedTask(userID, taskID string) (*models.Task, int, string) {
+	task, err := h.taskRepo.GetDeletedTaskByID(taskID)
+	if err != nil {
+		live, err := h.taskRepo.GetTaskByID(taskID)
+		if err != nil {
+			return nil, http.StatusNotFound, "Task not found"
+		}
+		if live.UserID != userID {
+			return nil, http.StatusForbidden, "Access denied"
+		}
+		return nil, http.StatusConflict, "Task is not in the trash"
+	}
+	if task.UserID != userID {
+		return nil, http.StatusForbidden, "Access denied"
+	}
+	return task, 0, ""
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+)
+
+// taskError トランザクション内で失敗した操作のステータスコードとエラーメッセージ（返すとトランザクションを取り消す）
+type taskError struct {
+	status  int
+	message string
+}
+
+func (e *taskError) Error() string {
+	return e.message
+}
+
+func newTaskError(status int, message string) error {
+	return &taskError{status: status, message: message}
+}
+
+// taskErrorOf エラーをステータスコードとエラーメッセージに変換する（taskError 以外は 500 と fallback）
+func taskErrorOf(err error, fallback string) (int, string) {
+	var taskErr *taskError
+	if errors.As(err, &taskErr) {
+		return taskErr.status, taskErr.message
+	}
+	log.Printf("%s: %v", fallback, err)
+	return http.StatusInternalServerError, fallback
+}
+
+// inTx タスクの変更を1つのトランザクションで行う
+// fn にはトランザクション内のリポジトリを使うハンドラーを渡し、fn がエラーを返せばすべて取り消す
+// 変更履歴は同じトランザクションで記録し、変更イベントの通知はコミットするまで保留する（入れ子にした場合は外側のコミットまで保留する）
+func (h *TaskHandler) inTx(fn func(tx *TaskHandler) error) error {
+	events := &pendingTaskEvents{}
+	err := h.taskRepo.WithTx(func(repo repository.TaskRepository, history repository.TaskHistoryRepository) error {
+		tx := &TaskHandler{taskRepo: repo, events: events}
+		if h.historyRepo != nil {
+			tx.historyRepo = history
+		}
+		return fn(tx)
+	})
+	if err != nil {
+		return err
+	}
+	for _, event := range events.events {
+		if h.events != nil {
+			h.events.PublishTaskEvent(event)
+		}
+	}
+	return nil
+}
+
+// pendingTaskEvents トランザクション内で通知する変更イベント（コミットするまで保留する）
+type pendingTaskEvents struct {
+	events []*models.TaskEvent
+}
+
+func (p *pendingTaskEvents) PublishTaskEvent(event *models.TaskEvent) {
+	p.events = append(p.events, event)
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/utils"
+)
+
+type ViewHandler struct {
+	viewRepo repository.ViewRepository
+	taskRepo repository.TaskRepository
+}
+
+func NewViewHandler(viewRepo repository.ViewRepository, taskRepo repository.TaskRepository) *ViewHandler {
+	return &ViewHandler{
+		viewRepo: viewRepo,
+		taskRepo: taskRepo,
+	}
+}
+
+// GetViews 保存したビューを作成順に返す
+func (h *ViewHandler) GetViews(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	views, err := h.viewRepo.GetViewsByUserID(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get views",
+		})
+	}
+	if views == nil {
+		views = []models.View{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    views,
+	})
+}
+
+// CreateView タスク一覧の条件と表示方法に名前を付けて保存する（条件は GET /api/tasks と同じく検証する）
+func (h *ViewHandler) CreateView(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateViewRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	view := models.View{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		Name:      req.Name,
+		Filters:   req.Filters,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if req.Display != nil {
+		view.Display = *req.Display
+	}
+	if err := view.Validate(); err != nil {
+		return invalidTaskFilters(c, err)
+	}
+
+	if err := h.viewRepo.CreateView(&view); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create view",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    view,
+	})
+}
+
+func (h *ViewHandler) GetView(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	view, status, message := h.ownedView(c.Param("id"), userID)
+	if view == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    view,
+	})
+}
+
+// UpdateView 名前・条件・表示方法を変更する（filters / display は指定した値に置き換える）
+func (h *ViewHandler) UpdateView(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	view, status, message := h.ownedView(c.Param("id"), userID)
+	if view == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	var req models.UpdateViewRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Name != nil {
+		view.Name = *req.Name
+	}
+	if req.Filters != nil {
+		view.Filters = *req.Filters
+	}
+	if req.Display != nil {
+		view.Display = *req.Display
+	}
+	if err := view.Validate(); err != nil {
+		return invalidTaskFilters(c, err)
+	}
+	view.UpdatedAt = time.Now()
+
+	if err := h.viewRepo.UpdateView(view); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update view",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    view,
+	})
+}
+
+// DeleteView ビューを削除する（タスクには影響しない）
+func (h *ViewHandler) DeleteView(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	view, status, message := h.ownedView(c.Param("id"), userID)
+	if view == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	if err := h.viewRepo.DeleteView(view.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete view",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "View deleted successfully",
+	})
+}
+
+// GetViewTasks ビューの条件でタスクを取得する
+// GET /api/tasks と同じ処理で絞り込み・並び替えを行い、ページング（limit / cursor）もクエリパラメータで指定する
+func (h *ViewHandler) GetViewTasks(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	view, status, message := h.ownedView(c.Param("id"), userID)
+	if view == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	return listTasks(c, h.taskRepo, userID, &view.Filters)
+}
+
+// ownedView ユーザーのビューを取得する（取得できない場合はステータスとメッセージを返す）
+func (h *ViewHandler) ownedView(viewID, userID string) (*models.View, int, string) {
+	view, err := h.viewRepo.GetViewByID(viewID)
+	if err != nil {
+		return nil, http.StatusNotFound, "View not found"
+	}
+
+	// ビューがユーザーのものかチェック
+	if view.UserID != userID {
+		return nil, http.StatusForbidden, "Access denied"
+	}
+	return view, 0, ""
+}
@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/services"
+	"todo-app-backend/internal/utils"
+)
+
+type WebhookHandler struct {
+	webhookRepo repository.WebhookRepository
+	dispatcher  *services.WebhookDispatcher
+}
+
+func NewWebhookHandler(webhookRepo repository.WebhookRepository, dispatcher *services.WebhookDispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo: webhookRepo,
+		dispatcher:  dispatcher,
+	}
+}
+
+// GetWebhooks ユーザーの Webhook を取得する（署名の鍵は含めない）
+func (h *WebhookHandler) GetWebhooks(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	webhooks, err := h.webhookRepo.GetWebhooksByUserID(userID)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get webhooks",
+		})
+	}
+	if webhooks == nil {
+		webhooks = []models.Webhook{}
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    webhooks,
+	})
+}
+
+// CreateWebhook Webhook を登録する
+// 署名の鍵はこのレスポンスでのみ返す（省略時はサーバーで生成する）
+func (h *WebhookHandler) CreateWebhook(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	webhook := models.Webhook{
+		ID:        utils.GenerateID(),
+		UserID:    userID,
+		URL:       req.URL,
+		Events:    req.Events,
+		Active:    true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	} else {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to generate secret",
+			})
+		}
+		webhook.Secret = secret
+	}
+	if err := webhook.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.webhookRepo.CreateWebhook(&webhook); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create webhook",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"success": true,
+		"data":    webhook,
+	})
+}
+
+func (h *WebhookHandler) GetWebhook(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	webhook, status, message := h.ownedWebhook(c.Param("id"), userID)
+	if webhook == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	webhook.Secret = ""
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    webhook,
+	})
+}
+
+// UpdateWebhook 送信先・購読イベント・有効/無効を変更する
+// 無効にした Webhook の送信待ちの配信は、有効に戻すと送信される
+func (h *WebhookHandler) UpdateWebhook(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	webhook, status, message := h.ownedWebhook(c.Param("id"), userID)
+	if webhook == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = *req.Events
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if err := webhook.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	webhook.UpdatedAt = time.Now()
+
+	if err := h.webhookRepo.UpdateWebhook(webhook); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update webhook",
+		})
+	}
+	webhook.Secret = ""
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    webhook,
+	})
+}
+
+// DeleteWebhook Webhook を配信ログごと削除する
+func (h *WebhookHandler) DeleteWebhook(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	webhook, status, message := h.ownedWebhook(c.Param("id"), userID)
+	if webhook == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	if err := h.webhookRepo.DeleteWebhook(webhook.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to delete webhook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Webhook deleted successfully",
+	})
+}
+
+// GetWebhookDeliveries 配信ログを新しい順に取得する（status・limit で絞り込む）
+func (h *WebhookHandler) GetWebhookDeliveries(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	webhook, status, message := h.ownedWebhook(c.Param("id"), userID)
+	if webhook == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	var filters models.WebhookDeliveryFilters
+	if err := c.Bind(&filters); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid query parameters",
+		})
+	}
+	if err := filters.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": err.Error(),
+		})
+	}
+	deliveryStatus := ""
+	if filters.Status != nil {
+		deliveryStatus = *filters.Status
+	}
+
+	deliveries, err := h.webhookRepo.GetWebhookDeliveries(webhook.ID, deliveryStatus, filters.PageSize())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to get webhook deliveries",
+		})
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    deliveries,
+	})
+}
+
+func (h *WebhookHandler) GetWebhookDelivery(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	delivery, status, message := h.ownedDelivery(c.Param("id"), c.Param("delivery_id"), userID)
+	if delivery == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    delivery,
+	})
+}
+
+// ReplayWebhookDelivery 同じイベントを新しい配信としてもう一度送信する
+// 送信待ちの配信はそのまま送信されるため、リプレイできない
+func (h *WebhookHandler) ReplayWebhookDelivery(c echo.Context) error {
+	userID := getUserIDFromContext(c)
+	if userID == "" {
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	original, status, message := h.ownedDelivery(c.Param("id"), c.Param("delivery_id"), userID)
+	if original == nil {
+		return c.JSON(status, map[string]string{
+			"error": message,
+		})
+	}
+	if original.Status == models.WebhookDeliveryPending {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"error": "Delivery is still pending",
+		})
+	}
+
+	delivery, err := h.dispatcher.Replay(original, time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to replay webhook delivery",
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, map[string]interface{}{
+		"success": true,
+		"data":    delivery,
+	})
+}
+
+// ownedWebhook ユーザーの Webhook を取得する（取得できなければステータスコードとエラーメッセージを返す）
+func (h *WebhookHandler) ownedWebhook(webhookID, userID string) (*models.Webhook, int, string) {
+	webhook, err := h.webhookRepo.GetWebhookByID(webhookID)
+	if err != nil {
+		return nil, http.StatusNotFound, "Webhook not found"
+	}
+
+	// Webhook がユーザーのものかチェック
+	if webhook.UserID != userID {
+		return nil, http.StatusForbidden, "Access denied"
+	}
+	return webhook, 0, ""
+}
+
+// ownedDelivery ユーザーの Webhook の配信を取得する
+func (h *WebhookHandler) ownedDelivery(webhookID, deliveryID, userID string) (*models.WebhookDelivery, int, string) {
+	webhook, status, message := h.ownedWebhook(webhookID, userID)
+	if webhook == nil {
+		return nil, status, message
+	}
+
+	delivery, err := h.webhookRepo.GetWebhookDeliveryByID(deliveryID)
+	if err != nil || delivery.WebhookID != webhook.ID {
+		return nil, http.StatusNotFound, "Delivery not found"
+	}
+	return delivery, 0, ""
+}
+
+// newWebhookSecret 署名の鍵を生成する
+func newWebhookSecret() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
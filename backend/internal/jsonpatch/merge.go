@@ -0,0 +1,43 @@
+// Package jsonpatch JSON 文書に JSON Merge Patch (RFC 7396) と JSON Patch (RFC 6902) を適用する
+// 文書は encoding/json でデコードした値（map[string]interface{}, []interface{} など）として扱う
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidPatch パッチ文書がJSONとして不正、または形式が合わない
+var ErrInvalidPatch = errors.New("invalid patch document")
+
+// MergePatch 文書に JSON Merge Patch を適用した結果を返す
+// パッチのオブジェクトの null のメンバーは文書から削除し、オブジェクト以外の値は置き換える
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, merge interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &merge); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	return json.Marshal(mergeValue(target, merge))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	object, ok := target.(map[string]interface{})
+	if !ok {
+		object = map[string]interface{}{}
+	}
+	for key, value := range members {
+		if value == nil {
+			delete(object, key)
+			continue
+		}
+		object[key] = mergeValue(object[key], value)
+	}
+	return object
+}
@@ -0,0 +1,90 @@
+package jsonpatch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestArrayIndex(t *testing.T) {
+	tests := []struct {
+		token string
+		want  int
+		ok    bool
+	}{
+		{"0", 0, true},
+		{"2", 2, true},
+		{"10", 10, true},
+		{"", 0, false},
+		{"01", 0, false},
+		{"00", 0, false},
+		{"+1", 0, false},
+		{"-0", 0, false},
+		{"-1", 0, false},
+		{" 1", 0, false},
+		{"1e1", 0, false},
+		{"11", 0, false},
+		{"99999999999999999999", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := arrayIndex(tt.token, 10)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("arrayIndex(%q) = %d, %v; want %d, ok=%v", tt.token, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	const doc = `{"title":"a","description":"d","tags":["x","y"],"meta":{"n":1}}`
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"add null", `[{"op":"add","path":"/deadline","value":null}]`,
+			`{"deadline":null,"description":"d","meta":{"n":1},"tags":["x","y"],"title":"a"}`},
+		{"replace with null", `[{"op":"replace","path":"/description","value":null}]`,
+			`{"description":null,"meta":{"n":1},"tags":["x","y"],"title":"a"}`},
+		{"test null", `[{"op":"add","path":"/deadline","value":null},{"op":"test","path":"/deadline","value":null}]`,
+			`{"deadline":null,"description":"d","meta":{"n":1},"tags":["x","y"],"title":"a"}`},
+		{"append with -", `[{"op":"add","path":"/tags/-","value":"z"}]`,
+			`{"description":"d","meta":{"n":1},"tags":["x","y","z"],"title":"a"}`},
+		{"insert before an index", `[{"op":"add","path":"/tags/0","value":"w"}]`,
+			`{"description":"d","meta":{"n":1},"tags":["w","x","y"],"title":"a"}`},
+		{"move", `[{"op":"move","from":"/title","path":"/meta/title"}]`,
+			`{"description":"d","meta":{"n":1,"title":"a"},"tags":["x","y"]}`},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(doc), []byte(tt.patch))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: Apply = %s, %v; want %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	const doc = `{"title":"a","tags":["x"],"meta":{"n":1}}`
+	tests := []struct {
+		name  string
+		patch string
+		want  error
+	}{
+		{"test mismatch", `[{"op":"test","path":"/title","value":"b"}]`, ErrTestFailed},
+		{"test null against a value", `[{"op":"test","path":"/title","value":null}]`, ErrTestFailed},
+		{"missing value", `[{"op":"add","path":"/deadline"}]`, nil},
+		{"move into a child", `[{"op":"move","from":"/meta","path":"/meta/inner"}]`, nil},
+		{"- is not an existing element", `[{"op":"replace","path":"/tags/-","value":"z"}]`, nil},
+		{"index out of range", `[{"op":"add","path":"/tags/2","value":"z"}]`, nil},
+		{"index with a sign", `[{"op":"add","path":"/tags/+1","value":"z"}]`, nil},
+	}
+	for _, tt := range tests {
+		got, err := Apply([]byte(doc), []byte(tt.patch))
+		var opErr *OperationError
+		if !errors.As(err, &opErr) || opErr.Index != 0 {
+			t.Errorf("%s: Apply = %s, %v; want an error for operation 0", tt.name, got, err)
+			continue
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
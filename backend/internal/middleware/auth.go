@@ -6,17 +6,18 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
-	"todo-app-backend/internal/config"
 	"todo-app-backend/internal/services"
 )
 
-func JWTAuth(cfg *config.Config) echo.MiddlewareFunc {
-	jwtService := services.NewJWTService(cfg)
-	
+func JWTAuth(jwtService *services.JWTService) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Authorizationヘッダーを取得
 			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				// ブラウザの WebSocket はヘッダーを付けられないため、サブプロトコルで送られたトークンを使う
+				authHeader = webSocketBearer(c.Request())
+			}
 			if authHeader == "" {
 				return c.JSON(http.StatusUnauthorized, map[string]string{
 					"error": "Authorization header required",
@@ -64,8 +65,18 @@ func JWTAuth(cfg *config.Config) echo.MiddlewareFunc {
 	}
 }
 
-
-
-
-
-
+// webSocketBearer WebSocket の接続要求のサブプロトコル "bearer.<トークン>" を Authorization ヘッダーの形式で返す
+// トークンを URL に含めるとアクセスログなどに残るため、クエリパラメーターでは受け付けない
+func webSocketBearer(req *http.Request) string {
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return ""
+	}
+	for _, value := range req.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(value, ",") {
+			if token, ok := strings.CutPrefix(strings.TrimSpace(protocol), "bearer."); ok && token != "" {
+				return "Bearer " + token
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+// WebSocket でクライアントが送るメッセージの種類
+const (
+	// CollabSubscribe トピックを購読し、プレゼンスに参加する
+	CollabSubscribe   = "subscribe"
+	CollabUnsubscribe = "unsubscribe"
+	// CollabPresence 購読中のトピックでの状態（閲覧中・編集中）を変更する
+	CollabPresence = "presence"
+	// CollabUpdate タスクを更新する（PUT /api/tasks/:id と同じ検証を行う）
+	CollabUpdate = "update"
+	CollabPing   = "ping"
+)
+
+// WebSocket でサーバーが送るメッセージの種類（このほかにタスクの変更イベントの種類を使う）
+const (
+	// CollabHello 接続した直後に、この接続のセッションIDを伝える
+	CollabHello = "hello"
+	// CollabAck クライアントのメッセージを処理した（ref で対応付ける）
+	CollabAck   = "ack"
+	CollabError = "error"
+	CollabPong  = "pong"
+)
+
+// プレゼンスの状態
+const (
+	PresenceViewing = "viewing"
+	PresenceEditing = "editing"
+)
+
+// 購読できるトピックの種類（"project:<ID>" または "task:<ID>"）
+const (
+	CollabTopicProject = "project"
+	CollabTopicTask    = "task"
+)
+
+// CollabClientMessage クライアントから受信するメッセージ
+type CollabClientMessage struct {
+	Type string `json:"type"`
+	// Ref 応答（ack / error）に付けて返す、クライアントが決めるID
+	Ref   string `json:"ref,omitempty"`
+	Topic string `json:"topic,omitempty"`
+	// State presence の状態（viewing または editing）
+	State string `json:"state,omitempty"`
+	// TaskID, Changes update で更新するタスクと変更内容
+	TaskID  string             `json:"task_id,omitempty"`
+	Changes *UpdateTaskRequest `json:"changes,omitempty"`
+	// Version update で変更の元にしたタスクの版数（REST API の If-Match に当たる）
+	Version *int64 `json:"version,omitempty"`
+}
+
+// CollabServerMessage クライアントに送信するメッセージ
+type CollabServerMessage struct {
+	Type  string `json:"type"`
+	Ref   string `json:"ref,omitempty"`
+	Topic string `json:"topic,omitempty"`
+	// SessionID hello の場合のこの接続のID（プレゼンスの参加者から自分を見分けるために使う）
+	SessionID string `json:"session_id,omitempty"`
+	// Status, Error エラーの場合の REST API と同じステータスコードとメッセージ
+	Status int         `json:"status,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	Data   interface{} `json:"data,omitempty"`
+	// NextOccurrence 繰り返しタスクを完了にした場合に作成した次の回
+	NextOccurrence *Task `json:"next_occurrence,omitempty"`
+	// Members presence の場合のトピックの参加者
+	Members []PresenceMember `json:"members,omitempty"`
+}
+
+// PresenceMember トピックに参加している接続
+type PresenceMember struct {
+	// SessionID 接続ごとのID（同じユーザーが複数のタブ・端末で接続している場合を区別する）
+	SessionID string `json:"session_id"`
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"`
+	State     string `json:"state"`
+}
+
+// ParseCollabTopic トピックを種類とIDに分ける
+func ParseCollabTopic(topic string) (string, string, error) {
+	kind, id, ok := strings.Cut(topic, ":")
+	if !ok || id == "" || (kind != CollabTopicProject && kind != CollabTopicTask) {
+		return "", "", errors.New("topic must be project:<id> or task:<id>")
+	}
+	return kind, id, nil
+}
+
+// IsPresenceState プレゼンスの状態として有効か
+func IsPresenceState(state string) bool {
+	return state == PresenceViewing || state == PresenceEditing
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// タスクの変更イベントの種類
+const (
+	EventTaskCreated = "task.created"
+	EventTaskUpdated = "task.updated"
+	// EventTaskCompleted 未完了から完了になった（task.updated と合わせて発行される）
+	EventTaskCompleted = "task.completed"
+	// EventTaskDeleted ゴミ箱に移した（完全な削除ではイベントを発行しない）
+	EventTaskDeleted = "task.deleted"
+	// EventTaskRestored ゴミ箱から元に戻した（一緒に戻した子孫はイベントを発行しない）
+	EventTaskRestored = "task.restored"
+)
+
+// TaskEvents 購読できるイベントの一覧
+var TaskEvents = []string{EventTaskCreated, EventTaskUpdated, EventTaskCompleted, EventTaskDeleted, EventTaskRestored}
+
+// TaskEvent タスクの変更イベント
+type TaskEvent struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	UserID string `json:"-"`
+	// Task 変更後のタスク（task.deleted の場合は削除前のタスク）
+	Task       *Task     `json:"data"`
+	OccurredAt time.Time `json:"created_at"`
+}
+
+// IsTaskEvent 購読できるイベントか
+func IsTaskEvent(event string) bool {
+	for _, e := range TaskEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 登録時に作成されるプロジェクトの名前と色
+const (
+	InboxProjectName    = "Inbox"
+	DefaultProjectColor = "#8c8c8c"
+)
+
+var projectColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+type Project struct {
+	ID       string `json:"id" db:"id"`
+	UserID   string `json:"user_id" db:"user_id"`
+	Name     string `json:"name" db:"name"`
+	Color    string `json:"color" db:"color"`
+	Position int    `json:"position" db:"position"`
+	Archived bool   `json:"archived" db:"archived"`
+	// IsInbox 登録時に作成される既定のプロジェクト（削除・アーカイブできない）
+	IsInbox   bool      `json:"is_inbox" db:"is_inbox"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateProjectRequest struct {
+	Name  string  `json:"name" validate:"required"`
+	Color *string `json:"color,omitempty"`
+}
+
+type UpdateProjectRequest struct {
+	Name     *string `json:"name,omitempty"`
+	Color    *string `json:"color,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+}
+
+// ReorderProjectsRequest 指定した順にプロジェクトの並び順を振り直す
+type ReorderProjectsRequest struct {
+	ProjectIDs []string `json:"project_ids" validate:"required"`
+}
+
+// MoveTaskRequest タスクを別のプロジェクトに移動する
+type MoveTaskRequest struct {
+	ProjectID string `json:"project_id" validate:"required"`
+}
+
+// Validate プロジェクト名と色を検証する
+func (p *Project) Validate() error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("project name is required")
+	}
+	if utf8.RuneCountInString(p.Name) > 100 {
+		return errors.New("project name must be at most 100 characters")
+	}
+	if !projectColorPattern.MatchString(p.Color) {
+		return errors.New("color must be a hex color like #1677ff")
+	}
+	return nil
+}
+
+// NewInboxProject ユーザーの既定のプロジェクトを作成する
+func NewInboxProject(id, userID string, now time.Time) *Project {
+	return &Project{
+		ID:        id,
+		UserID:    userID,
+		Name:      InboxProjectName,
+		Color:     DefaultProjectColor,
+		IsInbox:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
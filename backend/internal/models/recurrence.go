@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	// 実行環境（alpine イメージなど）にタイムゾーンデータがなくても LoadLocation できるよう埋め込む
+	_ "time/tzdata"
+
+	"github.com/teambition/rrule-go"
+)
+
+// DefaultRecurrenceTimezone タイムゾーン省略時に繰り返しを計算するタイムゾーン
+const DefaultRecurrenceTimezone = "UTC"
+
+// TaskSeries 繰り返しタスクの系列
+// 完了した回の次の回は、ルールと系列のタイトル・説明・優先度から作成する
+type TaskSeries struct {
+	ID     string `json:"id" db:"id"`
+	UserID string `json:"user_id" db:"user_id"`
+	// RRule iCalendar の RRULE（DTSTART を除く、例: FREQ=WEEKLY;BYDAY=MO）
+	RRule string `json:"rrule" db:"rrule"`
+	// Timezone 繰り返しを計算する IANA タイムゾーン（夏時間の切り替えをまたいでも現地時刻を保つ）
+	Timezone    string     `json:"timezone" db:"timezone"`
+	DTStart     time.Time  `json:"dtstart" db:"dtstart"`
+	Title       string     `json:"title" db:"title"`
+	Description *string    `json:"description,omitempty" db:"description"`
+	Priority    string     `json:"priority" db:"priority"`
+	EndedAt     *time.Time `json:"ended_at,omitempty" db:"ended_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// NormalizeRecurrence RRULE とタイムゾーンを検証し、保存する形式に揃える
+// "RRULE:" の接頭辞は省略でき、DTSTART はタスクの期限を使うため指定できない
+func NormalizeRecurrence(rule, timezone string) (string, string, error) {
+	if timezone == "" {
+		timezone = DefaultRecurrenceTimezone
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return "", "", fmt.Errorf("unknown timezone: %s", timezone)
+	}
+
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return "", "", errors.New("rrule is required")
+	}
+	if strings.Contains(strings.ToUpper(rule), "DTSTART") || strings.Contains(rule, "\n") {
+		return "", "", errors.New("rrule must not contain DTSTART; the task deadline is used as the start")
+	}
+	option, err := rrule.StrToROptionInLocation(strings.ToUpper(rule), loc)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid rrule: %v", err)
+	}
+	if _, err := rrule.NewRRule(*option); err != nil {
+		return "", "", fmt.Errorf("invalid rrule: %v", err)
+	}
+	return option.RRuleString(), timezone, nil
+}
+
+// NextOccurrence after より後の最初の回を返す（系列が終わっている場合は nil）
+// 計算は系列のタイムゾーンの現地時刻で行うため、夏時間の前後でも同じ時刻に繰り返す
+func (s *TaskSeries) NextOccurrence(after time.Time) (*time.Time, error) {
+	if s.EndedAt != nil {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	option, err := rrule.StrToROptionInLocation(s.RRule, loc)
+	if err != nil {
+		return nil, err
+	}
+	option.Dtstart = s.DTStart.In(loc)
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, err
+	}
+
+	next := rule.After(after.In(loc), false)
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+// NewOccurrence 完了した回 prev に続く回のタスクを作成する
+// タイトル・説明・優先度は系列から、プロジェクト・親タスク・タグは前の回から引き継ぐ
+func (s *TaskSeries) NewOccurrence(id string, prev *Task, occurrenceAt, now time.Time) *Task {
+	deadline := occurrenceAt
+	return &Task{
+		ID:           id,
+		UserID:       prev.UserID,
+		ProjectID:    prev.ProjectID,
+		ParentID:     prev.ParentID,
+		Title:        s.Title,
+		Description:  s.Description,
+		Deadline:     &deadline,
+		Priority:     s.Priority,
+		Status:       "pending",
+		Tags:         prev.Tags,
+		SeriesID:     &s.ID,
+		OccurrenceAt: &occurrenceAt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// NewTaskSeries タスクを最初の回とする系列を作成する（task.RRule と task.Timezone は正規化済みであること）
+func NewTaskSeries(id string, task *Task) *TaskSeries {
+	return &TaskSeries{
+		ID:          id,
+		UserID:      task.UserID,
+		RRule:       *task.RRule,
+		Timezone:    *task.Timezone,
+		DTStart:     *task.OccurrenceAt,
+		Title:       task.Title,
+		Description: task.Description,
+		Priority:    task.Priority,
+		CreatedAt:   task.UpdatedAt,
+		UpdatedAt:   task.UpdatedAt,
+	}
+}
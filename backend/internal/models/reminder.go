@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// リマインダーの種類
+const (
+	// ReminderBeforeDeadline 期限の OffsetMinutes 分前に通知する
+	ReminderBeforeDeadline = "before_deadline"
+	// ReminderDueDay 期限日の TimeOfDay（Timezone の現地時刻）に通知する
+	ReminderDueDay = "due_day"
+)
+
+// MaxReminderOffset 期限の何分前まで通知を設定できるか（スケジューラーが期限を先読みする範囲）
+const MaxReminderOffset = 7 * 24 * time.Hour
+
+// リマインダーの配信状態
+const (
+	DeliverySending = "sending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	// DeliverySkipped 停止中などで通知時刻から猶予を過ぎたため配信しなかった
+	DeliverySkipped = "skipped"
+)
+
+type Reminder struct {
+	ID            string    `json:"id" db:"id"`
+	TaskID        string    `json:"task_id" db:"task_id"`
+	Kind          string    `json:"kind" db:"kind"`
+	OffsetMinutes int       `json:"offset_minutes,omitempty" db:"offset_minutes"`
+	TimeOfDay     string    `json:"time_of_day,omitempty" db:"time_of_day"` // HH:MM
+	Timezone      string    `json:"timezone,omitempty" db:"timezone"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateReminderRequest struct {
+	Kind          string `json:"kind" validate:"required,oneof=before_deadline due_day"`
+	OffsetMinutes int    `json:"offset_minutes,omitempty"`
+	TimeOfDay     string `json:"time_of_day,omitempty"`
+	Timezone      string `json:"timezone,omitempty"`
+}
+
+// DueReminder スケジューラーが評価するリマインダーと、通知に使うタスク・ユーザーの情報
+type DueReminder struct {
+	Reminder
+	TaskTitle string
+	Deadline  time.Time
+	UserID    string
+	UserEmail string
+}
+
+// ReminderDelivery リマインダーの回（通知時刻）ごと・チャネルごとの配信記録
+// 再起動をまたいで同じ回を二重に配信しないために使う
+type ReminderDelivery struct {
+	ReminderID string    `json:"reminder_id" db:"reminder_id"`
+	FireAt     time.Time `json:"fire_at" db:"fire_at"`
+	Channel    string    `json:"channel" db:"channel"`
+	Status     string    `json:"status" db:"status"`
+	Attempts   int       `json:"attempts" db:"attempts"`
+	LastError  *string   `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Validate 種類ごとに必要な項目を検証する（使わない項目は空にする）
+func (r *Reminder) Validate() error {
+	switch r.Kind {
+	case ReminderBeforeDeadline:
+		if r.OffsetMinutes < 0 || time.Duration(r.OffsetMinutes)*time.Minute > MaxReminderOffset {
+			return fmt.Errorf("offset_minutes must be between 0 and %d", int(MaxReminderOffset/time.Minute))
+		}
+		r.TimeOfDay, r.Timezone = "", ""
+	case ReminderDueDay:
+		if _, err := time.Parse("15:04", r.TimeOfDay); err != nil {
+			return errors.New("time_of_day must be in HH:MM format")
+		}
+		if r.Timezone == "" {
+			r.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone: %s", r.Timezone)
+		}
+		r.OffsetMinutes = 0
+	default:
+		return errors.New("kind must be one of before_deadline, due_day")
+	}
+	return nil
+}
+
+// FireAt 期限に対する通知時刻（UTC、秒未満は切り捨て）
+func (r *Reminder) FireAt(deadline time.Time) (time.Time, error) {
+	switch r.Kind {
+	case ReminderBeforeDeadline:
+		return deadline.Add(-time.Duration(r.OffsetMinutes) * time.Minute).UTC().Truncate(time.Second), nil
+	case ReminderDueDay:
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		clock, err := time.Parse("15:04", r.TimeOfDay)
+		if err != nil {
+			return time.Time{}, err
+		}
+		local := deadline.In(loc)
+		fireAt := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		return fireAt.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown reminder kind: %s", r.Kind)
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// タグ名の最大文字数
+const MaxTagNameLength = 50
+
+type Tag struct {
+	ID        string    `json:"id" db:"id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+	TaskCount int       `json:"task_count"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type UpdateTagRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// NormalizeTagName 前後の空白を取り除き、タグ名として使えるか検証する
+func NormalizeTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("tag name is required")
+	}
+	if utf8.RuneCountInString(name) > MaxTagNameLength {
+		return "", errors.New("tag name must be at most 50 characters")
+	}
+	return name, nil
+}
+
+// NormalizeTagNames タグ名の一覧を正規化し、重複を除いて名前順に並べる
+func NormalizeTagNames(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name, err := NormalizeTagName(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
@@ -1,19 +1,34 @@
 package models
 
 import (
+	"errors"
+	"strings"
 	"time"
 )
 
 type Task struct {
-	ID          string    `json:"id" db:"id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	Title       string    `json:"title" db:"title"`
-	Description *string   `json:"description,omitempty" db:"description"`
+	ID          string     `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	ProjectID   string     `json:"project_id" db:"project_id"`
+	ParentID    *string    `json:"parent_id,omitempty" db:"parent_id"`
+	Title       string     `json:"title" db:"title"`
+	Description *string    `json:"description,omitempty" db:"description"`
 	Deadline    *time.Time `json:"deadline,omitempty" db:"deadline"`
-	Priority    string    `json:"priority" db:"priority"`
-	Status      string    `json:"status" db:"status"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Priority    string     `json:"priority" db:"priority"`
+	Status      string     `json:"status" db:"status"`
+	Tags        []string   `json:"tags"`
+	// RRule 繰り返しのルール（系列が終了している場合は nil）。Timezone はルールを計算するタイムゾーン
+	RRule    *string `json:"rrule,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
+	SeriesID *string `json:"series_id,omitempty" db:"series_id"`
+	// OccurrenceAt ルールから計算したこの回の本来の日時（この回だけ期限をずらしても変わらない）
+	OccurrenceAt *time.Time `json:"occurrence_at,omitempty" db:"occurrence_at"`
+	// Version 変更するたびに増える版数（ETag / If-Match による楽観的排他制御に使う）
+	Version int64 `json:"version" db:"version"`
+	// DeletedAt ゴミ箱に移した日時（ゴミ箱にないタスクは nil）
+	DeletedAt *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 type CreateTaskRequest struct {
@@ -21,25 +36,145 @@ type CreateTaskRequest struct {
 	Description *string    `json:"description,omitempty"`
 	Deadline    *time.Time `json:"deadline,omitempty"`
 	Priority    string     `json:"priority" validate:"required,oneof=high medium low"`
+	// ProjectID 省略時は Inbox（サブタスクは親と同じプロジェクトになる）
+	ProjectID *string  `json:"project_id,omitempty"`
+	ParentID  *string  `json:"parent_id,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	// RRule 指定すると期限を最初の回とする繰り返しタスクになる（期限が必須）
+	RRule    *string `json:"rrule,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
 }
 
 type UpdateTaskRequest struct {
-	Title       *string     `json:"title,omitempty"`
-	Description *string     `json:"description,omitempty"`
-	Deadline    *time.Time  `json:"deadline,omitempty"`
-	Priority    *string     `json:"priority,omitempty" validate:"omitempty,oneof=high medium low"`
-	Status      *string     `json:"status,omitempty" validate:"omitempty,oneof=pending completed"`
+	Title       *string    `json:"title,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	Priority    *string    `json:"priority,omitempty" validate:"omitempty,oneof=high medium low"`
+	Status      *string    `json:"status,omitempty" validate:"omitempty,oneof=pending completed"`
+	// ParentID 親タスクを変更する（空文字列でトップレベルに戻す）
+	ParentID *string `json:"parent_id,omitempty"`
+	// Tags タグを置き換える（省略時は変更しない、空配列ですべて外す）
+	Tags *[]string `json:"tags,omitempty"`
+	// Cascade 完了にする際、子孫のタスクもまとめて完了にする
+	Cascade bool `json:"cascade,omitempty"`
+	// RRule 繰り返しのルールを設定・変更する（繰り返しタスクのルールの変更は scope=series のみ）
+	RRule    *string `json:"rrule,omitempty"`
+	Timezone *string `json:"timezone,omitempty"`
+	// Scope 繰り返しタスクの変更範囲。this（省略時）はこの回のみ、series は以降の回にも引き継ぐ
+	Scope string `json:"scope,omitempty" validate:"omitempty,oneof=this series"`
+	// ClearDescription, ClearDeadline 説明・期限を消す（PATCH で null を指定した場合。PUT の本文では指定できない）
+	ClearDescription bool `json:"-"`
+	ClearDeadline    bool `json:"-"`
+	// RevertedFrom 履歴の版の内容に戻す変更の場合に、戻した版数（履歴に記録する）
+	RevertedFrom *int64 `json:"-"`
 }
 
+// TaskFilters タスク一覧の絞り込み・並び替えの条件
+// クエリパラメータ（GET /api/tasks）と、保存したビューの定義（JSON）の両方で使う
 type TaskFilters struct {
-	Status    *string `query:"status" validate:"omitempty,oneof=pending completed all"`
-	Priority  *string `query:"priority" validate:"omitempty,oneof=high medium low all"`
-	SortBy    *string `query:"sort_by" validate:"omitempty,oneof=deadline priority created_at"`
-	SortOrder *string `query:"sort_order" validate:"omitempty,oneof=asc desc"`
+	Status       *string    `query:"status" json:"status,omitempty" validate:"omitempty,oneof=pending completed all"`
+	Priority     *string    `query:"priority" json:"priority,omitempty" validate:"omitempty,oneof=high medium low all"`
+	SortBy       *string    `query:"sort_by" json:"sort_by,omitempty" validate:"omitempty,oneof=deadline priority created_at"`
+	SortOrder    *string    `query:"sort_order" json:"sort_order,omitempty" validate:"omitempty,oneof=asc desc"`
+	DeadlineFrom *time.Time `query:"deadline_from" json:"deadline_from,omitempty"`
+	DeadlineTo   *time.Time `query:"deadline_to" json:"deadline_to,omitempty"`
+	Overdue      *bool      `query:"overdue" json:"overdue,omitempty"`
+	NoDeadline   *bool      `query:"no_deadline" json:"no_deadline,omitempty"`
+	ProjectID    *string    `query:"project_id" json:"project_id,omitempty"`
+	// TagsAny いずれかのタグが付いたタスク、TagsAll すべてのタグが付いたタスク（クエリパラメータを繰り返して指定する）
+	TagsAny []string `query:"tags_any" json:"tags_any,omitempty"`
+	TagsAll []string `query:"tags_all" json:"tags_all,omitempty"`
+	// Query フィルター式（ParseTaskQuery を参照）。他の条件とすべて満たすものに一致する
+	Query *string `query:"query" json:"query,omitempty"`
+}
+
+// Validate フィルター値を検証する（validateタグと同じ制約をコード上でも確認する）
+func (f *TaskFilters) Validate() error {
+	if f.Status != nil && !oneOf(*f.Status, "pending", "completed", "all") {
+		return errors.New("status must be one of pending, completed, all")
+	}
+	if f.Priority != nil && !oneOf(*f.Priority, "high", "medium", "low", "all") {
+		return errors.New("priority must be one of high, medium, low, all")
+	}
+	if f.SortBy != nil && !oneOf(*f.SortBy, "deadline", "priority", "created_at") {
+		return errors.New("sort_by must be one of deadline, priority, created_at")
+	}
+	if f.SortOrder != nil && !oneOf(*f.SortOrder, "asc", "desc") {
+		return errors.New("sort_order must be one of asc, desc")
+	}
+	if f.DeadlineFrom != nil && f.DeadlineTo != nil && f.DeadlineFrom.After(*f.DeadlineTo) {
+		return errors.New("deadline_from must not be after deadline_to")
+	}
+	if _, err := NormalizeTagNames(f.TagsAny); err != nil {
+		return errors.New("tags_any: " + err.Error())
+	}
+	if _, err := NormalizeTagNames(f.TagsAll); err != nil {
+		return errors.New("tags_all: " + err.Error())
+	}
+	if f.NoDeadline != nil && *f.NoDeadline {
+		if f.DeadlineFrom != nil || f.DeadlineTo != nil || (f.Overdue != nil && *f.Overdue) {
+			return errors.New("no_deadline cannot be combined with deadline filters")
+		}
+	}
+	// フィルター式の構文エラーは位置を返せるよう *TaskQueryError のまま返す
+	if _, err := f.ParsedQuery(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ParsedQuery フィルター式を解析する（指定がない・空白のみの場合は nil）
+func (f *TaskFilters) ParsedQuery() (TaskQueryNode, error) {
+	if f.Query == nil || strings.TrimSpace(*f.Query) == "" {
+		return nil, nil
+	}
+	return ParseTaskQuery(*f.Query)
 }
 
+func oneOf(value string, candidates ...string) bool {
+	for _, candidate := range candidates {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
 
+// タスク一覧のページサイズ（limit省略時の件数とサーバー側の上限）
+const (
+	DefaultTaskPageSize = 50
+	MaxTaskPageSize     = 200
+)
 
+// TaskPagination カーソル方式のページング指定
+// Cursor は前ページのレスポンスの next_cursor をそのまま渡す
+type TaskPagination struct {
+	Limit  *int    `query:"limit"`
+	Cursor *string `query:"cursor"`
+}
 
+// PageSize 実際に取得する件数を返す（上限を超える指定は上限に丸める）
+func (p *TaskPagination) PageSize() int {
+	if p == nil || p.Limit == nil {
+		return DefaultTaskPageSize
+	}
+	if *p.Limit > MaxTaskPageSize {
+		return MaxTaskPageSize
+	}
+	return *p.Limit
+}
 
+// Validate ページング指定を検証する
+func (p *TaskPagination) Validate() error {
+	if p.Limit != nil && *p.Limit < 1 {
+		return errors.New("limit must be a positive integer")
+	}
+	return nil
+}
 
+// TaskPage タスク一覧の1ページ分
+type TaskPage struct {
+	Tasks      []Task
+	NextCursor string
+	HasMore    bool
+}
@@ -0,0 +1,54 @@
+package models
+
+// タスクの一括操作（POST /api/tasks/batch）の操作の種類
+const (
+	// TaskBatchCreate タスクを作成する（POST /api/tasks と同じ）
+	TaskBatchCreate = "create"
+	// TaskBatchUpdate タスクを更新する（PUT /api/tasks/:id と同じ）
+	TaskBatchUpdate = "update"
+	// TaskBatchComplete タスクを完了にする（status を completed にする更新と同じ）
+	TaskBatchComplete = "complete"
+	// TaskBatchDelete タスクを子孫ごとゴミ箱に移す（DELETE /api/tasks/:id と同じ）
+	TaskBatchDelete = "delete"
+)
+
+// MaxTaskBatchOperations 1回の一括操作で指定できる操作の数
+const MaxTaskBatchOperations = 100
+
+// TaskBatchRequest タスクの一括操作
+type TaskBatchRequest struct {
+	// Atomic true ならすべての操作が成功した場合のみ反映する（1つでも失敗すればすべて取り消す）
+	// false なら失敗した操作のみ取り消し、他の操作は反映する
+	Atomic     bool                 `json:"atomic"`
+	Operations []TaskBatchOperation `json:"operations"`
+}
+
+// TaskBatchOperation 一括操作の1件（指定した順に実行する）
+type TaskBatchOperation struct {
+	Op string `json:"op"`
+	// ID update / complete / delete の対象のタスク
+	ID string `json:"id,omitempty"`
+	// Version update / complete / delete で変更の元にしたタスクの版数（If-Match に当たり、必須）
+	Version *int64 `json:"version,omitempty"`
+	// Task create で作成するタスク
+	Task *CreateTaskRequest `json:"task,omitempty"`
+	// Changes update で変更する内容
+	Changes *UpdateTaskRequest `json:"changes,omitempty"`
+	// Cascade complete で子孫のタスクもまとめて完了にする
+	Cascade bool `json:"cascade,omitempty"`
+}
+
+// TaskBatchResult 一括操作の1件の結果（操作と同じ順に返す）
+type TaskBatchResult struct {
+	Index int    `json:"index"`
+	Op    string `json:"op"`
+	ID    string `json:"id,omitempty"`
+	// Status 同じ操作を単独の API で行った場合のステータスコード
+	// atomic で他の操作が失敗したため取り消した・実行しなかった操作は 424
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Data 作成・更新後のタスク（412 の場合は現在のタスク）
+	Data *Task `json:"data,omitempty"`
+	// NextOccurrence 繰り返しタスクを完了にした場合に作成した次の回
+	NextOccurrence *Task `json:"next_occurrence,omitempty"`
+}
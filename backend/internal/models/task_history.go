@@ -0,0 +1,133 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// タスクの変更履歴の操作の種類
+const (
+	TaskHistoryCreated  = "created"
+	TaskHistoryUpdated  = "updated"
+	TaskHistoryDeleted  = "deleted"
+	TaskHistoryRestored = "restored"
+	// TaskHistoryReverted 以前の版の内容に戻した（RevertedFrom に戻した版数を記録する）
+	TaskHistoryReverted = "reverted"
+)
+
+// TaskFieldChange 変更した項目の変更前後の値（値がない項目は null）
+type TaskFieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old"`
+	New   json.RawMessage `json:"new"`
+}
+
+// TaskHistoryEntry タスクの変更1回分の履歴
+type TaskHistoryEntry struct {
+	ID     string `json:"id" db:"id"`
+	TaskID string `json:"task_id" db:"task_id"`
+	// ActorID 変更したユーザー
+	ActorID string `json:"actor_id" db:"actor_id"`
+	Action  string `json:"action" db:"action"`
+	// Version 変更後のタスクの版数（この版に戻す際に指定する）
+	Version int64             `json:"version" db:"version"`
+	Changes []TaskFieldChange `json:"changes" db:"changes"`
+	// Snapshot 変更後のタスクの内容（PATCH の対象と同じ項目）
+	Snapshot     json.RawMessage `json:"snapshot" db:"snapshot"`
+	RevertedFrom *int64          `json:"reverted_from,omitempty" db:"reverted_from"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+}
+
+// taskHistoryFields 履歴で変更を比べる項目（PATCH の項目に、プロジェクトとゴミ箱に移した日時を加える）
+var taskHistoryFields = append(append([]string{}, taskPatchFields...), "project_id", "deleted_at")
+
+// NewTaskHistoryEntry 変更前後のタスクから履歴を作る（作成した場合の before は nil）
+func NewTaskHistoryEntry(id, action, actorID string, before, after *Task, createdAt time.Time) (*TaskHistoryEntry, error) {
+	snapshot, err := json.Marshal(NewTaskPatchDocument(after))
+	if err != nil {
+		return nil, err
+	}
+	changes, err := DiffTasks(before, after)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskHistoryEntry{
+		ID:        id,
+		TaskID:    after.ID,
+		ActorID:   actorID,
+		Action:    action,
+		Version:   after.Version,
+		Changes:   changes,
+		Snapshot:  snapshot,
+		CreatedAt: createdAt,
+	}, nil
+}
+
+// DiffTasks 変更前後のタスクで値が変わった項目を返す
+// before が nil の場合は、値のある項目をすべて null からの変更とする
+func DiffTasks(before, after *Task) ([]TaskFieldChange, error) {
+	old := map[string]json.RawMessage{}
+	if before != nil {
+		var err error
+		if old, err = taskHistoryValues(before); err != nil {
+			return nil, err
+		}
+	}
+	values, err := taskHistoryValues(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []TaskFieldChange{}
+	for _, name := range taskHistoryFields {
+		from, to := old[name], values[name]
+		if from == nil {
+			if before == nil && (sameJSON(to, json.RawMessage("null")) || sameJSON(to, json.RawMessage("[]"))) {
+				continue
+			}
+			from = json.RawMessage("null")
+		}
+		if sameJSON(from, to) {
+			continue
+		}
+		changes = append(changes, TaskFieldChange{Field: name, Old: from, New: to})
+	}
+	return changes, nil
+}
+
+// taskHistoryValues 履歴で比べる項目の値をJSONで返す
+func taskHistoryValues(task *Task) (map[string]json.RawMessage, error) {
+	document, err := json.Marshal(NewTaskPatchDocument(task))
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(document, &values); err != nil {
+		return nil, err
+	}
+	if values["project_id"], err = json.Marshal(task.ProjectID); err != nil {
+		return nil, err
+	}
+	if values["deleted_at"], err = json.Marshal(task.DeletedAt); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// TaskRevertChanges 履歴の版の内容に戻すための更新のリクエストを作る
+// 繰り返しのルールは系列全体に影響するため戻さず、現在のルールのままにする
+func TaskRevertChanges(task *Task, entry *TaskHistoryEntry) (*UpdateTaskRequest, error) {
+	var document TaskPatchDocument
+	if err := json.Unmarshal(entry.Snapshot, &document); err != nil {
+		return nil, err
+	}
+	document.RRule, document.Timezone = task.RRule, task.Timezone
+	if document.Tags == nil {
+		document.Tags = []string{}
+	}
+	reverted, err := json.Marshal(&document)
+	if err != nil {
+		return nil, err
+	}
+	return TaskPatchChanges(task, reverted)
+}
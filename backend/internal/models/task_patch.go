@@ -0,0 +1,159 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PATCH /api/tasks/:id で受け付けるパッチ文書の Content-Type
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+// TaskPatchDocument PATCH を適用する対象の、タスクの変更できる項目
+// null で消せる項目（description, deadline, parent_id）も省略せずに null として含める
+type TaskPatchDocument struct {
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	Deadline    *time.Time `json:"deadline"`
+	Priority    string     `json:"priority"`
+	Status      string     `json:"status"`
+	ParentID    *string    `json:"parent_id"`
+	Tags        []string   `json:"tags"`
+	RRule       *string    `json:"rrule"`
+	Timezone    *string    `json:"timezone"`
+}
+
+// taskPatchFields パッチ文書の項目（変更を決める順）
+var taskPatchFields = []string{"title", "description", "deadline", "priority", "status", "parent_id", "tags", "rrule", "timezone"}
+
+// NewTaskPatchDocument タスクのパッチ文書を作る
+func NewTaskPatchDocument(task *Task) *TaskPatchDocument {
+	tags := task.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return &TaskPatchDocument{
+		Title:       task.Title,
+		Description: task.Description,
+		Deadline:    task.Deadline,
+		Priority:    task.Priority,
+		Status:      task.Status,
+		ParentID:    task.ParentID,
+		Tags:        tags,
+		RRule:       task.RRule,
+		Timezone:    task.Timezone,
+	}
+}
+
+// TaskPatchChanges パッチを適用したタスクの文書と元のタスクを比べ、変わった項目を更新のリクエストにする
+// 文書から消えた項目は null として扱い、null にできない項目や値の型・値が不正な場合はエラーを返す
+func TaskPatchChanges(task *Task, patched []byte) (*UpdateTaskRequest, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(patched, &values); err != nil || values == nil {
+		return nil, errors.New("the patched task must be a JSON object")
+	}
+	for name := range values {
+		if !oneOf(name, taskPatchFields...) {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+	}
+
+	document, err := json.Marshal(NewTaskPatchDocument(task))
+	if err != nil {
+		return nil, err
+	}
+	var original map[string]json.RawMessage
+	if err := json.Unmarshal(document, &original); err != nil {
+		return nil, err
+	}
+
+	req := &UpdateTaskRequest{}
+	for _, name := range taskPatchFields {
+		value, ok := values[name]
+		if !ok {
+			value = json.RawMessage("null")
+		}
+		if sameJSON(value, original[name]) {
+			continue
+		}
+		isNull := bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+
+		switch name {
+		case "title":
+			if isNull {
+				return nil, errors.New("title cannot be null")
+			}
+			err = decodePatchField(name, value, &req.Title)
+		case "description":
+			if isNull {
+				req.ClearDescription = true
+				continue
+			}
+			err = decodePatchField(name, value, &req.Description)
+		case "deadline":
+			if isNull {
+				req.ClearDeadline = true
+				continue
+			}
+			err = decodePatchField(name, value, &req.Deadline)
+		case "priority":
+			if err = decodePatchField(name, value, &req.Priority); err == nil && (req.Priority == nil || !oneOf(*req.Priority, "high", "medium", "low")) {
+				err = errors.New("priority must be one of high, medium, low")
+			}
+		case "status":
+			if err = decodePatchField(name, value, &req.Status); err == nil && (req.Status == nil || !oneOf(*req.Status, "pending", "completed")) {
+				err = errors.New("status must be one of pending, completed")
+			}
+		case "parent_id":
+			// null はトップレベルに戻す（PUT の空文字列と同じ）
+			if isNull {
+				empty := ""
+				req.ParentID = &empty
+				continue
+			}
+			err = decodePatchField(name, value, &req.ParentID)
+		case "tags":
+			if isNull {
+				return nil, errors.New("tags cannot be null (use [] to remove all tags)")
+			}
+			err = decodePatchField(name, value, &req.Tags)
+		case "rrule":
+			if isNull {
+				return nil, errors.New("rrule cannot be removed (use POST /api/tasks/:id/end-series)")
+			}
+			err = decodePatchField(name, value, &req.RRule)
+		case "timezone":
+			if isNull {
+				return nil, errors.New("timezone cannot be null")
+			}
+			err = decodePatchField(name, value, &req.Timezone)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func decodePatchField(name string, value json.RawMessage, target interface{}) error {
+	if err := json.Unmarshal(value, target); err != nil {
+		return fmt.Errorf("%s has an invalid value", name)
+	}
+	return nil
+}
+
+// sameJSON 2つのJSONの値が等しいか（空白やメンバーの順序は区別しない）
+func sameJSON(a, b json.RawMessage) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
+	}
+	ax, _ := json.Marshal(x)
+	by, _ := json.Marshal(y)
+	return bytes.Equal(ax, by)
+}
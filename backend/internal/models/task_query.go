@@ -0,0 +1,508 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// フィルター式の上限（長さは文字数、深さは括弧と否定の入れ子の数）
+const (
+	MaxTaskQueryLength     = 1000
+	maxTaskQueryDepth      = 20
+	maxTaskQueryConditions = 50
+)
+
+// TaskQueryField フィルター式の条件の種類
+type TaskQueryField string
+
+const (
+	TaskQueryStatus   TaskQueryField = "status"
+	TaskQueryPriority TaskQueryField = "priority"
+	TaskQueryDue      TaskQueryField = "due"
+	TaskQueryCreated  TaskQueryField = "created"
+	TaskQueryUpdated  TaskQueryField = "updated"
+	TaskQueryTag      TaskQueryField = "tag"
+	TaskQueryProject  TaskQueryField = "project"
+	TaskQueryIs       TaskQueryField = "is"
+	TaskQueryHas      TaskQueryField = "has"
+	// TaskQueryText field: のない語・"..." のフレーズ（タイトルか説明に含むタスク）
+	TaskQueryText TaskQueryField = "text"
+)
+
+// taskQueryFields field: の形式で指定できる条件（deadline は due の別名）
+var taskQueryFields = map[string]TaskQueryField{
+	"status":   TaskQueryStatus,
+	"priority": TaskQueryPriority,
+	"due":      TaskQueryDue,
+	"deadline": TaskQueryDue,
+	"created":  TaskQueryCreated,
+	"updated":  TaskQueryUpdated,
+	"tag":      TaskQueryTag,
+	"project":  TaskQueryProject,
+	"is":       TaskQueryIs,
+	"has":      TaskQueryHas,
+}
+
+// TaskQueryNode フィルター式の構文木（TaskQueryAnd / TaskQueryOr / TaskQueryNot / TaskQueryCondition）
+type TaskQueryNode interface {
+	isTaskQueryNode()
+}
+
+// TaskQueryAnd すべての条件を満たす
+type TaskQueryAnd struct {
+	Operands []TaskQueryNode
+}
+
+// TaskQueryOr いずれかの条件を満たす
+type TaskQueryOr struct {
+	Operands []TaskQueryNode
+}
+
+// TaskQueryNot 条件を満たさない
+type TaskQueryNot struct {
+	Operand TaskQueryNode
+}
+
+// TaskQueryCondition 1つの条件
+type TaskQueryCondition struct {
+	Field TaskQueryField
+	// Op 比較演算子（=, <, <=, >, >=）。比較できるのは priority と日時の条件のみ
+	Op string
+	// Value 検証済みの値（status / priority / is / has はキーワード、tag は正規化したタグ名、text は ASCII の英字を小文字にした語）
+	Value string
+	// Date 日時の条件（due / created / updated）の値
+	Date *TaskQueryDate
+}
+
+func (*TaskQueryAnd) isTaskQueryNode()       {}
+func (*TaskQueryOr) isTaskQueryNode()        {}
+func (*TaskQueryNot) isTaskQueryNode()       {}
+func (*TaskQueryCondition) isTaskQueryNode() {}
+
+// TaskQueryDate 日時の条件の値
+// Day は日付（UTC の1日）、DayOffset は今日からの日数（today / tomorrow / yesterday）、
+// Offset は現在時刻からの相対時間、None は期限なし（due:none）
+type TaskQueryDate struct {
+	Day       *time.Time
+	DayOffset *int
+	Offset    time.Duration
+	None      bool
+}
+
+// TaskQueryBound 日時の範囲の端
+type TaskQueryBound struct {
+	Time      time.Time
+	Inclusive bool
+}
+
+// Range 比較演算子と値から日時の範囲を返す（nil の端は制限なし）
+// 日付は1日の範囲として比較し（due:<2025-01-10 は10日より前、due:<=2025-01-10 は10日を含む）、
+// 演算子のない相対時間は現在時刻との間（due:7d は7日以内、created:-7d は過去7日以内）
+func (d *TaskQueryDate) Range(op string, now time.Time) (lower, upper *TaskQueryBound) {
+	day := d.Day
+	if d.DayOffset != nil {
+		today := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, *d.DayOffset)
+		day = &today
+	}
+	if day != nil {
+		start, end := *day, day.AddDate(0, 0, 1)
+		switch op {
+		case "<":
+			return nil, &TaskQueryBound{Time: start}
+		case "<=":
+			return nil, &TaskQueryBound{Time: end}
+		case ">":
+			return &TaskQueryBound{Time: end, Inclusive: true}, nil
+		case ">=":
+			return &TaskQueryBound{Time: start, Inclusive: true}, nil
+		default:
+			return &TaskQueryBound{Time: start, Inclusive: true}, &TaskQueryBound{Time: end}
+		}
+	}
+
+	t := now.Add(d.Offset)
+	switch op {
+	case "<":
+		return nil, &TaskQueryBound{Time: t}
+	case "<=":
+		return nil, &TaskQueryBound{Time: t, Inclusive: true}
+	case ">":
+		return &TaskQueryBound{Time: t}, nil
+	case ">=":
+		return &TaskQueryBound{Time: t, Inclusive: true}, nil
+	default:
+		if t.Before(now) {
+			return &TaskQueryBound{Time: t, Inclusive: true}, &TaskQueryBound{Time: now, Inclusive: true}
+		}
+		return &TaskQueryBound{Time: now, Inclusive: true}, &TaskQueryBound{Time: t, Inclusive: true}
+	}
+}
+
+// Contains 日時 t が比較演算子 op の条件を満たすか判定する（Range の範囲に含まれるか）
+func (d *TaskQueryDate) Contains(op string, now, t time.Time) bool {
+	lower, upper := d.Range(op, now)
+	if lower != nil && (t.Before(lower.Time) || (!lower.Inclusive && t.Equal(lower.Time))) {
+		return false
+	}
+	if upper != nil && (t.After(upper.Time) || (!upper.Inclusive && t.Equal(upper.Time))) {
+		return false
+	}
+	return true
+}
+
+// TaskQueryError フィルター式の構文エラー
+// Offset と Length はエラー箇所の位置と長さ（文字数。先頭は 0）
+type TaskQueryError struct {
+	Message string `json:"message"`
+	Offset  int    `json:"offset"`
+	Length  int    `json:"length"`
+}
+
+func (e *TaskQueryError) Error() string {
+	return fmt.Sprintf("query: %s (at offset %d)", e.Message, e.Offset)
+}
+
+// ParseTaskQuery フィルター式を解析する
+//
+//	priority:high status:pending due:<7d tag:backend -tag:blocked "release notes"
+//
+// 空白で区切った条件はすべて満たすもの（AND）に一致する。OR でいずれか、先頭の - か NOT で否定、
+// (...) でまとめる。AND / OR / NOT は大文字のみ演算子として扱う
+// エラーは *TaskQueryError で返す
+func ParseTaskQuery(query string) (TaskQueryNode, error) {
+	p := &taskQueryParser{input: []rune(query)}
+	if len(p.input) > MaxTaskQueryLength {
+		return nil, p.errorAt(MaxTaskQueryLength, len(p.input)-MaxTaskQueryLength,
+			fmt.Sprintf("query must be at most %d characters", MaxTaskQueryLength))
+	}
+
+	p.skipSpace()
+	if p.pos == len(p.input) {
+		return nil, p.errorAt(0, 0, "query is empty")
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.input) {
+		// parseOr が止まるのは対応しない ) のみ
+		return nil, p.errorAt(p.pos, 1, "unexpected )")
+	}
+	return node, nil
+}
+
+type taskQueryParser struct {
+	input      []rune
+	pos        int
+	depth      int
+	conditions int
+}
+
+func (p *taskQueryParser) errorAt(offset, length int, message string) *TaskQueryError {
+	return &TaskQueryError{Message: message, Offset: offset, Length: length}
+}
+
+func (p *taskQueryParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// isTaskQueryDelimiter 語の区切りになる文字
+func isTaskQueryDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+}
+
+// peekKeyword 現在位置の語が演算子 keyword なら true
+func (p *taskQueryParser) peekKeyword(keyword string) bool {
+	end := p.pos + len(keyword)
+	if end > len(p.input) || string(p.input[p.pos:end]) != keyword {
+		return false
+	}
+	return end == len(p.input) || isTaskQueryDelimiter(p.input[end])
+}
+
+// parseOr or := and ("OR" and)*
+func (p *taskQueryParser) parseOr() (TaskQueryNode, error) {
+	var operands []TaskQueryNode
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, node)
+
+		if !p.peekKeyword("OR") {
+			break
+		}
+		p.pos += len("OR")
+		p.skipSpace()
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &TaskQueryOr{Operands: operands}, nil
+}
+
+// parseAnd and := unary (["AND"] unary)*
+func (p *taskQueryParser) parseAnd() (TaskQueryNode, error) {
+	var operands []TaskQueryNode
+	for {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, node)
+
+		p.skipSpace()
+		if p.pos == len(p.input) || p.input[p.pos] == ')' || p.peekKeyword("OR") {
+			break
+		}
+		if p.peekKeyword("AND") {
+			p.pos += len("AND")
+			p.skipSpace()
+		}
+	}
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return &TaskQueryAnd{Operands: operands}, nil
+}
+
+// parseUnary unary := ("-" | "NOT") unary | "(" or ")" | term
+func (p *taskQueryParser) parseUnary() (TaskQueryNode, error) {
+	start := p.pos
+	if p.pos == len(p.input) {
+		return nil, p.errorAt(p.pos, 0, "expected a condition")
+	}
+
+	switch {
+	case p.peekKeyword("AND") || p.peekKeyword("OR"):
+		keyword := "AND"
+		if p.peekKeyword("OR") {
+			keyword = "OR"
+		}
+		return nil, p.errorAt(start, len(keyword), "expected a condition before "+keyword)
+	case p.input[p.pos] == ')':
+		return nil, p.errorAt(start, 1, "expected a condition before )")
+	case p.input[p.pos] == '-' || p.peekKeyword("NOT"):
+		if p.input[p.pos] == '-' {
+			p.pos++
+			if p.pos == len(p.input) || unicode.IsSpace(p.input[p.pos]) {
+				return nil, p.errorAt(start, 1, "expected a condition after -")
+			}
+		} else {
+			p.pos += len("NOT")
+			p.skipSpace()
+		}
+		if err := p.enter(start); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		p.depth--
+		return &TaskQueryNot{Operand: operand}, nil
+	case p.input[p.pos] == '(':
+		if err := p.enter(start); err != nil {
+			return nil, err
+		}
+		p.pos++
+		p.skipSpace()
+		if p.pos < len(p.input) && p.input[p.pos] == ')' {
+			return nil, p.errorAt(start, p.pos-start+1, "empty parentheses")
+		}
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos == len(p.input) {
+			return nil, p.errorAt(start, 1, "unclosed (")
+		}
+		p.pos++
+		p.depth--
+		return node, nil
+	}
+	return p.parseTerm()
+}
+
+// enter 括弧・否定の入れ子を1段深くする
+func (p *taskQueryParser) enter(start int) error {
+	p.depth++
+	if p.depth > maxTaskQueryDepth {
+		return p.errorAt(start, 1, fmt.Sprintf("query must not be nested more than %d levels", maxTaskQueryDepth))
+	}
+	return nil
+}
+
+// parseTerm term := field ":" value | "..." | word
+func (p *taskQueryParser) parseTerm() (TaskQueryNode, error) {
+	start := p.pos
+	p.conditions++
+	if p.conditions > maxTaskQueryConditions {
+		return nil, p.errorAt(start, 0, fmt.Sprintf("query must not contain more than %d conditions", maxTaskQueryConditions))
+	}
+
+	if p.input[p.pos] == '"' {
+		phrase, err := p.readQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return p.textCondition(phrase, start)
+	}
+
+	word := p.readWord()
+	colon := strings.IndexRune(word, ':')
+	if colon < 0 {
+		return p.textCondition(word, start)
+	}
+
+	name := word[:colon]
+	if name == "" {
+		return nil, p.errorAt(start, 1, "missing field name before :")
+	}
+	field, ok := taskQueryFields[strings.ToLower(name)]
+	if !ok {
+		return nil, p.errorAt(start, len([]rune(name)), fmt.Sprintf("unknown field %q (quote the text to search for it)", name))
+	}
+	valueStart := start + len([]rune(name)) + 1
+	value := word[colon+1:]
+	if value == "" && p.pos < len(p.input) && p.input[p.pos] == '"' {
+		quoted, err := p.readQuoted()
+		if err != nil {
+			return nil, err
+		}
+		value = quoted
+	}
+	return p.fieldCondition(field, name, value, valueStart, p.pos-valueStart)
+}
+
+// readWord 区切り文字までの語を読む
+func (p *taskQueryParser) readWord() string {
+	start := p.pos
+	for p.pos < len(p.input) && !isTaskQueryDelimiter(p.input[p.pos]) {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+// readQuoted "..." を読み、引用符の内側を返す（引用符の中に " は書けない）
+func (p *taskQueryParser) readQuoted() (string, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.input) && p.input[p.pos] != '"' {
+		p.pos++
+	}
+	if p.pos == len(p.input) {
+		return "", p.errorAt(start, 1, "unterminated quoted text")
+	}
+	p.pos++
+	return string(p.input[start+1 : p.pos-1]), nil
+}
+
+func (p *taskQueryParser) textCondition(text string, start int) (TaskQueryNode, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, p.errorAt(start, p.pos-start, "quoted text is empty")
+	}
+	return &TaskQueryCondition{Field: TaskQueryText, Op: "=", Value: LowerASCII(text)}, nil
+}
+
+// LowerASCII ASCII の英字のみ小文字にする
+// SQLite の LOWER() は ASCII の英字のみを変換するため、どのデータベースでも同じ結果になるよう語の比較はこの規則にそろえる
+func LowerASCII(s string) string {
+	return strings.Map(func(r rune) rune {
+		if 'A' <= r && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return r
+	}, s)
+}
+
+// fieldCondition field: の値を検証する（offset と length は値の位置）
+func (p *taskQueryParser) fieldCondition(field TaskQueryField, name, value string, offset, length int) (TaskQueryNode, error) {
+	op := "="
+	for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op, value = candidate, value[len(candidate):]
+			break
+		}
+	}
+	if value == "" {
+		return nil, p.errorAt(offset, length, fmt.Sprintf("missing value for %s:", name))
+	}
+	if op != "=" && field != TaskQueryPriority && field != TaskQueryDue && field != TaskQueryCreated && field != TaskQueryUpdated {
+		return nil, p.errorAt(offset, len(op), fmt.Sprintf("%s: does not support %s", name, op))
+	}
+
+	condition := &TaskQueryCondition{Field: field, Op: op, Value: LowerASCII(value)}
+	invalid := func(message string) (TaskQueryNode, error) {
+		return nil, p.errorAt(offset, length, fmt.Sprintf("%s: %s", name, message))
+	}
+	switch field {
+	case TaskQueryStatus:
+		if !oneOf(condition.Value, "pending", "completed") {
+			return invalid("must be one of pending, completed")
+		}
+	case TaskQueryPriority:
+		if !oneOf(condition.Value, "high", "medium", "low") {
+			return invalid("must be one of high, medium, low")
+		}
+	case TaskQueryIs:
+		if !oneOf(condition.Value, "overdue", "recurring", "subtask") {
+			return invalid("must be one of overdue, recurring, subtask")
+		}
+	case TaskQueryHas:
+		if !oneOf(condition.Value, "deadline", "description", "tags") {
+			return invalid("must be one of deadline, description, tags")
+		}
+	case TaskQueryTag:
+		tag, err := NormalizeTagName(value)
+		if err != nil {
+			return invalid(err.Error())
+		}
+		condition.Value = tag
+	case TaskQueryProject:
+		// プロジェクトは ID か名前（大文字・小文字を区別する）で指定する
+		condition.Value = value
+	case TaskQueryDue, TaskQueryCreated, TaskQueryUpdated:
+		date, err := parseTaskQueryDate(condition.Value, field == TaskQueryDue && op == "=")
+		if err != nil {
+			return invalid(err.Error())
+		}
+		condition.Date = date
+	}
+	return condition, nil
+}
+
+// parseTaskQueryDate 日時の条件の値を解析する
+// YYYY-MM-DD、today / tomorrow / yesterday（UTC の日付）、符号付きの相対時間（7d, -2w, 12h）、none（期限なし）
+func parseTaskQueryDate(value string, allowNone bool) (*TaskQueryDate, error) {
+	days := map[string]int{"today": 0, "tomorrow": 1, "yesterday": -1}
+	if offset, ok := days[value]; ok {
+		return &TaskQueryDate{DayOffset: &offset}, nil
+	}
+	if value == "none" {
+		if !allowNone {
+			return nil, fmt.Errorf("none can only be used as due:none")
+		}
+		return &TaskQueryDate{None: true}, nil
+	}
+	if day, err := time.Parse("2006-01-02", value); err == nil {
+		return &TaskQueryDate{Day: &day}, nil
+	}
+
+	units := map[byte]time.Duration{'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[value[len(value)-1]]; ok {
+		// 10年を超える相対時間は受け付けない（time.Duration のオーバーフローを避ける）
+		limit := int64(10 * 365 * 24 * time.Hour / unit)
+		if n, err := strconv.ParseInt(value[:len(value)-1], 10, 64); err == nil && n >= -limit && n <= limit {
+			return &TaskQueryDate{Offset: time.Duration(n) * unit}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date %q (use YYYY-MM-DD, today, tomorrow, yesterday or a relative time such as 7d, -2w, 12h)", value)
+}
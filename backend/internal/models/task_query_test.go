@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"unicode/utf8"
+)
+
+// FuzzParseTaskQuery どんな入力でもパニックせず、エラーの位置が入力の範囲に収まることを確認する
+func FuzzParseTaskQuery(f *testing.F) {
+	for _, query := range []string{
+		`priority:high status:pending due:<7d tag:backend -tag:blocked "release notes"`,
+		`(tag:frontend OR priority:low) -status:completed`,
+		`NOT tag:backend AND has:description`,
+		`-(priority:high OR status:completed)`,
+		`created:<=2025-01-02 updated:<today`,
+		`deadline:>=tomorrow is:overdue`,
+		`status:pending priority:urgent`,
+		`"unterminated`,
+		`((tag:a`,
+		`OR AND NOT`,
+		`Ärger im Büro`,
+		`due:<`,
+		"\xff:\xfe",
+	} {
+		f.Add(query)
+	}
+
+	f.Fuzz(func(t *testing.T, query string) {
+		_, err := ParseTaskQuery(query)
+		if err == nil {
+			return
+		}
+		var queryErr *TaskQueryError
+		if !errors.As(err, &queryErr) {
+			t.Fatalf("ParseTaskQuery(%q) error = %v, want a TaskQueryError", query, err)
+		}
+		// 位置は文字（rune）単位で数える
+		runes := utf8.RuneCountInString(query)
+		if queryErr.Offset < 0 || queryErr.Length < 0 || queryErr.Offset+queryErr.Length > runes {
+			t.Fatalf("ParseTaskQuery(%q) error at offset %d length %d, want within %d characters", query, queryErr.Offset, queryErr.Length, runes)
+		}
+		if queryErr.Offset > len(query) {
+			t.Fatalf("ParseTaskQuery(%q) error offset %d, want within len(input) %d", query, queryErr.Offset, len(query))
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// タスク検索の件数（limit省略時の件数とサーバー側の上限）
+const (
+	DefaultTaskSearchLimit = 20
+	MaxTaskSearchLimit     = 100
+)
+
+// 検索語の最大数
+const maxTaskSearchTerms = 16
+
+// TaskSearchTerm 検索語（すべての語を含むタスクが一致する）
+// Words は語を単語に分けたもの（2語以上はその順に連続している場合のみ一致する）
+type TaskSearchTerm struct {
+	Words []string
+	// Prefix 最後の単語を前方一致で検索する（foo* の形式）
+	Prefix bool
+}
+
+// TaskSearchQuery 検索文字列を解析した結果
+type TaskSearchQuery struct {
+	Terms []TaskSearchTerm
+}
+
+// TaskSearchResult 検索に一致したタスク
+type TaskSearchResult struct {
+	Task Task `json:"task"`
+	// Score 一致の度合い（大きいほど上位。タイトルの一致を説明の一致より重く扱う）
+	Score float64 `json:"score"`
+	// TitleHighlight, Snippet 一致した語を <mark> で囲んだタイトルと説明の抜粋（HTML。それ以外の文字はエスケープ済み）
+	TitleHighlight string `json:"title_highlight"`
+	Snippet        string `json:"snippet"`
+}
+
+// ParseTaskSearchQuery 検索文字列を解析する
+// 空白区切りの語をすべて含むタスクを検索する。"..." で囲んだ語は語順どおりのフレーズ、末尾の * は前方一致
+// 英数字以外の文字（記号など）は単語の区切りとして扱い、検索の演算子としては解釈しない
+func ParseTaskSearchQuery(q string) (*TaskSearchQuery, error) {
+	query := &TaskSearchQuery{}
+	rest := strings.TrimSpace(q)
+	for rest != "" {
+		var text string
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return nil, errors.New("q has an unterminated quoted phrase")
+			}
+			text, rest = rest[1:end+1], rest[end+2:]
+			// 閉じた引用符の直後の * はフレーズの前方一致
+			if strings.HasPrefix(rest, "*") {
+				text += "*"
+				rest = rest[1:]
+			}
+		} else if end := strings.IndexFunc(rest, unicode.IsSpace); end >= 0 {
+			text, rest = rest[:end], rest[end:]
+		} else {
+			text, rest = rest, ""
+		}
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+
+		term := TaskSearchTerm{Prefix: strings.HasSuffix(text, "*"), Words: SearchWords(text)}
+		if len(term.Words) == 0 {
+			continue
+		}
+		query.Terms = append(query.Terms, term)
+	}
+
+	if len(query.Terms) == 0 {
+		return nil, errors.New("q must contain at least one word")
+	}
+	if len(query.Terms) > maxTaskSearchTerms {
+		return nil, fmt.Errorf("q must not contain more than %d terms", maxTaskSearchTerms)
+	}
+	return query, nil
+}
+
+// SearchWords 文字列を検索の単語に分ける（文字・数字の連続を1語とし、小文字にそろえる）
+// 全文検索のトークナイザーと同じく、空白のない日本語の文は1語になる
+func SearchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
@@ -0,0 +1,56 @@
+package models
+
+import "math"
+
+// TaskTree タスクとその子孫のサブタスク
+type TaskTree struct {
+	Task
+	// CompletionPercent 完了率（0〜100）
+	// 完了済みのタスクは100、子のない未完了のタスクは0、それ以外は子の完了率の平均
+	CompletionPercent float64     `json:"completion_percent"`
+	Children          []*TaskTree `json:"children"`
+}
+
+// BuildTaskTree 根のタスクと子孫のタスクの一覧から木構造を組み立てる
+// 子の並び順は tasks の順序に従う。根が含まれない場合は nil を返す
+func BuildTaskTree(rootID string, tasks []Task) *TaskTree {
+	nodes := make(map[string]*TaskTree, len(tasks))
+	for i := range tasks {
+		nodes[tasks[i].ID] = &TaskTree{Task: tasks[i], Children: []*TaskTree{}}
+	}
+
+	root, ok := nodes[rootID]
+	if !ok {
+		return nil
+	}
+	for i := range tasks {
+		task := &tasks[i]
+		if task.ID == rootID || task.ParentID == nil {
+			continue
+		}
+		if parent, ok := nodes[*task.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[task.ID])
+		}
+	}
+
+	root.rollUp()
+	return root
+}
+
+// rollUp 子から順に完了率を集計する（丸めによる誤差が積み重ならないよう、丸めるのは表示用の値だけ）
+func (t *TaskTree) rollUp() float64 {
+	var total float64
+	for _, child := range t.Children {
+		total += child.rollUp()
+	}
+
+	var completion float64
+	switch {
+	case t.Status == "completed":
+		completion = 100
+	case len(t.Children) > 0:
+		completion = total / float64(len(t.Children))
+	}
+	t.CompletionPercent = math.Round(completion*10) / 10
+	return completion
+}
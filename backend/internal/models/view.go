@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ビューの一覧に表示できる列（ViewDisplay.Columns）
+var viewColumns = []string{"title", "description", "priority", "status", "deadline", "tags", "project", "created_at", "updated_at"}
+
+// View 保存したビュー（スマートリスト）。タスク一覧の条件と表示方法に名前を付けてユーザーごとに保存する
+type View struct {
+	ID     string `json:"id" db:"id"`
+	UserID string `json:"user_id" db:"user_id"`
+	Name   string `json:"name" db:"name"`
+	// Filters GET /api/tasks と同じ絞り込み・並び替えの条件（sort_by / sort_order を含む）
+	Filters   TaskFilters `json:"filters" db:"filters"`
+	Display   ViewDisplay `json:"display" db:"display"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// ViewDisplay ビューの表示方法（サーバーは保存するのみで、タスクの取得には影響しない）
+type ViewDisplay struct {
+	// ShowCompleted 完了済みのタスクを表示する
+	ShowCompleted bool `json:"show_completed"`
+	// GroupBy タスクをまとめる項目（空ならまとめない）
+	GroupBy string `json:"group_by,omitempty" validate:"omitempty,oneof=project priority status deadline"`
+	// Columns 表示する列と順序（空なら既定の列）
+	Columns []string `json:"columns,omitempty"`
+}
+
+type CreateViewRequest struct {
+	Name    string       `json:"name" validate:"required"`
+	Filters TaskFilters  `json:"filters"`
+	Display *ViewDisplay `json:"display,omitempty"`
+}
+
+// UpdateViewRequest 指定した項目のみ置き換える（filters / display は全体を置き換える）
+type UpdateViewRequest struct {
+	Name    *string      `json:"name,omitempty"`
+	Filters *TaskFilters `json:"filters,omitempty"`
+	Display *ViewDisplay `json:"display,omitempty"`
+}
+
+// Validate ビューの名前・条件・表示方法を検証する（条件の構文エラーは *TaskQueryError のまま返す）
+func (v *View) Validate() error {
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		return errors.New("view name is required")
+	}
+	if utf8.RuneCountInString(v.Name) > 100 {
+		return errors.New("view name must be at most 100 characters")
+	}
+	if err := v.Filters.Validate(); err != nil {
+		return err
+	}
+	return v.Display.Validate()
+}
+
+// Validate 表示方法を検証する
+func (d *ViewDisplay) Validate() error {
+	if d.GroupBy != "" && !oneOf(d.GroupBy, "project", "priority", "status", "deadline") {
+		return errors.New("display.group_by must be one of project, priority, status, deadline")
+	}
+	seen := make(map[string]bool, len(d.Columns))
+	for _, column := range d.Columns {
+		if !oneOf(column, viewColumns...) {
+			return errors.New("display.columns must contain only " + strings.Join(viewColumns, ", "))
+		}
+		if seen[column] {
+			return errors.New("display.columns must not contain duplicates")
+		}
+		seen[column] = true
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Webhook の配信状態
+const (
+	// WebhookDeliveryPending 送信待ち（失敗した場合は NextAttemptAt に再送する）
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryFailed 再送の上限に達した
+	WebhookDeliveryFailed = "failed"
+)
+
+// Webhook タスクの変更イベントを送信する先
+type Webhook struct {
+	ID     string   `json:"id" db:"id"`
+	UserID string   `json:"user_id" db:"user_id"`
+	URL    string   `json:"url" db:"url"`
+	Events []string `json:"events" db:"events"`
+	// Secret 署名の鍵（作成時のレスポンスにのみ含める）
+	Secret    string    `json:"secret,omitempty" db:"secret"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" validate:"required"`
+	Events []string `json:"events" validate:"required"`
+	// Secret 省略時はサーバーで生成する
+	Secret *string `json:"secret,omitempty"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string   `json:"url,omitempty"`
+	Events *[]string `json:"events,omitempty"`
+	Active *bool     `json:"active,omitempty"`
+}
+
+// WebhookDelivery イベントごと・送信先ごとの配信ログ
+type WebhookDelivery struct {
+	ID        string `json:"id" db:"id"`
+	WebhookID string `json:"webhook_id" db:"webhook_id"`
+	// EventID 再送・リプレイでも変わらないイベントのID（受信側の重複排除に使う）
+	EventID       string          `json:"event_id" db:"event_id"`
+	Event         string          `json:"event" db:"event"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"`
+	Attempts      int             `json:"attempts" db:"attempts"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	// ResponseStatus 最後の試行のHTTPステータス（接続できなかった場合は nil）
+	ResponseStatus *int `json:"response_status,omitempty" db:"response_status"`
+	// LastError 最後の試行のエラー（レスポンスの本文は保存しない）
+	LastError   *string    `json:"last_error,omitempty" db:"last_error"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// 配信ログの取得件数（limit省略時の件数とサーバー側の上限）
+const (
+	DefaultWebhookDeliveryPageSize = 50
+	MaxWebhookDeliveryPageSize     = 200
+)
+
+// WebhookDeliveryFilters 配信ログの絞り込み
+type WebhookDeliveryFilters struct {
+	Status *string `query:"status"`
+	Limit  *int    `query:"limit"`
+}
+
+// Validate 絞り込み条件を検証する
+func (f *WebhookDeliveryFilters) Validate() error {
+	if f.Status != nil {
+		switch *f.Status {
+		case WebhookDeliveryPending, WebhookDeliverySucceeded, WebhookDeliveryFailed:
+		default:
+			return errors.New("status must be one of pending, succeeded, failed")
+		}
+	}
+	if f.Limit != nil && *f.Limit < 1 {
+		return errors.New("limit must be a positive integer")
+	}
+	return nil
+}
+
+// PageSize 実際に取得する件数を返す（上限を超える指定は上限に丸める）
+func (f *WebhookDeliveryFilters) PageSize() int {
+	if f.Limit == nil {
+		return DefaultWebhookDeliveryPageSize
+	}
+	if *f.Limit > MaxWebhookDeliveryPageSize {
+		return MaxWebhookDeliveryPageSize
+	}
+	return *f.Limit
+}
+
+// Validate 送信先のURLと購読イベントを検証する（イベントは重複を除いて並べ替える）
+func (w *Webhook) Validate() error {
+	w.URL = strings.TrimSpace(w.URL)
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	// 内部のサービスに送信させないよう、ループバック・プライベートなどのアドレスは登録できない
+	// ホスト名で登録した場合は、送信時に名前解決したアドレスを確認する
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("url must not point to a private or local address")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicWebhookAddr(addr) {
+		return errors.New("url must not point to a private or local address")
+	}
+
+	if len(w.Events) == 0 {
+		return errors.New("events must not be empty")
+	}
+	seen := map[string]bool{}
+	events := make([]string, 0, len(w.Events))
+	for _, event := range w.Events {
+		event = strings.TrimSpace(event)
+		if !IsTaskEvent(event) {
+			return fmt.Errorf("unknown event: %s (must be one of %s)", event, strings.Join(TaskEvents, ", "))
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	sort.Strings(events)
+	w.Events = events
+
+	if len(w.Secret) < 16 {
+		return errors.New("secret must be at least 16 characters")
+	}
+	return nil
+}
+
+// cgnatPrefix キャリアグレードNATの共有アドレス（RFC 6598）
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicWebhookAddr Webhook の送信先にできるアドレスか
+// ループバック・プライベート・リンクローカル・未指定・マルチキャスト・共有アドレスは送信先にできない
+func IsPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	switch {
+	case !addr.IsValid(),
+		addr.IsUnspecified(),
+		addr.IsLoopback(),
+		addr.IsPrivate(),
+		addr.IsLinkLocalUnicast(),
+		addr.IsLinkLocalMulticast(),
+		addr.IsInterfaceLocalMulticast(),
+		addr.IsMulticast(),
+		cgnatPrefix.Contains(addr):
+		return false
+	}
+	// 0.0.0.0/8 は「このネットワーク」を表し、Linux ではループバックに接続される
+	if addr.Is4() && addr.As4()[0] == 0 {
+		return false
+	}
+	return true
+}
+
+// Subscribes イベントを購読しているか
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
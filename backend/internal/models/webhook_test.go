@@ -0,0 +1,54 @@
+package models
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicWebhookAddr(t *testing.T) {
+	cases := map[string]bool{
+		"93.184.216.34":          true,
+		"2606:2800:220:1::1":     true,
+		"127.0.0.1":              false,
+		"::1":                    false,
+		"10.1.2.3":               false,
+		"172.16.0.1":             false,
+		"192.168.1.1":            false,
+		"169.254.169.254":        false,
+		"100.64.0.1":             false,
+		"0.0.0.0":                false,
+		"0.1.2.3":                false,
+		"fe80::1":                false,
+		"fd00::1":                false,
+		"ff02::1":                false,
+		"224.0.0.1":              false,
+		"::ffff:127.0.0.1":       false,
+		"::ffff:169.254.169.254": false,
+	}
+	for addr, want := range cases {
+		if got := IsPublicWebhookAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublicWebhookAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestWebhookValidateURL(t *testing.T) {
+	cases := map[string]bool{
+		"https://example.com/hook":       true,
+		"http://93.184.216.34:8080/hook": true,
+		"http://localhost:8080/hook":     false,
+		"http://api.localhost/hook":      false,
+		"http://LOCALHOST./hook":         false,
+		"http://127.0.0.1/hook":          false,
+		"http://[::1]/hook":              false,
+		"http://169.254.169.254/latest":  false,
+		"http://10.0.0.5/hook":           false,
+		"ftp://example.com/hook":         false,
+	}
+	for url, valid := range cases {
+		webhook := &Webhook{URL: url, Events: []string{EventTaskCreated}, Secret: "0123456789abcdef"}
+		if err := webhook.Validate(); (err == nil) != valid {
+			t.Errorf("Validate(%s) error = %v, want valid %v", url, err, valid)
+		}
+	}
+}
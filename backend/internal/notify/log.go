@@ -0,0 +1,24 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// LogNotifier 通知をサーバーのログに出力する（開発用）
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (l *LogNotifier) Name() string {
+	return "log"
+}
+
+func (l *LogNotifier) Notify(ctx context.Context, n *Notification) error {
+	log.Printf("Reminder %s for task %s (%q) due %s, late=%t",
+		n.ReminderID, n.TaskID, n.TaskTitle, n.Deadline.UTC().Format(time.RFC3339), n.Late)
+	return nil
+}
@@ -0,0 +1,70 @@
+// Package notify リマインダーなどの通知を外部に配信するチャネル
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-app-backend/internal/config"
+)
+
+// Notification 配信する通知の内容
+type Notification struct {
+	ReminderID string    `json:"reminder_id"`
+	TaskID     string    `json:"task_id"`
+	TaskTitle  string    `json:"task_title"`
+	Deadline   time.Time `json:"deadline"`
+	FireAt     time.Time `json:"fire_at"`
+	UserID     string    `json:"user_id"`
+	UserEmail  string    `json:"-"`
+	// Late 停止中などで通知時刻より遅れて配信する
+	Late bool `json:"late"`
+}
+
+// Notifier 通知チャネル
+type Notifier interface {
+	// Name 配信記録に使うチャネル名
+	Name() string
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// Subject 通知の件名
+func (n *Notification) Subject() string {
+	return fmt.Sprintf("Reminder: %s", n.TaskTitle)
+}
+
+// Body 通知の本文
+func (n *Notification) Body() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Task %q is due at %s.\n", n.TaskTitle, n.Deadline.UTC().Format(time.RFC3339))
+	if n.Late {
+		fmt.Fprintf(&b, "This reminder was scheduled for %s and is delivered late.\n", n.FireAt.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// NewNotifiers 設定で有効にしたチャネルを作成する
+func NewNotifiers(cfg *config.Config) ([]Notifier, error) {
+	var notifiers []Notifier
+	for _, channel := range cfg.NotifyChannels {
+		switch channel {
+		case "log":
+			notifiers = append(notifiers, NewLogNotifier())
+		case "smtp":
+			if cfg.SMTPHost == "" {
+				return nil, fmt.Errorf("SMTP_HOST is required for the smtp channel")
+			}
+			notifiers = append(notifiers, NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom))
+		case "webhook":
+			if cfg.ReminderWebhookURL == "" {
+				return nil, fmt.Errorf("REMINDER_WEBHOOK_URL is required for the webhook channel")
+			}
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.ReminderWebhookURL))
+		default:
+			return nil, fmt.Errorf("unknown notification channel: %s", channel)
+		}
+	}
+	return notifiers, nil
+}
@@ -0,0 +1,201 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpStandIn テスト用の最小限のSMTPサーバー（受け取ったコマンドとメッセージを記録する）
+type smtpStandIn struct {
+	listener net.Listener
+	// rejectRcpt RCPT TO を 550 で拒否する
+	rejectRcpt bool
+
+	mu       sync.Mutex
+	commands []string
+	data     string
+	done     chan struct{}
+}
+
+func newSMTPStandIn(t *testing.T, rejectRcpt bool) *smtpStandIn {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpStandIn{listener: listener, rejectRcpt: rejectRcpt, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+// notifier スタンドインに送信する SMTPNotifier を作成する
+func (s *smtpStandIn) notifier(t *testing.T, username, password string) *SMTPNotifier {
+	t.Helper()
+
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("port: %v", err)
+	}
+	return NewSMTPNotifier(host, portNumber, username, password, "todo@example.com")
+}
+
+func (s *smtpStandIn) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 stand-in ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case verb == "EHLO":
+			reply("250-stand-in")
+			reply("250 AUTH PLAIN")
+		case verb == "AUTH":
+			reply("235 2.7.0 Authentication successful")
+		case verb == "RCPT" && s.rejectRcpt:
+			reply("550 5.1.1 No such user")
+		case verb == "MAIL", verb == "RCPT", verb == "RSET", verb == "NOOP":
+			reply("250 OK")
+		case verb == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK: queued")
+		case verb == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// received 接続が閉じるのを待ち、受け取ったコマンドとメッセージを返す
+func (s *smtpStandIn) received(t *testing.T) ([]string, string) {
+	t.Helper()
+
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP session did not finish")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commands, s.data
+}
+
+func testNotification() *Notification {
+	return &Notification{
+		ReminderID: "r1",
+		TaskID:     "t1",
+		TaskTitle:  "Write report",
+		Deadline:   time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC),
+		FireAt:     time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC),
+		UserID:     "u1",
+		UserEmail:  "user@example.com",
+		Late:       true,
+	}
+}
+
+func TestSMTPNotifierSendsMail(t *testing.T) {
+	server := newSMTPStandIn(t, false)
+
+	if err := server.notifier(t, "", "").Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	commands, data := server.received(t)
+
+	want := []string{"MAIL FROM:<todo@example.com>", "RCPT TO:<user@example.com>", "DATA", "QUIT"}
+	var got []string
+	for _, command := range commands {
+		if strings.HasPrefix(command, "AUTH") {
+			t.Errorf("AUTH sent without a username: %q", command)
+		}
+		if !strings.HasPrefix(command, "EHLO") {
+			got = append(got, strings.SplitN(command, " BODY=", 2)[0])
+		}
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+
+	for _, header := range []string{"From: todo@example.com\r\n", "To: user@example.com\r\n", "Subject: Reminder: Write report\r\n", "Content-Type: text/plain; charset=UTF-8\r\n"} {
+		if !strings.Contains(data, header) {
+			t.Errorf("message is missing %q:\n%s", header, data)
+		}
+	}
+	body := "\r\n\r\nTask \"Write report\" is due at 2026-01-02T09:00:00Z.\r\n" +
+		"This reminder was scheduled for 2026-01-02T08:00:00Z and is delivered late.\r\n"
+	if !strings.HasSuffix(data, body) {
+		t.Errorf("message body = %q, want suffix %q", data, body)
+	}
+}
+
+func TestSMTPNotifierAuthenticates(t *testing.T) {
+	server := newSMTPStandIn(t, false)
+
+	if err := server.notifier(t, "user", "secret").Notify(context.Background(), testNotification()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	commands, _ := server.received(t)
+
+	want := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00user\x00secret"))
+	for _, command := range commands {
+		if command == want {
+			return
+		}
+	}
+	t.Errorf("commands = %q, want %q", commands, want)
+}
+
+func TestSMTPNotifierErrors(t *testing.T) {
+	server := newSMTPStandIn(t, true)
+
+	if err := server.notifier(t, "", "").Notify(context.Background(), testNotification()); err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("Notify with a rejected recipient = %v, want the 550 reply", err)
+	}
+
+	n := testNotification()
+	n.UserEmail = ""
+	if err := server.notifier(t, "", "").Notify(context.Background(), n); err == nil {
+		t.Error("Notify without a recipient = nil, want an error")
+	}
+}
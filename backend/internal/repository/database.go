@@ -2,63 +2,174 @@ package repository
 
 import (
 	"database/sql"
-	"sync"
+	"errors"
+	"fmt"
+	"strconv"
 
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/mattn/go-sqlite3"
-)
 
-var (
-	db   *sql.DB
-	once sync.Once
+	"todo-app-backend/internal/config"
 )
 
-// GetDB returns a singleton database connection
-func GetDB() *sql.DB {
-	once.Do(func() {
-		var err error
-		db, err = sql.Open("sqlite3", "./todo.db")
-		if err != nil {
-			panic(err)
-		}
+// SQLiteの接続オプション
+// WALモードで読み込みと書き込みを並行させ、ロック競合時は一定時間待機する
+const sqliteOptions = "_journal_mode=WAL&_foreign_keys=on&_busy_timeout=5000"
+
+// ErrSQLiteWithoutFTS5 SQLite が FTS5 なしでビルドされている（全文検索のテーブルを作成できない）
+var ErrSQLiteWithoutFTS5 = errors.New("sqlite was built without FTS5; build with -tags sqlite_fts5")
+
+// DB データベース接続と方言
+// Exec / Query / QueryRow は ? プレースホルダを方言に合わせて変換してから実行する
+type DB struct {
+	*sql.DB
+	dialect *dialect
+}
+
+// Open 設定に従ってデータベースに接続する
+// スキーマの作成・更新は Migrator が行う
+func Open(cfg *config.Config) (*DB, error) {
+	switch cfg.DatabaseDriver {
+	case "sqlite", "sqlite3":
+		return openSQLite(cfg.DatabasePath)
+	case "postgres", "postgresql":
+		return openPostgres(cfg.DatabaseURL)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", cfg.DatabaseDriver)
+	}
+}
+
+func openSQLite(path string) (*DB, error) {
+	db, err := sql.Open(sqliteDialect.driverName, path+"?"+sqliteOptions)
+	if err != nil {
+		return nil, err
+	}
 
-		// テーブルを作成
-		createTables()
-	})
-	return db
-}
-
-func createTables() {
-	// Users table
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id TEXT PRIMARY KEY,
-		email TEXT UNIQUE NOT NULL,
-		password_hash TEXT NOT NULL,
-		name TEXT NOT NULL,
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL
-	);`
-
-	// Tasks table
-	createTasksTable := `
-	CREATE TABLE IF NOT EXISTS tasks (
-		id TEXT PRIMARY KEY,
-		user_id TEXT NOT NULL,
-		title TEXT NOT NULL,
-		description TEXT,
-		deadline DATETIME,
-		priority TEXT NOT NULL,
-		status TEXT NOT NULL,
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL,
-		FOREIGN KEY (user_id) REFERENCES users (id)
-	);`
-
-	if _, err := db.Exec(createUsersTable); err != nil {
-		panic(err)
+	// インメモリDBは接続ごとに別のDBになるため、接続を1つに制限する
+	if path == ":memory:" {
+		db.SetMaxOpenConns(1)
 	}
 
-	if _, err := db.Exec(createTasksTable); err != nil {
-		panic(err)
+	conn, err := ping(db, sqliteDialect)
+	if err != nil {
+		return nil, err
+	}
+
+	// マイグレーションの途中で失敗しないよう、全文検索に必要な FTS5 を接続時に確認する
+	var fts5 bool
+	if err := conn.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !fts5 {
+		conn.Close()
+		return nil, ErrSQLiteWithoutFTS5
+	}
+	return conn, nil
+}
+
+func openPostgres(url string) (*DB, error) {
+	if url == "" {
+		return nil, fmt.Errorf("DATABASE_URL is required for the postgres driver")
+	}
+
+	db, err := sql.Open(postgresDialect.driverName, url)
+	if err != nil {
+		return nil, err
+	}
+	return ping(db, postgresDialect)
+}
+
+func ping(db *sql.DB, d *dialect) (*DB, error) {
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{DB: db, dialect: d}, nil
+}
+
+// Driver 接続先のデータベースの種類（sqlite / postgres）
+func (db *DB) Driver() string {
+	return db.dialect.name
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.dialect.rebind(query), args...)
+}
+
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.dialect.rebind(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.dialect.rebind(query), args...)
+}
+
+// Tx トランザクション（DB と同じく ? プレースホルダを変換してから実行する）
+type Tx struct {
+	*sql.Tx
+	dialect *dialect
+	// savepoints 入れ子にした withTx の深さ（セーブポイント名に使う）
+	savepoints int
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.rebind(query), args...)
+}
+
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.rebind(query), args...)
+}
+
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.rebind(query), args...)
+}
+
+// querier DB と Tx の共通インターフェース
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// executor DB と Tx の共通インターフェース（リポジトリをトランザクション内でも同じ実装で動かす）
+type executor interface {
+	querier
+	withTx(fn func(tx *Tx) error) error
+}
+
+// withTx fn をトランザクション内で実行し、エラーがなければコミットする
+func (db *DB) withTx(fn func(tx *Tx) error) error {
+	sqlTx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer sqlTx.Rollback()
+
+	if err := fn(&Tx{Tx: sqlTx, dialect: db.dialect}); err != nil {
+		return err
+	}
+	return sqlTx.Commit()
+}
+
+// withTx トランザクション内ではセーブポイントで入れ子にし、fn がエラーを返せば fn の変更のみを取り消す
+func (tx *Tx) withTx(fn func(tx *Tx) error) error {
+	tx.savepoints++
+	defer func() { tx.savepoints-- }()
+	savepoint := "sp" + strconv.Itoa(tx.savepoints)
+
+	if _, err := tx.Exec(`SAVEPOINT ` + savepoint); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if _, rollbackErr := tx.Exec(`ROLLBACK TO SAVEPOINT ` + savepoint); rollbackErr != nil {
+			return rollbackErr
+		}
+		if _, releaseErr := tx.Exec(`RELEASE SAVEPOINT ` + savepoint); releaseErr != nil {
+			return releaseErr
+		}
+		return err
 	}
+	_, err := tx.Exec(`RELEASE SAVEPOINT ` + savepoint)
+	return err
 }
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dialect データベースごとのSQLの違い
+//
+// リポジトリのクエリは ? プレースホルダで記述し、実行時に rebind で変換する。
+// 日時の比較・ソートは timeExpr を経由させる（SQLiteは文字列で保存するため julianday() で正規化する）。
+// UPSERT は両方が対応する INSERT ... ON CONFLICT (...) DO UPDATE / DO NOTHING 構文で記述する。
+// 全文検索は SQLite の FTS5 と PostgreSQL の tsvector で構文が大きく異なるため、fullText がクエリ全体を組み立てる。
+type dialect struct {
+	name       string // マイグレーションのディレクトリ名
+	driverName string // database/sql のドライバー名
+	numbered   bool   // $1, $2, ... 形式のプレースホルダを使う
+	timeFunc   string // 日時を比較可能な値に変換する関数（不要な場合は空）
+	// unicodeLower LOWER() が ASCII 以外の文字も小文字にする（SQLite の LOWER() は ASCII の英字のみ）
+	unicodeLower bool
+	fullText     fullTextSearch
+
+	// schema_migrations テーブルの定義
+	versionTableDDL string
+}
+
+var sqliteDialect = &dialect{
+	name:       "sqlite",
+	driverName: "sqlite3",
+	timeFunc:   "julianday",
+	fullText:   sqliteFullText{},
+	versionTableDDL: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`,
+}
+
+var postgresDialect = &dialect{
+	name:         "postgres",
+	driverName:   "pgx",
+	numbered:     true,
+	unicodeLower: true,
+	fullText:     postgresFullText{},
+	versionTableDDL: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+}
+
+// rebind ? プレースホルダを方言の形式に変換する（文字列リテラル内の ? はそのまま）
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+			b.WriteRune(r)
+		case r == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// timeExpr 日時のカラムまたはプレースホルダを、比較・ソートに使える式にする
+func (d *dialect) timeExpr(expr string) string {
+	if d.timeFunc == "" {
+		return expr
+	}
+	return d.timeFunc + "(" + expr + ")"
+}
+
+// lowerASCII ASCII の英字のみを小文字にする式（models.LowerASCII と同じ規則）
+func (d *dialect) lowerASCII(expr string) string {
+	if !d.unicodeLower {
+		return "LOWER(" + expr + ")"
+	}
+	return "TRANSLATE(" + expr + ", 'ABCDEFGHIJKLMNOPQRSTUVWXYZ', 'abcdefghijklmnopqrstuvwxyz')"
+}
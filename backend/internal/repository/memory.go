@@ -0,0 +1,2058 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/utils"
+)
+
+// ErrDuplicateEmail メールアドレスが既に登録されている（インメモリ実装の一意制約違反）
+var ErrDuplicateEmail = errors.New("email already exists")
+
+// ErrUserNotFound 存在しないユーザーを参照した（インメモリ実装の外部キー制約違反）
+var ErrUserNotFound = errors.New("user not found")
+
+// memoryStore インメモリ実装の共有データ
+// SQL実装と同じく、外部キー・一意制約を検査する
+type memoryStore struct {
+	mu            sync.RWMutex
+	users         map[string]models.User
+	tasks         map[string]models.Task
+	projects      map[string]models.Project
+	series        map[string]models.TaskSeries
+	tags          map[string]models.Tag
+	taskTags      map[string]map[string]bool // タスクID → タグIDの集合
+	taskHistory   map[string]models.TaskHistoryEntry
+	views         map[string]models.View
+	reminders     map[string]models.Reminder
+	deliveries    map[deliveryKey]models.ReminderDelivery
+	webhooks      map[string]models.Webhook
+	webhookLog    map[string]models.WebhookDelivery
+	refreshTokens map[string]models.RefreshToken
+}
+
+// NewMemoryRepositories データベースを使わないリポジトリを作成する
+// ハンドラーのテストなど、永続化が不要な場面で使用する
+func NewMemoryRepositories() *Repositories {
+	store := &memoryStore{
+		users:         map[string]models.User{},
+		tasks:         map[string]models.Task{},
+		projects:      map[string]models.Project{},
+		series:        map[string]models.TaskSeries{},
+		tags:          map[string]models.Tag{},
+		taskTags:      map[string]map[string]bool{},
+		taskHistory:   map[string]models.TaskHistoryEntry{},
+		views:         map[string]models.View{},
+		reminders:     map[string]models.Reminder{},
+		deliveries:    map[deliveryKey]models.ReminderDelivery{},
+		webhooks:      map[string]models.Webhook{},
+		webhookLog:    map[string]models.WebhookDelivery{},
+		refreshTokens: map[string]models.RefreshToken{},
+	}
+	return &Repositories{
+		Tasks:         &memoryTaskRepository{store: store},
+		TaskHistory:   &memoryTaskHistoryRepository{store: store},
+		Projects:      &memoryProjectRepository{store: store},
+		Views:         &memoryViewRepository{store: store},
+		Tags:          &memoryTagRepository{store: store},
+		Reminders:     &memoryReminderRepository{store: store},
+		Webhooks:      &memoryWebhookRepository{store: store},
+		Users:         &memoryUserRepository{store: store},
+		RefreshTokens: &memoryRefreshTokenRepository{store: store},
+	}
+}
+
+type memoryTaskRepository struct {
+	store *memoryStore
+	// inTx WithTx の fn に渡したリポジトリ（ロックは WithTx が保持している）
+	inTx bool
+}
+
+// lock ストアの書き込みロックを取得し、解放する関数を返す（WithTx の中ではロック済みのため何もしない）
+func (r *memoryTaskRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.store.mu.Lock()
+	return r.store.mu.Unlock
+}
+
+// rlock ストアの読み込みロックを取得し、解放する関数を返す（WithTx の中ではロック済みのため何もしない）
+func (r *memoryTaskRepository) rlock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.store.mu.RLock()
+	return r.store.mu.RUnlock
+}
+
+// WithTx ロックを保持したまま fn を実行し、fn がエラーを返せばタスクに関連するデータ（変更履歴を含む）を実行前の状態に戻す
+func (r *memoryTaskRepository) WithTx(fn func(repo TaskRepository, history TaskHistoryRepository) error) error {
+	defer r.lock()()
+
+	snapshot := r.store.snapshotTasks()
+	err := fn(&memoryTaskRepository{store: r.store, inTx: true}, &memoryTaskHistoryRepository{store: r.store, inTx: true})
+	if err != nil {
+		r.store.restoreTasks(snapshot)
+		return err
+	}
+	return nil
+}
+
+func (r *memoryTaskRepository) CreateTask(task *models.Task) error {
+	defer r.lock()()
+
+	if _, ok := r.store.tasks[task.ID]; ok {
+		return errors.New("task already exists")
+	}
+	if _, ok := r.store.users[task.UserID]; !ok {
+		return ErrUserNotFound
+	}
+	if err := r.checkParent(task); err != nil {
+		return err
+	}
+	if err := r.resolveProject(task); err != nil {
+		return err
+	}
+	r.store.createSeries(task)
+	task.Version = 1
+	r.store.tasks[task.ID] = *task
+	r.store.replaceTaskTags(task)
+	return nil
+}
+
+// checkParent checkTaskParent と同じ条件で親タスクを検証する（呼び出し側でロックを取得する）
+func (r *memoryTaskRepository) checkParent(task *models.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+
+	parent, ok := r.store.liveTask(*task.ParentID)
+	if !ok || parent.UserID != task.UserID {
+		return ErrParentNotFound
+	}
+
+	// 親をたどってこのタスクに戻るなら循環する（既存データの循環でも停止するよう訪問済みを記録する）
+	visited := map[string]bool{}
+	for id := parent.ID; !visited[id]; {
+		if id == task.ID {
+			return ErrTaskCycle
+		}
+		visited[id] = true
+		ancestor, ok := r.store.tasks[id]
+		if !ok || ancestor.ParentID == nil {
+			break
+		}
+		id = *ancestor.ParentID
+	}
+	return nil
+}
+
+// resolveProject resolveTaskProject と同じ規則でプロジェクトを決める（呼び出し側でロックを取得する）
+func (r *memoryTaskRepository) resolveProject(task *models.Task) error {
+	if task.ParentID != nil {
+		task.ProjectID = r.store.tasks[*task.ParentID].ProjectID
+		return nil
+	}
+	if task.ProjectID == "" {
+		task.ProjectID = r.store.ensureInbox(task.UserID, task.CreatedAt).ID
+		return nil
+	}
+	return r.store.checkTaskProject(task.UserID, task.ProjectID)
+}
+
+// moveSubtree moveTaskSubtree と同じくタスクと子孫のプロジェクトを変更する（呼び出し側でロックを取得する）
+func (r *memoryTaskRepository) moveSubtree(taskID, projectID string) {
+	for _, id := range append(r.descendantIDs(taskID), taskID) {
+		task := r.store.tasks[id]
+		if task.ProjectID != projectID {
+			task.ProjectID = projectID
+			task.Version++
+			r.store.tasks[id] = task
+		}
+	}
+}
+
+func (r *memoryTaskRepository) GetTasksByUserID(userID string, filters *models.TaskFilters, pagination *models.TaskPagination) (*models.TaskPage, error) {
+	if filters == nil {
+		filters = &models.TaskFilters{}
+	}
+	if err := filters.Validate(); err != nil {
+		return nil, err
+	}
+
+	sortBy, sortOrder := taskSortOf(filters)
+	var cursor *taskCursor
+	if pagination != nil && pagination.Cursor != nil && *pagination.Cursor != "" {
+		var err error
+		cursor, err = decodeTaskCursor(*pagination.Cursor, sortBy, sortOrder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query, _ := filters.ParsedQuery()
+
+	unlock := r.rlock()
+	now := time.Now()
+	var tasks []models.Task
+	for _, task := range r.store.tasks {
+		task = r.store.withDetails(task)
+		if task.UserID == userID && task.DeletedAt == nil && matchTaskFilters(&task, filters, now) &&
+			(query == nil || r.store.matchTaskQuery(&task, query, now)) {
+			tasks = append(tasks, task)
+		}
+	}
+	unlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return compareTasks(&tasks[i], &tasks[j], sortBy, sortOrder) < 0
+	})
+
+	// カーソルより後ろのタスクから limit 件を返す
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(tasks), func(i int) bool {
+			return compareTaskToCursor(&tasks[i], cursor) > 0
+		})
+	}
+	tasks = tasks[start:]
+
+	limit := pagination.PageSize()
+	page := &models.TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.HasMore = true
+		page.NextCursor = newTaskCursor(&page.Tasks[limit-1], sortBy, sortOrder).encode()
+	}
+	if len(page.Tasks) == 0 {
+		page.Tasks = nil
+	}
+	return page, nil
+}
+
+// matchTaskFilters buildTaskListQuery のWHERE句と同じ条件で判定する
+func matchTaskFilters(task *models.Task, filters *models.TaskFilters, now time.Time) bool {
+	if filters.ProjectID != nil && *filters.ProjectID != "" && task.ProjectID != *filters.ProjectID {
+		return false
+	}
+	if filters.Status != nil && *filters.Status != "all" && task.Status != *filters.Status {
+		return false
+	}
+	if filters.Priority != nil && *filters.Priority != "all" && task.Priority != *filters.Priority {
+		return false
+	}
+	if filters.DeadlineFrom != nil && (task.Deadline == nil || task.Deadline.Before(*filters.DeadlineFrom)) {
+		return false
+	}
+	if filters.DeadlineTo != nil && (task.Deadline == nil || task.Deadline.After(*filters.DeadlineTo)) {
+		return false
+	}
+	if filters.Overdue != nil && *filters.Overdue {
+		if task.Deadline == nil || !task.Deadline.Before(now) || task.Status == "completed" {
+			return false
+		}
+	}
+	if filters.NoDeadline != nil && *filters.NoDeadline && task.Deadline != nil {
+		return false
+	}
+
+	tags := make(map[string]bool, len(task.Tags))
+	for _, name := range task.Tags {
+		tags[name] = true
+	}
+	if names, _ := models.NormalizeTagNames(filters.TagsAny); len(names) > 0 {
+		found := false
+		for _, name := range names {
+			found = found || tags[name]
+		}
+		if !found {
+			return false
+		}
+	}
+	if names, _ := models.NormalizeTagNames(filters.TagsAll); len(names) > 0 {
+		for _, name := range names {
+			if !tags[name] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchTaskQuery compileTaskQuery の条件と同じ判定をする
+func (s *memoryStore) matchTaskQuery(task *models.Task, node models.TaskQueryNode, now time.Time) bool {
+	switch node := node.(type) {
+	case *models.TaskQueryAnd:
+		for _, operand := range node.Operands {
+			if !s.matchTaskQuery(task, operand, now) {
+				return false
+			}
+		}
+		return true
+	case *models.TaskQueryOr:
+		for _, operand := range node.Operands {
+			if s.matchTaskQuery(task, operand, now) {
+				return true
+			}
+		}
+		return false
+	case *models.TaskQueryNot:
+		return !s.matchTaskQuery(task, node.Operand, now)
+	}
+
+	c := node.(*models.TaskQueryCondition)
+	switch c.Field {
+	case models.TaskQueryStatus:
+		return task.Status == c.Value
+	case models.TaskQueryPriority:
+		rank, value := priorityRank[task.Priority], priorityRank[c.Value]
+		switch c.Op {
+		case "<":
+			return rank < value
+		case "<=":
+			return rank <= value
+		case ">":
+			return rank > value
+		case ">=":
+			return rank >= value
+		}
+		return task.Priority == c.Value
+	case models.TaskQueryDue:
+		if c.Date.None {
+			return task.Deadline == nil
+		}
+		return task.Deadline != nil && c.Date.Contains(c.Op, now, *task.Deadline)
+	case models.TaskQueryCreated:
+		return c.Date.Contains(c.Op, now, task.CreatedAt)
+	case models.TaskQueryUpdated:
+		return c.Date.Contains(c.Op, now, task.UpdatedAt)
+	case models.TaskQueryTag:
+		for _, name := range task.Tags {
+			if name == c.Value {
+				return true
+			}
+		}
+		return false
+	case models.TaskQueryProject:
+		project, ok := s.projects[task.ProjectID]
+		return ok && project.UserID == task.UserID && (project.ID == c.Value || project.Name == c.Value)
+	case models.TaskQueryIs:
+		switch c.Value {
+		case "overdue":
+			return task.Deadline != nil && task.Deadline.Before(now) && task.Status != "completed"
+		case "recurring":
+			return task.SeriesID != nil
+		default:
+			return task.ParentID != nil
+		}
+	case models.TaskQueryHas:
+		switch c.Value {
+		case "deadline":
+			return task.Deadline != nil
+		case "description":
+			return task.Description != nil && *task.Description != ""
+		default:
+			return len(task.Tags) > 0
+		}
+	default:
+		// SQLite の LOWER と同じく ASCII の英字のみ小文字にそろえる
+		description := ""
+		if task.Description != nil {
+			description = *task.Description
+		}
+		return strings.Contains(models.LowerASCII(task.Title), c.Value) || strings.Contains(models.LowerASCII(description), c.Value)
+	}
+}
+
+// compareTasks taskOrderBy と同じ並び順で比較する
+func compareTasks(a, b *models.Task, sortBy, sortOrder string) int {
+	return compareTaskToCursor(a, newTaskCursor(b, sortBy, sortOrder))
+}
+
+// compareTaskToCursor タスクがカーソルより前なら負、後ろなら正を返す
+func compareTaskToCursor(task *models.Task, cursor *taskCursor) int {
+	other := newTaskCursor(task, cursor.SortBy, cursor.SortOrder)
+
+	// 期限なしは並び順に関わらず末尾
+	if cursor.SortBy == "deadline" && (other.Time == nil) != (cursor.Time == nil) {
+		if other.Time == nil {
+			return 1
+		}
+		return -1
+	}
+
+	result := 0
+	switch {
+	case other.Rank != nil:
+		result = compareInts(*other.Rank, *cursor.Rank)
+	case other.Time != nil:
+		result = other.Time.Compare(*cursor.Time)
+	}
+	if result == 0 {
+		result = compareStrings(other.ID, cursor.ID)
+	}
+	if cursor.SortOrder == "desc" {
+		result = -result
+	}
+	return result
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareStrings(a, b string) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (r *memoryTaskRepository) GetTaskByID(taskID string) (*models.Task, error) {
+	defer r.rlock()()
+
+	task, ok := r.store.liveTask(taskID)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	task = r.store.withDetails(task)
+	return &task, nil
+}
+
+// SearchTasks SQL実装の全文検索を単語の一致で再現する（タイトルの一致を説明の一致の5倍に数える）
+func (r *memoryTaskRepository) SearchTasks(userID string, query *models.TaskSearchQuery, limit int) ([]models.TaskSearchResult, error) {
+	defer r.rlock()()
+
+	var results []models.TaskSearchResult
+	for _, task := range r.store.tasks {
+		if task.UserID != userID || task.DeletedAt != nil {
+			continue
+		}
+		description := ""
+		if task.Description != nil {
+			description = *task.Description
+		}
+		title, body := newSearchText(task.Title), newSearchText(description)
+
+		score, matched := 0.0, true
+		for _, term := range query.Terms {
+			inTitle, inBody := title.match(term), body.match(term)
+			if inTitle+inBody == 0 {
+				matched = false
+				break
+			}
+			score += float64(5*inTitle + inBody)
+		}
+		if !matched {
+			continue
+		}
+		results = append(results, models.TaskSearchResult{
+			Task:           r.store.withDetails(task),
+			Score:          score,
+			TitleHighlight: title.highlight(),
+			Snippet:        body.highlight(),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchText 検索対象の文字列と単語の位置（一致した単語に印を付ける）
+type searchText struct {
+	text   string
+	words  []string
+	spans  [][2]int
+	marked []bool
+}
+
+func newSearchText(text string) *searchText {
+	s := &searchText{text: text}
+	start := -1
+	for i, r := range text + " " {
+		isWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if isWord && start < 0 {
+			start = i
+		} else if !isWord && start >= 0 {
+			s.words = append(s.words, strings.ToLower(text[start:i]))
+			s.spans = append(s.spans, [2]int{start, i})
+			start = -1
+		}
+	}
+	s.marked = make([]bool, len(s.words))
+	return s
+}
+
+// match 検索語が連続して現れる箇所に印を付け、その数を返す
+func (s *searchText) match(term models.TaskSearchTerm) int {
+	count := 0
+	last := len(term.Words) - 1
+	for i := 0; i+last < len(s.words); i++ {
+		matched := true
+		for j, word := range term.Words {
+			if s.words[i+j] != word && !(term.Prefix && j == last && strings.HasPrefix(s.words[i+j], word)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			count++
+			for j := i; j <= i+last; j++ {
+				s.marked[j] = true
+			}
+		}
+	}
+	return count
+}
+
+// highlight 印を付けた単語（連続する場合はまとめて）を <mark> で囲む
+func (s *searchText) highlight() string {
+	var b strings.Builder
+	pos := 0
+	for i, span := range s.spans {
+		if !s.marked[i] || (i > 0 && s.marked[i-1]) {
+			continue
+		}
+		end := i
+		for end+1 < len(s.spans) && s.marked[end+1] {
+			end++
+		}
+		b.WriteString(s.text[pos:span[0]] + searchMarkStart + s.text[span[0]:s.spans[end][1]] + searchMarkEnd)
+		pos = s.spans[end][1]
+	}
+	b.WriteString(s.text[pos:])
+	return searchHighlightHTML(b.String())
+}
+
+func (r *memoryTaskRepository) UpdateTask(task *models.Task) error {
+	defer r.lock()()
+
+	current, ok := r.store.liveTask(task.ID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if current.Version != task.Version {
+		return ErrTaskVersionConflict
+	}
+	if err := r.checkParent(task); err != nil {
+		return err
+	}
+	r.store.createSeries(task)
+	task.Version++
+	current.Version = task.Version
+	if task.ParentID != nil {
+		task.ProjectID = r.store.tasks[*task.ParentID].ProjectID
+		current.ProjectID = task.ProjectID
+	}
+	current.ParentID = task.ParentID
+	current.SeriesID = task.SeriesID
+	current.OccurrenceAt = task.OccurrenceAt
+	current.Title = task.Title
+	current.Description = task.Description
+	current.Deadline = task.Deadline
+	current.Priority = task.Priority
+	current.Status = task.Status
+	current.UpdatedAt = task.UpdatedAt
+	r.store.tasks[task.ID] = current
+	// 子孫も親のプロジェクトに移す
+	r.moveSubtree(task.ID, current.ProjectID)
+	r.store.replaceTaskTags(task)
+	return nil
+}
+
+func (r *memoryTaskRepository) MoveTask(taskID string, version int64, projectID string, updatedAt time.Time) error {
+	defer r.lock()()
+
+	task, ok := r.store.liveTask(taskID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if err := r.store.checkTaskProject(task.UserID, projectID); err != nil {
+		return err
+	}
+	if task.Version != version {
+		return ErrTaskVersionConflict
+	}
+
+	if task.ParentID != nil && r.store.tasks[*task.ParentID].ProjectID != projectID {
+		task.ParentID = nil
+	}
+	task.ProjectID = projectID
+	task.UpdatedAt = updatedAt
+	task.Version++
+	r.store.tasks[taskID] = task
+	r.moveSubtree(taskID, projectID)
+	return nil
+}
+
+func (r *memoryTaskRepository) DeleteTask(taskID string, version int64, deletedAt time.Time) error {
+	defer r.lock()()
+
+	task, ok := r.store.liveTask(taskID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if task.Version != version {
+		return ErrTaskVersionConflict
+	}
+	r.trashSubtree(taskID, deletedAt)
+	return nil
+}
+
+// trashSubtree タスクと子孫を同じ日時でゴミ箱に移す（呼び出し側でロックを取得する）
+func (r *memoryTaskRepository) trashSubtree(taskID string, deletedAt time.Time) {
+	for _, id := range append(r.descendantIDs(taskID), taskID) {
+		task := r.store.tasks[id]
+		if task.DeletedAt == nil {
+			task.DeletedAt = &deletedAt
+			task.Version++
+			r.store.tasks[id] = task
+		}
+	}
+}
+
+// memoryTaskSnapshot WithTx で取り消すための、タスクの操作で変更されうるデータの複製
+type memoryTaskSnapshot struct {
+	tasks       map[string]models.Task
+	projects    map[string]models.Project
+	series      map[string]models.TaskSeries
+	tags        map[string]models.Tag
+	taskTags    map[string]map[string]bool
+	taskHistory map[string]models.TaskHistoryEntry
+	reminders   map[string]models.Reminder
+	deliveries  map[deliveryKey]models.ReminderDelivery
+}
+
+// snapshotTasks タスクに関連するデータを複製する（呼び出し側でロックを取得する）
+func (s *memoryStore) snapshotTasks() *memoryTaskSnapshot {
+	taskTags := make(map[string]map[string]bool, len(s.taskTags))
+	for taskID, tagIDs := range s.taskTags {
+		taskTags[taskID] = maps.Clone(tagIDs)
+	}
+	return &memoryTaskSnapshot{
+		tasks:       maps.Clone(s.tasks),
+		projects:    maps.Clone(s.projects),
+		series:      maps.Clone(s.series),
+		tags:        maps.Clone(s.tags),
+		taskTags:    taskTags,
+		taskHistory: maps.Clone(s.taskHistory),
+		reminders:   maps.Clone(s.reminders),
+		deliveries:  maps.Clone(s.deliveries),
+	}
+}
+
+// restoreTasks snapshotTasks の時点の状態に戻す（呼び出し側でロックを取得する）
+func (s *memoryStore) restoreTasks(snapshot *memoryTaskSnapshot) {
+	s.tasks = snapshot.tasks
+	s.projects = snapshot.projects
+	s.series = snapshot.series
+	s.tags = snapshot.tags
+	s.taskTags = snapshot.taskTags
+	s.taskHistory = snapshot.taskHistory
+	s.reminders = snapshot.reminders
+	s.deliveries = snapshot.deliveries
+}
+
+// liveTask ゴミ箱にないタスクを返す（呼び出し側でロックを取得する）
+func (s *memoryStore) liveTask(taskID string) (models.Task, bool) {
+	task, ok := s.tasks[taskID]
+	if !ok || task.DeletedAt != nil {
+		return models.Task{}, false
+	}
+	return task, true
+}
+
+// deleteTasks 外部キーの ON DELETE CASCADE と同じく、タスクに関連するデータも削除する（呼び出し側でロックを取得する）
+// 変更履歴は監査のため残す
+func (s *memoryStore) deleteTasks(taskIDs []string) {
+	deleted := make(map[string]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		delete(s.tasks, id)
+		delete(s.taskTags, id)
+		deleted[id] = true
+	}
+	for id, reminder := range s.reminders {
+		if deleted[reminder.TaskID] {
+			s.deleteReminder(id)
+		}
+	}
+}
+
+// descendantIDs 子孫のタスクのIDを返す（呼び出し側でロックを取得する）
+func (r *memoryTaskRepository) descendantIDs(taskID string) []string {
+	children := map[string][]string{}
+	for _, task := range r.store.tasks {
+		if task.ParentID != nil {
+			children[*task.ParentID] = append(children[*task.ParentID], task.ID)
+		}
+	}
+
+	var ids []string
+	visited := map[string]bool{taskID: true}
+	queue := []string{taskID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if !visited[child] {
+				visited[child] = true
+				ids = append(ids, child)
+				queue = append(queue, child)
+			}
+		}
+	}
+	return ids
+}
+
+func (r *memoryTaskRepository) GetChildTasks(parentID string) ([]models.Task, error) {
+	defer r.rlock()()
+
+	var tasks []models.Task
+	for _, task := range r.store.tasks {
+		if task.ParentID != nil && *task.ParentID == parentID && task.DeletedAt == nil {
+			tasks = append(tasks, r.store.withDetails(task))
+		}
+	}
+	sortTasksByCreatedAt(tasks)
+	return tasks, nil
+}
+
+func (r *memoryTaskRepository) GetTaskSubtree(rootID string) ([]models.Task, error) {
+	defer r.rlock()()
+
+	root, ok := r.store.liveTask(rootID)
+	if !ok {
+		return nil, nil
+	}
+	tasks := []models.Task{r.store.withDetails(root)}
+	for _, id := range r.descendantIDs(rootID) {
+		if task := r.store.tasks[id]; task.DeletedAt == nil {
+			tasks = append(tasks, r.store.withDetails(task))
+		}
+	}
+	sortTasksByCreatedAt(tasks)
+	return tasks, nil
+}
+
+func (r *memoryTaskRepository) CompleteSubtasks(parentID string, updatedAt time.Time) ([]models.Task, error) {
+	defer r.lock()()
+
+	var completed []models.Task
+	for _, id := range r.descendantIDs(parentID) {
+		task := r.store.tasks[id]
+		if task.Status != "completed" && task.DeletedAt == nil {
+			task.Status = "completed"
+			task.UpdatedAt = updatedAt
+			task.Version++
+			r.store.tasks[id] = task
+			completed = append(completed, r.store.withDetails(task))
+		}
+	}
+	sortTasksByCreatedAt(completed)
+	return completed, nil
+}
+
+func (r *memoryTaskRepository) GetDeletedTasks(userID string) ([]models.Task, error) {
+	defer r.rlock()()
+
+	var tasks []models.Task
+	for _, task := range r.store.tasks {
+		if task.UserID != userID || task.DeletedAt == nil {
+			continue
+		}
+		// 親と一緒に削除した子孫は含めない
+		if task.ParentID != nil {
+			if parent, ok := r.store.tasks[*task.ParentID]; ok && parent.DeletedAt != nil && parent.DeletedAt.Equal(*task.DeletedAt) {
+				continue
+			}
+		}
+		tasks = append(tasks, r.store.withDetails(task))
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].DeletedAt.Equal(*tasks[j].DeletedAt) {
+			return tasks[i].DeletedAt.After(*tasks[j].DeletedAt)
+		}
+		return tasks[i].ID > tasks[j].ID
+	})
+	return tasks, nil
+}
+
+func (r *memoryTaskRepository) GetDeletedTaskByID(taskID string) (*models.Task, error) {
+	defer r.rlock()()
+
+	task, ok := r.store.tasks[taskID]
+	if !ok || task.DeletedAt == nil {
+		return nil, sql.ErrNoRows
+	}
+	task = r.store.withDetails(task)
+	return &task, nil
+}
+
+func (r *memoryTaskRepository) RestoreTask(taskID string, restoredAt time.Time) error {
+	defer r.lock()()
+
+	root, ok := r.store.tasks[taskID]
+	if !ok || root.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	deletedAt := *root.DeletedAt
+	if root.ParentID != nil {
+		if _, ok := r.store.liveTask(*root.ParentID); !ok {
+			root.ParentID = nil
+			r.store.tasks[taskID] = root
+		}
+	}
+	// プロジェクトが削除されていれば、親タスクのプロジェクト（トップレベルなら Inbox）に戻す
+	restoredProject := ""
+	if _, ok := r.store.projects[root.ProjectID]; !ok {
+		if root.ParentID != nil {
+			restoredProject = r.store.tasks[*root.ParentID].ProjectID
+		} else {
+			restoredProject = r.store.ensureInbox(root.UserID, restoredAt).ID
+		}
+	}
+
+	// 同じ日時に削除した子孫のみ戻す（それより前に個別に削除したものはゴミ箱に残る）
+	for _, id := range append(r.descendantIDs(taskID), taskID) {
+		task := r.store.tasks[id]
+		if task.DeletedAt != nil && task.DeletedAt.Equal(deletedAt) {
+			task.DeletedAt = nil
+			if restoredProject != "" {
+				task.ProjectID = restoredProject
+			}
+			task.UpdatedAt = restoredAt
+			task.Version++
+			r.store.tasks[id] = task
+		}
+	}
+	return nil
+}
+
+func (r *memoryTaskRepository) PurgeTask(taskID string) error {
+	defer r.lock()()
+
+	task, ok := r.store.tasks[taskID]
+	if !ok || task.DeletedAt == nil {
+		return sql.ErrNoRows
+	}
+	// 外部キーの ON DELETE CASCADE と同じく子孫も削除する
+	r.store.deleteTasks(append(r.descendantIDs(taskID), taskID))
+	return nil
+}
+
+func (r *memoryTaskRepository) PurgeDeletedTasks(before time.Time) (int64, error) {
+	defer r.lock()()
+
+	var expired []string
+	for id, task := range r.store.tasks {
+		if task.DeletedAt != nil && task.DeletedAt.Before(before) {
+			expired = append(expired, id)
+		}
+	}
+	for _, id := range expired {
+		if _, ok := r.store.tasks[id]; ok {
+			r.store.deleteTasks(append(r.descendantIDs(id), id))
+		}
+	}
+	return int64(len(expired)), nil
+}
+
+// sortTasksByCreatedAt SQL実装の ORDER BY created_at, id と同じ順に並べる
+func sortTasksByCreatedAt(tasks []models.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].CreatedAt.Equal(tasks[j].CreatedAt) {
+			return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+}
+
+// withDetails loadTaskDetails と同じくタグと繰り返しのルールを設定する（呼び出し側でロックを取得する）
+func (s *memoryStore) withDetails(task models.Task) models.Task {
+	task = s.withTags(task)
+	task.RRule, task.Timezone = nil, nil
+	if task.SeriesID != nil {
+		if series, ok := s.series[*task.SeriesID]; ok && series.EndedAt == nil {
+			task.RRule, task.Timezone = &series.RRule, &series.Timezone
+		}
+	}
+	return task
+}
+
+// createSeries createTaskSeries と同じく、系列のない繰り返しタスクに系列を作成する（呼び出し側でロックを取得する）
+func (s *memoryStore) createSeries(task *models.Task) {
+	if task.RRule == nil || task.SeriesID != nil {
+		return
+	}
+	series := models.NewTaskSeries(utils.GenerateID(), task)
+	s.series[series.ID] = *series
+	task.SeriesID = &series.ID
+}
+
+func (r *memoryTaskRepository) GetTaskSeries(seriesID string) (*models.TaskSeries, error) {
+	defer r.rlock()()
+
+	series, ok := r.store.series[seriesID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &series, nil
+}
+
+func (r *memoryTaskRepository) UpdateTaskSeries(series *models.TaskSeries) error {
+	defer r.lock()()
+
+	current, ok := r.store.series[series.ID]
+	if !ok {
+		return nil
+	}
+	current.RRule = series.RRule
+	current.Timezone = series.Timezone
+	current.DTStart = series.DTStart
+	current.Title = series.Title
+	current.Description = series.Description
+	current.Priority = series.Priority
+	current.UpdatedAt = series.UpdatedAt
+	r.store.series[series.ID] = current
+	return nil
+}
+
+func (r *memoryTaskRepository) EndTaskSeries(seriesID string, endedAt time.Time) ([]models.Task, error) {
+	defer r.lock()()
+
+	series, ok := r.store.series[seriesID]
+	if !ok || series.EndedAt != nil {
+		return nil, nil
+	}
+	series.EndedAt = &endedAt
+	series.UpdatedAt = endedAt
+	r.store.series[seriesID] = series
+
+	var ended []models.Task
+	for id, task := range r.store.tasks {
+		if task.SeriesID != nil && *task.SeriesID == seriesID && task.DeletedAt == nil {
+			task.Version++
+			r.store.tasks[id] = task
+			ended = append(ended, r.store.withDetails(task))
+		}
+	}
+	sortTasksByCreatedAt(ended)
+	return ended, nil
+}
+
+func (r *memoryTaskRepository) CreateOccurrence(task *models.Task) (bool, error) {
+	defer r.lock()()
+
+	return r.createOccurrence(task)
+}
+
+func (r *memoryTaskRepository) SkipOccurrence(taskID string, version int64, next *models.Task, skippedAt time.Time) error {
+	defer r.lock()()
+
+	task, ok := r.store.liveTask(taskID)
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if task.Version != version {
+		return ErrTaskVersionConflict
+	}
+	if next != nil {
+		if _, err := r.createOccurrence(next); err != nil {
+			return err
+		}
+	}
+	r.trashSubtree(taskID, skippedAt)
+	return nil
+}
+
+// createOccurrence createOccurrence と同じく、同じ回が既にあれば作成しない（呼び出し側でロックを取得する）
+func (r *memoryTaskRepository) createOccurrence(task *models.Task) (bool, error) {
+	for _, existing := range r.store.tasks {
+		if existing.SeriesID != nil && *existing.SeriesID == *task.SeriesID &&
+			existing.OccurrenceAt != nil && existing.OccurrenceAt.Equal(*task.OccurrenceAt) {
+			return false, nil
+		}
+	}
+	if _, ok := r.store.tasks[task.ID]; ok {
+		return false, errors.New("task already exists")
+	}
+	if err := r.checkParent(task); err != nil {
+		return false, err
+	}
+	if err := r.resolveProject(task); err != nil {
+		return false, err
+	}
+	task.Version = 1
+	r.store.tasks[task.ID] = *task
+	r.store.replaceTaskTags(task)
+	return true, nil
+}
+
+// withTags タスクにタグ名（名前順）を設定する（呼び出し側でロックを取得する）
+func (s *memoryStore) withTags(task models.Task) models.Task {
+	task.Tags = []string{}
+	for tagID := range s.taskTags[task.ID] {
+		task.Tags = append(task.Tags, s.tags[tagID].Name)
+	}
+	sort.Strings(task.Tags)
+	return task
+}
+
+// replaceTaskTags replaceTaskTags と同じく、未登録のタグ名はタグとして作成する（呼び出し側でロックを取得する）
+func (s *memoryStore) replaceTaskTags(task *models.Task) {
+	tagIDs := make(map[string]bool, len(task.Tags))
+	for _, name := range task.Tags {
+		tag, ok := s.findTag(task.UserID, name)
+		if !ok {
+			tag = models.Tag{
+				ID:        utils.GenerateID(),
+				UserID:    task.UserID,
+				Name:      name,
+				CreatedAt: task.UpdatedAt,
+				UpdatedAt: task.UpdatedAt,
+			}
+			s.tags[tag.ID] = tag
+		}
+		tagIDs[tag.ID] = true
+	}
+	s.taskTags[task.ID] = tagIDs
+}
+
+func (s *memoryStore) findTag(userID, name string) (models.Tag, bool) {
+	for _, tag := range s.tags {
+		if tag.UserID == userID && tag.Name == name {
+			return tag, true
+		}
+	}
+	return models.Tag{}, false
+}
+
+// withTaskCount タグに付与されているタスク数を設定する（呼び出し側でロックを取得する）
+func (s *memoryStore) withTaskCount(tag models.Tag) models.Tag {
+	tag.TaskCount = 0
+	for taskID, tagIDs := range s.taskTags {
+		if _, live := s.liveTask(taskID); live && tagIDs[tag.ID] {
+			tag.TaskCount++
+		}
+	}
+	return tag
+}
+
+type memoryTagRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryTagRepository) CreateTag(tag *models.Tag) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[tag.UserID]; !ok {
+		return ErrUserNotFound
+	}
+	if _, ok := r.store.findTag(tag.UserID, tag.Name); ok {
+		return ErrTagExists
+	}
+	r.store.tags[tag.ID] = *tag
+	return nil
+}
+
+func (r *memoryTagRepository) GetTagsByUserID(userID string) ([]models.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var tags []models.Tag
+	for _, tag := range r.store.tags {
+		if tag.UserID == userID {
+			tags = append(tags, r.store.withTaskCount(tag))
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (r *memoryTagRepository) GetTagByID(tagID string) (*models.Tag, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tag, ok := r.store.tags[tagID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	tag = r.store.withTaskCount(tag)
+	return &tag, nil
+}
+
+func (r *memoryTagRepository) RenameTag(tagID, name string, updatedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tag, ok := r.store.tags[tagID]
+	if !ok {
+		return nil
+	}
+	if existing, ok := r.store.findTag(tag.UserID, name); ok && existing.ID != tagID {
+		return ErrTagExists
+	}
+	tag.Name = name
+	tag.UpdatedAt = updatedAt
+	r.store.tags[tagID] = tag
+	r.store.bumpTaggedTaskVersions(tagID)
+	return nil
+}
+
+func (r *memoryTagRepository) DeleteTag(tagID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.bumpTaggedTaskVersions(tagID)
+	delete(r.store.tags, tagID)
+	for _, tagIDs := range r.store.taskTags {
+		delete(tagIDs, tagID)
+	}
+	return nil
+}
+
+// bumpTaggedTaskVersions bumpTaggedTaskVersions と同じくタグが付いたタスクの版数を増やす（呼び出し側でロックを取得する）
+func (s *memoryStore) bumpTaggedTaskVersions(tagID string) {
+	for taskID, tagIDs := range s.taskTags {
+		if task, ok := s.tasks[taskID]; ok && tagIDs[tagID] {
+			task.Version++
+			s.tasks[taskID] = task
+		}
+	}
+}
+
+// ensureInbox ensureInbox と同じくユーザーの Inbox を返し、なければ作成する（呼び出し側でロックを取得する）
+func (s *memoryStore) ensureInbox(userID string, now time.Time) models.Project {
+	for _, project := range s.projects {
+		if project.UserID == userID && project.IsInbox {
+			return project
+		}
+	}
+	inbox := models.NewInboxProject(utils.GenerateID(), userID, now)
+	inbox.Position = s.nextProjectPosition(userID)
+	s.projects[inbox.ID] = *inbox
+	return *inbox
+}
+
+func (s *memoryStore) nextProjectPosition(userID string) int {
+	position := 0
+	for _, project := range s.projects {
+		if project.UserID == userID && project.Position >= position {
+			position = project.Position + 1
+		}
+	}
+	return position
+}
+
+// checkTaskProject checkTaskProject と同じ条件で移動先のプロジェクトを検証する（呼び出し側でロックを取得する）
+func (s *memoryStore) checkTaskProject(userID, projectID string) error {
+	project, ok := s.projects[projectID]
+	if !ok || project.UserID != userID {
+		return ErrProjectNotFound
+	}
+	if project.Archived {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// sortedProjects ユーザーのプロジェクトを ORDER BY position, id と同じ順に並べる（呼び出し側でロックを取得する）
+func (s *memoryStore) sortedProjects(userID string) []models.Project {
+	var projects []models.Project
+	for _, project := range s.projects {
+		if project.UserID == userID {
+			projects = append(projects, project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Position != projects[j].Position {
+			return projects[i].Position < projects[j].Position
+		}
+		return projects[i].ID < projects[j].ID
+	})
+	return projects
+}
+
+type memoryProjectRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryProjectRepository) CreateProject(project *models.Project) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.projects[project.ID]; ok {
+		return errors.New("project already exists")
+	}
+	if _, ok := r.store.users[project.UserID]; !ok {
+		return ErrUserNotFound
+	}
+	project.Position = r.store.nextProjectPosition(project.UserID)
+	r.store.projects[project.ID] = *project
+	return nil
+}
+
+func (r *memoryProjectRepository) EnsureInbox(userID string, now time.Time) (*models.Project, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[userID]; !ok {
+		return nil, ErrUserNotFound
+	}
+	inbox := r.store.ensureInbox(userID, now)
+	return &inbox, nil
+}
+
+func (r *memoryProjectRepository) GetProjectsByUserID(userID string, includeArchived bool) ([]models.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var projects []models.Project
+	for _, project := range r.store.sortedProjects(userID) {
+		if includeArchived || !project.Archived {
+			projects = append(projects, project)
+		}
+	}
+	return projects, nil
+}
+
+func (r *memoryProjectRepository) GetProjectByID(projectID string) (*models.Project, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	project, ok := r.store.projects[projectID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &project, nil
+}
+
+func (r *memoryProjectRepository) UpdateProject(project *models.Project) error {
+	if project.IsInbox && project.Archived {
+		return ErrInboxProject
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.projects[project.ID]
+	if !ok {
+		return nil
+	}
+	current.Name = project.Name
+	current.Color = project.Color
+	current.Archived = project.Archived
+	current.UpdatedAt = project.UpdatedAt
+	r.store.projects[project.ID] = current
+	return nil
+}
+
+func (r *memoryProjectRepository) ReorderProjects(userID string, projectIDs []string, updatedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var current []string
+	for _, project := range r.store.sortedProjects(userID) {
+		current = append(current, project.ID)
+	}
+	order, err := projectOrder(current, projectIDs)
+	if err != nil {
+		return err
+	}
+	for position, id := range order {
+		project := r.store.projects[id]
+		project.Position = position
+		project.UpdatedAt = updatedAt
+		r.store.projects[id] = project
+	}
+	return nil
+}
+
+func (r *memoryProjectRepository) DeleteProject(projectID string, reassignTo *string, deletedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	project, ok := r.store.projects[projectID]
+	if !ok {
+		return ErrProjectNotFound
+	}
+	if project.IsInbox {
+		return ErrInboxProject
+	}
+	if reassignTo != nil {
+		if *reassignTo == projectID {
+			return ErrProjectNotFound
+		}
+		if err := r.store.checkTaskProject(project.UserID, *reassignTo); err != nil {
+			return err
+		}
+	}
+
+	for id, task := range r.store.tasks {
+		if task.ProjectID != projectID {
+			continue
+		}
+		switch {
+		case reassignTo != nil:
+			task.ProjectID = *reassignTo
+		case task.DeletedAt == nil:
+			task.DeletedAt = &deletedAt
+		default:
+			continue
+		}
+		task.Version++
+		r.store.tasks[id] = task
+	}
+	delete(r.store.projects, projectID)
+	return nil
+}
+
+// deliveryKey 配信記録の主キー
+type deliveryKey struct {
+	reminderID string
+	fireAt     int64
+	channel    string
+}
+
+func deliveryKeyOf(delivery *models.ReminderDelivery) deliveryKey {
+	return deliveryKey{delivery.ReminderID, delivery.FireAt.Unix(), delivery.Channel}
+}
+
+// deleteReminder 外部キーの ON DELETE CASCADE と同じく配信記録も削除する（呼び出し側でロックを取得する）
+func (s *memoryStore) deleteReminder(reminderID string) {
+	delete(s.reminders, reminderID)
+	for key := range s.deliveries {
+		if key.reminderID == reminderID {
+			delete(s.deliveries, key)
+		}
+	}
+}
+
+type memoryReminderRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryReminderRepository) CreateReminder(reminder *models.Reminder) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.reminders[reminder.ID]; ok {
+		return errors.New("reminder already exists")
+	}
+	if _, ok := r.store.tasks[reminder.TaskID]; !ok {
+		return errors.New("task not found")
+	}
+	r.store.reminders[reminder.ID] = *reminder
+	return nil
+}
+
+func (r *memoryReminderRepository) GetRemindersByTaskID(taskID string) ([]models.Reminder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var reminders []models.Reminder
+	for _, reminder := range r.store.reminders {
+		if reminder.TaskID == taskID {
+			reminders = append(reminders, reminder)
+		}
+	}
+	sort.Slice(reminders, func(i, j int) bool {
+		if !reminders[i].CreatedAt.Equal(reminders[j].CreatedAt) {
+			return reminders[i].CreatedAt.Before(reminders[j].CreatedAt)
+		}
+		return reminders[i].ID < reminders[j].ID
+	})
+	return reminders, nil
+}
+
+func (r *memoryReminderRepository) GetReminderByID(reminderID string) (*models.Reminder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	reminder, ok := r.store.reminders[reminderID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &reminder, nil
+}
+
+func (r *memoryReminderRepository) DeleteReminder(reminderID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.deleteReminder(reminderID)
+	return nil
+}
+
+func (r *memoryReminderRepository) GetDueReminders(from, to time.Time) ([]models.DueReminder, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var reminders []models.DueReminder
+	for _, reminder := range r.store.reminders {
+		task := r.store.tasks[reminder.TaskID]
+		if task.Status != "pending" || task.Deadline == nil || task.DeletedAt != nil || task.Deadline.Before(from) || task.Deadline.After(to) {
+			continue
+		}
+		reminders = append(reminders, models.DueReminder{
+			Reminder:  reminder,
+			TaskTitle: task.Title,
+			Deadline:  *task.Deadline,
+			UserID:    task.UserID,
+			UserEmail: r.store.users[task.UserID].Email,
+		})
+	}
+	sort.Slice(reminders, func(i, j int) bool { return reminders[i].ID < reminders[j].ID })
+	return reminders, nil
+}
+
+func (r *memoryReminderRepository) ClaimReminderDelivery(delivery *models.ReminderDelivery, staleBefore time.Time, maxAttempts int) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := deliveryKeyOf(delivery)
+	current, ok := r.store.deliveries[key]
+	if !ok {
+		current = models.ReminderDelivery{
+			ReminderID: delivery.ReminderID,
+			FireAt:     delivery.FireAt.UTC(),
+			Channel:    delivery.Channel,
+			CreatedAt:  delivery.UpdatedAt,
+		}
+	} else {
+		retryable := current.Status == models.DeliveryFailed ||
+			(current.Status == models.DeliverySending && current.UpdatedAt.Before(staleBefore))
+		if !retryable || current.Attempts >= maxAttempts {
+			return false, nil
+		}
+	}
+	current.Status = models.DeliverySending
+	current.Attempts++
+	current.UpdatedAt = delivery.UpdatedAt
+	r.store.deliveries[key] = current
+	return true, nil
+}
+
+func (r *memoryReminderRepository) FinishReminderDelivery(delivery *models.ReminderDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := deliveryKeyOf(delivery)
+	current, ok := r.store.deliveries[key]
+	if !ok {
+		return nil
+	}
+	current.Status = delivery.Status
+	current.LastError = delivery.LastError
+	current.UpdatedAt = delivery.UpdatedAt
+	r.store.deliveries[key] = current
+	return nil
+}
+
+func (r *memoryReminderRepository) SkipReminderDelivery(delivery *models.ReminderDelivery) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	key := deliveryKeyOf(delivery)
+	if _, ok := r.store.deliveries[key]; ok {
+		return false, nil
+	}
+	r.store.deliveries[key] = models.ReminderDelivery{
+		ReminderID: delivery.ReminderID,
+		FireAt:     delivery.FireAt.UTC(),
+		Channel:    delivery.Channel,
+		Status:     models.DeliverySkipped,
+		CreatedAt:  delivery.UpdatedAt,
+		UpdatedAt:  delivery.UpdatedAt,
+	}
+	return true, nil
+}
+
+func (r *memoryReminderRepository) GetSettledReminderDeliveries(reminderIDs []string, staleBefore time.Time, maxAttempts int) ([]models.ReminderDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	ids := make(map[string]bool, len(reminderIDs))
+	for _, id := range reminderIDs {
+		ids[id] = true
+	}
+	var deliveries []models.ReminderDelivery
+	for key, delivery := range r.store.deliveries {
+		if !ids[key.reminderID] {
+			continue
+		}
+		retryable := delivery.Status == models.DeliveryFailed ||
+			(delivery.Status == models.DeliverySending && delivery.UpdatedAt.Before(staleBefore))
+		if !retryable || delivery.Attempts >= maxAttempts {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (r *memoryReminderRepository) GetReminderDeliveries(reminderID string) ([]models.ReminderDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var deliveries []models.ReminderDelivery
+	for key, delivery := range r.store.deliveries {
+		if key.reminderID == reminderID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+// sortDeliveries リマインダー・通知時刻・チャネルの順に並べる
+func sortDeliveries(deliveries []models.ReminderDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].ReminderID != deliveries[j].ReminderID {
+			return deliveries[i].ReminderID < deliveries[j].ReminderID
+		}
+		if !deliveries[i].FireAt.Equal(deliveries[j].FireAt) {
+			return deliveries[i].FireAt.Before(deliveries[j].FireAt)
+		}
+		return deliveries[i].Channel < deliveries[j].Channel
+	})
+}
+
+type memoryTaskHistoryRepository struct {
+	store *memoryStore
+	// inTx TaskRepository.WithTx の fn に渡したリポジトリ（ロックは WithTx が保持している）
+	inTx bool
+}
+
+// lock memoryTaskRepository.lock と同じく、WithTx の中ではロック済みのため何もしない
+func (r *memoryTaskHistoryRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.store.mu.Lock()
+	return r.store.mu.Unlock
+}
+
+// rlock memoryTaskRepository.rlock と同じく、WithTx の中ではロック済みのため何もしない
+func (r *memoryTaskHistoryRepository) rlock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.store.mu.RLock()
+	return r.store.mu.RUnlock
+}
+
+// copyTaskHistory 呼び出し側と保存した値がスライスを共有しないようにする
+func copyTaskHistory(entry models.TaskHistoryEntry) models.TaskHistoryEntry {
+	changes := make([]models.TaskFieldChange, len(entry.Changes))
+	for i, change := range entry.Changes {
+		change.Old = append([]byte(nil), change.Old...)
+		change.New = append([]byte(nil), change.New...)
+		changes[i] = change
+	}
+	entry.Changes = changes
+	entry.Snapshot = append([]byte(nil), entry.Snapshot...)
+	return entry
+}
+
+// CreateTaskHistory SQL実装と同じく、タスクの存在は確認しない（完全に削除したタスクの履歴も残すため外部キーがない）
+func (r *memoryTaskHistoryRepository) CreateTaskHistory(entry *models.TaskHistoryEntry) error {
+	defer r.lock()()
+
+	if _, ok := r.store.taskHistory[entry.ID]; ok {
+		return errors.New("task history entry already exists")
+	}
+	if _, ok := r.store.users[entry.ActorID]; !ok {
+		return ErrUserNotFound
+	}
+	r.store.taskHistory[entry.ID] = copyTaskHistory(*entry)
+	return nil
+}
+
+// GetTaskHistory SQL実装の ORDER BY version DESC, created_at DESC, id DESC と同じ順に並べる
+func (r *memoryTaskHistoryRepository) GetTaskHistory(taskID string) ([]models.TaskHistoryEntry, error) {
+	defer r.rlock()()
+
+	var entries []models.TaskHistoryEntry
+	for _, entry := range r.store.taskHistory {
+		if entry.TaskID == taskID {
+			entries = append(entries, copyTaskHistory(entry))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Version != entries[j].Version {
+			return entries[i].Version > entries[j].Version
+		}
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	return entries, nil
+}
+
+func (r *memoryTaskHistoryRepository) GetTaskRevision(taskID string, version int64) (*models.TaskHistoryEntry, error) {
+	entries, err := r.GetTaskHistory(taskID)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.Version == version {
+			return &entry, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+type memoryViewRepository struct {
+	store *memoryStore
+}
+
+// copyView SQL実装と同じく条件と表示方法をJSONにして保存し、呼び出し側とポインタ・スライスを共有しないようにする
+func copyView(view models.View) (models.View, error) {
+	filters, display, err := marshalView(&view)
+	if err != nil {
+		return models.View{}, err
+	}
+	view.Filters, view.Display = models.TaskFilters{}, models.ViewDisplay{}
+	if err := json.Unmarshal([]byte(filters), &view.Filters); err != nil {
+		return models.View{}, err
+	}
+	if err := json.Unmarshal([]byte(display), &view.Display); err != nil {
+		return models.View{}, err
+	}
+	return view, nil
+}
+
+func (r *memoryViewRepository) CreateView(view *models.View) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.views[view.ID]; ok {
+		return errors.New("view already exists")
+	}
+	if _, ok := r.store.users[view.UserID]; !ok {
+		return ErrUserNotFound
+	}
+	stored, err := copyView(*view)
+	if err != nil {
+		return err
+	}
+	r.store.views[view.ID] = stored
+	return nil
+}
+
+// GetViewsByUserID SQL実装の ORDER BY created_at, id と同じ順に並べる
+func (r *memoryViewRepository) GetViewsByUserID(userID string) ([]models.View, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var views []models.View
+	for _, view := range r.store.views {
+		if view.UserID == userID {
+			view, err := copyView(view)
+			if err != nil {
+				return nil, err
+			}
+			views = append(views, view)
+		}
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if !views[i].CreatedAt.Equal(views[j].CreatedAt) {
+			return views[i].CreatedAt.Before(views[j].CreatedAt)
+		}
+		return views[i].ID < views[j].ID
+	})
+	return views, nil
+}
+
+func (r *memoryViewRepository) GetViewByID(viewID string) (*models.View, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	view, ok := r.store.views[viewID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	view, err := copyView(view)
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+func (r *memoryViewRepository) UpdateView(view *models.View) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.views[view.ID]
+	if !ok {
+		return nil
+	}
+	current.Name, current.Filters, current.Display, current.UpdatedAt = view.Name, view.Filters, view.Display, view.UpdatedAt
+	stored, err := copyView(current)
+	if err != nil {
+		return err
+	}
+	r.store.views[view.ID] = stored
+	return nil
+}
+
+func (r *memoryViewRepository) DeleteView(viewID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.views, viewID)
+	return nil
+}
+
+type memoryWebhookRepository struct {
+	store *memoryStore
+}
+
+// copyWebhook 呼び出し側と保存した値がスライスを共有しないようにする
+func copyWebhook(webhook models.Webhook) models.Webhook {
+	webhook.Events = append([]string(nil), webhook.Events...)
+	return webhook
+}
+
+func copyWebhookDelivery(delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Payload = append([]byte(nil), delivery.Payload...)
+	return delivery
+}
+
+func (r *memoryWebhookRepository) CreateWebhook(webhook *models.Webhook) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.webhooks[webhook.ID]; ok {
+		return errors.New("webhook already exists")
+	}
+	if _, ok := r.store.users[webhook.UserID]; !ok {
+		return ErrUserNotFound
+	}
+	r.store.webhooks[webhook.ID] = copyWebhook(*webhook)
+	return nil
+}
+
+func (r *memoryWebhookRepository) GetWebhooksByUserID(userID string) ([]models.Webhook, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var webhooks []models.Webhook
+	for _, webhook := range r.store.webhooks {
+		if webhook.UserID == userID {
+			webhooks = append(webhooks, copyWebhook(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+func (r *memoryWebhookRepository) GetWebhookByID(webhookID string) (*models.Webhook, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	webhook, ok := r.store.webhooks[webhookID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	webhook = copyWebhook(webhook)
+	return &webhook, nil
+}
+
+func (r *memoryWebhookRepository) UpdateWebhook(webhook *models.Webhook) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.webhooks[webhook.ID]
+	if !ok {
+		return nil
+	}
+	current.URL = webhook.URL
+	current.Events = webhook.Events
+	current.Active = webhook.Active
+	current.UpdatedAt = webhook.UpdatedAt
+	r.store.webhooks[webhook.ID] = copyWebhook(current)
+	return nil
+}
+
+// DeleteWebhook 外部キーの ON DELETE CASCADE と同じく配信ログも削除する
+func (r *memoryWebhookRepository) DeleteWebhook(webhookID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.webhooks, webhookID)
+	for id, delivery := range r.store.webhookLog {
+		if delivery.WebhookID == webhookID {
+			delete(r.store.webhookLog, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryWebhookRepository) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.webhookLog[delivery.ID]; ok {
+		return errors.New("webhook delivery already exists")
+	}
+	if _, ok := r.store.webhooks[delivery.WebhookID]; !ok {
+		return errors.New("webhook not found")
+	}
+	r.store.webhookLog[delivery.ID] = copyWebhookDelivery(*delivery)
+	return nil
+}
+
+func (r *memoryWebhookRepository) GetWebhookDeliveries(webhookID, status string, limit int) ([]models.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range r.store.webhookLog {
+		if delivery.WebhookID == webhookID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, copyWebhookDelivery(delivery))
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID > deliveries[j].ID
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (r *memoryWebhookRepository) GetWebhookDeliveryByID(deliveryID string) (*models.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	delivery, ok := r.store.webhookLog[deliveryID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	delivery = copyWebhookDelivery(delivery)
+	return &delivery, nil
+}
+
+func (r *memoryWebhookRepository) GetDueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range r.store.webhookLog {
+		if !r.store.webhooks[delivery.WebhookID].Active || !webhookDeliveryDue(delivery, now) {
+			continue
+		}
+		deliveries = append(deliveries, copyWebhookDelivery(delivery))
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].NextAttemptAt.Equal(*deliveries[j].NextAttemptAt) {
+			return deliveries[i].NextAttemptAt.Before(*deliveries[j].NextAttemptAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+func (r *memoryWebhookRepository) ClaimWebhookDelivery(deliveryID string, now, leaseUntil time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delivery, ok := r.store.webhookLog[deliveryID]
+	if !ok || !webhookDeliveryDue(delivery, now) {
+		return false, nil
+	}
+	leaseUntil = leaseUntil.UTC()
+	delivery.Attempts++
+	delivery.NextAttemptAt = &leaseUntil
+	delivery.UpdatedAt = now
+	r.store.webhookLog[deliveryID] = delivery
+	return true, nil
+}
+
+func (r *memoryWebhookRepository) FinishWebhookDelivery(delivery *models.WebhookDelivery) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	current, ok := r.store.webhookLog[delivery.ID]
+	if !ok {
+		return nil
+	}
+	current.Status = delivery.Status
+	current.NextAttemptAt = delivery.NextAttemptAt
+	current.ResponseStatus = delivery.ResponseStatus
+	current.LastError = delivery.LastError
+	current.DeliveredAt = delivery.DeliveredAt
+	current.UpdatedAt = delivery.UpdatedAt
+	r.store.webhookLog[delivery.ID] = current
+	return nil
+}
+
+// webhookDeliveryDue 送信待ちで送信時刻を迎えているか
+func webhookDeliveryDue(delivery models.WebhookDelivery, now time.Time) bool {
+	return delivery.Status == models.WebhookDeliveryPending && delivery.NextAttemptAt != nil && !delivery.NextAttemptAt.After(now)
+}
+
+type memoryUserRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryUserRepository) CreateUser(user *models.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.users[user.ID]; ok {
+		return errors.New("user already exists")
+	}
+	for _, existing := range r.store.users {
+		if existing.Email == user.Email {
+			return ErrDuplicateEmail
+		}
+	}
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email {
+			return &user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryUserRepository) GetUserByID(userID string) (*models.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.users[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &user, nil
+}
+
+func (r *memoryUserRepository) UserExists(email string) (bool, error) {
+	_, err := r.GetUserByEmail(email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type memoryRefreshTokenRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryRefreshTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.refreshTokens[token.ID]; ok {
+		return errors.New("refresh token already exists")
+	}
+	if _, ok := r.store.users[token.UserID]; !ok {
+		return ErrUserNotFound
+	}
+	r.store.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (r *memoryRefreshTokenRepository) GetRefreshTokenByID(id string) (*models.RefreshToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	token, ok := r.store.refreshTokens[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &token, nil
+}
+
+func (r *memoryRefreshTokenRepository) MarkRefreshTokenUsed(id string, usedAt time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.refreshTokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &usedAt
+	token.RevokedAt = &usedAt
+	r.store.refreshTokens[id] = token
+	return true, nil
+}
+
+func (r *memoryRefreshTokenRepository) RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var revoked int64
+	for id, token := range r.store.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+			r.store.refreshTokens[id] = token
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (r *memoryRefreshTokenRepository) DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var deleted int64
+	for id, token := range r.store.refreshTokens {
+		if token.ExpiresAt.Before(now) {
+			delete(r.store.refreshTokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,14 @@
+package repository_test
+
+import (
+	"testing"
+
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/repository/repotest"
+)
+
+func TestMemoryRepositories(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) *repository.Repositories {
+		return repository.NewMemoryRepositories()
+	})
+}
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// マイグレーションファイル（migrations/<方言>/NNNN_name.up.sql / NNNN_name.down.sql）
+// SQLiteとPostgreSQLで同じ番号・名前のマイグレーションを用意する
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+var (
+	// ErrSchemaTooNew DBのスキーマがこのバイナリより新しい
+	ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+	// ErrSchemaOutdated 未適用のマイグレーションがある
+	ErrSchemaOutdated = errors.New("database schema has pending migrations")
+)
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 番号付きのスキーマ変更
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus マイグレーションの適用状況
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Migrator 埋め込みのマイグレーションをDBに適用する
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+}
+
+func NewMigrator(db *DB) (*Migrator, error) {
+	migrations, err := loadMigrations(db.dialect.name)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func loadMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations/"+dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(matches[1])
+
+		content, err := migrationFiles.ReadFile("migrations/" + dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		} else if migration.Name != matches[2] {
+			return nil, fmt.Errorf("conflicting names for migration %d: %s, %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// LatestVersion このバイナリが知っている最新のスキーマバージョン
+func (m *Migrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	_, err := m.db.Exec(m.db.dialect.versionTableDDL)
+	return err
+}
+
+// appliedVersions 適用済みのバージョンと適用日時
+func (m *Migrator) appliedVersions() (map[int]time.Time, error) {
+	if err := m.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// CurrentVersion DBに適用済みの最大バージョン
+func (m *Migrator) CurrentVersion() (int, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// Check DBのスキーマがこのバイナリと一致しているか確認する
+func (m *Migrator) Check() error {
+	current, err := m.CurrentVersion()
+	if err != nil {
+		return err
+	}
+	if current > m.LatestVersion() {
+		return fmt.Errorf("%w (database: %d, binary: %d)", ErrSchemaTooNew, current, m.LatestVersion())
+	}
+
+	pending, err := m.pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w (%d pending)", ErrSchemaOutdated, len(pending))
+	}
+	return nil
+}
+
+func (m *Migrator) pending() ([]Migration, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up 未適用のマイグレーションを順に適用し、適用した（dryRunの場合は適用予定の）ものを返す
+// DBのスキーマがバイナリより新しい場合は何もせず ErrSchemaTooNew を返す
+func (m *Migrator) Up(dryRun bool) ([]Migration, error) {
+	current, err := m.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > m.LatestVersion() {
+		return nil, fmt.Errorf("%w (database: %d, binary: %d)", ErrSchemaTooNew, current, m.LatestVersion())
+	}
+
+	pending, err := m.pending()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		if dryRun {
+			log.Printf("[dry-run] would apply migration %04d_%s:\n%s", migration.Version, migration.Name, migration.Up)
+			continue
+		}
+		if err := m.apply(migration); err != nil {
+			return pending[:i], err
+		}
+		log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+	}
+	return pending, nil
+}
+
+// Down 適用済みのマイグレーションを新しい順に steps 件ロールバックする
+func (m *Migrator) Down(steps int, dryRun bool) ([]Migration, error) {
+	// バイナリが知らないマイグレーションはロールバックできない
+	current, err := m.CurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > m.LatestVersion() {
+		return nil, fmt.Errorf("%w (database: %d, binary: %d)", ErrSchemaTooNew, current, m.LatestVersion())
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(targets) < steps; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			targets = append(targets, m.migrations[i])
+		}
+	}
+
+	for i, migration := range targets {
+		if dryRun {
+			log.Printf("[dry-run] would roll back migration %04d_%s:\n%s", migration.Version, migration.Name, migration.Down)
+			continue
+		}
+		if err := m.revert(migration); err != nil {
+			return targets[:i], err
+		}
+		log.Printf("Rolled back migration %04d_%s", migration.Version, migration.Name)
+	}
+	return targets, nil
+}
+
+// Status 全マイグレーションの適用状況を返す
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// apply マイグレーションとバージョンの記録を1つのトランザクションで実行する
+func (m *Migrator) apply(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration.Up); err != nil {
+		return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.Exec(m.db.dialect.rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+		migration.Version, migration.Name, time.Now().UTC()); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) revert(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration.Down); err != nil {
+		return fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.Exec(m.db.dialect.rebind(`DELETE FROM schema_migrations WHERE version = ?`), migration.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,17 @@
+-- タグ名はユーザーごとに一意。タスクとは task_tags で多対多に関連付ける
+CREATE TABLE IF NOT EXISTS tags (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users (id),
+	name TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS task_tags (
+	task_id TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	tag_id TEXT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags (tag_id);
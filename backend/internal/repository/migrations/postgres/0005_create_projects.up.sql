@@ -0,0 +1,25 @@
+-- プロジェクト：タスクをまとめるリスト。ユーザーごとに削除できない Inbox を1つ持つ
+CREATE TABLE IF NOT EXISTS projects (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users (id),
+	name TEXT NOT NULL,
+	color TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	is_inbox BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_user_id ON projects (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_inbox ON projects (user_id) WHERE is_inbox;
+
+-- 既存のユーザーに Inbox を作成する
+INSERT INTO projects (id, user_id, name, color, position, archived, is_inbox, created_at, updated_at)
+SELECT 'inbox-' || id, id, 'Inbox', '#8c8c8c', 0, FALSE, TRUE, created_at, created_at FROM users;
+
+ALTER TABLE tasks ADD COLUMN project_id TEXT REFERENCES projects (id);
+
+UPDATE tasks SET project_id = 'inbox-' || user_id;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_project_id ON tasks (project_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tasks_series_occurrence;
+ALTER TABLE tasks DROP COLUMN IF EXISTS occurrence_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS task_series;
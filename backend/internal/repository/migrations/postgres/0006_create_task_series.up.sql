@@ -0,0 +1,20 @@
+-- 繰り返しタスクの系列：ルールと、次の回に引き継ぐタイトル・説明・優先度を持つ
+CREATE TABLE IF NOT EXISTS task_series (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users (id),
+	rrule TEXT NOT NULL,
+	timezone TEXT NOT NULL,
+	dtstart TIMESTAMPTZ NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	priority TEXT NOT NULL,
+	ended_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE tasks ADD COLUMN series_id TEXT REFERENCES task_series (id);
+ALTER TABLE tasks ADD COLUMN occurrence_at TIMESTAMPTZ;
+
+-- 同じ回のタスクを二重に作成しない
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_series_occurrence ON tasks (series_id, occurrence_at);
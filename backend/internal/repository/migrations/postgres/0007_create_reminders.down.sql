@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS reminder_deliveries;
+DROP TABLE IF EXISTS reminders;
@@ -0,0 +1,26 @@
+-- タスクごとのリマインダー（通知時刻は期限から計算する）
+CREATE TABLE IF NOT EXISTS reminders (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	offset_minutes INTEGER NOT NULL DEFAULT 0,
+	time_of_day TEXT NOT NULL DEFAULT '',
+	timezone TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_task_id ON reminders (task_id);
+
+-- 通知時刻・チャネルごとの配信記録。再起動をまたいで同じ通知を二重に送らないために使う
+-- 期限が変わると通知時刻も変わるため、新しい期限に対して改めて通知される
+CREATE TABLE IF NOT EXISTS reminder_deliveries (
+	reminder_id TEXT NOT NULL REFERENCES reminders (id) ON DELETE CASCADE,
+	fire_at TIMESTAMPTZ NOT NULL,
+	channel TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (reminder_id, fire_at, channel)
+);
@@ -0,0 +1,5 @@
+-- ゴミ箱のタスクは元に戻せないため削除する
+DELETE FROM tasks WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_tasks_deleted_at;
+ALTER TABLE tasks DROP COLUMN IF EXISTS deleted_at;
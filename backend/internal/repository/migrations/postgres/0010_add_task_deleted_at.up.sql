@@ -0,0 +1,4 @@
+-- ゴミ箱：削除したタスクは deleted_at を設定して残し、保持期間を過ぎたら完全に削除する
+ALTER TABLE tasks ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_deleted_at ON tasks (deleted_at);
@@ -0,0 +1,15 @@
+-- タスクの変更履歴。changes は項目ごとの変更前後の値、snapshot は変更後の内容（どちらもJSON）
+-- タスクを完全に削除しても監査のため履歴を残すよう、task_id には外部キーを付けない
+CREATE TABLE IF NOT EXISTS task_history (
+	id TEXT PRIMARY KEY,
+	task_id TEXT NOT NULL,
+	actor_id TEXT NOT NULL REFERENCES users (id),
+	action TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	changes TEXT NOT NULL,
+	snapshot TEXT NOT NULL,
+	reverted_from INTEGER,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_history_task_id ON task_history (task_id, version);
@@ -0,0 +1,5 @@
+-- タスクのタイトル・説明の全文検索。SQLite の FTS5 の代わりに tsvector の式インデックスを使う
+-- 式は検索のクエリ（repository の postgresTaskSearchVector）と完全に一致させる
+CREATE INDEX IF NOT EXISTS idx_tasks_search ON tasks USING GIN (
+	(setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', COALESCE(description, '')), 'B'))
+);
@@ -0,0 +1,23 @@
+-- 外部キー付きのカラムは DROP COLUMN できないため、テーブルを作り直す
+DROP INDEX IF EXISTS idx_tasks_parent_id;
+
+CREATE TABLE tasks_without_parent (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	deadline DATETIME,
+	priority TEXT NOT NULL,
+	status TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+INSERT INTO tasks_without_parent (id, user_id, title, description, deadline, priority, status, created_at, updated_at)
+SELECT id, user_id, title, description, deadline, priority, status, created_at, updated_at FROM tasks;
+
+DROP TABLE tasks;
+ALTER TABLE tasks_without_parent RENAME TO tasks;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_user_id ON tasks (user_id);
@@ -0,0 +1,4 @@
+-- サブタスク：親タスクを削除すると子孫もまとめて削除する
+ALTER TABLE tasks ADD COLUMN parent_id TEXT REFERENCES tasks (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks (parent_id);
@@ -0,0 +1,20 @@
+-- タグ名はユーザーごとに一意。タスクとは task_tags で多対多に関連付ける
+CREATE TABLE IF NOT EXISTS tags (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (id),
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS task_tags (
+	task_id TEXT NOT NULL,
+	tag_id TEXT NOT NULL,
+	PRIMARY KEY (task_id, tag_id),
+	FOREIGN KEY (task_id) REFERENCES tasks (id) ON DELETE CASCADE,
+	FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags (tag_id);
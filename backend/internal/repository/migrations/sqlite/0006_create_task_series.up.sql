@@ -0,0 +1,22 @@
+-- 繰り返しタスクの系列：ルールと、次の回に引き継ぐタイトル・説明・優先度を持つ
+CREATE TABLE IF NOT EXISTS task_series (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	rrule TEXT NOT NULL,
+	timezone TEXT NOT NULL,
+	dtstart DATETIME NOT NULL,
+	title TEXT NOT NULL,
+	description TEXT,
+	priority TEXT NOT NULL,
+	ended_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+-- project_id と同じく、series_id の参照整合性はリポジトリで保証する
+ALTER TABLE tasks ADD COLUMN series_id TEXT;
+ALTER TABLE tasks ADD COLUMN occurrence_at DATETIME;
+
+-- 同じ回のタスクを二重に作成しない
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_series_occurrence ON tasks (series_id, occurrence_at);
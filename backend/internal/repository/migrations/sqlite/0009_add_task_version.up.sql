@@ -0,0 +1,2 @@
+-- 楽観的排他制御：タスクを変更するたびに増やし、ETag / If-Match で比較する
+ALTER TABLE tasks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,25 @@
+-- タスクのタイトル・説明の全文検索（FTS5。ビルドに -tags sqlite_fts5 が必要）
+-- tasks を外部コンテンツとし、tasks の rowid をキーにトリガーで同期する（rowid で更新・削除するため全件を走査しない）
+-- rowid は VACUUM で変わりうるため、VACUUM した後は INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild') で作り直す
+CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts USING fts5(
+	title,
+	description,
+	content = 'tasks',
+	content_rowid = 'rowid',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild');
+
+CREATE TRIGGER IF NOT EXISTS tasks_fts_insert AFTER INSERT ON tasks BEGIN
+	INSERT INTO tasks_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tasks_fts_update AFTER UPDATE OF title, description ON tasks BEGIN
+	INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+	INSERT INTO tasks_fts (rowid, title, description) VALUES (new.rowid, new.title, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tasks_fts_delete AFTER DELETE ON tasks BEGIN
+	INSERT INTO tasks_fts (tasks_fts, rowid, title, description) VALUES ('delete', old.rowid, old.title, old.description);
+END;
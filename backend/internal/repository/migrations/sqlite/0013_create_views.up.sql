@@ -0,0 +1,13 @@
+-- 保存したビュー（スマートリスト）。filters はタスク一覧の条件、display は表示方法（どちらもJSON）
+CREATE TABLE IF NOT EXISTS views (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	filters TEXT NOT NULL,
+	display TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_views_user_id ON views (user_id);
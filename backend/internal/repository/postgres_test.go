@@ -0,0 +1,17 @@
+package repository_test
+
+import (
+	"os"
+	"testing"
+
+	"todo-app-backend/internal/repository/repotest"
+)
+
+// TestPostgresRepositories DATABASE_URL の PostgreSQL で実行する（テストごとにスキーマを作成・削除する）
+func TestPostgresRepositories(t *testing.T) {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("DATABASE_URL is not set")
+	}
+	repotest.Run(t, repotest.NewPostgres(databaseURL))
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/utils"
+)
+
+// ErrProjectNotFound プロジェクトが存在しない、または別のユーザーのプロジェクト
+var ErrProjectNotFound = errors.New("project not found")
+
+// ErrProjectArchived アーカイブ済みのプロジェクトにはタスクを追加できない
+var ErrProjectArchived = errors.New("project is archived")
+
+// ErrInboxProject Inbox は削除・アーカイブできない
+var ErrInboxProject = errors.New("inbox project cannot be deleted or archived")
+
+// SELECT対象のカラム（scanProject と順序を合わせる）
+const projectColumns = `id, user_id, name, color, position, archived, is_inbox, created_at, updated_at`
+
+type sqlProjectRepository struct {
+	db *DB
+}
+
+func NewProjectRepository(db *DB) ProjectRepository {
+	return &sqlProjectRepository{
+		db: db,
+	}
+}
+
+// CreateProject プロジェクトを末尾に追加する（Position は採番した値で上書きする）
+func (r *sqlProjectRepository) CreateProject(project *models.Project) error {
+	return r.db.withTx(func(tx *Tx) error {
+		if err := tx.QueryRow(`SELECT COALESCE(MAX(position) + 1, 0) FROM projects WHERE user_id = ?`,
+			project.UserID).Scan(&project.Position); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO projects (` + projectColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		_, err := tx.Exec(query, project.ID, project.UserID, project.Name, project.Color, project.Position,
+			project.Archived, project.IsInbox, project.CreatedAt, project.UpdatedAt)
+		return err
+	})
+}
+
+func (r *sqlProjectRepository) EnsureInbox(userID string, now time.Time) (*models.Project, error) {
+	projectID, err := ensureInbox(r.db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetProjectByID(projectID)
+}
+
+func (r *sqlProjectRepository) GetProjectsByUserID(userID string, includeArchived bool) ([]models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE user_id = ?`
+	if !includeArchived {
+		query += ` AND archived = ?`
+	}
+	query += ` ORDER BY position, id`
+
+	args := []interface{}{userID}
+	if !includeArchived {
+		args = append(args, false)
+	}
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []models.Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, *project)
+	}
+	return projects, rows.Err()
+}
+
+func (r *sqlProjectRepository) GetProjectByID(projectID string) (*models.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id = ?`
+	return scanProject(r.db.QueryRow(query, projectID))
+}
+
+func (r *sqlProjectRepository) UpdateProject(project *models.Project) error {
+	if project.IsInbox && project.Archived {
+		return ErrInboxProject
+	}
+	query := `UPDATE projects SET name = ?, color = ?, archived = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.Exec(query, project.Name, project.Color, project.Archived, project.UpdatedAt, project.ID)
+	return err
+}
+
+// ReorderProjects projectIDs の順に 0 から並び順を振り直す
+// 指定されなかったプロジェクトはその後ろに元の順序で並ぶ
+func (r *sqlProjectRepository) ReorderProjects(userID string, projectIDs []string, updatedAt time.Time) error {
+	return r.db.withTx(func(tx *Tx) error {
+		rows, err := tx.Query(`SELECT id FROM projects WHERE user_id = ? ORDER BY position, id`, userID)
+		if err != nil {
+			return err
+		}
+		var current []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			current = append(current, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		order, err := projectOrder(current, projectIDs)
+		if err != nil {
+			return err
+		}
+		for position, id := range order {
+			if _, err := tx.Exec(`UPDATE projects SET position = ?, updated_at = ? WHERE id = ?`,
+				position, updatedAt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteProject プロジェクトを削除する
+// reassignTo を指定した場合はタスクをそのプロジェクトに移し、nil の場合はタスクをゴミ箱に移す
+func (r *sqlProjectRepository) DeleteProject(projectID string, reassignTo *string, deletedAt time.Time) error {
+	return r.db.withTx(func(tx *Tx) error {
+		var userID string
+		var isInbox bool
+		err := tx.QueryRow(`SELECT user_id, is_inbox FROM projects WHERE id = ?`, projectID).Scan(&userID, &isInbox)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrProjectNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if isInbox {
+			return ErrInboxProject
+		}
+
+		if reassignTo != nil {
+			if *reassignTo == projectID {
+				return ErrProjectNotFound
+			}
+			if err := checkTaskProject(tx, userID, *reassignTo); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`UPDATE tasks SET project_id = ?, version = version + 1 WHERE project_id = ?`, *reassignTo, projectID); err != nil {
+				return err
+			}
+		} else {
+			// サブタスクは親と同じプロジェクトにあるため、まとめてゴミ箱に移る（完全な削除はゴミ箱の自動削除に任せる）
+			query := `UPDATE tasks SET deleted_at = ?, version = version + 1 WHERE project_id = ? AND deleted_at IS NULL`
+			if _, err := tx.Exec(query, deletedAt, projectID); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.Exec(`DELETE FROM projects WHERE id = ?`, projectID)
+		return err
+	})
+}
+
+func scanProject(row rowScanner) (*models.Project, error) {
+	project := &models.Project{}
+	err := row.Scan(&project.ID, &project.UserID, &project.Name, &project.Color, &project.Position,
+		&project.Archived, &project.IsInbox, &project.CreatedAt, &project.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// projectOrder 指定された順序のあとに、指定されなかったプロジェクトを元の順序で並べる
+func projectOrder(current, requested []string) ([]string, error) {
+	owned := make(map[string]bool, len(current))
+	for _, id := range current {
+		owned[id] = true
+	}
+
+	seen := make(map[string]bool, len(current))
+	order := make([]string, 0, len(current))
+	for _, id := range requested {
+		if !owned[id] {
+			return nil, ErrProjectNotFound
+		}
+		if !seen[id] {
+			seen[id] = true
+			order = append(order, id)
+		}
+	}
+	for _, id := range current {
+		if !seen[id] {
+			order = append(order, id)
+		}
+	}
+	return order, nil
+}
+
+// ensureInbox ユーザーの Inbox のIDを返す（存在しなければ作成する）
+func ensureInbox(q querier, userID string, now time.Time) (string, error) {
+	inbox := models.NewInboxProject(utils.GenerateID(), userID, now)
+	query := `INSERT INTO projects (` + projectColumns + `)
+			  SELECT ?, ?, ?, ?, COALESCE(MAX(position) + 1, 0), ?, ?, ?, ? FROM projects WHERE user_id = ?
+			  ON CONFLICT DO NOTHING`
+	if _, err := q.Exec(query, inbox.ID, inbox.UserID, inbox.Name, inbox.Color, inbox.Archived, inbox.IsInbox,
+		inbox.CreatedAt, inbox.UpdatedAt, userID); err != nil {
+		return "", err
+	}
+
+	var projectID string
+	err := q.QueryRow(`SELECT id FROM projects WHERE user_id = ? AND is_inbox = ?`, userID, true).Scan(&projectID)
+	return projectID, err
+}
+
+// checkTaskProject タスクを追加するプロジェクトが同じユーザーのもので、アーカイブされていないことを確認する
+func checkTaskProject(q querier, userID, projectID string) error {
+	var ownerID string
+	var archived bool
+	err := q.QueryRow(`SELECT user_id, archived FROM projects WHERE id = ?`, projectID).Scan(&ownerID, &archived)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && ownerID != userID) {
+		return ErrProjectNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if archived {
+		return ErrProjectArchived
+	}
+	return nil
+}
+
+// resolveTaskProject 作成するタスクのプロジェクトを決める
+// サブタスクは親と同じプロジェクト、指定がなければ Inbox
+func resolveTaskProject(q querier, task *models.Task) error {
+	if task.ParentID != nil {
+		return q.QueryRow(`SELECT project_id FROM tasks WHERE id = ? AND deleted_at IS NULL`, *task.ParentID).Scan(&task.ProjectID)
+	}
+	if task.ProjectID == "" {
+		projectID, err := ensureInbox(q, task.UserID, task.CreatedAt)
+		if err != nil {
+			return err
+		}
+		task.ProjectID = projectID
+		return nil
+	}
+	return checkTaskProject(q, task.UserID, task.ProjectID)
+}
+
+// moveTaskSubtree タスクとその子孫をプロジェクトに移動する（移動したタスクの版数を増やす）
+func moveTaskSubtree(q querier, taskID, projectID string) error {
+	query := `WITH RECURSIVE subtree (id) AS (
+				  SELECT id FROM tasks WHERE id = ?
+				  UNION
+				  SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id
+			  )
+			  UPDATE tasks SET project_id = ?, version = version + 1 WHERE id IN (SELECT id FROM subtree) AND project_id <> ?`
+	_, err := q.Exec(query, taskID, projectID, projectID)
+	return err
+}
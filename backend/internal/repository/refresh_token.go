@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// sqlRefreshTokenRepository SQLite / PostgreSQL 共通の実装（方言の違いは DB が吸収する）
+type sqlRefreshTokenRepository struct {
+	db *DB
+}
+
+func NewRefreshTokenRepository(db *DB) RefreshTokenRepository {
+	return &sqlRefreshTokenRepository{
+		db: db,
+	}
+}
+
+func (r *sqlRefreshTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, token.ID, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt,
+		token.UsedAt, token.RevokedAt, token.CreatedAt)
+	return err
+}
+
+func (r *sqlRefreshTokenRepository) GetRefreshTokenByID(id string) (*models.RefreshToken, error) {
+	query := `SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+			  FROM refresh_tokens WHERE id = ?`
+	row := r.db.QueryRow(query, id)
+
+	token := &models.RefreshToken{}
+	err := row.Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt,
+		&token.UsedAt, &token.RevokedAt, &token.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// MarkRefreshTokenUsed 未使用かつ有効なトークンを使用済みにする
+// 他のリクエストが先に使用していた場合は false を返す
+func (r *sqlRefreshTokenRepository) MarkRefreshTokenUsed(id string, usedAt time.Time) (bool, error) {
+	query := `UPDATE refresh_tokens SET used_at = ?, revoked_at = ?
+			  WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, usedAt, usedAt, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// RevokeRefreshTokenFamily ファミリー内の有効なトークンをすべて失効させる
+func (r *sqlRefreshTokenRepository) RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) (int64, error) {
+	query := `UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`
+	result, err := r.db.Exec(query, revokedAt, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpiredRefreshTokens 有効期限切れのトークンを削除し、削除件数を返す
+func (r *sqlRefreshTokenRepository) DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	query := `DELETE FROM refresh_tokens WHERE expires_at < ?`
+	result, err := r.db.Exec(query, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// SELECT対象のカラム（scanReminder と順序を合わせる）
+const reminderColumns = `id, task_id, kind, offset_minutes, time_of_day, timezone, created_at`
+
+type sqlReminderRepository struct {
+	db *DB
+}
+
+func NewReminderRepository(db *DB) ReminderRepository {
+	return &sqlReminderRepository{
+		db: db,
+	}
+}
+
+func (r *sqlReminderRepository) CreateReminder(reminder *models.Reminder) error {
+	query := `INSERT INTO reminders (` + reminderColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, reminder.ID, reminder.TaskID, reminder.Kind, reminder.OffsetMinutes,
+		reminder.TimeOfDay, reminder.Timezone, reminder.CreatedAt)
+	return err
+}
+
+func (r *sqlReminderRepository) GetRemindersByTaskID(taskID string) ([]models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE task_id = ?
+			  ORDER BY ` + r.db.dialect.timeExpr("created_at") + `, id`
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []models.Reminder
+	for rows.Next() {
+		reminder, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, *reminder)
+	}
+	return reminders, rows.Err()
+}
+
+func (r *sqlReminderRepository) GetReminderByID(reminderID string) (*models.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE id = ?`
+	return scanReminder(r.db.QueryRow(query, reminderID))
+}
+
+// DeleteReminder リマインダーを削除する（配信記録は外部キーの ON DELETE CASCADE で削除される）
+func (r *sqlReminderRepository) DeleteReminder(reminderID string) error {
+	_, err := r.db.Exec(`DELETE FROM reminders WHERE id = ?`, reminderID)
+	return err
+}
+
+// GetDueReminders 期限が from 〜 to にある未完了タスクのリマインダーを取得する
+func (r *sqlReminderRepository) GetDueReminders(from, to time.Time) ([]models.DueReminder, error) {
+	deadline, param := r.db.dialect.timeExpr("t.deadline"), r.db.dialect.timeExpr("?")
+	query := `SELECT r.id, r.task_id, r.kind, r.offset_minutes, r.time_of_day, r.timezone, r.created_at,
+					 t.title, t.deadline, t.user_id, u.email
+			  FROM reminders r
+			  JOIN tasks t ON t.id = r.task_id
+			  JOIN users u ON u.id = t.user_id
+			  WHERE t.status = 'pending' AND t.deadline IS NOT NULL AND t.deleted_at IS NULL
+				AND ` + deadline + ` >= ` + param + ` AND ` + deadline + ` <= ` + param + `
+			  ORDER BY r.id`
+	rows, err := r.db.Query(query, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []models.DueReminder
+	for rows.Next() {
+		var due models.DueReminder
+		err := rows.Scan(&due.ID, &due.TaskID, &due.Kind, &due.OffsetMinutes, &due.TimeOfDay, &due.Timezone,
+			&due.CreatedAt, &due.TaskTitle, &due.Deadline, &due.UserID, &due.UserEmail)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, due)
+	}
+	return reminders, rows.Err()
+}
+
+// ClaimReminderDelivery 配信する権利を取得する
+// 記録のない回、失敗した回、送信中のまま staleBefore より前に止まった回（送信中の再起動など）のみ取得でき、
+// 配信済み・スキップ済み・試行回数が maxAttempts に達した回は false を返す
+func (r *sqlReminderRepository) ClaimReminderDelivery(delivery *models.ReminderDelivery, staleBefore time.Time, maxAttempts int) (bool, error) {
+	claimed := false
+	err := r.db.withTx(func(tx *Tx) error {
+		query := `INSERT INTO reminder_deliveries (reminder_id, fire_at, channel, status, attempts, created_at, updated_at)
+				  VALUES (?, ?, ?, ?, 1, ?, ?)
+				  ON CONFLICT (reminder_id, fire_at, channel) DO NOTHING`
+		result, err := tx.Exec(query, delivery.ReminderID, delivery.FireAt.UTC(), delivery.Channel, models.DeliverySending,
+			delivery.UpdatedAt, delivery.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+			claimed = affected > 0
+			return err
+		}
+
+		updatedAt, param := r.db.dialect.timeExpr("updated_at"), r.db.dialect.timeExpr("?")
+		query = `UPDATE reminder_deliveries SET status = ?, attempts = attempts + 1, updated_at = ?
+				 WHERE reminder_id = ? AND fire_at = ? AND channel = ? AND attempts < ?
+				   AND (status = ? OR (status = ? AND ` + updatedAt + ` < ` + param + `))`
+		result, err = tx.Exec(query, models.DeliverySending, delivery.UpdatedAt, delivery.ReminderID, delivery.FireAt.UTC(),
+			delivery.Channel, maxAttempts, models.DeliveryFailed, models.DeliverySending, staleBefore)
+		if err != nil {
+			return err
+		}
+		affected, err := result.RowsAffected()
+		claimed = affected > 0
+		return err
+	})
+	return claimed, err
+}
+
+// FinishReminderDelivery 配信結果（Status と LastError）を記録する
+func (r *sqlReminderRepository) FinishReminderDelivery(delivery *models.ReminderDelivery) error {
+	query := `UPDATE reminder_deliveries SET status = ?, last_error = ?, updated_at = ?
+			  WHERE reminder_id = ? AND fire_at = ? AND channel = ?`
+	_, err := r.db.Exec(query, delivery.Status, delivery.LastError, delivery.UpdatedAt, delivery.ReminderID,
+		delivery.FireAt.UTC(), delivery.Channel)
+	return err
+}
+
+// SkipReminderDelivery 配信しなかった回を記録する（既に記録がある場合は何もせず false を返す）
+func (r *sqlReminderRepository) SkipReminderDelivery(delivery *models.ReminderDelivery) (bool, error) {
+	query := `INSERT INTO reminder_deliveries (reminder_id, fire_at, channel, status, attempts, created_at, updated_at)
+			  VALUES (?, ?, ?, ?, 0, ?, ?)
+			  ON CONFLICT (reminder_id, fire_at, channel) DO NOTHING`
+	result, err := r.db.Exec(query, delivery.ReminderID, delivery.FireAt.UTC(), delivery.Channel, models.DeliverySkipped,
+		delivery.UpdatedAt, delivery.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// GetSettledReminderDeliveries ClaimReminderDelivery で取得できない配信記録を取得する
+// 配信済み・スキップ済み・試行回数が maxAttempts に達した記録と、staleBefore 以降に更新された送信中の記録が該当する
+func (r *sqlReminderRepository) GetSettledReminderDeliveries(reminderIDs []string, staleBefore time.Time, maxAttempts int) ([]models.ReminderDelivery, error) {
+	if len(reminderIDs) == 0 {
+		return nil, nil
+	}
+	updatedAt, param := r.db.dialect.timeExpr("updated_at"), r.db.dialect.timeExpr("?")
+	query := `SELECT reminder_id, fire_at, channel, status, attempts, last_error, created_at, updated_at
+			  FROM reminder_deliveries
+			  WHERE reminder_id IN (` + placeholders(len(reminderIDs)) + `)
+				AND (status IN (?, ?) OR attempts >= ? OR (status = ? AND ` + updatedAt + ` >= ` + param + `))
+			  ORDER BY reminder_id, ` + r.db.dialect.timeExpr("fire_at") + `, channel`
+	args := append(stringArgs(reminderIDs), models.DeliverySent, models.DeliverySkipped, maxAttempts, models.DeliverySending, staleBefore)
+	return r.queryDeliveries(query, args...)
+}
+
+// GetReminderDeliveries リマインダーの配信記録を通知時刻順に取得する
+func (r *sqlReminderRepository) GetReminderDeliveries(reminderID string) ([]models.ReminderDelivery, error) {
+	query := `SELECT reminder_id, fire_at, channel, status, attempts, last_error, created_at, updated_at
+			  FROM reminder_deliveries WHERE reminder_id = ?
+			  ORDER BY ` + r.db.dialect.timeExpr("fire_at") + `, channel`
+	return r.queryDeliveries(query, reminderID)
+}
+
+func (r *sqlReminderRepository) queryDeliveries(query string, args ...interface{}) ([]models.ReminderDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.ReminderDelivery
+	for rows.Next() {
+		var d models.ReminderDelivery
+		if err := rows.Scan(&d.ReminderID, &d.FireAt, &d.Channel, &d.Status, &d.Attempts, &d.LastError,
+			&d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanReminder(row rowScanner) (*models.Reminder, error) {
+	reminder := &models.Reminder{}
+	err := row.Scan(&reminder.ID, &reminder.TaskID, &reminder.Kind, &reminder.OffsetMinutes, &reminder.TimeOfDay,
+		&reminder.Timezone, &reminder.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return reminder, nil
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// TaskRepository タスクの永続化
+type TaskRepository interface {
+	CreateTask(task *models.Task) error
+	// GetTasksByUserID ユーザーのタスクをフィルター・ソート条件に従ってページ単位で取得する
+	// ゴミ箱のタスクは、ゴミ箱を扱うメソッド以外では存在しないものとして扱う
+	GetTasksByUserID(userID string, filters *models.TaskFilters, pagination *models.TaskPagination) (*models.TaskPage, error)
+	GetTaskByID(taskID string) (*models.Task, error)
+	// SearchTasks ユーザーのタスクのタイトル・説明を全文検索し、一致の度合いの順に limit 件まで返す（ゴミ箱のタスクは含めない）
+	SearchTasks(userID string, query *models.TaskSearchQuery, limit int) ([]models.TaskSearchResult, error)
+	// UpdateTask task.Version の版数から変更されていない場合のみ更新し、task.Version を増やす
+	UpdateTask(task *models.Task) error
+	// DeleteTask version の版数から変更されていない場合のみ、タスクを子孫とともにゴミ箱に移す
+	DeleteTask(taskID string, version int64, deletedAt time.Time) error
+	// GetDeletedTasks ユーザーのゴミ箱のタスクを削除日時の新しい順に取得する（親と一緒に削除した子孫は含めない）
+	GetDeletedTasks(userID string) ([]models.Task, error)
+	// GetDeletedTaskByID ゴミ箱のタスクを取得する
+	GetDeletedTaskByID(taskID string) (*models.Task, error)
+	// RestoreTask ゴミ箱のタスクを、一緒に削除した子孫とともに元に戻す（親がゴミ箱にあればトップレベルに戻す）
+	RestoreTask(taskID string, restoredAt time.Time) error
+	// PurgeTask ゴミ箱のタスクを子孫ごと完全に削除する
+	PurgeTask(taskID string) error
+	// PurgeDeletedTasks before より前にゴミ箱に移したタスクを完全に削除し、削除件数を返す
+	PurgeDeletedTasks(before time.Time) (int64, error)
+	// GetChildTasks 直下のサブタスクを作成日時順に取得する
+	GetChildTasks(parentID string) ([]models.Task, error)
+	// GetTaskSubtree タスクとその子孫をすべて作成日時順に取得する
+	GetTaskSubtree(rootID string) ([]models.Task, error)
+	// CompleteSubtasks 子孫の未完了のタスクをすべて完了にし、完了にしたタスク（更新後）を作成日時順に返す
+	CompleteSubtasks(parentID string, updatedAt time.Time) ([]models.Task, error)
+	// MoveTask version の版数のタスクがまだ変更されていない場合のみ、子孫ごと別のプロジェクトに移動する
+	MoveTask(taskID string, version int64, projectID string, updatedAt time.Time) error
+	// GetTaskSeries 繰り返しタスクの系列を取得する
+	GetTaskSeries(seriesID string) (*models.TaskSeries, error)
+	// UpdateTaskSeries 系列のルールと、以降の回に引き継ぐ内容を更新する
+	UpdateTaskSeries(series *models.TaskSeries) error
+	// EndTaskSeries 系列を終了する（以降の回は作成されない）。ゴミ箱にない系列のタスクの版数を1つ増やし、作成順に返す
+	EndTaskSeries(seriesID string, endedAt time.Time) ([]models.Task, error)
+	// CreateOccurrence 繰り返しの次の回を作成する（同じ回が既にあれば作成せず false を返す）
+	CreateOccurrence(task *models.Task) (bool, error)
+	// SkipOccurrence version の版数の回がまだ変更されていない場合のみ、skippedAt でゴミ箱に移し、next（nil の場合は作成しない）に置き換える
+	SkipOccurrence(taskID string, version int64, next *models.Task, skippedAt time.Time) error
+	// WithTx fn を1つのトランザクション内で実行し、fn がエラーを返せば fn に渡したリポジトリでの変更をすべて取り消す
+	// history は同じトランザクションで変更履歴を記録するためのリポジトリ
+	// fn の中では渡したリポジトリのみを使う。入れ子にした場合は内側の fn の変更のみを取り消す
+	WithTx(fn func(repo TaskRepository, history TaskHistoryRepository) error) error
+}
+
+// TaskHistoryRepository タスクの変更履歴の永続化（監査のため、タスクを完全に削除しても履歴は残す）
+type TaskHistoryRepository interface {
+	CreateTaskHistory(entry *models.TaskHistoryEntry) error
+	// GetTaskHistory タスクの変更履歴を新しい順に取得する
+	GetTaskHistory(taskID string) ([]models.TaskHistoryEntry, error)
+	// GetTaskRevision タスクの版数 version の履歴を取得する（なければ sql.ErrNoRows）
+	GetTaskRevision(taskID string, version int64) (*models.TaskHistoryEntry, error)
+}
+
+// ProjectRepository プロジェクトの永続化
+type ProjectRepository interface {
+	// CreateProject プロジェクトをユーザーの並び順の末尾に追加する
+	CreateProject(project *models.Project) error
+	// EnsureInbox ユーザーの Inbox を返す（存在しなければ作成する）
+	EnsureInbox(userID string, now time.Time) (*models.Project, error)
+	// GetProjectsByUserID ユーザーのプロジェクトを並び順に取得する
+	GetProjectsByUserID(userID string, includeArchived bool) ([]models.Project, error)
+	GetProjectByID(projectID string) (*models.Project, error)
+	UpdateProject(project *models.Project) error
+	// ReorderProjects 指定した順にプロジェクトを並べ替える
+	ReorderProjects(userID string, projectIDs []string, updatedAt time.Time) error
+	// DeleteProject プロジェクトを削除する
+	// reassignTo を指定した場合はタスクをそのプロジェクトに移し、nil の場合はタスクを deletedAt でゴミ箱に移す
+	// ゴミ箱のタスクは、元に戻すと Inbox に入る
+	DeleteProject(projectID string, reassignTo *string, deletedAt time.Time) error
+}
+
+// TagRepository タグの永続化
+type TagRepository interface {
+	CreateTag(tag *models.Tag) error
+	// GetTagsByUserID ユーザーのタグを、付与されているタスク数とともに名前順に取得する
+	GetTagsByUserID(userID string) ([]models.Tag, error)
+	GetTagByID(tagID string) (*models.Tag, error)
+	// RenameTag タグ名を変更する。同じ名前のタグが既にある場合は ErrTagExists を返す
+	RenameTag(tagID, name string, updatedAt time.Time) error
+	// DeleteTag タグを削除し、すべてのタスクから外す
+	DeleteTag(tagID string) error
+}
+
+// ReminderRepository リマインダーと配信記録の永続化
+type ReminderRepository interface {
+	CreateReminder(reminder *models.Reminder) error
+	// GetRemindersByTaskID タスクのリマインダーを作成日時順に取得する
+	GetRemindersByTaskID(taskID string) ([]models.Reminder, error)
+	GetReminderByID(reminderID string) (*models.Reminder, error)
+	DeleteReminder(reminderID string) error
+	// GetDueReminders 期限が from 〜 to にある未完了タスクのリマインダーを取得する
+	GetDueReminders(from, to time.Time) ([]models.DueReminder, error)
+	// ClaimReminderDelivery 回・チャネルごとに配信する権利を取得する（配信済みなどで取得できなければ false）
+	ClaimReminderDelivery(delivery *models.ReminderDelivery, staleBefore time.Time, maxAttempts int) (bool, error)
+	// FinishReminderDelivery 配信結果を記録する
+	FinishReminderDelivery(delivery *models.ReminderDelivery) error
+	// SkipReminderDelivery 配信しなかった回を記録する（既に記録がある場合は何もせず false を返す）
+	SkipReminderDelivery(delivery *models.ReminderDelivery) (bool, error)
+	// GetSettledReminderDeliveries リマインダーの配信記録のうち、ClaimReminderDelivery で取得できないものを取得する
+	GetSettledReminderDeliveries(reminderIDs []string, staleBefore time.Time, maxAttempts int) ([]models.ReminderDelivery, error)
+	// GetReminderDeliveries リマインダーの配信記録を通知時刻順に取得する
+	GetReminderDeliveries(reminderID string) ([]models.ReminderDelivery, error)
+}
+
+// WebhookRepository Webhook の送信先と配信ログの永続化
+type WebhookRepository interface {
+	CreateWebhook(webhook *models.Webhook) error
+	// GetWebhooksByUserID ユーザーの Webhook を作成日時順に取得する
+	GetWebhooksByUserID(userID string) ([]models.Webhook, error)
+	GetWebhookByID(webhookID string) (*models.Webhook, error)
+	UpdateWebhook(webhook *models.Webhook) error
+	// DeleteWebhook Webhook を配信ログごと削除する
+	DeleteWebhook(webhookID string) error
+	CreateWebhookDelivery(delivery *models.WebhookDelivery) error
+	// GetWebhookDeliveries 配信ログを新しい順に取得する（status が空文字列なら状態を問わない）
+	GetWebhookDeliveries(webhookID, status string, limit int) ([]models.WebhookDelivery, error)
+	GetWebhookDeliveryByID(deliveryID string) (*models.WebhookDelivery, error)
+	// GetDueWebhookDeliveries 有効な Webhook の送信待ちのうち、送信時刻を迎えた配信を古い順に取得する
+	GetDueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// ClaimWebhookDelivery 送信する権利を取得し、試行回数を増やして leaseUntil まで他の送信を止める
+	// 他の送信が先に取得していた場合は false を返す
+	ClaimWebhookDelivery(deliveryID string, now, leaseUntil time.Time) (bool, error)
+	// FinishWebhookDelivery 送信結果（状態・次の送信時刻・レスポンス）を記録する
+	FinishWebhookDelivery(delivery *models.WebhookDelivery) error
+}
+
+// UserRepository ユーザーの永続化
+type UserRepository interface {
+	CreateUser(user *models.User) error
+	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(userID string) (*models.User, error)
+	UserExists(email string) (bool, error)
+}
+
+// RefreshTokenRepository リフレッシュトークンの永続化
+type RefreshTokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	GetRefreshTokenByID(id string) (*models.RefreshToken, error)
+	// MarkRefreshTokenUsed 未使用かつ有効なトークンを使用済みにする
+	// 他のリクエストが先に使用していた場合は false を返す
+	MarkRefreshTokenUsed(id string, usedAt time.Time) (bool, error)
+	// RevokeRefreshTokenFamily ファミリー内の有効なトークンをすべて失効させる
+	RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) (int64, error)
+	// DeleteExpiredRefreshTokens 有効期限切れのトークンを削除し、削除件数を返す
+	DeleteExpiredRefreshTokens(now time.Time) (int64, error)
+}
+
+// ViewRepository 保存したビューの永続化
+type ViewRepository interface {
+	CreateView(view *models.View) error
+	// GetViewsByUserID ユーザーのビューを作成順に取得する
+	GetViewsByUserID(userID string) ([]models.View, error)
+	GetViewByID(viewID string) (*models.View, error)
+	UpdateView(view *models.View) error
+	DeleteView(viewID string) error
+}
+
+// Repositories アプリケーションが使用するリポジトリ一式
+// SQL実装とインメモリ実装のどちらも同じ形で扱えるようにまとめる
+type Repositories struct {
+	Tasks         TaskRepository
+	TaskHistory   TaskHistoryRepository
+	Projects      ProjectRepository
+	Views         ViewRepository
+	Tags          TagRepository
+	Reminders     ReminderRepository
+	Webhooks      WebhookRepository
+	Users         UserRepository
+	RefreshTokens RefreshTokenRepository
+}
+
+// NewRepositories データベースを使うリポジトリ一式を作成する
+func NewRepositories(db *DB) *Repositories {
+	return &Repositories{
+		Tasks:         NewTaskRepository(db),
+		TaskHistory:   NewTaskHistoryRepository(db),
+		Projects:      NewProjectRepository(db),
+		Views:         NewViewRepository(db),
+		Tags:          NewTagRepository(db),
+		Reminders:     NewReminderRepository(db),
+		Webhooks:      NewWebhookRepository(db),
+		Users:         NewUserRepository(db),
+		RefreshTokens: NewRefreshTokenRepository(db),
+	}
+}
@@ -0,0 +1,1980 @@
+// Package repotest リポジトリの各実装が満たすべき共通の振る舞いを検証するテストスイート
+//
+// 新しい実装を追加した場合は、その実装のテストから Run を呼び出す:
+//
+//	func TestMemoryRepositories(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) *repository.Repositories {
+//			return repository.NewMemoryRepositories()
+//		})
+//	}
+//
+// SQLite（NewSQLite）は FTS5 が必要なため -tags sqlite_fts5 を付けて、
+// PostgreSQL（NewPostgres）は DATABASE_URL を指定した場合に実行する
+package repotest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-app-backend/internal/config"
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/utils"
+)
+
+// Factory テストごとに空のリポジトリ一式を作成する
+type Factory func(t *testing.T) *repository.Repositories
+
+// Run すべての共通テストを実行する
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos(t)) })
+	t.Run("TaskCRUD", func(t *testing.T) { testTaskCRUD(t, newRepos(t)) })
+	t.Run("ConcurrentTaskIDs", func(t *testing.T) { testConcurrentTaskIDs(t, newRepos(t)) })
+	t.Run("TaskFilters", func(t *testing.T) { testTaskFilters(t, newRepos(t)) })
+	t.Run("TaskQuery", func(t *testing.T) { testTaskQuery(t, newRepos(t)) })
+	t.Run("TaskSortAndPagination", func(t *testing.T) { testTaskSortAndPagination(t, newRepos(t)) })
+	t.Run("TaskHierarchy", func(t *testing.T) { testTaskHierarchy(t, newRepos(t)) })
+	t.Run("TaskVersion", func(t *testing.T) { testTaskVersion(t, newRepos(t)) })
+	t.Run("TaskTransaction", func(t *testing.T) { testTaskTransaction(t, newRepos(t)) })
+	t.Run("Trash", func(t *testing.T) { testTrash(t, newRepos(t)) })
+	t.Run("TaskHistory", func(t *testing.T) { testTaskHistory(t, newRepos(t)) })
+	t.Run("TaskSearch", func(t *testing.T) { testTaskSearch(t, newRepos(t)) })
+	t.Run("Projects", func(t *testing.T) { testProjects(t, newRepos(t)) })
+	t.Run("Views", func(t *testing.T) { testViews(t, newRepos(t)) })
+	t.Run("Recurrence", func(t *testing.T) { testRecurrence(t, newRepos(t)) })
+	t.Run("Tags", func(t *testing.T) { testTags(t, newRepos(t)) })
+	t.Run("Reminders", func(t *testing.T) { testReminders(t, newRepos(t)) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newRepos(t)) })
+	t.Run("RefreshTokens", func(t *testing.T) { testRefreshTokens(t, newRepos(t)) })
+}
+
+// NewSQLite マイグレーション済みのインメモリSQLiteを使うリポジトリ一式を作成する
+func NewSQLite(t *testing.T) *repository.Repositories {
+	t.Helper()
+
+	db, err := repository.Open(&config.Config{DatabaseDriver: "sqlite", DatabasePath: ":memory:"})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(false); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return repository.NewRepositories(db)
+}
+
+// NewPostgres databaseURL の PostgreSQL にテストごとのスキーマを作成し、マイグレーション済みのリポジトリ一式を作成する
+// スキーマはテストの終了時に削除する
+func NewPostgres(databaseURL string) Factory {
+	return func(t *testing.T) *repository.Repositories {
+		t.Helper()
+
+		admin, err := repository.Open(&config.Config{DatabaseDriver: "postgres", DatabaseURL: databaseURL})
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		t.Cleanup(func() { admin.Close() })
+
+		schema := "repotest_" + strings.ToLower(utils.GenerateID())
+		if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+			t.Fatalf("create schema: %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+				t.Errorf("drop schema: %v", err)
+			}
+		})
+
+		db, err := repository.Open(&config.Config{DatabaseDriver: "postgres", DatabaseURL: withSearchPath(databaseURL, schema)})
+		if err != nil {
+			t.Fatalf("open database: %v", err)
+		}
+		// スキーマの削除より先に閉じる（Cleanup は登録の逆順に実行される）
+		t.Cleanup(func() { db.Close() })
+
+		migrator, err := repository.NewMigrator(db)
+		if err != nil {
+			t.Fatalf("load migrations: %v", err)
+		}
+		if _, err := migrator.Up(false); err != nil {
+			t.Fatalf("migrate: %v", err)
+		}
+		return repository.NewRepositories(db)
+	}
+}
+
+// withSearchPath 接続文字列（URL またはキーと値の形式）に search_path を追加する
+func withSearchPath(databaseURL, schema string) string {
+	u, err := url.Parse(databaseURL)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		return databaseURL + " search_path=" + schema
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// 比較しやすいよう秒単位に丸めた基準時刻
+var baseTime = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func createUser(t *testing.T, repos *repository.Repositories, id string) *models.User {
+	t.Helper()
+
+	user := &models.User{
+		ID:        id,
+		Email:     id + "@example.com",
+		Password:  "hash",
+		Name:      "User " + id,
+		CreatedAt: baseTime,
+		UpdatedAt: baseTime,
+	}
+	if err := repos.Users.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", id, err)
+	}
+	return user
+}
+
+func createTask(t *testing.T, repos *repository.Repositories, task models.Task) *models.Task {
+	t.Helper()
+
+	if task.Priority == "" {
+		task.Priority = "medium"
+	}
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = baseTime
+	}
+	task.UpdatedAt = task.CreatedAt
+	if err := repos.Tasks.CreateTask(&task); err != nil {
+		t.Fatalf("CreateTask(%s): %v", task.ID, err)
+	}
+	return &task
+}
+
+func listTaskIDs(t *testing.T, repos *repository.Repositories, userID string, filters *models.TaskFilters) []string {
+	t.Helper()
+
+	page, err := repos.Tasks.GetTasksByUserID(userID, filters, &models.TaskPagination{Limit: intPtr(models.MaxTaskPageSize)})
+	if err != nil {
+		t.Fatalf("GetTasksByUserID: %v", err)
+	}
+	return taskIDs(page.Tasks)
+}
+
+func taskIDs(tasks []models.Task) []string {
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, label string, got, want []string) {
+	t.Helper()
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%s: got %v, want %v", label, got, want)
+	}
+}
+
+// taskVersion タスクの現在の版数を返す
+func taskVersion(t *testing.T, repos *repository.Repositories, taskID string) int64 {
+	t.Helper()
+
+	task, err := repos.Tasks.GetTaskByID(taskID)
+	if err != nil {
+		t.Fatalf("GetTaskByID(%s): %v", taskID, err)
+	}
+	return task.Version
+}
+
+func strPtr(s string) *string        { return &s }
+func intPtr(n int) *int              { return &n }
+func boolPtr(b bool) *bool           { return &b }
+func timePtr(t time.Time) *time.Time { return &t }
+
+func testUsers(t *testing.T, repos *repository.Repositories) {
+	exists, err := repos.Users.UserExists("u1@example.com")
+	if err != nil || exists {
+		t.Fatalf("UserExists before create = %v, %v; want false, nil", exists, err)
+	}
+
+	user := createUser(t, repos, "u1")
+
+	exists, err = repos.Users.UserExists(user.Email)
+	if err != nil || !exists {
+		t.Fatalf("UserExists after create = %v, %v; want true, nil", exists, err)
+	}
+
+	got, err := repos.Users.GetUserByEmail(user.Email)
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if got.ID != user.ID || got.Name != user.Name || got.Password != user.Password {
+		t.Errorf("GetUserByEmail = %+v, want %+v", got, user)
+	}
+
+	if _, err := repos.Users.GetUserByEmail("missing@example.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUserByEmail(missing) error = %v, want sql.ErrNoRows", err)
+	}
+
+	got, err = repos.Users.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if got.Email != user.Email || got.Name != user.Name {
+		t.Errorf("GetUserByID = %+v, want %+v", got, user)
+	}
+	if _, err := repos.Users.GetUserByID("missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetUserByID(missing) error = %v, want sql.ErrNoRows", err)
+	}
+
+	duplicate := *user
+	duplicate.ID = "u2"
+	if err := repos.Users.CreateUser(&duplicate); err == nil {
+		t.Error("CreateUser with a duplicate email succeeded")
+	}
+}
+
+func testTaskCRUD(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+
+	if err := repos.Tasks.CreateTask(&models.Task{ID: "orphan", UserID: "missing", Title: "x", Priority: "low", Status: "pending", CreatedAt: baseTime, UpdatedAt: baseTime}); err == nil {
+		t.Error("CreateTask for a missing user succeeded")
+	}
+
+	deadline := baseTime.Add(48 * time.Hour)
+	task := createTask(t, repos, models.Task{
+		ID:          "t1",
+		UserID:      "u1",
+		Title:       "Write tests",
+		Description: strPtr("conformance"),
+		Deadline:    &deadline,
+		Priority:    "high",
+	})
+
+	got, err := repos.Tasks.GetTaskByID(task.ID)
+	if err != nil {
+		t.Fatalf("GetTaskByID: %v", err)
+	}
+	if got.Title != task.Title || got.Priority != "high" || got.Description == nil || *got.Description != "conformance" ||
+		got.Deadline == nil || !got.Deadline.Equal(deadline) {
+		t.Errorf("GetTaskByID = %+v, want %+v", got, task)
+	}
+
+	got.Title = "Write more tests"
+	got.Description = nil
+	got.Deadline = nil
+	got.Status = "completed"
+	got.UpdatedAt = baseTime.Add(time.Hour)
+	if err := repos.Tasks.UpdateTask(got); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+
+	updated, err := repos.Tasks.GetTaskByID(task.ID)
+	if err != nil {
+		t.Fatalf("GetTaskByID after update: %v", err)
+	}
+	if updated.Title != "Write more tests" || updated.Description != nil || updated.Deadline != nil ||
+		updated.Status != "completed" || !updated.UpdatedAt.Equal(got.UpdatedAt) || !updated.CreatedAt.Equal(task.CreatedAt) {
+		t.Errorf("GetTaskByID after update = %+v", updated)
+	}
+
+	if err := repos.Tasks.DeleteTask(task.ID, got.Version, baseTime); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if _, err := repos.Tasks.GetTaskByID(task.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTaskByID after delete error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// testConcurrentTaskIDs 並行して作成したタスクのIDが重複せず、作成した順に並ぶ
+func testConcurrentTaskIDs(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+
+	const workers, perWorker = 8, 250
+	ids := make([][]string, workers)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				task := models.Task{
+					ID: utils.GenerateID(), UserID: "u1", Title: fmt.Sprintf("w%d-%d", w, i), Priority: "medium", Status: "pending",
+					CreatedAt: baseTime, UpdatedAt: baseTime,
+				}
+				if err := repos.Tasks.CreateTask(&task); err != nil {
+					errs <- fmt.Errorf("CreateTask(%s): %w", task.Title, err)
+					return
+				}
+				ids[w] = append(ids[w], task.ID)
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool, workers*perWorker)
+	for w, workerIDs := range ids {
+		for i, id := range workerIDs {
+			if seen[id] {
+				t.Errorf("duplicate ID %s", id)
+			}
+			seen[id] = true
+			if i > 0 && id <= workerIDs[i-1] {
+				t.Errorf("worker %d: ID %s is not after %s", w, id, workerIDs[i-1])
+			}
+		}
+	}
+	if len(seen) != workers*perWorker {
+		t.Errorf("created %d tasks, want %d", len(seen), workers*perWorker)
+	}
+}
+
+func testTaskFilters(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+
+	past := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	future := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+
+	createTask(t, repos, models.Task{ID: "a", UserID: "u1", Title: "overdue", Deadline: &past, Priority: "high"})
+	createTask(t, repos, models.Task{ID: "b", UserID: "u1", Title: "done late", Deadline: &past, Status: "completed"})
+	createTask(t, repos, models.Task{ID: "c", UserID: "u1", Title: "upcoming", Deadline: &future, Priority: "low"})
+	createTask(t, repos, models.Task{ID: "d", UserID: "u1", Title: "someday", Status: "in_progress"})
+	createTask(t, repos, models.Task{ID: "e", UserID: "u2", Title: "other user", Deadline: &past})
+
+	sortByID := func(f models.TaskFilters) *models.TaskFilters {
+		f.SortBy = strPtr("created_at")
+		f.SortOrder = strPtr("asc")
+		return &f
+	}
+
+	assertIDs(t, "all", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{})), []string{"a", "b", "c", "d"})
+	assertIDs(t, "status=completed", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{Status: strPtr("completed")})), []string{"b"})
+	assertIDs(t, "status=all", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{Status: strPtr("all")})), []string{"a", "b", "c", "d"})
+	assertIDs(t, "priority=medium", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{Priority: strPtr("medium")})), []string{"b", "d"})
+	assertIDs(t, "overdue", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{Overdue: boolPtr(true)})), []string{"a"})
+	assertIDs(t, "no_deadline", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{NoDeadline: boolPtr(true)})), []string{"d"})
+	assertIDs(t, "deadline_from", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{DeadlineFrom: timePtr(time.Now())})), []string{"c"})
+	assertIDs(t, "deadline_to", listTaskIDs(t, repos, "u1", sortByID(models.TaskFilters{DeadlineTo: timePtr(past)})), []string{"a", "b"})
+
+	if _, err := repos.Tasks.GetTasksByUserID("u1", &models.TaskFilters{Status: strPtr("unknown")}, nil); err == nil {
+		t.Error("GetTasksByUserID with an invalid status succeeded")
+	}
+}
+
+func testTaskQuery(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+	work := &models.Project{ID: "p-work", UserID: "u1", Name: "Work", Color: models.DefaultProjectColor, CreatedAt: baseTime, UpdatedAt: baseTime}
+	if err := repos.Projects.CreateProject(work); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	soon, later, past := now.Add(3*24*time.Hour), now.Add(10*24*time.Hour), now.Add(-24*time.Hour)
+	day := 24 * time.Hour
+	createTask(t, repos, models.Task{ID: "a", UserID: "u1", ProjectID: "p-work", Title: "Write release notes", Description: strPtr("For v2"),
+		Priority: "high", Deadline: &soon, Tags: []string{"backend"}})
+	createTask(t, repos, models.Task{ID: "b", UserID: "u1", Title: "Fix login bug", Priority: "high", Deadline: &past,
+		Tags: []string{"backend", "blocked"}, CreatedAt: baseTime.Add(day)})
+	createTask(t, repos, models.Task{ID: "c", UserID: "u1", Title: "Plan 100% offsite", Description: strPtr("team_event"),
+		Priority: "low", Status: "completed", CreatedAt: baseTime.Add(2 * day)})
+	createTask(t, repos, models.Task{ID: "d", UserID: "u1", ParentID: strPtr("a"), ProjectID: "p-work", Title: "Review PR",
+		Deadline: &later, Tags: []string{"frontend"}, CreatedAt: baseTime.Add(3 * day)})
+	createTask(t, repos, models.Task{ID: "e", UserID: "u2", Title: "release notes of u2"})
+	createTask(t, repos, models.Task{ID: "f", UserID: "u2", Title: "Ärger im Büro", CreatedAt: baseTime.Add(day)})
+
+	query := func(userID, q string) []string {
+		t.Helper()
+		return listTaskIDs(t, repos, userID, &models.TaskFilters{Query: strPtr(q), SortBy: strPtr("created_at"), SortOrder: strPtr("asc")})
+	}
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{`priority:high status:pending due:<7d tag:backend -tag:blocked "release notes"`, []string{"a"}},
+		{`priority:high`, []string{"a", "b"}},
+		{`priority:>=medium`, []string{"a", "b", "d"}},
+		{`priority:<medium`, []string{"c"}},
+		{`due:<7d`, []string{"a", "b"}},
+		// 期限のないタスクは否定した条件に一致する
+		{`-due:<7d`, []string{"c", "d"}},
+		{`due:none`, []string{"c"}},
+		{`due:7d`, []string{"a"}},
+		{`deadline:>=tomorrow`, []string{"a", "d"}},
+		{`is:overdue`, []string{"b"}},
+		{`tag:backend OR tag:frontend`, []string{"a", "b", "d"}},
+		{`(tag:frontend OR priority:low) -status:completed`, []string{"d"}},
+		{`NOT tag:backend AND has:description`, []string{"c"}},
+		{`project:Work`, []string{"a", "d"}},
+		{`project:p-work -is:subtask`, []string{"a"}},
+		{`project:work`, []string{}},
+		{`has:tags has:deadline`, []string{"a", "b", "d"}},
+		{`LOGIN`, []string{"b"}},
+		{`100%`, []string{"c"}},
+		{`team_event`, []string{"c"}},
+		// LIKE のワイルドカードは文字どおりに扱う
+		{`"login_bug"`, []string{}},
+		{`created:2025-01-02`, []string{"b"}},
+		{`created:<=2025-01-02`, []string{"a", "b"}},
+		{`created:>2025-01-02 updated:<today`, []string{"c", "d"}},
+		{`-(priority:high OR status:completed)`, []string{"d"}},
+	}
+	for _, tc := range cases {
+		assertIDs(t, tc.query, query("u1", tc.query), tc.want)
+	}
+
+	// フィルター式は他の条件と組み合わせられ、他のユーザーのタスク・プロジェクトには一致しない
+	filters := &models.TaskFilters{Query: strPtr("priority:high"), Overdue: boolPtr(true)}
+	assertIDs(t, "query with overdue", listTaskIDs(t, repos, "u1", filters), []string{"b"})
+	assertIDs(t, "u2 release", query("u2", "release"), []string{"e"})
+	assertIDs(t, "u2 project", query("u2", "project:Work"), []string{})
+
+	// 大文字・小文字はどのデータベースでも ASCII の英字のみ区別しない
+	assertIDs(t, "ascii fold", query("u2", "ÄRGER IM"), []string{"f"})
+	assertIDs(t, "non-ascii fold", query("u2", "ärger"), []string{})
+	assertIDs(t, "non-ascii exact", query("u2", "BüRO"), []string{"f"})
+	assertIDs(t, "non-ascii mismatch", query("u2", "BÜRO"), []string{})
+
+	var queryErr *models.TaskQueryError
+	_, err := repos.Tasks.GetTasksByUserID("u1", &models.TaskFilters{Query: strPtr("status:pending priority:urgent")}, nil)
+	if !errors.As(err, &queryErr) || queryErr.Offset != 24 || queryErr.Length != 6 {
+		t.Errorf("invalid query: error = %v, want a TaskQueryError at offset 24", err)
+	}
+}
+
+func testTaskSortAndPagination(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+
+	// 作成日時・期限・優先度に同じ値を含め、IDでの並び替えも検証する
+	priorities := []string{"low", "high", "medium", "high", "low", "medium", "high"}
+	for i, priority := range priorities {
+		task := models.Task{
+			ID:        fmt.Sprintf("t%02d", i),
+			UserID:    "u1",
+			Title:     fmt.Sprintf("task %d", i),
+			Priority:  priority,
+			CreatedAt: baseTime.Add(time.Duration(i/2) * time.Hour),
+		}
+		if i%3 != 0 {
+			deadline := baseTime.Add(time.Duration(10-i%4) * 24 * time.Hour)
+			task.Deadline = &deadline
+		}
+		createTask(t, repos, task)
+	}
+
+	cases := []struct {
+		sortBy, sortOrder string
+		want              []string
+	}{
+		{"created_at", "asc", []string{"t00", "t01", "t02", "t03", "t04", "t05", "t06"}},
+		{"created_at", "desc", []string{"t06", "t05", "t04", "t03", "t02", "t01", "t00"}},
+		{"priority", "desc", []string{"t06", "t03", "t01", "t05", "t02", "t04", "t00"}},
+		{"priority", "asc", []string{"t00", "t04", "t02", "t05", "t01", "t03", "t06"}},
+		// 期限なし（t00, t03, t06）は並び順に関わらず末尾
+		{"deadline", "asc", []string{"t02", "t01", "t05", "t04", "t00", "t03", "t06"}},
+		{"deadline", "desc", []string{"t04", "t05", "t01", "t02", "t06", "t03", "t00"}},
+	}
+
+	for _, tc := range cases {
+		label := tc.sortBy + " " + tc.sortOrder
+		filters := &models.TaskFilters{SortBy: strPtr(tc.sortBy), SortOrder: strPtr(tc.sortOrder)}
+
+		assertIDs(t, label, listTaskIDs(t, repos, "u1", filters), tc.want)
+
+		// 2件ずつたどった結果が一括取得と一致すること
+		var paged []string
+		pagination := &models.TaskPagination{Limit: intPtr(2)}
+		for {
+			page, err := repos.Tasks.GetTasksByUserID("u1", filters, pagination)
+			if err != nil {
+				t.Fatalf("%s: GetTasksByUserID: %v", label, err)
+			}
+			paged = append(paged, taskIDs(page.Tasks)...)
+			if !page.HasMore {
+				if page.NextCursor != "" {
+					t.Errorf("%s: last page has a next cursor", label)
+				}
+				break
+			}
+			if len(paged) > len(tc.want) {
+				t.Fatalf("%s: pagination did not terminate", label)
+			}
+			pagination.Cursor = strPtr(page.NextCursor)
+		}
+		assertIDs(t, label+" paged", paged, tc.want)
+	}
+
+	// 別の並び順で発行されたカーソルは拒否する
+	page, err := repos.Tasks.GetTasksByUserID("u1", nil, &models.TaskPagination{Limit: intPtr(2)})
+	if err != nil {
+		t.Fatalf("GetTasksByUserID: %v", err)
+	}
+	_, err = repos.Tasks.GetTasksByUserID("u1", &models.TaskFilters{SortBy: strPtr("priority")}, &models.TaskPagination{Cursor: strPtr(page.NextCursor)})
+	if !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Errorf("cursor from another sort order: error = %v, want ErrInvalidCursor", err)
+	}
+	_, err = repos.Tasks.GetTasksByUserID("u1", nil, &models.TaskPagination{Cursor: strPtr("not-a-cursor")})
+	if !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Errorf("malformed cursor: error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func testTaskHierarchy(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+
+	root := createTask(t, repos, models.Task{ID: "root", UserID: "u1", Title: "root"})
+	child := createTask(t, repos, models.Task{ID: "child", UserID: "u1", Title: "child", ParentID: strPtr("root"), CreatedAt: baseTime.Add(time.Minute)})
+	createTask(t, repos, models.Task{ID: "grandchild", UserID: "u1", Title: "grandchild", ParentID: strPtr("child"), CreatedAt: baseTime.Add(2 * time.Minute)})
+	createTask(t, repos, models.Task{ID: "sibling", UserID: "u1", Title: "sibling", ParentID: strPtr("root"), Status: "completed", CreatedAt: baseTime.Add(3 * time.Minute)})
+	createTask(t, repos, models.Task{ID: "foreign", UserID: "u2", Title: "foreign"})
+
+	children, err := repos.Tasks.GetChildTasks("root")
+	if err != nil {
+		t.Fatalf("GetChildTasks: %v", err)
+	}
+	assertIDs(t, "children", taskIDs(children), []string{"child", "sibling"})
+
+	subtree, err := repos.Tasks.GetTaskSubtree("root")
+	if err != nil {
+		t.Fatalf("GetTaskSubtree: %v", err)
+	}
+	assertIDs(t, "subtree", taskIDs(subtree), []string{"root", "child", "grandchild", "sibling"})
+
+	// 親の指定の検証
+	err = repos.Tasks.CreateTask(&models.Task{ID: "x", UserID: "u1", ParentID: strPtr("missing"), Title: "x", Priority: "low", Status: "pending", CreatedAt: baseTime, UpdatedAt: baseTime})
+	if !errors.Is(err, repository.ErrParentNotFound) {
+		t.Errorf("CreateTask with a missing parent: error = %v, want ErrParentNotFound", err)
+	}
+	err = repos.Tasks.CreateTask(&models.Task{ID: "y", UserID: "u1", ParentID: strPtr("foreign"), Title: "y", Priority: "low", Status: "pending", CreatedAt: baseTime, UpdatedAt: baseTime})
+	if !errors.Is(err, repository.ErrParentNotFound) {
+		t.Errorf("CreateTask under another user's task: error = %v, want ErrParentNotFound", err)
+	}
+
+	for _, parentID := range []string{"root", "child", "grandchild"} {
+		moved := *root
+		moved.ParentID = strPtr(parentID)
+		if err := repos.Tasks.UpdateTask(&moved); !errors.Is(err, repository.ErrTaskCycle) {
+			t.Errorf("UpdateTask moving root under %s: error = %v, want ErrTaskCycle", parentID, err)
+		}
+	}
+
+	// 別の枝への移動と、トップレベルへの移動
+	moved := *child
+	moved.ParentID = strPtr("sibling")
+	if err := repos.Tasks.UpdateTask(&moved); err != nil {
+		t.Fatalf("UpdateTask moving child under sibling: %v", err)
+	}
+	children, _ = repos.Tasks.GetChildTasks("sibling")
+	assertIDs(t, "children after move", taskIDs(children), []string{"child"})
+	moved.ParentID = nil
+	if err := repos.Tasks.UpdateTask(&moved); err != nil {
+		t.Fatalf("UpdateTask moving child to the top level: %v", err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("child"); got == nil || got.ParentID != nil {
+		t.Errorf("child after moving to the top level = %+v", got)
+	}
+	moved.ParentID = strPtr("root")
+	if err := repos.Tasks.UpdateTask(&moved); err != nil {
+		t.Fatalf("UpdateTask moving child back: %v", err)
+	}
+
+	// 子孫の一括完了は完了済みのタスクを含めず、完了にしたタスクを更新後の状態で返す
+	completed, err := repos.Tasks.CompleteSubtasks("root", baseTime.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("CompleteSubtasks: %v", err)
+	}
+	assertIDs(t, "CompleteSubtasks", taskIDs(completed), []string{"child", "grandchild"})
+	for _, task := range completed {
+		if task.Status != "completed" || !task.UpdatedAt.Equal(baseTime.Add(time.Hour)) || task.Version != taskVersion(t, repos, task.ID) {
+			t.Errorf("task returned by CompleteSubtasks = %+v", task)
+		}
+	}
+	if completed, err := repos.Tasks.CompleteSubtasks("root", baseTime.Add(2*time.Hour)); err != nil || len(completed) != 0 {
+		t.Errorf("CompleteSubtasks again = %v, %v; want none", taskIDs(completed), err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("grandchild"); got == nil || got.Status != "completed" {
+		t.Errorf("grandchild after CompleteSubtasks = %+v", got)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("root"); got == nil || got.Status != "pending" {
+		t.Errorf("root after CompleteSubtasks = %+v, want unchanged", got)
+	}
+
+	// 親を削除すると子孫も削除される
+	if err := repos.Tasks.DeleteTask("root", taskVersion(t, repos, "root"), baseTime); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	for _, id := range []string{"child", "grandchild", "sibling"} {
+		if _, err := repos.Tasks.GetTaskByID(id); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetTaskByID(%s) after deleting root: error = %v, want sql.ErrNoRows", id, err)
+		}
+	}
+}
+
+func testTaskVersion(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	if err := repos.Projects.CreateProject(&models.Project{ID: "work", UserID: "u1", Name: "work", Color: models.DefaultProjectColor, CreatedAt: baseTime, UpdatedAt: baseTime}); err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+
+	task := createTask(t, repos, models.Task{ID: "a", UserID: "u1", Title: "a", Tags: []string{"x"}})
+	if task.Version != 1 {
+		t.Errorf("version after CreateTask = %d, want 1", task.Version)
+	}
+
+	// 版数が一致する場合のみ更新し、版数を増やす
+	stale := *task
+	task.Title = "a2"
+	if err := repos.Tasks.UpdateTask(task); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	if task.Version != 2 || taskVersion(t, repos, "a") != 2 {
+		t.Errorf("version after UpdateTask = %d (stored %d), want 2", task.Version, taskVersion(t, repos, "a"))
+	}
+	stale.Title = "lost update"
+	if err := repos.Tasks.UpdateTask(&stale); !errors.Is(err, repository.ErrTaskVersionConflict) {
+		t.Errorf("UpdateTask with a stale version: error = %v, want ErrTaskVersionConflict", err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("a"); got == nil || got.Title != "a2" {
+		t.Errorf("task after a conflicting update = %+v", got)
+	}
+	missing := models.Task{ID: "missing", UserID: "u1", Title: "x", Priority: "low", Status: "pending", Version: 1}
+	if err := repos.Tasks.UpdateTask(&missing); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("UpdateTask(missing): error = %v, want sql.ErrNoRows", err)
+	}
+
+	// タスクの表示が変わる変更はすべて版数を増やす
+	createTask(t, repos, models.Task{ID: "child", UserID: "u1", ParentID: strPtr("a"), Title: "child"})
+	if _, err := repos.Tasks.CompleteSubtasks("a", baseTime); err != nil {
+		t.Fatalf("CompleteSubtasks: %v", err)
+	}
+	if v := taskVersion(t, repos, "child"); v != 2 {
+		t.Errorf("child version after CompleteSubtasks = %d, want 2", v)
+	}
+	if err := repos.Tasks.MoveTask("a", taskVersion(t, repos, "a"), "work", baseTime); err != nil {
+		t.Fatalf("MoveTask: %v", err)
+	}
+	if a, child := taskVersion(t, repos, "a"), taskVersion(t, repos, "child"); a != 3 || child != 3 {
+		t.Errorf("versions after MoveTask = %d, %d; want 3, 3", a, child)
+	}
+	if err := repos.Tasks.MoveTask("a", 2, "work", baseTime); !errors.Is(err, repository.ErrTaskVersionConflict) {
+		t.Errorf("MoveTask with a stale version: error = %v, want ErrTaskVersionConflict", err)
+	}
+	if err := repos.Tasks.MoveTask("missing", 1, "work", baseTime); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("MoveTask(missing): error = %v, want sql.ErrNoRows", err)
+	}
+
+	// 別のプロジェクトのタスクの下に移すと、タスクは1回、移動した子孫も1回増える
+	other := createTask(t, repos, models.Task{ID: "other", UserID: "u1", Title: "other"})
+	moved, _ := repos.Tasks.GetTaskByID("a")
+	moved.ParentID = strPtr(other.ID)
+	if err := repos.Tasks.UpdateTask(moved); err != nil {
+		t.Fatalf("UpdateTask moving a under other: %v", err)
+	}
+	if a, child := taskVersion(t, repos, "a"), taskVersion(t, repos, "child"); a != 4 || child != 4 {
+		t.Errorf("versions after moving under another project = %d, %d; want 4, 4", a, child)
+	}
+
+	tags, _ := repos.Tags.GetTagsByUserID("u1")
+	if err := repos.Tags.RenameTag(tags[0].ID, "y", baseTime); err != nil {
+		t.Fatalf("RenameTag: %v", err)
+	}
+	if v := taskVersion(t, repos, "a"); v != 5 {
+		t.Errorf("version after RenameTag = %d, want 5", v)
+	}
+
+	recurring := createTask(t, repos, recurringTask(t, "daily", "FREQ=DAILY", "UTC", time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)))
+	if _, err := repos.Tasks.EndTaskSeries(*recurring.SeriesID, baseTime); err != nil {
+		t.Fatalf("EndTaskSeries: %v", err)
+	}
+	if v := taskVersion(t, repos, "daily"); v != 2 {
+		t.Errorf("version after EndTaskSeries = %d, want 2", v)
+	}
+
+	// 削除も版数が一致する場合のみ
+	if err := repos.Tasks.DeleteTask("a", 4, baseTime); !errors.Is(err, repository.ErrTaskVersionConflict) {
+		t.Errorf("DeleteTask with a stale version: error = %v, want ErrTaskVersionConflict", err)
+	}
+	if err := repos.Tasks.DeleteTask("a", 5, baseTime); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if err := repos.Tasks.DeleteTask("a", 5, baseTime); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteTask(deleted): error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testTaskTransaction(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+	createTask(t, repos, models.Task{ID: "a", UserID: "u1", Title: "a", Tags: []string{"x"}})
+	errRollback := errors.New("rollback")
+
+	// 入れ子にした WithTx は内側の変更のみを取り消す
+	err := repos.Tasks.WithTx(func(tx repository.TaskRepository, _ repository.TaskHistoryRepository) error {
+		b := models.Task{ID: "b", UserID: "u1", Title: "b", Priority: "low", Status: "pending", Tags: []string{"new"}, CreatedAt: baseTime.Add(time.Hour), UpdatedAt: baseTime}
+		if err := tx.CreateTask(&b); err != nil {
+			return err
+		}
+		a, err := tx.GetTaskByID("a")
+		if err != nil {
+			return err
+		}
+		a.Title = "a2"
+		if err := tx.UpdateTask(a); err != nil {
+			return err
+		}
+		err = tx.WithTx(func(tx repository.TaskRepository, _ repository.TaskHistoryRepository) error {
+			if err := tx.DeleteTask("a", a.Version, baseTime); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			return fmt.Errorf("nested WithTx: error = %v, want errRollback", err)
+		}
+		if _, err := tx.GetTaskByID("a"); err != nil {
+			return fmt.Errorf("task deleted in a rolled back nested WithTx: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	assertIDs(t, "tasks after WithTx", listTaskIDs(t, repos, "u1", nil), []string{"b", "a"})
+	if a, _ := repos.Tasks.GetTaskByID("a"); a == nil || a.Title != "a2" || a.Version != 2 {
+		t.Errorf("task after WithTx = %+v, want title a2 and version 2", a)
+	}
+	if tags, _ := repos.Tags.GetTagsByUserID("u1"); len(tags) != 2 {
+		t.Errorf("tags after WithTx = %+v, want x and new", tags)
+	}
+
+	// fn がエラーを返せば、作成したタグ・Inbox を含めてすべて取り消す
+	err = repos.Tasks.WithTx(func(tx repository.TaskRepository, _ repository.TaskHistoryRepository) error {
+		c := models.Task{ID: "c", UserID: "u2", Title: "c", Priority: "low", Status: "pending", Tags: []string{"y"}, CreatedAt: baseTime, UpdatedAt: baseTime}
+		if err := tx.CreateTask(&c); err != nil {
+			return err
+		}
+		b, err := tx.GetTaskByID("b")
+		if err != nil {
+			return err
+		}
+		b.Status = "completed"
+		if err := tx.UpdateTask(b); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx returning an error: error = %v, want errRollback", err)
+	}
+	if _, err := repos.Tasks.GetTaskByID("c"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTaskByID(c) after rollback: error = %v, want sql.ErrNoRows", err)
+	}
+	if b, _ := repos.Tasks.GetTaskByID("b"); b == nil || b.Status != "pending" || b.Version != 1 {
+		t.Errorf("task after rollback = %+v, want pending at version 1", b)
+	}
+	if projects, _ := repos.Projects.GetProjectsByUserID("u2", true); len(projects) != 0 {
+		t.Errorf("projects after rollback = %+v, want none", projects)
+	}
+	if tags, _ := repos.Tags.GetTagsByUserID("u2"); len(tags) != 0 {
+		t.Errorf("tags after rollback = %+v, want none", tags)
+	}
+}
+
+func testTrash(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createTask(t, repos, models.Task{ID: "root", UserID: "u1", Title: "root", CreatedAt: baseTime})
+	createTask(t, repos, models.Task{ID: "child", UserID: "u1", ParentID: strPtr("root"), Title: "child", Tags: []string{"x"}, CreatedAt: baseTime.Add(time.Minute)})
+	createTask(t, repos, models.Task{ID: "grandchild", UserID: "u1", ParentID: strPtr("child"), Title: "grandchild", CreatedAt: baseTime.Add(2 * time.Minute)})
+	createTask(t, repos, models.Task{ID: "other", UserID: "u1", Title: "other", CreatedAt: baseTime.Add(3 * time.Minute)})
+
+	deleteAt := func(id string, at time.Time) {
+		t.Helper()
+		if err := repos.Tasks.DeleteTask(id, taskVersion(t, repos, id), at); err != nil {
+			t.Fatalf("DeleteTask(%s): %v", id, err)
+		}
+	}
+	deletedIDs := func() []string {
+		t.Helper()
+		tasks, err := repos.Tasks.GetDeletedTasks("u1")
+		if err != nil {
+			t.Fatalf("GetDeletedTasks: %v", err)
+		}
+		return taskIDs(tasks)
+	}
+
+	// 個別に削除した孫と、後から子孫ごと削除したルートは別々にゴミ箱に入る
+	deleteAt("grandchild", baseTime.Add(time.Hour))
+	deleteAt("root", baseTime.Add(2*time.Hour))
+	assertIDs(t, "tasks after delete", listTaskIDs(t, repos, "u1", nil), []string{"other"})
+	assertIDs(t, "trash", deletedIDs(), []string{"root", "grandchild"})
+	for _, id := range []string{"root", "child", "grandchild"} {
+		if _, err := repos.Tasks.GetTaskByID(id); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("GetTaskByID(%s) in trash: error = %v, want sql.ErrNoRows", id, err)
+		}
+	}
+	child, err := repos.Tasks.GetDeletedTaskByID("child")
+	if err != nil || child.DeletedAt == nil || !child.DeletedAt.Equal(baseTime.Add(2*time.Hour)) {
+		t.Errorf("GetDeletedTaskByID(child) = %+v, %v", child, err)
+	}
+	if _, err := repos.Tasks.GetDeletedTaskByID("other"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetDeletedTaskByID(other): error = %v, want sql.ErrNoRows", err)
+	}
+
+	// ゴミ箱のタスクは他の操作からも見えない
+	if tags, _ := repos.Tags.GetTagsByUserID("u1"); len(tags) != 1 || tags[0].TaskCount != 0 {
+		t.Errorf("tags with a deleted task = %+v, want a count of 0", tags)
+	}
+	sub := models.Task{ID: "sub", UserID: "u1", ParentID: strPtr("root"), Title: "sub", Priority: "low", Status: "pending", CreatedAt: baseTime, UpdatedAt: baseTime}
+	if err := repos.Tasks.CreateTask(&sub); !errors.Is(err, repository.ErrParentNotFound) {
+		t.Errorf("CreateTask under a deleted task: error = %v, want ErrParentNotFound", err)
+	}
+	if err := repos.Tasks.DeleteTask("root", child.Version, baseTime); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("DeleteTask(deleted): error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repos.Tasks.RestoreTask("other", baseTime); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreTask(not deleted): error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repos.Tasks.PurgeTask("other"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("PurgeTask(not deleted): error = %v, want sql.ErrNoRows", err)
+	}
+
+	// ルートを戻すと一緒に削除した子も戻り、先に削除した孫はゴミ箱に残る
+	if err := repos.Tasks.RestoreTask("root", baseTime.Add(3*time.Hour)); err != nil {
+		t.Fatalf("RestoreTask(root): %v", err)
+	}
+	assertIDs(t, "tasks after restore", listTaskIDs(t, repos, "u1", nil), []string{"other", "child", "root"})
+	assertIDs(t, "trash after restore", deletedIDs(), []string{"grandchild"})
+	if restored, err := repos.Tasks.GetTaskByID("child"); err != nil || restored.DeletedAt != nil || restored.Version != child.Version+1 ||
+		!restored.UpdatedAt.Equal(baseTime.Add(3*time.Hour)) {
+		t.Errorf("child after restore = %+v, %v", restored, err)
+	}
+	if tags, _ := repos.Tags.GetTagsByUserID("u1"); len(tags) != 1 || tags[0].TaskCount != 1 {
+		t.Errorf("tags after restore = %+v, want a count of 1", tags)
+	}
+
+	// 親が戻っていれば親の下に、ゴミ箱にあればトップレベルに戻る
+	if err := repos.Tasks.RestoreTask("grandchild", baseTime.Add(3*time.Hour)); err != nil {
+		t.Fatalf("RestoreTask(grandchild): %v", err)
+	}
+	if got, err := repos.Tasks.GetTaskByID("grandchild"); err != nil || got.ParentID == nil || *got.ParentID != "child" {
+		t.Errorf("grandchild after restore = %+v, %v", got, err)
+	}
+	deleteAt("grandchild", baseTime.Add(4*time.Hour))
+	deleteAt("child", baseTime.Add(5*time.Hour))
+	if err := repos.Tasks.RestoreTask("grandchild", baseTime.Add(6*time.Hour)); err != nil {
+		t.Fatalf("RestoreTask(grandchild): %v", err)
+	}
+	if got, err := repos.Tasks.GetTaskByID("grandchild"); err != nil || got.ParentID != nil {
+		t.Errorf("grandchild restored under a deleted parent = %+v, %v; want a top-level task", got, err)
+	}
+
+	// 完全な削除と、保持期間を過ぎたタスクの削除
+	deleteAt("other", baseTime.Add(7*time.Hour))
+	if err := repos.Tasks.PurgeTask("child"); err != nil {
+		t.Fatalf("PurgeTask: %v", err)
+	}
+	if _, err := repos.Tasks.GetDeletedTaskByID("child"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetDeletedTaskByID after purge: error = %v, want sql.ErrNoRows", err)
+	}
+	deleteAt("root", baseTime.Add(8*time.Hour))
+	purged, err := repos.Tasks.PurgeDeletedTasks(baseTime.Add(8 * time.Hour))
+	if err != nil || purged != 1 {
+		t.Errorf("PurgeDeletedTasks = %d, %v; want 1", purged, err)
+	}
+	assertIDs(t, "trash after purge", deletedIDs(), []string{"root"})
+	assertIDs(t, "tasks after purge", listTaskIDs(t, repos, "u1", nil), []string{"grandchild"})
+}
+
+func testTaskHistory(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	task := createTask(t, repos, models.Task{ID: "t1", UserID: "u1", Title: "before", Priority: "low"})
+	createTask(t, repos, models.Task{ID: "t2", UserID: "u1", Title: "other"})
+
+	record := func(id, action string, before, after *models.Task, at time.Time) {
+		t.Helper()
+		entry, err := models.NewTaskHistoryEntry(id, action, "u1", before, after, at)
+		if err != nil {
+			t.Fatalf("NewTaskHistoryEntry(%s): %v", id, err)
+		}
+		if err := repos.TaskHistory.CreateTaskHistory(entry); err != nil {
+			t.Fatalf("CreateTaskHistory(%s): %v", id, err)
+		}
+	}
+	record("h1", models.TaskHistoryCreated, nil, task, baseTime)
+	updated := *task
+	updated.Title, updated.Priority, updated.Version = "after", "high", 2
+	record("h2", models.TaskHistoryUpdated, task, &updated, baseTime.Add(time.Minute))
+
+	// 存在しないユーザーによる変更は記録できない
+	missing := &models.TaskHistoryEntry{ID: "h3", TaskID: "t1", ActorID: "nobody", Action: models.TaskHistoryUpdated, Version: 1,
+		Changes: []models.TaskFieldChange{}, Snapshot: []byte("{}"), CreatedAt: baseTime}
+	if err := repos.TaskHistory.CreateTaskHistory(missing); err == nil {
+		t.Error("CreateTaskHistory by a missing user succeeded")
+	}
+
+	entries, err := repos.TaskHistory.GetTaskHistory("t1")
+	if err != nil {
+		t.Fatalf("GetTaskHistory: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != "h2" || entries[1].ID != "h1" {
+		t.Fatalf("GetTaskHistory = %+v, want h2, h1", entries)
+	}
+	changes := entries[0].Changes
+	if len(changes) != 2 || changes[0].Field != "title" || string(changes[0].Old) != `"before"` || string(changes[0].New) != `"after"` ||
+		changes[1].Field != "priority" || string(changes[1].Old) != `"low"` || string(changes[1].New) != `"high"` {
+		t.Errorf("changes = %+v, want title and priority", changes)
+	}
+	if entries[0].Version != 2 || entries[0].ActorID != "u1" || !entries[0].CreatedAt.Equal(baseTime.Add(time.Minute)) {
+		t.Errorf("entry = %+v", entries[0])
+	}
+
+	revision, err := repos.TaskHistory.GetTaskRevision("t1", 1)
+	if err != nil || revision.ID != "h1" {
+		t.Fatalf("GetTaskRevision(1) = %+v, %v", revision, err)
+	}
+	var snapshot models.TaskPatchDocument
+	if err := json.Unmarshal(revision.Snapshot, &snapshot); err != nil || snapshot.Title != "before" || snapshot.Priority != "low" {
+		t.Errorf("snapshot = %s, %v", revision.Snapshot, err)
+	}
+	if _, err := repos.TaskHistory.GetTaskRevision("t1", 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTaskRevision(3): error = %v, want sql.ErrNoRows", err)
+	}
+	if entries, _ := repos.TaskHistory.GetTaskHistory("t2"); len(entries) != 0 {
+		t.Errorf("GetTaskHistory(t2) = %+v, want none", entries)
+	}
+
+	// WithTx で記録した履歴は、タスクの変更と一緒に取り消される
+	errRollback := errors.New("rollback")
+	err = repos.Tasks.WithTx(func(tx repository.TaskRepository, history repository.TaskHistoryRepository) error {
+		if err := tx.DeleteTask("t2", 1, baseTime); err != nil {
+			return err
+		}
+		deleted := models.Task{ID: "t2", UserID: "u1", Title: "other", Version: 2}
+		entry, err := models.NewTaskHistoryEntry("h4", models.TaskHistoryDeleted, "u1", nil, &deleted, baseTime)
+		if err != nil {
+			return err
+		}
+		if err := history.CreateTaskHistory(entry); err != nil {
+			return err
+		}
+		if entries, err := history.GetTaskHistory("t2"); err != nil || len(entries) != 1 {
+			return fmt.Errorf("history in WithTx = %+v, %v; want h4", entries, err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("WithTx recording history: error = %v, want errRollback", err)
+	}
+	if entries, _ := repos.TaskHistory.GetTaskHistory("t2"); len(entries) != 0 {
+		t.Errorf("history after rollback = %+v, want none", entries)
+	}
+	if _, err := repos.Tasks.GetTaskByID("t2"); err != nil {
+		t.Errorf("task after rollback: %v", err)
+	}
+
+	// ゴミ箱にある間も、完全に削除した後も、監査のため履歴は残る
+	if err := repos.Tasks.DeleteTask("t1", 1, baseTime); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	if entries, _ := repos.TaskHistory.GetTaskHistory("t1"); len(entries) != 2 {
+		t.Errorf("history in trash = %d entries, want 2", len(entries))
+	}
+	if err := repos.Tasks.PurgeTask("t1"); err != nil {
+		t.Fatalf("PurgeTask: %v", err)
+	}
+	if entries, _ := repos.TaskHistory.GetTaskHistory("t1"); len(entries) != 2 {
+		t.Errorf("history after purge = %d entries, want 2", len(entries))
+	}
+}
+
+func testTaskSearch(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+	createTask(t, repos, models.Task{ID: "buy", UserID: "u1", Title: "Buy milk", Description: strPtr("at the store")})
+	createTask(t, repos, models.Task{ID: "list", UserID: "u1", Title: "Grocery list", Description: strPtr("milk, eggs and bread")})
+	shake := createTask(t, repos, models.Task{ID: "shake", UserID: "u1", Title: "Milkshake recipe"})
+	createTask(t, repos, models.Task{ID: "report", UserID: "u1", Title: "Write report", Description: strPtr("quarterly report for <b>sales</b>")})
+	createTask(t, repos, models.Task{ID: "trashed", UserID: "u1", Title: "Old milk"})
+	createTask(t, repos, models.Task{ID: "other", UserID: "u2", Title: "Milk for u2"})
+	if err := repos.Tasks.DeleteTask("trashed", 1, baseTime); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+
+	search := func(userID, q string, limit int) []models.TaskSearchResult {
+		t.Helper()
+		query, err := models.ParseTaskSearchQuery(q)
+		if err != nil {
+			t.Fatalf("ParseTaskSearchQuery(%q): %v", q, err)
+		}
+		results, err := repos.Tasks.SearchTasks(userID, query, limit)
+		if err != nil {
+			t.Fatalf("SearchTasks(%q): %v", q, err)
+		}
+		return results
+	}
+	resultIDs := func(results []models.TaskSearchResult) []string {
+		ids := make([]string, len(results))
+		for i, result := range results {
+			ids[i] = result.Task.ID
+		}
+		return ids
+	}
+
+	// タイトルの一致が説明の一致より上位になり、ゴミ箱のタスクと他のユーザーのタスクは含まれない
+	results := search("u1", "MILK", 10)
+	assertIDs(t, "milk", resultIDs(results), []string{"buy", "list"})
+	if len(results) == 2 && (results[0].Score <= results[1].Score || results[0].TitleHighlight != "Buy <mark>milk</mark>") {
+		t.Errorf("milk results = %+v", results)
+	}
+	assertIDs(t, "milk (u2)", resultIDs(search("u2", "milk", 10)), []string{"other"})
+	assertIDs(t, "limit", resultIDs(search("u1", "milk", 1)), []string{"buy"})
+
+	// 前方一致・フレーズ・複数の語
+	if ids := resultIDs(search("u1", "milk*", 10)); len(ids) != 3 {
+		t.Errorf("milk* = %v, want buy, list and shake", ids)
+	}
+	assertIDs(t, "phrase", resultIDs(search("u1", `"eggs and bread"`, 10)), []string{"list"})
+	assertIDs(t, "phrase out of order", resultIDs(search("u1", `"bread eggs"`, 10)), []string{})
+	assertIDs(t, "prefix phrase", resultIDs(search("u1", `"eggs and bre"*`, 10)), []string{"list"})
+	assertIDs(t, "all terms", resultIDs(search("u1", "grocery bread", 10)), []string{"list"})
+	assertIDs(t, "operators are words", resultIDs(search("u1", "milk OR report", 10)), []string{})
+
+	// 抜粋は一致箇所以外をエスケープする
+	results = search("u1", "sales", 10)
+	if len(results) != 1 || !strings.Contains(results[0].Snippet, "&lt;b&gt;<mark>sales</mark>&lt;/b&gt;") {
+		t.Errorf("sales snippet = %+v", results)
+	}
+
+	// タイトルの変更・タスクの削除が検索に反映される
+	shake.Title = "Smoothie recipe"
+	shake.Version = 1
+	if err := repos.Tasks.UpdateTask(shake); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	assertIDs(t, "after rename", resultIDs(search("u1", "smoothie", 10)), []string{"shake"})
+	if ids := resultIDs(search("u1", "milk*", 10)); len(ids) != 2 {
+		t.Errorf("milk* after rename = %v, want buy and list", ids)
+	}
+	if err := repos.Tasks.PurgeTask("trashed"); err != nil {
+		t.Fatalf("PurgeTask: %v", err)
+	}
+	if err := repos.Tasks.RestoreTask("trashed", baseTime); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("RestoreTask after purge: error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func projectIDs(projects []models.Project) []string {
+	ids := []string{}
+	for _, project := range projects {
+		ids = append(ids, project.ID)
+	}
+	return ids
+}
+
+func testProjects(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+
+	// Inbox は何度呼んでも同じものを返す
+	inbox, err := repos.Projects.EnsureInbox("u1", baseTime)
+	if err != nil {
+		t.Fatalf("EnsureInbox: %v", err)
+	}
+	if again, err := repos.Projects.EnsureInbox("u1", baseTime); err != nil || again.ID != inbox.ID || !again.IsInbox {
+		t.Fatalf("EnsureInbox again = %+v, %v", again, err)
+	}
+
+	for _, id := range []string{"work", "home"} {
+		project := &models.Project{ID: id, UserID: "u1", Name: id, Color: models.DefaultProjectColor, CreatedAt: baseTime, UpdatedAt: baseTime}
+		if err := repos.Projects.CreateProject(project); err != nil {
+			t.Fatalf("CreateProject(%s): %v", id, err)
+		}
+	}
+	other := &models.Project{ID: "other", UserID: "u2", Name: "other", Color: models.DefaultProjectColor, CreatedAt: baseTime, UpdatedAt: baseTime}
+	if err := repos.Projects.CreateProject(other); err != nil {
+		t.Fatalf("CreateProject(other): %v", err)
+	}
+	projects, err := repos.Projects.GetProjectsByUserID("u1", false)
+	if err != nil {
+		t.Fatalf("GetProjectsByUserID: %v", err)
+	}
+	assertIDs(t, "projects", projectIDs(projects), []string{inbox.ID, "work", "home"})
+
+	// 指定しなかったプロジェクトは後ろに並び、他のユーザーのプロジェクトは指定できない
+	if err := repos.Projects.ReorderProjects("u1", []string{"home", "work"}, baseTime); err != nil {
+		t.Fatalf("ReorderProjects: %v", err)
+	}
+	projects, _ = repos.Projects.GetProjectsByUserID("u1", false)
+	assertIDs(t, "projects after reorder", projectIDs(projects), []string{"home", "work", inbox.ID})
+	if err := repos.Projects.ReorderProjects("u1", []string{"other"}, baseTime); !errors.Is(err, repository.ErrProjectNotFound) {
+		t.Errorf("ReorderProjects with another user's project: error = %v, want ErrProjectNotFound", err)
+	}
+
+	// プロジェクト未指定のタスクは Inbox、サブタスクは親のプロジェクトに入る
+	createTask(t, repos, models.Task{ID: "a", UserID: "u1", Title: "a"})
+	createTask(t, repos, models.Task{ID: "b", UserID: "u1", ProjectID: "work", Title: "b", CreatedAt: baseTime.Add(time.Minute)})
+	createTask(t, repos, models.Task{ID: "b1", UserID: "u1", ProjectID: "home", ParentID: strPtr("b"), Title: "b1", CreatedAt: baseTime.Add(2 * time.Minute)})
+	if got, _ := repos.Tasks.GetTaskByID("a"); got == nil || got.ProjectID != inbox.ID {
+		t.Errorf("project of a = %+v, want inbox", got)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("b1"); got == nil || got.ProjectID != "work" {
+		t.Errorf("project of b1 = %+v, want work", got)
+	}
+	err = repos.Tasks.CreateTask(&models.Task{ID: "x", UserID: "u1", ProjectID: "other", Title: "x", Priority: "low", Status: "pending", CreatedAt: baseTime, UpdatedAt: baseTime})
+	if !errors.Is(err, repository.ErrProjectNotFound) {
+		t.Errorf("CreateTask in another user's project: error = %v, want ErrProjectNotFound", err)
+	}
+
+	filter := func(projectID string) *models.TaskFilters {
+		return &models.TaskFilters{ProjectID: strPtr(projectID), SortBy: strPtr("created_at"), SortOrder: strPtr("asc")}
+	}
+	assertIDs(t, "tasks in work", listTaskIDs(t, repos, "u1", filter("work")), []string{"b", "b1"})
+
+	// サブタスクだけを移動すると親から切り離され、親ごと移動すると子孫も移動する
+	if err := repos.Tasks.MoveTask("b1", taskVersion(t, repos, "b1"), "home", baseTime.Add(time.Hour)); err != nil {
+		t.Fatalf("MoveTask(b1): %v", err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("b1"); got == nil || got.ProjectID != "home" || got.ParentID != nil {
+		t.Errorf("b1 after move = %+v, want a root task in home", got)
+	}
+	b1, _ := repos.Tasks.GetTaskByID("b1")
+	b1.ParentID = strPtr("b")
+	if err := repos.Tasks.UpdateTask(b1); err != nil {
+		t.Fatalf("UpdateTask(b1): %v", err)
+	}
+	if err := repos.Tasks.MoveTask("b", taskVersion(t, repos, "b"), "home", baseTime.Add(time.Hour)); err != nil {
+		t.Fatalf("MoveTask(b): %v", err)
+	}
+	assertIDs(t, "tasks in home", listTaskIDs(t, repos, "u1", filter("home")), []string{"b", "b1"})
+	if got, _ := repos.Tasks.GetTaskByID("b1"); got == nil || got.ParentID == nil {
+		t.Errorf("b1 after moving its parent = %+v, want parent b", got)
+	}
+
+	// アーカイブ済みのプロジェクトにはタスクを移動できず、Inbox はアーカイブできない
+	work, _ := repos.Projects.GetProjectByID("work")
+	work.Archived = true
+	if err := repos.Projects.UpdateProject(work); err != nil {
+		t.Fatalf("UpdateProject: %v", err)
+	}
+	if err := repos.Tasks.MoveTask("a", taskVersion(t, repos, "a"), "work", baseTime); !errors.Is(err, repository.ErrProjectArchived) {
+		t.Errorf("MoveTask to an archived project: error = %v, want ErrProjectArchived", err)
+	}
+	projects, _ = repos.Projects.GetProjectsByUserID("u1", false)
+	assertIDs(t, "projects without archived", projectIDs(projects), []string{"home", inbox.ID})
+	inbox.Archived = true
+	if err := repos.Projects.UpdateProject(inbox); !errors.Is(err, repository.ErrInboxProject) {
+		t.Errorf("UpdateProject archiving the inbox: error = %v, want ErrInboxProject", err)
+	}
+
+	// 削除は reassign でタスクを移し、cascade でタスクをゴミ箱に移す
+	deletedAt := baseTime.Add(2 * time.Hour)
+	if err := repos.Projects.DeleteProject(inbox.ID, nil, deletedAt); !errors.Is(err, repository.ErrInboxProject) {
+		t.Errorf("DeleteProject(inbox): error = %v, want ErrInboxProject", err)
+	}
+	if err := repos.Projects.DeleteProject("home", strPtr("work"), deletedAt); !errors.Is(err, repository.ErrProjectArchived) {
+		t.Errorf("DeleteProject reassigning to an archived project: error = %v, want ErrProjectArchived", err)
+	}
+	if err := repos.Projects.DeleteProject("home", strPtr(inbox.ID), deletedAt); err != nil {
+		t.Fatalf("DeleteProject(home, reassign): %v", err)
+	}
+	assertIDs(t, "tasks in inbox", listTaskIDs(t, repos, "u1", filter(inbox.ID)), []string{"a", "b", "b1"})
+	if err := repos.Tasks.MoveTask("b", taskVersion(t, repos, "b"), "other", baseTime); !errors.Is(err, repository.ErrProjectNotFound) {
+		t.Errorf("MoveTask to another user's project: error = %v, want ErrProjectNotFound", err)
+	}
+
+	createTask(t, repos, models.Task{ID: "c", UserID: "u1", ProjectID: inbox.ID, Title: "c"})
+	work.Archived = false
+	_ = repos.Projects.UpdateProject(work)
+	if err := repos.Tasks.MoveTask("b", taskVersion(t, repos, "b"), "work", baseTime); err != nil {
+		t.Fatalf("MoveTask(b, work): %v", err)
+	}
+	if err := repos.Projects.DeleteProject("work", nil, deletedAt); err != nil {
+		t.Fatalf("DeleteProject(work, cascade): %v", err)
+	}
+	assertIDs(t, "tasks after cascade", listTaskIDs(t, repos, "u1", filter("")), []string{"a", "c"})
+	if _, err := repos.Projects.GetProjectByID("work"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetProjectByID after delete: error = %v, want sql.ErrNoRows", err)
+	}
+	trashed, err := repos.Tasks.GetDeletedTasks("u1")
+	if err != nil {
+		t.Fatalf("GetDeletedTasks: %v", err)
+	}
+	assertIDs(t, "trash after cascade", taskIDs(trashed), []string{"b"})
+
+	// 削除したプロジェクトのタスクを元に戻すと、子孫とともに Inbox に入る
+	if err := repos.Tasks.RestoreTask("b", deletedAt.Add(time.Hour)); err != nil {
+		t.Fatalf("RestoreTask(b): %v", err)
+	}
+	assertIDs(t, "inbox after restore", listTaskIDs(t, repos, "u1", filter(inbox.ID)), []string{"a", "c", "b", "b1"})
+}
+
+// recurringTask 現地時刻 local を最初の回とする繰り返しタスク
+func testViews(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+
+	deadline := baseTime.Add(24 * time.Hour)
+	overdue := &models.View{ID: "v2", UserID: "u1", Name: "Overdue high priority",
+		Filters: models.TaskFilters{Priority: strPtr("high"), Overdue: boolPtr(true), DeadlineTo: &deadline,
+			TagsAll: []string{"backend"}, SortBy: strPtr("deadline"), SortOrder: strPtr("asc"), Query: strPtr("-tag:blocked")},
+		Display:   models.ViewDisplay{ShowCompleted: true, GroupBy: "project", Columns: []string{"title", "deadline"}},
+		CreatedAt: baseTime.Add(time.Hour), UpdatedAt: baseTime.Add(time.Hour)}
+	inbox := &models.View{ID: "v1", UserID: "u1", Name: "Everything", CreatedAt: baseTime, UpdatedAt: baseTime}
+	other := &models.View{ID: "v3", UserID: "u2", Name: "Other", CreatedAt: baseTime, UpdatedAt: baseTime}
+	for _, view := range []*models.View{overdue, inbox, other} {
+		if err := repos.Views.CreateView(view); err != nil {
+			t.Fatalf("CreateView(%s): %v", view.ID, err)
+		}
+	}
+	if err := repos.Views.CreateView(&models.View{ID: "v4", UserID: "missing", Name: "x", CreatedAt: baseTime, UpdatedAt: baseTime}); err == nil {
+		t.Error("CreateView for a missing user succeeded")
+	}
+
+	// 条件と表示方法はそのまま読み戻せる
+	got, err := repos.Views.GetViewByID("v2")
+	if err != nil {
+		t.Fatalf("GetViewByID: %v", err)
+	}
+	if fmt.Sprintf("%+v", got.Display) != fmt.Sprintf("%+v", overdue.Display) || got.Name != overdue.Name ||
+		*got.Filters.Priority != "high" || !*got.Filters.Overdue || !got.Filters.DeadlineTo.Equal(deadline) ||
+		fmt.Sprint(got.Filters.TagsAll) != "[backend]" || *got.Filters.SortBy != "deadline" || *got.Filters.Query != "-tag:blocked" ||
+		got.Filters.Status != nil || got.Filters.TagsAny != nil {
+		t.Errorf("GetViewByID = %+v", got)
+	}
+	if _, err := repos.Views.GetViewByID("missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetViewByID(missing): error = %v, want sql.ErrNoRows", err)
+	}
+
+	views, err := repos.Views.GetViewsByUserID("u1")
+	if err != nil {
+		t.Fatalf("GetViewsByUserID: %v", err)
+	}
+	ids := make([]string, len(views))
+	for i, view := range views {
+		ids[i] = view.ID
+	}
+	assertIDs(t, "views", ids, []string{"v1", "v2"})
+
+	// 更新は条件と表示方法を置き換える
+	got.Name = "Renamed"
+	got.Filters = models.TaskFilters{Status: strPtr("completed")}
+	got.Display = models.ViewDisplay{}
+	got.UpdatedAt = baseTime.Add(2 * time.Hour)
+	if err := repos.Views.UpdateView(got); err != nil {
+		t.Fatalf("UpdateView: %v", err)
+	}
+	updated, err := repos.Views.GetViewByID("v2")
+	if err != nil || updated.Name != "Renamed" || updated.Filters.Priority != nil || *updated.Filters.Status != "completed" ||
+		updated.Display.GroupBy != "" || updated.Display.Columns != nil || !updated.UpdatedAt.Equal(got.UpdatedAt) {
+		t.Errorf("after UpdateView = %+v, %v", updated, err)
+	}
+
+	if err := repos.Views.DeleteView("v2"); err != nil {
+		t.Fatalf("DeleteView: %v", err)
+	}
+	if _, err := repos.Views.GetViewByID("v2"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetViewByID after delete: error = %v, want sql.ErrNoRows", err)
+	}
+	if views, err := repos.Views.GetViewsByUserID("u2"); err != nil || len(views) != 1 {
+		t.Errorf("GetViewsByUserID(u2) = %v, %v", views, err)
+	}
+}
+
+func recurringTask(t *testing.T, id, rule, timezone string, local time.Time) models.Task {
+	t.Helper()
+
+	rule, timezone, err := models.NormalizeRecurrence(rule, timezone)
+	if err != nil {
+		t.Fatalf("NormalizeRecurrence: %v", err)
+	}
+	occurrenceAt := local.UTC()
+	return models.Task{ID: id, UserID: "u1", Title: id, Deadline: timePtr(occurrenceAt), OccurrenceAt: timePtr(occurrenceAt),
+		RRule: &rule, Timezone: &timezone}
+}
+
+// nextOccurrence 保存した系列から次の回を計算する（DTSTART のタイムゾーンが保存をまたいで失われないことも確認する）
+func nextOccurrence(t *testing.T, repos *repository.Repositories, taskID string) *time.Time {
+	t.Helper()
+
+	task, err := repos.Tasks.GetTaskByID(taskID)
+	if err != nil {
+		t.Fatalf("GetTaskByID(%s): %v", taskID, err)
+	}
+	if task.SeriesID == nil {
+		t.Fatalf("task %s has no series", taskID)
+	}
+	series, err := repos.Tasks.GetTaskSeries(*task.SeriesID)
+	if err != nil {
+		t.Fatalf("GetTaskSeries: %v", err)
+	}
+	next, err := series.NextOccurrence(*task.OccurrenceAt)
+	if err != nil {
+		t.Fatalf("NextOccurrence: %v", err)
+	}
+	return next
+}
+
+func testRecurrence(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	// 夏時間の開始（ベルリン 2026-03-29）をまたいでも現地時刻 9:00 を保つ
+	createTask(t, repos, recurringTask(t, "daily", "RRULE:freq=daily", "Europe/Berlin", time.Date(2026, 3, 28, 9, 0, 0, 0, berlin)))
+	task, err := repos.Tasks.GetTaskByID("daily")
+	if err != nil {
+		t.Fatalf("GetTaskByID: %v", err)
+	}
+	if task.RRule == nil || *task.RRule != "FREQ=DAILY" || task.Timezone == nil || *task.Timezone != "Europe/Berlin" {
+		t.Fatalf("recurrence of daily = %v, %v", task.RRule, task.Timezone)
+	}
+	want := time.Date(2026, 3, 29, 9, 0, 0, 0, berlin)
+	if next := nextOccurrence(t, repos, "daily"); next == nil || !next.Equal(want) || next.Sub(*task.Deadline) != 23*time.Hour {
+		t.Errorf("next occurrence across spring forward = %v, want %v", next, want)
+	}
+
+	// 夏時間の終了（ニューヨーク 2026-11-01）をまたいでも現地時刻 9:00 を保つ
+	createTask(t, repos, recurringTask(t, "weekly", "FREQ=WEEKLY;BYDAY=MO", "America/New_York", time.Date(2026, 10, 26, 9, 0, 0, 0, newYork)))
+	want = time.Date(2026, 11, 2, 9, 0, 0, 0, newYork)
+	if next := nextOccurrence(t, repos, "weekly"); next == nil || !next.Equal(want) || next.UTC().Hour() != 14 {
+		t.Errorf("next occurrence across fall back = %v, want %v", next, want)
+	}
+
+	// 同じ回は二重に作成されない
+	series, _ := repos.Tasks.GetTaskSeries(*task.SeriesID)
+	next := series.NewOccurrence("daily-2", task, *nextOccurrence(t, repos, "daily"), baseTime)
+	if created, err := repos.Tasks.CreateOccurrence(next); err != nil || !created {
+		t.Fatalf("CreateOccurrence = %v, %v", created, err)
+	}
+	duplicate := series.NewOccurrence("daily-2b", task, *next.OccurrenceAt, baseTime)
+	if created, err := repos.Tasks.CreateOccurrence(duplicate); err != nil || created {
+		t.Errorf("CreateOccurrence for an existing occurrence = %v, %v, want false", created, err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("daily-2"); got == nil || got.RRule == nil || got.SeriesID == nil || *got.SeriesID != series.ID {
+		t.Errorf("next occurrence = %+v", got)
+	}
+
+	// 系列の変更は以降の回に引き継がれる
+	series.Title = "renamed"
+	series.RRule = "FREQ=WEEKLY"
+	series.UpdatedAt = baseTime
+	if err := repos.Tasks.UpdateTaskSeries(series); err != nil {
+		t.Fatalf("UpdateTaskSeries: %v", err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("daily"); got == nil || got.RRule == nil || *got.RRule != "FREQ=WEEKLY" {
+		t.Errorf("rrule after UpdateTaskSeries = %+v", got)
+	}
+	want = time.Date(2026, 4, 4, 9, 0, 0, 0, berlin)
+	if next := nextOccurrence(t, repos, "daily"); next == nil || !next.Equal(want) {
+		t.Errorf("next occurrence after changing the rule = %v, want %v", next, want)
+	}
+
+	// スキップすると次の回に置き換わり、スキップした回はゴミ箱に移る（版数が変わっていれば何もしない）
+	skipped := series.NewOccurrence("daily-3", task, want, baseTime)
+	if err := repos.Tasks.SkipOccurrence("daily-2", taskVersion(t, repos, "daily-2")-1, skipped, baseTime); !errors.Is(err, repository.ErrTaskVersionConflict) {
+		t.Errorf("SkipOccurrence with a stale version: error = %v, want ErrTaskVersionConflict", err)
+	}
+	if _, err := repos.Tasks.GetTaskByID("daily-3"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("occurrence after a conflicting skip: error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repos.Tasks.SkipOccurrence("daily-2", taskVersion(t, repos, "daily-2"), skipped, baseTime.Add(time.Hour)); err != nil {
+		t.Fatalf("SkipOccurrence: %v", err)
+	}
+	if _, err := repos.Tasks.GetTaskByID("daily-2"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTaskByID of a skipped occurrence: error = %v, want sql.ErrNoRows", err)
+	}
+	if got, err := repos.Tasks.GetDeletedTaskByID("daily-2"); err != nil || !got.DeletedAt.Equal(baseTime.Add(time.Hour)) {
+		t.Errorf("GetDeletedTaskByID of a skipped occurrence = %+v, %v", got, err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("daily-3"); got == nil || got.Title != "renamed" {
+		t.Errorf("occurrence after skip = %+v", got)
+	}
+
+	// 回数の上限に達すると次の回はない
+	createTask(t, repos, recurringTask(t, "twice", "FREQ=DAILY;COUNT=2", "UTC", time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)))
+	second := nextOccurrence(t, repos, "twice")
+	if second == nil {
+		t.Fatalf("second occurrence of COUNT=2 is missing")
+	}
+	twice, _ := repos.Tasks.GetTaskByID("twice")
+	twiceSeries, _ := repos.Tasks.GetTaskSeries(*twice.SeriesID)
+	if after, err := twiceSeries.NextOccurrence(*second); err != nil || after != nil {
+		t.Errorf("occurrence after the last one = %v, %v, want nil", after, err)
+	}
+
+	// 終了した系列のタスクにはルールが表示されない。版数を増やした回を返し、終了済みの系列では何もしない
+	ended, err := repos.Tasks.EndTaskSeries(*twice.SeriesID, baseTime)
+	if err != nil {
+		t.Fatalf("EndTaskSeries: %v", err)
+	}
+	if len(ended) != 1 || ended[0].ID != "twice" || ended[0].Version != 2 || ended[0].RRule != nil {
+		t.Errorf("EndTaskSeries = %+v, want twice at version 2 without a rule", ended)
+	}
+	if ended, err := repos.Tasks.EndTaskSeries(*twice.SeriesID, baseTime); err != nil || len(ended) != 0 {
+		t.Errorf("EndTaskSeries of an ended series = %+v, %v; want none", ended, err)
+	}
+	if got, _ := repos.Tasks.GetTaskByID("twice"); got == nil || got.RRule != nil || got.SeriesID == nil {
+		t.Errorf("task after EndTaskSeries = %+v", got)
+	}
+	if ended, _ := repos.Tasks.GetTaskSeries(*twice.SeriesID); ended == nil || ended.EndedAt == nil {
+		t.Errorf("series after EndTaskSeries = %+v", ended)
+	}
+
+	// ゴミ箱にある回（スキップした回）の版数は増やさない
+	skippedVersion := func() int64 {
+		got, err := repos.Tasks.GetDeletedTaskByID("daily-2")
+		if err != nil {
+			t.Fatalf("GetDeletedTaskByID: %v", err)
+		}
+		return got.Version
+	}
+	before := skippedVersion()
+	daily, _ := repos.Tasks.GetTaskByID("daily")
+	ended, err = repos.Tasks.EndTaskSeries(*daily.SeriesID, baseTime)
+	if err != nil {
+		t.Fatalf("EndTaskSeries: %v", err)
+	}
+	endedIDs := make([]string, len(ended))
+	for i := range ended {
+		endedIDs[i] = ended[i].ID
+	}
+	assertIDs(t, "EndTaskSeries with a skipped occurrence", endedIDs, []string{"daily", "daily-3"})
+	if after := skippedVersion(); after != before {
+		t.Errorf("version of the skipped occurrence = %d, want %d", after, before)
+	}
+}
+
+func testTags(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+
+	// 未登録のタグ名はタスクの作成時にタグとして作成される
+	createTask(t, repos, models.Task{ID: "a", UserID: "u1", Title: "a", Tags: []string{"backend", "review"}})
+	createTask(t, repos, models.Task{ID: "b", UserID: "u1", Title: "b", Tags: []string{"backend"}, CreatedAt: baseTime.Add(time.Minute)})
+	createTask(t, repos, models.Task{ID: "c", UserID: "u1", Title: "c", CreatedAt: baseTime.Add(2 * time.Minute)})
+	createTask(t, repos, models.Task{ID: "d", UserID: "u2", Title: "d", Tags: []string{"backend"}})
+
+	got, err := repos.Tasks.GetTaskByID("a")
+	if err != nil {
+		t.Fatalf("GetTaskByID: %v", err)
+	}
+	assertIDs(t, "tags of a", got.Tags, []string{"backend", "review"})
+
+	tags, err := repos.Tags.GetTagsByUserID("u1")
+	if err != nil {
+		t.Fatalf("GetTagsByUserID: %v", err)
+	}
+	if len(tags) != 2 || tags[0].Name != "backend" || tags[0].TaskCount != 2 || tags[1].Name != "review" || tags[1].TaskCount != 1 {
+		t.Fatalf("GetTagsByUserID = %+v", tags)
+	}
+	backend, review := tags[0], tags[1]
+
+	filter := func(f models.TaskFilters) *models.TaskFilters {
+		f.SortBy = strPtr("created_at")
+		f.SortOrder = strPtr("asc")
+		return &f
+	}
+	assertIDs(t, "tags_any", listTaskIDs(t, repos, "u1", filter(models.TaskFilters{TagsAny: []string{"review", "backend"}})), []string{"a", "b"})
+	assertIDs(t, "tags_all", listTaskIDs(t, repos, "u1", filter(models.TaskFilters{TagsAll: []string{"review", "backend"}})), []string{"a"})
+	assertIDs(t, "tags_all unknown", listTaskIDs(t, repos, "u1", filter(models.TaskFilters{TagsAll: []string{"backend", "missing"}})), []string{})
+
+	// 作成済みのタグと同じ名前は作成できない
+	err = repos.Tags.CreateTag(&models.Tag{ID: "dup", UserID: "u1", Name: "backend", CreatedAt: baseTime, UpdatedAt: baseTime})
+	if !errors.Is(err, repository.ErrTagExists) {
+		t.Errorf("CreateTag with a duplicate name: error = %v, want ErrTagExists", err)
+	}
+	if err := repos.Tags.CreateTag(&models.Tag{ID: "empty", UserID: "u1", Name: "unused", CreatedAt: baseTime, UpdatedAt: baseTime}); err != nil {
+		t.Fatalf("CreateTag: %v", err)
+	}
+	if tag, err := repos.Tags.GetTagByID("empty"); err != nil || tag.TaskCount != 0 {
+		t.Errorf("GetTagByID(empty) = %+v, %v", tag, err)
+	}
+
+	// 名前の変更はすべてのタスクに反映される
+	if err := repos.Tags.RenameTag(backend.ID, "review", baseTime); !errors.Is(err, repository.ErrTagExists) {
+		t.Errorf("RenameTag to an existing name: error = %v, want ErrTagExists", err)
+	}
+	if err := repos.Tags.RenameTag(backend.ID, "api", baseTime.Add(time.Hour)); err != nil {
+		t.Fatalf("RenameTag: %v", err)
+	}
+	got, _ = repos.Tasks.GetTaskByID("a")
+	assertIDs(t, "tags of a after rename", got.Tags, []string{"api", "review"})
+	assertIDs(t, "tags_any after rename", listTaskIDs(t, repos, "u1", filter(models.TaskFilters{TagsAny: []string{"api"}})), []string{"a", "b"})
+	other, _ := repos.Tasks.GetTaskByID("d")
+	assertIDs(t, "other user's tags after rename", other.Tags, []string{"backend"})
+
+	// タスクの更新でタグを置き換える
+	got.Tags = []string{"review"}
+	if err := repos.Tasks.UpdateTask(got); err != nil {
+		t.Fatalf("UpdateTask: %v", err)
+	}
+	got, _ = repos.Tasks.GetTaskByID("a")
+	assertIDs(t, "tags of a after update", got.Tags, []string{"review"})
+
+	// タグを削除するとタスクから外れる
+	if err := repos.Tags.DeleteTag(review.ID); err != nil {
+		t.Fatalf("DeleteTag: %v", err)
+	}
+	got, _ = repos.Tasks.GetTaskByID("a")
+	assertIDs(t, "tags of a after delete", got.Tags, []string{})
+	if _, err := repos.Tags.GetTagByID(review.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetTagByID after delete: error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testReminders(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	deadline := baseTime.Add(48 * time.Hour) // 2025-01-03 00:00 UTC
+	createTask(t, repos, models.Task{ID: "a", UserID: "u1", Title: "a", Deadline: timePtr(deadline)})
+	createTask(t, repos, models.Task{ID: "done", UserID: "u1", Title: "done", Deadline: timePtr(deadline), Status: "completed"})
+	createTask(t, repos, models.Task{ID: "later", UserID: "u1", Title: "later", Deadline: timePtr(deadline.Add(30 * 24 * time.Hour))})
+
+	newReminder := func(id, taskID string, r models.Reminder) *models.Reminder {
+		r.ID, r.TaskID, r.CreatedAt = id, taskID, baseTime
+		if err := r.Validate(); err != nil {
+			t.Fatalf("Validate(%s): %v", id, err)
+		}
+		if err := repos.Reminders.CreateReminder(&r); err != nil {
+			t.Fatalf("CreateReminder(%s): %v", id, err)
+		}
+		return &r
+	}
+	hour := newReminder("r1", "a", models.Reminder{Kind: models.ReminderBeforeDeadline, OffsetMinutes: 60})
+	morning := newReminder("r2", "a", models.Reminder{Kind: models.ReminderDueDay, TimeOfDay: "09:00", Timezone: "Asia/Tokyo"})
+	newReminder("r3", "done", models.Reminder{Kind: models.ReminderBeforeDeadline, OffsetMinutes: 60})
+	newReminder("r4", "later", models.Reminder{Kind: models.ReminderBeforeDeadline, OffsetMinutes: 60})
+
+	// 期限日は通知先のタイムゾーンの日付で決まる（UTC 00:00 は東京の 09:00）
+	if fireAt, err := hour.FireAt(deadline); err != nil || !fireAt.Equal(deadline.Add(-time.Hour)) {
+		t.Errorf("FireAt(before_deadline) = %v, %v", fireAt, err)
+	}
+	if fireAt, err := morning.FireAt(deadline); err != nil || !fireAt.Equal(deadline) {
+		t.Errorf("FireAt(due_day) = %v, %v; want %v", fireAt, err, deadline)
+	}
+
+	reminders, err := repos.Reminders.GetRemindersByTaskID("a")
+	if err != nil || len(reminders) != 2 || reminders[1].TimeOfDay != "09:00" || reminders[1].Timezone != "Asia/Tokyo" {
+		t.Fatalf("GetRemindersByTaskID = %+v, %v", reminders, err)
+	}
+
+	// 未完了のタスクで、期限が範囲内のものだけが対象になる
+	due, err := repos.Reminders.GetDueReminders(baseTime, deadline.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetDueReminders: %v", err)
+	}
+	dueIDs := make([]string, len(due))
+	for i, d := range due {
+		dueIDs[i] = d.ID
+	}
+	assertIDs(t, "due reminders", dueIDs, []string{"r1", "r2"})
+	if due[0].TaskTitle != "a" || due[0].UserEmail != "u1@example.com" || !due[0].Deadline.Equal(deadline) {
+		t.Errorf("GetDueReminders[0] = %+v", due[0])
+	}
+
+	// 同じ回は一度しか配信する権利を取得できない
+	fireAt := deadline.Add(-time.Hour)
+	delivery := func(channel string, at time.Time) *models.ReminderDelivery {
+		return &models.ReminderDelivery{ReminderID: "r1", FireAt: fireAt, Channel: channel, UpdatedAt: at}
+	}
+	claim := func(d *models.ReminderDelivery, maxAttempts int) bool {
+		t.Helper()
+		claimed, err := repos.Reminders.ClaimReminderDelivery(d, d.UpdatedAt.Add(-5*time.Minute), maxAttempts)
+		if err != nil {
+			t.Fatalf("ClaimReminderDelivery: %v", err)
+		}
+		return claimed
+	}
+	finish := func(d *models.ReminderDelivery, status string) {
+		t.Helper()
+		d.Status = status
+		if status == models.DeliveryFailed {
+			d.LastError = strPtr("boom")
+		}
+		if err := repos.Reminders.FinishReminderDelivery(d); err != nil {
+			t.Fatalf("FinishReminderDelivery: %v", err)
+		}
+	}
+
+	sent := delivery("log", fireAt)
+	if !claim(sent, 3) {
+		t.Fatal("first claim = false, want true")
+	}
+	if claim(delivery("log", fireAt), 3) {
+		t.Error("claim while sending = true, want false")
+	}
+	finish(sent, models.DeliverySent)
+	if claim(delivery("log", fireAt.Add(time.Hour)), 3) {
+		t.Error("claim after sent = true, want false")
+	}
+
+	// 失敗した回は試行回数の上限まで再試行できる
+	failed := delivery("webhook", fireAt)
+	for attempt := 1; attempt <= 2; attempt++ {
+		if !claim(failed, 2) {
+			t.Fatalf("claim attempt %d = false, want true", attempt)
+		}
+		finish(failed, models.DeliveryFailed)
+	}
+	if claim(failed, 2) {
+		t.Error("claim after max attempts = true, want false")
+	}
+
+	// 送信中のまま止まった回は一定時間後に取得し直せる
+	stale := delivery("smtp", fireAt)
+	if !claim(stale, 3) {
+		t.Fatal("claim smtp = false, want true")
+	}
+	if claim(delivery("smtp", fireAt.Add(time.Minute)), 3) {
+		t.Error("claim of a recent sending delivery = true, want false")
+	}
+	if !claim(delivery("smtp", fireAt.Add(10*time.Minute)), 3) {
+		t.Error("claim of a stale sending delivery = false, want true")
+	}
+
+	// スキップは記録がない回にだけ記録される
+	skip := &models.ReminderDelivery{ReminderID: "r2", FireAt: deadline, Channel: "log", UpdatedAt: deadline}
+	if recorded, err := repos.Reminders.SkipReminderDelivery(skip); err != nil || !recorded {
+		t.Errorf("SkipReminderDelivery = %v, %v; want true, nil", recorded, err)
+	}
+	if recorded, err := repos.Reminders.SkipReminderDelivery(skip); err != nil || recorded {
+		t.Errorf("SkipReminderDelivery again = %v, %v; want false, nil", recorded, err)
+	}
+	if claimed, err := repos.Reminders.ClaimReminderDelivery(skip, deadline.Add(time.Hour), 3); err != nil || claimed {
+		t.Errorf("claim after skip = %v, %v; want false, nil", claimed, err)
+	}
+
+	deliveries, err := repos.Reminders.GetReminderDeliveries("r1")
+	if err != nil || len(deliveries) != 3 {
+		t.Fatalf("GetReminderDeliveries = %+v, %v", deliveries, err)
+	}
+	byChannel := map[string]models.ReminderDelivery{}
+	for _, d := range deliveries {
+		byChannel[d.Channel] = d
+	}
+	if d := byChannel["log"]; d.Status != models.DeliverySent || d.Attempts != 1 || !d.FireAt.Equal(fireAt) {
+		t.Errorf("log delivery = %+v", d)
+	}
+	if d := byChannel["webhook"]; d.Status != models.DeliveryFailed || d.Attempts != 2 || d.LastError == nil || *d.LastError != "boom" {
+		t.Errorf("webhook delivery = %+v", d)
+	}
+	if d := byChannel["smtp"]; d.Status != models.DeliverySending || d.Attempts != 2 {
+		t.Errorf("smtp delivery = %+v", d)
+	}
+
+	// 取得できない記録のみを返す（失敗した回は上限まで、送信中の回は止まったとみなすまで取得できる）
+	settled := func(staleBefore time.Time, maxAttempts int) string {
+		t.Helper()
+		deliveries, err := repos.Reminders.GetSettledReminderDeliveries([]string{"r1", "r2", "r3"}, staleBefore, maxAttempts)
+		if err != nil {
+			t.Fatalf("GetSettledReminderDeliveries: %v", err)
+		}
+		keys := make([]string, len(deliveries))
+		for i, d := range deliveries {
+			keys[i] = d.ReminderID + "/" + d.Channel + "/" + d.Status
+		}
+		return strings.Join(keys, ",")
+	}
+	if got, want := settled(fireAt.Add(10*time.Minute), 3), "r1/log/sent,r1/smtp/sending,r2/log/skipped"; got != want {
+		t.Errorf("GetSettledReminderDeliveries = %s, want %s", got, want)
+	}
+	if got, want := settled(fireAt.Add(11*time.Minute), 2), "r1/log/sent,r1/smtp/sending,r1/webhook/failed,r2/log/skipped"; got != want {
+		t.Errorf("GetSettledReminderDeliveries at max attempts = %s, want %s", got, want)
+	}
+	if got, want := settled(fireAt.Add(11*time.Minute), 3), "r1/log/sent,r2/log/skipped"; got != want {
+		t.Errorf("GetSettledReminderDeliveries after the sending delivery is stale = %s, want %s", got, want)
+	}
+	if deliveries, err := repos.Reminders.GetSettledReminderDeliveries(nil, fireAt, 3); err != nil || len(deliveries) != 0 {
+		t.Errorf("GetSettledReminderDeliveries(nil) = %+v, %v; want none", deliveries, err)
+	}
+
+	// リマインダーの削除で配信記録も、タスクの完全な削除でリマインダーも削除される
+	if err := repos.Reminders.DeleteReminder("r2"); err != nil {
+		t.Fatalf("DeleteReminder: %v", err)
+	}
+	if deliveries, err := repos.Reminders.GetReminderDeliveries("r2"); err != nil || len(deliveries) != 0 {
+		t.Errorf("deliveries after DeleteReminder = %+v, %v", deliveries, err)
+	}
+	if err := repos.Tasks.DeleteTask("a", taskVersion(t, repos, "a"), baseTime); err != nil {
+		t.Fatalf("DeleteTask: %v", err)
+	}
+	// ゴミ箱のタスクのリマインダーは通知しない
+	if due, err := repos.Reminders.GetDueReminders(baseTime, deadline.Add(time.Hour)); err != nil || len(due) != 0 {
+		t.Errorf("GetDueReminders after task delete = %+v, %v", due, err)
+	}
+	if err := repos.Tasks.PurgeTask("a"); err != nil {
+		t.Fatalf("PurgeTask: %v", err)
+	}
+	if _, err := repos.Reminders.GetReminderByID("r1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetReminderByID after task delete: error = %v, want sql.ErrNoRows", err)
+	}
+	if deliveries, err := repos.Reminders.GetReminderDeliveries("r1"); err != nil || len(deliveries) != 0 {
+		t.Errorf("deliveries after task delete = %+v, %v", deliveries, err)
+	}
+}
+
+func testWebhooks(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+	createUser(t, repos, "u2")
+
+	newWebhook := func(id, userID string, active bool, createdAt time.Time) *models.Webhook {
+		webhook := &models.Webhook{
+			ID:        id,
+			UserID:    userID,
+			URL:       "https://example.com/" + id,
+			Events:    []string{models.EventTaskCompleted, models.EventTaskCreated},
+			Secret:    "secret-" + id,
+			Active:    active,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+		if err := repos.Webhooks.CreateWebhook(webhook); err != nil {
+			t.Fatalf("CreateWebhook(%s): %v", id, err)
+		}
+		return webhook
+	}
+	newWebhook("w1", "u1", true, baseTime)
+	newWebhook("w2", "u1", false, baseTime.Add(time.Minute))
+	newWebhook("other", "u2", true, baseTime)
+
+	webhooks, err := repos.Webhooks.GetWebhooksByUserID("u1")
+	if err != nil || len(webhooks) != 2 || webhooks[0].ID != "w1" || webhooks[1].ID != "w2" {
+		t.Fatalf("GetWebhooksByUserID = %+v, %v", webhooks, err)
+	}
+	got, err := repos.Webhooks.GetWebhookByID("w1")
+	if err != nil {
+		t.Fatalf("GetWebhookByID: %v", err)
+	}
+	assertIDs(t, "events", got.Events, []string{models.EventTaskCompleted, models.EventTaskCreated})
+	if got.Secret != "secret-w1" || !got.Active || got.URL != "https://example.com/w1" {
+		t.Errorf("GetWebhookByID = %+v", got)
+	}
+
+	got.URL = "https://example.com/changed"
+	got.Events = []string{models.EventTaskDeleted}
+	got.Active = false
+	got.UpdatedAt = baseTime.Add(time.Hour)
+	if err := repos.Webhooks.UpdateWebhook(got); err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+	got, _ = repos.Webhooks.GetWebhookByID("w1")
+	assertIDs(t, "events after update", got.Events, []string{models.EventTaskDeleted})
+	if got.URL != "https://example.com/changed" || got.Active || got.Secret != "secret-w1" {
+		t.Errorf("webhook after update = %+v", got)
+	}
+	got.Active = true
+	if err := repos.Webhooks.UpdateWebhook(got); err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+
+	newDelivery := func(id, webhookID string, createdAt time.Time) {
+		next := createdAt
+		delivery := &models.WebhookDelivery{
+			ID:            id,
+			WebhookID:     webhookID,
+			EventID:       "event-" + id,
+			Event:         models.EventTaskCreated,
+			Payload:       []byte(`{"id":"event-` + id + `"}`),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &next,
+			CreatedAt:     createdAt,
+			UpdatedAt:     createdAt,
+		}
+		if err := repos.Webhooks.CreateWebhookDelivery(delivery); err != nil {
+			t.Fatalf("CreateWebhookDelivery(%s): %v", id, err)
+		}
+	}
+	newDelivery("d1", "w1", baseTime)
+	newDelivery("d2", "w1", baseTime.Add(time.Minute))
+	newDelivery("d3", "w1", baseTime.Add(time.Hour))
+	newDelivery("inactive", "w2", baseTime)
+
+	deliveryIDs := func(deliveries []models.WebhookDelivery, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("deliveries: %v", err)
+		}
+		ids := make([]string, len(deliveries))
+		for i, d := range deliveries {
+			ids[i] = d.ID
+		}
+		return ids
+	}
+
+	// 送信時刻を迎えた、有効な Webhook の配信だけを古い順に取得する
+	now := baseTime.Add(2 * time.Minute)
+	assertIDs(t, "due deliveries", deliveryIDs(repos.Webhooks.GetDueWebhookDeliveries(now, 10)), []string{"d1", "d2"})
+	assertIDs(t, "due deliveries with limit", deliveryIDs(repos.Webhooks.GetDueWebhookDeliveries(now, 1)), []string{"d1"})
+
+	// 取得できるのは1回だけで、期限（lease）を過ぎると再び取得できる
+	lease := now.Add(time.Minute)
+	if claimed, err := repos.Webhooks.ClaimWebhookDelivery("d1", now, lease); err != nil || !claimed {
+		t.Fatalf("ClaimWebhookDelivery = %v, %v; want true, nil", claimed, err)
+	}
+	if claimed, err := repos.Webhooks.ClaimWebhookDelivery("d1", now, lease); err != nil || claimed {
+		t.Errorf("second ClaimWebhookDelivery = %v, %v; want false, nil", claimed, err)
+	}
+	assertIDs(t, "due deliveries while claimed", deliveryIDs(repos.Webhooks.GetDueWebhookDeliveries(now, 10)), []string{"d2"})
+	if claimed, err := repos.Webhooks.ClaimWebhookDelivery("d1", lease, lease.Add(time.Minute)); err != nil || !claimed {
+		t.Errorf("ClaimWebhookDelivery after lease = %v, %v; want true, nil", claimed, err)
+	}
+
+	// 失敗を記録して再送時刻まで待つ
+	retryAt := lease.Add(time.Hour)
+	failed := &models.WebhookDelivery{
+		ID:             "d1",
+		Status:         models.WebhookDeliveryPending,
+		NextAttemptAt:  &retryAt,
+		ResponseStatus: intPtr(500),
+		LastError:      strPtr("unexpected status 500"),
+		UpdatedAt:      lease,
+	}
+	if err := repos.Webhooks.FinishWebhookDelivery(failed); err != nil {
+		t.Fatalf("FinishWebhookDelivery: %v", err)
+	}
+	d1, err := repos.Webhooks.GetWebhookDeliveryByID("d1")
+	if err != nil {
+		t.Fatalf("GetWebhookDeliveryByID: %v", err)
+	}
+	if d1.Attempts != 2 || d1.ResponseStatus == nil || *d1.ResponseStatus != 500 || d1.LastError == nil ||
+		d1.NextAttemptAt == nil || !d1.NextAttemptAt.Equal(retryAt) || string(d1.Payload) != `{"id":"event-d1"}` {
+		t.Errorf("failed delivery = %+v", d1)
+	}
+	assertIDs(t, "due deliveries before retry", deliveryIDs(repos.Webhooks.GetDueWebhookDeliveries(lease, 10)), []string{"d2"})
+	assertIDs(t, "due deliveries at retry", deliveryIDs(repos.Webhooks.GetDueWebhookDeliveries(retryAt, 10)), []string{"d2", "d3", "d1"})
+
+	// 成功を記録すると送信待ちから外れる
+	if claimed, err := repos.Webhooks.ClaimWebhookDelivery("d2", now, lease); err != nil || !claimed {
+		t.Fatalf("ClaimWebhookDelivery(d2) = %v, %v", claimed, err)
+	}
+	deliveredAt := now.Add(time.Second)
+	succeeded := &models.WebhookDelivery{
+		ID:             "d2",
+		Status:         models.WebhookDeliverySucceeded,
+		ResponseStatus: intPtr(204),
+		DeliveredAt:    &deliveredAt,
+		UpdatedAt:      deliveredAt,
+	}
+	if err := repos.Webhooks.FinishWebhookDelivery(succeeded); err != nil {
+		t.Fatalf("FinishWebhookDelivery: %v", err)
+	}
+	if claimed, err := repos.Webhooks.ClaimWebhookDelivery("d2", retryAt, retryAt); err != nil || claimed {
+		t.Errorf("ClaimWebhookDelivery after success = %v, %v; want false, nil", claimed, err)
+	}
+	d2, _ := repos.Webhooks.GetWebhookDeliveryByID("d2")
+	if d2.Status != models.WebhookDeliverySucceeded || d2.NextAttemptAt != nil || d2.DeliveredAt == nil || d2.Attempts != 1 {
+		t.Errorf("succeeded delivery = %+v", d2)
+	}
+
+	// 配信ログは新しい順で、状態と件数で絞り込める
+	assertIDs(t, "delivery log", deliveryIDs(repos.Webhooks.GetWebhookDeliveries("w1", "", 10)), []string{"d3", "d2", "d1"})
+	assertIDs(t, "delivery log by status", deliveryIDs(repos.Webhooks.GetWebhookDeliveries("w1", models.WebhookDeliverySucceeded, 10)), []string{"d2"})
+	assertIDs(t, "delivery log with limit", deliveryIDs(repos.Webhooks.GetWebhookDeliveries("w1", "", 2)), []string{"d3", "d2"})
+
+	// Webhook を削除すると配信ログも削除される
+	if err := repos.Webhooks.DeleteWebhook("w1"); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := repos.Webhooks.GetWebhookByID("w1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetWebhookByID after delete: error = %v, want sql.ErrNoRows", err)
+	}
+	if _, err := repos.Webhooks.GetWebhookDeliveryByID("d1"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetWebhookDeliveryByID after webhook delete: error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func testRefreshTokens(t *testing.T, repos *repository.Repositories) {
+	createUser(t, repos, "u1")
+
+	newToken := func(id, familyID string, expiresAt time.Time) *models.RefreshToken {
+		token := &models.RefreshToken{
+			ID:        id,
+			UserID:    "u1",
+			FamilyID:  familyID,
+			TokenHash: "hash-" + id,
+			ExpiresAt: expiresAt,
+			CreatedAt: baseTime,
+		}
+		if err := repos.RefreshTokens.CreateRefreshToken(token); err != nil {
+			t.Fatalf("CreateRefreshToken(%s): %v", id, err)
+		}
+		return token
+	}
+
+	now := time.Now().Truncate(time.Second)
+	newToken("r1", "f1", now.Add(time.Hour))
+	newToken("r2", "f1", now.Add(time.Hour))
+	newToken("r3", "f2", now.Add(time.Hour))
+	newToken("expired", "f3", now.Add(-time.Hour))
+
+	got, err := repos.RefreshTokens.GetRefreshTokenByID("r1")
+	if err != nil {
+		t.Fatalf("GetRefreshTokenByID: %v", err)
+	}
+	if got.FamilyID != "f1" || got.TokenHash != "hash-r1" || got.UsedAt != nil || got.RevokedAt != nil {
+		t.Errorf("GetRefreshTokenByID = %+v", got)
+	}
+	if _, err := repos.RefreshTokens.GetRefreshTokenByID("missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetRefreshTokenByID(missing) error = %v, want sql.ErrNoRows", err)
+	}
+
+	// 使用済みにできるのは1回だけ
+	marked, err := repos.RefreshTokens.MarkRefreshTokenUsed("r1", now)
+	if err != nil || !marked {
+		t.Fatalf("MarkRefreshTokenUsed first call = %v, %v; want true, nil", marked, err)
+	}
+	marked, err = repos.RefreshTokens.MarkRefreshTokenUsed("r1", now)
+	if err != nil || marked {
+		t.Errorf("MarkRefreshTokenUsed second call = %v, %v; want false, nil", marked, err)
+	}
+	got, err = repos.RefreshTokens.GetRefreshTokenByID("r1")
+	if err != nil {
+		t.Fatalf("GetRefreshTokenByID after use: %v", err)
+	}
+	if got.UsedAt == nil || got.RevokedAt == nil {
+		t.Errorf("used token = %+v, want used_at and revoked_at set", got)
+	}
+
+	// ファミリーの失効は有効なトークンだけを数え、他のファミリーには影響しない
+	revoked, err := repos.RefreshTokens.RevokeRefreshTokenFamily("f1", now)
+	if err != nil || revoked != 1 {
+		t.Errorf("RevokeRefreshTokenFamily = %d, %v; want 1, nil", revoked, err)
+	}
+	if marked, _ := repos.RefreshTokens.MarkRefreshTokenUsed("r2", now); marked {
+		t.Error("MarkRefreshTokenUsed succeeded for a revoked token")
+	}
+	if got, err := repos.RefreshTokens.GetRefreshTokenByID("r3"); err != nil || got.RevokedAt != nil {
+		t.Errorf("token in another family = %+v, %v; want not revoked", got, err)
+	}
+
+	deleted, err := repos.RefreshTokens.DeleteExpiredRefreshTokens(now)
+	if err != nil || deleted != 1 {
+		t.Errorf("DeleteExpiredRefreshTokens = %d, %v; want 1, nil", deleted, err)
+	}
+	if _, err := repos.RefreshTokens.GetRefreshTokenByID("expired"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expired token still exists: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build sqlite_fts5
+
+package repository_test
+
+import (
+	"testing"
+
+	"todo-app-backend/internal/repository/repotest"
+)
+
+// TestSQLiteRepositories 全文検索に FTS5 が必要なため、-tags sqlite_fts5 を付けた場合のみ実行する
+func TestSQLiteRepositories(t *testing.T) {
+	repotest.Run(t, repotest.NewSQLite)
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/utils"
+)
+
+// ErrTagExists 同じ名前のタグが既に存在する
+var ErrTagExists = errors.New("tag already exists")
+
+// タグ一覧の SELECT 句（ゴミ箱にないタスクのうち付与されている数を含む。scanTag と順序を合わせる）
+const tagSelect = `SELECT g.id, g.user_id, g.name, g.created_at, g.updated_at, COUNT(t.id)
+				   FROM tags g LEFT JOIN task_tags tt ON tt.tag_id = g.id
+				   LEFT JOIN tasks t ON t.id = tt.task_id AND t.deleted_at IS NULL`
+
+const tagGroupBy = ` GROUP BY g.id, g.user_id, g.name, g.created_at, g.updated_at`
+
+type sqlTagRepository struct {
+	db *DB
+}
+
+func NewTagRepository(db *DB) TagRepository {
+	return &sqlTagRepository{
+		db: db,
+	}
+}
+
+func (r *sqlTagRepository) CreateTag(tag *models.Tag) error {
+	query := `INSERT INTO tags (id, user_id, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+			  ON CONFLICT (user_id, name) DO NOTHING`
+	result, err := r.db.Exec(query, tag.ID, tag.UserID, tag.Name, tag.CreatedAt, tag.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTagExists
+	}
+	return nil
+}
+
+func (r *sqlTagRepository) GetTagsByUserID(userID string) ([]models.Tag, error) {
+	rows, err := r.db.Query(tagSelect+` WHERE g.user_id = ?`+tagGroupBy+` ORDER BY g.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, rows.Err()
+}
+
+func (r *sqlTagRepository) GetTagByID(tagID string) (*models.Tag, error) {
+	return scanTag(r.db.QueryRow(tagSelect+` WHERE g.id = ?`+tagGroupBy, tagID))
+}
+
+// RenameTag タグ名を変更する
+// タスクはタグをIDで参照しているため、1行の更新ですべてのタスクに反映される
+func (r *sqlTagRepository) RenameTag(tagID, name string, updatedAt time.Time) error {
+	return r.db.withTx(func(tx *Tx) error {
+		query := `SELECT COUNT(*) FROM tags
+				  WHERE user_id = (SELECT user_id FROM tags WHERE id = ?) AND name = ? AND id <> ?`
+		var count int
+		if err := tx.QueryRow(query, tagID, name, tagID).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrTagExists
+		}
+
+		if _, err := tx.Exec(`UPDATE tags SET name = ?, updated_at = ? WHERE id = ?`, name, updatedAt, tagID); err != nil {
+			return err
+		}
+		return bumpTaggedTaskVersions(tx, tagID)
+	})
+}
+
+// DeleteTag タグを削除する（タスクとの関連付けは外部キーの ON DELETE CASCADE で削除される）
+func (r *sqlTagRepository) DeleteTag(tagID string) error {
+	return r.db.withTx(func(tx *Tx) error {
+		if err := bumpTaggedTaskVersions(tx, tagID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM tags WHERE id = ?`, tagID)
+		return err
+	})
+}
+
+// bumpTaggedTaskVersions タグ名の変更・削除でタグの一覧が変わるタスクの版数を増やす
+func bumpTaggedTaskVersions(q querier, tagID string) error {
+	_, err := q.Exec(`UPDATE tasks SET version = version + 1 WHERE id IN (SELECT task_id FROM task_tags WHERE tag_id = ?)`, tagID)
+	return err
+}
+
+func scanTag(row rowScanner) (*models.Tag, error) {
+	tag := &models.Tag{}
+	err := row.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.CreatedAt, &tag.UpdatedAt, &tag.TaskCount)
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// replaceTaskTags タスクのタグを task.Tags に置き換える
+// 未登録のタグ名はタスクの所有者のタグとして作成する
+func replaceTaskTags(q querier, task *models.Task) error {
+	if _, err := q.Exec(`DELETE FROM task_tags WHERE task_id = ?`, task.ID); err != nil {
+		return err
+	}
+
+	for _, name := range task.Tags {
+		insertTag := `INSERT INTO tags (id, user_id, name, created_at, updated_at) VALUES (?, ?, ?, ?, ?)
+					  ON CONFLICT (user_id, name) DO NOTHING`
+		if _, err := q.Exec(insertTag, utils.GenerateID(), task.UserID, name, task.UpdatedAt, task.UpdatedAt); err != nil {
+			return err
+		}
+
+		link := `INSERT INTO task_tags (task_id, tag_id)
+				 SELECT ?, id FROM tags WHERE user_id = ? AND name = ?
+				 ON CONFLICT (task_id, tag_id) DO NOTHING`
+		if _, err := q.Exec(link, task.ID, task.UserID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTaskTags タスクの一覧にタグ名（名前順）を設定する
+func loadTaskTags(q querier, tasks []models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	index := make(map[string]int, len(tasks))
+	args := make([]interface{}, len(tasks))
+	for i := range tasks {
+		tasks[i].Tags = []string{}
+		index[tasks[i].ID] = i
+		args[i] = tasks[i].ID
+	}
+
+	query := `SELECT tt.task_id, g.name FROM task_tags tt JOIN tags g ON g.id = tt.tag_id
+			  WHERE tt.task_id IN (` + placeholders(len(tasks)) + `) ORDER BY g.name`
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID, name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		if i, ok := index[taskID]; ok {
+			tasks[i].Tags = append(tasks[i].Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+// placeholders IN 句用に n 個の ? を並べる
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
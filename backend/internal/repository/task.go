@@ -2,75 +2,252 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
+	"time"
+
 	"todo-app-backend/internal/models"
 )
 
-type TaskRepository struct {
-	db *sql.DB
+// SELECT対象のカラム（scanTask と順序を合わせる）
+const taskColumns = `id, user_id, project_id, parent_id, title, description, deadline, priority, status, series_id, occurrence_at, version, deleted_at, created_at, updated_at`
+
+// ErrTaskVersionConflict 更新・削除しようとした版数のタスクが、既に他の変更で更新されている
+var ErrTaskVersionConflict = errors.New("task has been modified")
+
+// sqlTaskRepository SQLite / PostgreSQL 共通の実装（方言の違いは DB が吸収する）
+type sqlTaskRepository struct {
+	db      executor
+	dialect *dialect
 }
 
-func NewTaskRepository() *TaskRepository {
-	return &TaskRepository{
-		db: GetDB(),
+func NewTaskRepository(db *DB) TaskRepository {
+	return &sqlTaskRepository{
+		db:      db,
+		dialect: db.dialect,
 	}
 }
 
-func (r *TaskRepository) CreateTask(task *models.Task) error {
-	query := `INSERT INTO tasks (id, user_id, title, description, deadline, priority, status, created_at, updated_at) 
-			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := r.db.Exec(query, task.ID, task.UserID, task.Title, task.Description, task.Deadline, 
-		task.Priority, task.Status, task.CreatedAt, task.UpdatedAt)
-	return err
+// WithTx fn を1つのトランザクション内で実行する（入れ子にした場合はセーブポイントを使う）
+func (r *sqlTaskRepository) WithTx(fn func(repo TaskRepository, history TaskHistoryRepository) error) error {
+	return r.db.withTx(func(tx *Tx) error {
+		return fn(&sqlTaskRepository{db: tx, dialect: r.dialect}, &sqlTaskHistoryRepository{db: tx, dialect: r.dialect})
+	})
 }
 
-func (r *TaskRepository) GetTasksByUserID(userID string) ([]models.Task, error) {
-	query := `SELECT id, user_id, title, description, deadline, priority, status, created_at, updated_at 
-			  FROM tasks WHERE user_id = ? ORDER BY created_at DESC`
-	rows, err := r.db.Query(query, userID)
+// CreateTask タスクを作成する（親タスクは同じユーザーのものでなければならない）
+// サブタスクは親と同じプロジェクト、プロジェクト未指定のタスクは Inbox に入る
+// 未登録のタグ名はタグとして作成する。繰り返しのルールがあればタスクを最初の回とする系列も作成する
+func (r *sqlTaskRepository) CreateTask(task *models.Task) error {
+	return r.db.withTx(func(tx *Tx) error {
+		return insertTask(tx, task)
+	})
+}
+
+func insertTask(q querier, task *models.Task) error {
+	if err := checkTaskParent(q, task); err != nil {
+		return err
+	}
+	if err := resolveTaskProject(q, task); err != nil {
+		return err
+	}
+	if err := createTaskSeries(q, task); err != nil {
+		return err
+	}
+
+	task.Version = 1
+	query := `INSERT INTO tasks (` + taskColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := q.Exec(query, task.ID, task.UserID, task.ProjectID, task.ParentID, task.Title, task.Description, task.Deadline,
+		task.Priority, task.Status, task.SeriesID, task.OccurrenceAt, task.Version, task.DeletedAt, task.CreatedAt, task.UpdatedAt)
 	if err != nil {
+		return err
+	}
+	return replaceTaskTags(q, task)
+}
+
+// GetTasksByUserID ユーザーのタスクをフィルター・ソート条件に従ってページ単位で取得する
+func (r *sqlTaskRepository) GetTasksByUserID(userID string, filters *models.TaskFilters, pagination *models.TaskPagination) (*models.TaskPage, error) {
+	if filters == nil {
+		filters = &models.TaskFilters{}
+	}
+	if err := filters.Validate(); err != nil {
 		return nil, err
 	}
-	defer rows.Close()
 
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(&task.ID, &task.UserID, &task.Title, &task.Description, &task.Deadline,
-			&task.Priority, &task.Status, &task.CreatedAt, &task.UpdatedAt)
+	sortBy, sortOrder := taskSortOf(filters)
+	var cursor *taskCursor
+	if pagination != nil && pagination.Cursor != nil && *pagination.Cursor != "" {
+		var err error
+		cursor, err = decodeTaskCursor(*pagination.Cursor, sortBy, sortOrder)
 		if err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
 	}
 
-	return tasks, nil
-}
+	limit := pagination.PageSize()
+	query, args := buildTaskListQuery(r.dialect, userID, filters, cursor, limit, time.Now())
+	tasks, err := r.queryTasks(query, args...)
+	if err != nil {
+		return nil, err
+	}
 
-func (r *TaskRepository) GetTaskByID(taskID string) (*models.Task, error) {
-	query := `SELECT id, user_id, title, description, deadline, priority, status, created_at, updated_at 
-			  FROM tasks WHERE id = ?`
-	row := r.db.QueryRow(query, taskID)
+	page := &models.TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.HasMore = true
+		page.NextCursor = newTaskCursor(&page.Tasks[limit-1], sortBy, sortOrder).encode()
+	}
+	return page, nil
+}
 
-	task := &models.Task{}
-	err := row.Scan(&task.ID, &task.UserID, &task.Title, &task.Description, &task.Deadline,
-		&task.Priority, &task.Status, &task.CreatedAt, &task.UpdatedAt)
+func (r *sqlTaskRepository) GetTaskByID(taskID string) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = ? AND deleted_at IS NULL`
+	task, err := scanTask(r.db.QueryRow(query, taskID))
 	if err != nil {
 		return nil, err
 	}
+	tasks := []models.Task{*task}
+	if err := loadTaskDetails(r.db, tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
 
-	return task, nil
+// UpdateTask タスクを更新する（タグは task.Tags に置き換える）
+// task.Version の版数のタスクがまだ変更されていない場合のみ更新し、版数を1つ増やす（変更されていれば ErrTaskVersionConflict）
+// 親タスクの変更で循環が生じる場合は ErrTaskCycle を返す
+// 別のプロジェクトのタスクの下に移した場合は、子孫ごと親のプロジェクトに移る
+func (r *sqlTaskRepository) UpdateTask(task *models.Task) error {
+	return r.db.withTx(func(tx *Tx) error {
+		if err := checkTaskParent(tx, task); err != nil {
+			return err
+		}
+		projectID := task.ProjectID
+		if task.ParentID != nil {
+			if err := tx.QueryRow(`SELECT project_id FROM tasks WHERE id = ? AND deleted_at IS NULL`, *task.ParentID).Scan(&projectID); err != nil {
+				return err
+			}
+		}
+
+		if err := createTaskSeries(tx, task); err != nil {
+			return err
+		}
+
+		query := `UPDATE tasks SET project_id = ?, parent_id = ?, title = ?, description = ?, deadline = ?, priority = ?, status = ?,
+				  series_id = ?, occurrence_at = ?, updated_at = ?, version = version + 1
+				  WHERE id = ? AND version = ? AND deleted_at IS NULL`
+		result, err := tx.Exec(query, projectID, task.ParentID, task.Title, task.Description, task.Deadline, task.Priority,
+			task.Status, task.SeriesID, task.OccurrenceAt, task.UpdatedAt, task.ID, task.Version)
+		if err != nil {
+			return err
+		}
+		if err := checkTaskVersion(tx, result, task.ID); err != nil {
+			return err
+		}
+		task.Version++
+
+		// 子孫も親のプロジェクトに移す
+		if projectID != task.ProjectID {
+			task.ProjectID = projectID
+			if err := moveTaskSubtree(tx, task.ID, projectID); err != nil {
+				return err
+			}
+		}
+		return replaceTaskTags(tx, task)
+	})
 }
 
-func (r *TaskRepository) UpdateTask(task *models.Task) error {
-	query := `UPDATE tasks SET title = ?, description = ?, deadline = ?, priority = ?, status = ?, updated_at = ? 
-			  WHERE id = ?`
-	_, err := r.db.Exec(query, task.Title, task.Description, task.Deadline, task.Priority, 
-		task.Status, task.UpdatedAt, task.ID)
-	return err
+// MoveTask タスクを子孫ごと別のプロジェクトに移動する
+// 親タスクが移動先にない場合は親から切り離してルートのタスクにする
+// version の版数から変更されていれば ErrTaskVersionConflict を返す
+func (r *sqlTaskRepository) MoveTask(taskID string, version int64, projectID string, updatedAt time.Time) error {
+	return r.db.withTx(func(tx *Tx) error {
+		var userID string
+		var parentID *string
+		if err := tx.QueryRow(`SELECT user_id, parent_id FROM tasks WHERE id = ? AND deleted_at IS NULL`, taskID).Scan(&userID, &parentID); err != nil {
+			return err
+		}
+		if err := checkTaskProject(tx, userID, projectID); err != nil {
+			return err
+		}
+
+		query := `UPDATE tasks SET project_id = ?, parent_id = NULL, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?`
+		if parentID != nil {
+			var parentProjectID string
+			if err := tx.QueryRow(`SELECT project_id FROM tasks WHERE id = ?`, *parentID).Scan(&parentProjectID); err != nil {
+				return err
+			}
+			if parentProjectID == projectID {
+				query = `UPDATE tasks SET project_id = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?`
+			}
+		}
+		result, err := tx.Exec(query, projectID, updatedAt, taskID, version)
+		if err != nil {
+			return err
+		}
+		if err := checkTaskVersion(tx, result, taskID); err != nil {
+			return err
+		}
+		// タスク自身は移動済みのため、版数を増やすのは移動した子孫のみ
+		return moveTaskSubtree(tx, taskID, projectID)
+	})
 }
 
-func (r *TaskRepository) DeleteTask(taskID string) error {
-	query := `DELETE FROM tasks WHERE id = ?`
-	_, err := r.db.Exec(query, taskID)
+// DeleteTask version の版数のタスクがまだ変更されていない場合のみ、子孫とともにゴミ箱に移す
+func (r *sqlTaskRepository) DeleteTask(taskID string, version int64, deletedAt time.Time) error {
+	return r.db.withTx(func(tx *Tx) error {
+		result, err := tx.Exec(`UPDATE tasks SET deleted_at = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL`,
+			deletedAt, taskID, version)
+		if err != nil {
+			return err
+		}
+		if err := checkTaskVersion(tx, result, taskID); err != nil {
+			return err
+		}
+
+		return trashDescendants(tx, taskID, deletedAt)
+	})
+}
+
+// trashDescendants 子孫も同じ日時でゴミ箱に移す（元に戻す際に一緒に削除したタスクを見分ける）
+func trashDescendants(q querier, taskID string, deletedAt time.Time) error {
+	query := `WITH RECURSIVE descendants (id) AS (
+				  SELECT id FROM tasks WHERE parent_id = ? AND deleted_at IS NULL
+				  UNION
+				  SELECT t.id FROM tasks t JOIN descendants d ON t.parent_id = d.id WHERE t.deleted_at IS NULL
+			  )
+			  UPDATE tasks SET deleted_at = ?, version = version + 1 WHERE id IN (SELECT id FROM descendants)`
+	_, err := q.Exec(query, taskID, deletedAt)
 	return err
 }
+
+// checkTaskVersion 版数を指定した更新・削除で対象の行がなかった場合に、
+// タスクがない（sql.ErrNoRows）のか、版数が変わっていた（ErrTaskVersionConflict）のかを返す
+func checkTaskVersion(q querier, result sql.Result, taskID string) error {
+	affected, err := result.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+	var count int
+	if err := q.QueryRow(`SELECT COUNT(*) FROM tasks WHERE id = ? AND deleted_at IS NULL`, taskID).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return ErrTaskVersionConflict
+}
+
+// rowScanner *sql.Row と *sql.Rows の共通インターフェース
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row rowScanner) (*models.Task, error) {
+	task := &models.Task{}
+	err := row.Scan(&task.ID, &task.UserID, &task.ProjectID, &task.ParentID, &task.Title, &task.Description, &task.Deadline,
+		&task.Priority, &task.Status, &task.SeriesID, &task.OccurrenceAt, &task.Version, &task.DeletedAt, &task.CreatedAt, &task.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"encoding/json"
+
+	"todo-app-backend/internal/models"
+)
+
+// SELECT対象のカラム（scanTaskHistory と順序を合わせる）
+const taskHistoryColumns = `id, task_id, actor_id, action, version, changes, snapshot, reverted_from, created_at`
+
+// sqlTaskHistoryRepository タスクの変更履歴の実装（TaskRepository.WithTx ではトランザクション内の querier を使う）
+type sqlTaskHistoryRepository struct {
+	db      querier
+	dialect *dialect
+}
+
+func NewTaskHistoryRepository(db *DB) TaskHistoryRepository {
+	return &sqlTaskHistoryRepository{
+		db:      db,
+		dialect: db.dialect,
+	}
+}
+
+func (r *sqlTaskHistoryRepository) CreateTaskHistory(entry *models.TaskHistoryEntry) error {
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO task_history (` + taskHistoryColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.Exec(query, entry.ID, entry.TaskID, entry.ActorID, entry.Action, entry.Version, string(changes),
+		string(entry.Snapshot), entry.RevertedFrom, entry.CreatedAt)
+	return err
+}
+
+// GetTaskHistory タスクの変更履歴を新しい順に取得する
+func (r *sqlTaskHistoryRepository) GetTaskHistory(taskID string) ([]models.TaskHistoryEntry, error) {
+	query := `SELECT ` + taskHistoryColumns + ` FROM task_history WHERE task_id = ?
+			  ORDER BY version DESC, ` + r.dialect.timeExpr("created_at") + ` DESC, id DESC`
+	rows, err := r.db.Query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.TaskHistoryEntry
+	for rows.Next() {
+		entry, err := scanTaskHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetTaskRevision タスクの版数 version の履歴を取得する
+func (r *sqlTaskHistoryRepository) GetTaskRevision(taskID string, version int64) (*models.TaskHistoryEntry, error) {
+	query := `SELECT ` + taskHistoryColumns + ` FROM task_history WHERE task_id = ? AND version = ?
+			  ORDER BY ` + r.dialect.timeExpr("created_at") + ` DESC, id DESC LIMIT 1`
+	return scanTaskHistory(r.db.QueryRow(query, taskID, version))
+}
+
+func scanTaskHistory(row rowScanner) (*models.TaskHistoryEntry, error) {
+	entry := &models.TaskHistoryEntry{}
+	var changes, snapshot string
+	err := row.Scan(&entry.ID, &entry.TaskID, &entry.ActorID, &entry.Action, &entry.Version, &changes, &snapshot,
+		&entry.RevertedFrom, &entry.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(changes), &entry.Changes); err != nil {
+		return nil, err
+	}
+	entry.Snapshot = []byte(snapshot)
+	return entry, nil
+}
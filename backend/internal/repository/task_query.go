@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// ErrInvalidCursor 改ざん・破損したカーソル、またはソート条件と一致しないカーソル
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const priorityRankExpr = "CASE priority WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END"
+
+// priorityRank priorityRankExpr と同じ順位をGo側で計算する
+var priorityRank = map[string]int{"high": 3, "medium": 2, "low": 1}
+
+// taskSortKey ソートキーごとのSQL式
+type taskSortKey struct {
+	expr       string // ORDER BY とカーソル比較に使う式
+	param      string // カーソル値のプレースホルダ式
+	nullColumn string // NULLになりうるカラム（NULLは常に末尾に並べる）
+}
+
+// taskSortKeyOf ソートキーのSQL式を返す
+// ユーザー入力は必ずここを経由させ、SQLに直接埋め込まない
+func taskSortKeyOf(d *dialect, sortBy string) taskSortKey {
+	switch sortBy {
+	case "deadline":
+		return taskSortKey{expr: d.timeExpr("deadline"), param: d.timeExpr("?"), nullColumn: "deadline"}
+	case "priority":
+		return taskSortKey{expr: priorityRankExpr, param: "?"}
+	default:
+		return taskSortKey{expr: d.timeExpr("created_at"), param: d.timeExpr("?")}
+	}
+}
+
+// taskCursor 前ページ最後のタスクのソートキー値とID
+// クライアントには base64url でエンコードした不透明な文字列として渡す
+type taskCursor struct {
+	SortBy    string     `json:"s"`
+	SortOrder string     `json:"o"`
+	Time      *time.Time `json:"t,omitempty"`
+	Rank      *int       `json:"r,omitempty"`
+	ID        string     `json:"id"`
+}
+
+func newTaskCursor(task *models.Task, sortBy, sortOrder string) *taskCursor {
+	cursor := &taskCursor{SortBy: sortBy, SortOrder: sortOrder, ID: task.ID}
+	switch sortBy {
+	case "created_at":
+		createdAt := task.CreatedAt
+		cursor.Time = &createdAt
+	case "deadline":
+		cursor.Time = task.Deadline
+	case "priority":
+		rank := priorityRank[task.Priority]
+		cursor.Rank = &rank
+	}
+	return cursor
+}
+
+func (c *taskCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// value カーソル比較に使う値（期限なしの場合は nil）
+func (c *taskCursor) value() interface{} {
+	if c.Rank != nil {
+		return *c.Rank
+	}
+	if c.Time != nil {
+		return c.Time.UTC()
+	}
+	return nil
+}
+
+func decodeTaskCursor(encoded, sortBy, sortOrder string) (*taskCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor taskCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	// ソート条件が変わった場合、カーソルは使えない
+	if cursor.SortBy != sortBy || cursor.SortOrder != sortOrder {
+		return nil, ErrInvalidCursor
+	}
+	if sortBy == "priority" && cursor.Rank == nil {
+		return nil, ErrInvalidCursor
+	}
+	if sortBy == "created_at" && cursor.Time == nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// taskListQuery タスク一覧用のWHERE句と引数を組み立てる
+type taskListQuery struct {
+	conditions []string
+	args       []interface{}
+}
+
+func (q *taskListQuery) where(condition string, args ...interface{}) {
+	q.conditions = append(q.conditions, condition)
+	q.args = append(q.args, args...)
+}
+
+func stringArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
+
+// taskSortOf フィルターからソートキーと方向を取り出す（デフォルトは作成日時の降順）
+func taskSortOf(filters *models.TaskFilters) (string, string) {
+	sortBy := "created_at"
+	if filters.SortBy != nil {
+		sortBy = *filters.SortBy
+	}
+	sortOrder := "desc"
+	if filters.SortOrder != nil {
+		sortOrder = *filters.SortOrder
+	}
+	return sortBy, sortOrder
+}
+
+// buildTaskListQuery フィルターとカーソルからパラメータ化されたSELECT文を組み立てる
+// 次ページの有無を判定するため、limit+1件を取得する
+func buildTaskListQuery(d *dialect, userID string, filters *models.TaskFilters, cursor *taskCursor, limit int, now time.Time) (string, []interface{}) {
+	q := &taskListQuery{}
+	q.where("user_id = ?", userID)
+	q.where("deleted_at IS NULL")
+
+	if filters.ProjectID != nil && *filters.ProjectID != "" {
+		q.where("project_id = ?", *filters.ProjectID)
+	}
+	if filters.Status != nil && *filters.Status != "all" {
+		q.where("status = ?", *filters.Status)
+	}
+	if filters.Priority != nil && *filters.Priority != "all" {
+		q.where("priority = ?", *filters.Priority)
+	}
+	deadline, param := d.timeExpr("deadline"), d.timeExpr("?")
+	if filters.DeadlineFrom != nil {
+		q.where(deadline+" >= "+param, filters.DeadlineFrom.UTC())
+	}
+	if filters.DeadlineTo != nil {
+		q.where(deadline+" <= "+param, filters.DeadlineTo.UTC())
+	}
+	if filters.Overdue != nil && *filters.Overdue {
+		// 期限切れ = 期限を過ぎていて未完了
+		q.where(deadline+" < "+param+" AND status <> 'completed'", now.UTC())
+	}
+	if filters.NoDeadline != nil && *filters.NoDeadline {
+		q.where("deadline IS NULL")
+	}
+	if names, _ := models.NormalizeTagNames(filters.TagsAny); len(names) > 0 {
+		q.where(`id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id
+				 WHERE g.name IN (`+placeholders(len(names))+`))`, stringArgs(names)...)
+	}
+	if names, _ := models.NormalizeTagNames(filters.TagsAll); len(names) > 0 {
+		// 指定したタグがすべて付いている = 一致するタグの数が指定した数と等しい
+		q.where(`id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id
+				 WHERE g.name IN (`+placeholders(len(names))+`)
+				 GROUP BY tt.task_id HAVING COUNT(*) = ?)`, append(stringArgs(names), len(names))...)
+	}
+	if node, _ := filters.ParsedQuery(); node != nil {
+		condition, args := compileTaskQuery(d, node, userID, now)
+		q.where(condition, args...)
+	}
+
+	sortBy, sortOrder := taskSortOf(filters)
+	key := taskSortKeyOf(d, sortBy)
+	if cursor != nil {
+		addCursorCondition(q, key, sortOrder, cursor)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM tasks WHERE %s ORDER BY %s LIMIT ?`,
+		taskColumns, strings.Join(q.conditions, " AND "), taskOrderBy(key, sortOrder))
+	return query, append(q.args, limit+1)
+}
+
+// addCursorCondition カーソルより後ろの行だけを対象にするキーセット条件を追加する
+func addCursorCondition(q *taskListQuery, key taskSortKey, sortOrder string, cursor *taskCursor) {
+	op := "<"
+	if sortOrder == "asc" {
+		op = ">"
+	}
+
+	value := cursor.value()
+	after := fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND id %[2]s ?))", key.expr, op, key.param)
+
+	if key.nullColumn == "" {
+		q.where(after, value, value, cursor.ID)
+		return
+	}
+
+	// NULLは末尾に並ぶため、カーソルがNULL区間にあればNULL同士をIDで比較し、
+	// 非NULL区間にあれば後続の非NULL行とすべてのNULL行が対象になる
+	if value == nil {
+		q.where(fmt.Sprintf("%s IS NULL AND id %s ?", key.nullColumn, op), cursor.ID)
+		return
+	}
+	q.where(fmt.Sprintf("((%[1]s IS NOT NULL AND %[2]s) OR %[1]s IS NULL)", key.nullColumn, after),
+		value, value, cursor.ID)
+}
+
+// taskOrderBy ORDER BY句を返す
+// 期限でソートする場合、期限なしのタスクは昇順・降順に関わらず常に末尾に並べる
+// 同じ値のタスクはIDで並べ、結果の順序を安定させる
+func taskOrderBy(key taskSortKey, sortOrder string) string {
+	direction := strings.ToUpper(sortOrder)
+	if key.nullColumn != "" {
+		return fmt.Sprintf("%s IS NULL, %s %s, id %s", key.nullColumn, key.expr, direction, direction)
+	}
+	return fmt.Sprintf("%s %s, id %s", key.expr, direction, direction)
+}
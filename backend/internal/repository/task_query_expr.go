@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// compileTaskQuery フィルター式の構文木をパラメータ化されたWHERE句の条件にする
+// 値はすべてプレースホルダで渡し、SQLに埋め込むのは構文木の種類ごとに決まった式のみ
+// 否定（NOT）が SQL の NULL に影響されないよう、各条件は必ず真か偽になる式にする
+func compileTaskQuery(d *dialect, node models.TaskQueryNode, userID string, now time.Time) (string, []interface{}) {
+	switch node := node.(type) {
+	case *models.TaskQueryAnd:
+		return compileTaskQueryOperands(d, node.Operands, " AND ", userID, now)
+	case *models.TaskQueryOr:
+		return compileTaskQueryOperands(d, node.Operands, " OR ", userID, now)
+	case *models.TaskQueryNot:
+		condition, args := compileTaskQuery(d, node.Operand, userID, now)
+		return "NOT " + condition, args
+	}
+	condition, args := compileTaskQueryCondition(d, node.(*models.TaskQueryCondition), userID, now)
+	return "(" + condition + ")", args
+}
+
+func compileTaskQueryOperands(d *dialect, operands []models.TaskQueryNode, separator, userID string, now time.Time) (string, []interface{}) {
+	conditions := make([]string, len(operands))
+	var args []interface{}
+	for i, operand := range operands {
+		condition, operandArgs := compileTaskQuery(d, operand, userID, now)
+		conditions[i] = condition
+		args = append(args, operandArgs...)
+	}
+	return "(" + strings.Join(conditions, separator) + ")", args
+}
+
+func compileTaskQueryCondition(d *dialect, c *models.TaskQueryCondition, userID string, now time.Time) (string, []interface{}) {
+	switch c.Field {
+	case models.TaskQueryStatus:
+		return "status = ?", []interface{}{c.Value}
+	case models.TaskQueryPriority:
+		if c.Op == "=" {
+			return "priority = ?", []interface{}{c.Value}
+		}
+		// 比較演算子は ParseTaskQuery で検証済み
+		return priorityRankExpr + " " + c.Op + " ?", []interface{}{priorityRank[c.Value]}
+	case models.TaskQueryDue:
+		if c.Date.None {
+			return "deadline IS NULL", nil
+		}
+		condition, args := compileTaskQueryRange(d, "deadline", c, now)
+		return "deadline IS NOT NULL AND " + condition, args
+	case models.TaskQueryCreated:
+		return compileTaskQueryRange(d, "created_at", c, now)
+	case models.TaskQueryUpdated:
+		return compileTaskQueryRange(d, "updated_at", c, now)
+	case models.TaskQueryTag:
+		return `id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE g.name = ?)`,
+			[]interface{}{c.Value}
+	case models.TaskQueryProject:
+		return `project_id IN (SELECT id FROM projects WHERE user_id = ? AND (id = ? OR name = ?))`,
+			[]interface{}{userID, c.Value, c.Value}
+	case models.TaskQueryIs:
+		switch c.Value {
+		case "overdue":
+			return "deadline IS NOT NULL AND " + d.timeExpr("deadline") + " < " + d.timeExpr("?") + " AND status <> 'completed'",
+				[]interface{}{now.UTC()}
+		case "recurring":
+			return "series_id IS NOT NULL", nil
+		default:
+			return "parent_id IS NOT NULL", nil
+		}
+	case models.TaskQueryHas:
+		switch c.Value {
+		case "deadline":
+			return "deadline IS NOT NULL", nil
+		case "description":
+			return "COALESCE(description, '') <> ''", nil
+		default:
+			return "id IN (SELECT task_id FROM task_tags)", nil
+		}
+	default:
+		pattern := "%" + escapeLikePattern(c.Value) + "%"
+		// 大文字・小文字は ASCII の英字のみ区別しない（データベースごとの LOWER() の違いに左右されないようにする）
+		return d.lowerASCII("title") + ` LIKE ? ESCAPE '\' OR ` + d.lowerASCII("COALESCE(description, '')") + ` LIKE ? ESCAPE '\'`,
+			[]interface{}{pattern, pattern}
+	}
+}
+
+// compileTaskQueryRange 日時の条件を column の範囲の比較にする
+func compileTaskQueryRange(d *dialect, column string, c *models.TaskQueryCondition, now time.Time) (string, []interface{}) {
+	lower, upper := c.Date.Range(c.Op, now)
+	var conditions []string
+	var args []interface{}
+	if lower != nil {
+		op := ">"
+		if lower.Inclusive {
+			op = ">="
+		}
+		conditions = append(conditions, d.timeExpr(column)+" "+op+" "+d.timeExpr("?"))
+		args = append(args, lower.Time.UTC())
+	}
+	if upper != nil {
+		op := "<"
+		if upper.Inclusive {
+			op = "<="
+		}
+		conditions = append(conditions, d.timeExpr(column)+" "+op+" "+d.timeExpr("?"))
+		args = append(args, upper.Time.UTC())
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// escapeLikePattern LIKE のワイルドカード（% と _）とエスケープ文字を文字どおりに一致させる
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"html"
+	"strings"
+
+	"todo-app-backend/internal/models"
+)
+
+// 全文検索で一致した語を囲む目印（エスケープしてから <mark> に置き換える）
+const (
+	searchMarkStart = "\x02"
+	searchMarkEnd   = "\x03"
+)
+
+// postgresTaskSearchVector PostgreSQL の全文検索の対象（マイグレーションの式インデックスと完全に一致させる）
+const postgresTaskSearchVector = `(setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', COALESCE(description, '')), 'B'))`
+
+// fullTextSearch 方言ごとの全文検索のクエリ
+type fullTextSearch interface {
+	// searchQuery ユーザーのゴミ箱にないタスクを一致の度合いの順に検索する SELECT 文と引数を返す
+	// 列は taskColumns、スコア（大きいほど上位）、タイトルのハイライト、説明の抜粋の順
+	searchQuery(query *models.TaskSearchQuery, userID string, limit int) (string, []interface{})
+}
+
+// sqliteFullText tasks_fts（FTS5。tasks の rowid をキーにした外部コンテンツ）を検索する。タイトルの一致を説明の一致より重く扱う
+type sqliteFullText struct{}
+
+func (sqliteFullText) searchQuery(query *models.TaskSearchQuery, userID string, limit int) (string, []interface{}) {
+	rank := `bm25(tasks_fts, 5.0, 1.0)`
+	stmt := `SELECT ` + qualifyColumns("t", taskColumns) + `, -` + rank + `,
+				   highlight(tasks_fts, 0, ?, ?), COALESCE(snippet(tasks_fts, 1, ?, ?, '…', 16), '')
+			FROM tasks_fts JOIN tasks t ON t.rowid = tasks_fts.rowid
+			WHERE tasks_fts MATCH ? AND t.user_id = ? AND t.deleted_at IS NULL
+			ORDER BY ` + rank + `, t.id LIMIT ?`
+	return stmt, []interface{}{searchMarkStart, searchMarkEnd, searchMarkStart, searchMarkEnd, sqliteMatchQuery(query), userID, limit}
+}
+
+// sqliteMatchQuery FTS5 の MATCH 式にする
+// 単語はすべて "..." で囲むため、入力が FTS5 の演算子（AND / OR / NEAR / 列の指定など）として解釈されることはない
+func sqliteMatchQuery(query *models.TaskSearchQuery) string {
+	terms := make([]string, 0, len(query.Terms))
+	for _, term := range query.Terms {
+		phrase := `"` + strings.ReplaceAll(strings.Join(term.Words, " "), `"`, `""`) + `"`
+		if term.Prefix {
+			phrase += "*"
+		}
+		terms = append(terms, phrase)
+	}
+	return strings.Join(terms, " ")
+}
+
+// postgresFullText tsvector の式インデックスを検索する
+type postgresFullText struct{}
+
+func (postgresFullText) searchQuery(query *models.TaskSearchQuery, userID string, limit int) (string, []interface{}) {
+	stmt := `SELECT ` + taskColumns + `, ts_rank(` + postgresTaskSearchVector + `, query),
+				   ts_headline('simple', title, query, ?), ts_headline('simple', COALESCE(description, ''), query, ?)
+			FROM tasks, to_tsquery('simple', ?) query
+			WHERE ` + postgresTaskSearchVector + ` @@ query AND user_id = ? AND deleted_at IS NULL
+			ORDER BY ts_rank(` + postgresTaskSearchVector + `, query) DESC, id LIMIT ?`
+	selection := "StartSel=" + searchMarkStart + ", StopSel=" + searchMarkEnd
+	return stmt, []interface{}{selection + ", HighlightAll=true", selection + ", MaxWords=16, MinWords=8",
+		postgresTSQuery(query), userID, limit}
+}
+
+// postgresTSQuery to_tsquery の式にする（単語は英数字のみのため、引用符で囲めば演算子として解釈されない）
+func postgresTSQuery(query *models.TaskSearchQuery) string {
+	terms := make([]string, 0, len(query.Terms))
+	for _, term := range query.Terms {
+		words := make([]string, len(term.Words))
+		for i, word := range term.Words {
+			words[i] = "'" + strings.ReplaceAll(word, "'", "''") + "'"
+		}
+		if term.Prefix {
+			words[len(words)-1] += ":*"
+		}
+		terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// SearchTasks ユーザーのタスクのタイトル・説明を全文検索する（ゴミ箱のタスクは含めない）
+func (r *sqlTaskRepository) SearchTasks(userID string, query *models.TaskSearchQuery, limit int) ([]models.TaskSearchResult, error) {
+	stmt, args := r.dialect.fullText.searchQuery(query, userID, limit)
+	rows, err := r.db.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.TaskSearchResult
+	var tasks []models.Task
+	for rows.Next() {
+		var result models.TaskSearchResult
+		task, err := scanTask(withExtraColumns(rows, &result.Score, &result.TitleHighlight, &result.Snippet))
+		if err != nil {
+			return nil, err
+		}
+		result.TitleHighlight = searchHighlightHTML(result.TitleHighlight)
+		result.Snippet = searchHighlightHTML(result.Snippet)
+		results = append(results, result)
+		tasks = append(tasks, *task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadTaskDetails(r.db, tasks); err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Task = tasks[i]
+	}
+	return results, nil
+}
+
+// searchHighlightHTML 目印で囲んだ一致箇所を <mark> にし、それ以外の文字をHTMLとしてエスケープする
+func searchHighlightHTML(s string) string {
+	return strings.NewReplacer(searchMarkStart, "<mark>", searchMarkEnd, "</mark>").Replace(html.EscapeString(s))
+}
+
+// qualifyColumns カンマ区切りのカラムにテーブルの別名を付ける
+func qualifyColumns(alias, columns string) string {
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = alias + "." + name
+	}
+	return strings.Join(names, ", ")
+}
+
+// extraColumnsScanner scanTask などの読み取りの後ろに、追加の列を読み取る
+type extraColumnsScanner struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+func withExtraColumns(row rowScanner, extra ...interface{}) rowScanner {
+	return extraColumnsScanner{row: row, extra: extra}
+}
+
+func (s extraColumnsScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"time"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/utils"
+)
+
+// SELECT対象のカラム（scanTaskSeries と順序を合わせる）
+const taskSeriesColumns = `id, user_id, rrule, timezone, dtstart, title, description, priority, ended_at, created_at, updated_at`
+
+func (r *sqlTaskRepository) GetTaskSeries(seriesID string) (*models.TaskSeries, error) {
+	query := `SELECT ` + taskSeriesColumns + ` FROM task_series WHERE id = ?`
+	series := &models.TaskSeries{}
+	err := r.db.QueryRow(query, seriesID).Scan(&series.ID, &series.UserID, &series.RRule, &series.Timezone,
+		&series.DTStart, &series.Title, &series.Description, &series.Priority, &series.EndedAt,
+		&series.CreatedAt, &series.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (r *sqlTaskRepository) UpdateTaskSeries(series *models.TaskSeries) error {
+	query := `UPDATE task_series SET rrule = ?, timezone = ?, dtstart = ?, title = ?, description = ?, priority = ?, updated_at = ?
+			  WHERE id = ?`
+	_, err := r.db.Exec(query, series.RRule, series.Timezone, series.DTStart, series.Title, series.Description,
+		series.Priority, series.UpdatedAt, series.ID)
+	return err
+}
+
+// EndTaskSeries 系列を終了し、版数を増やしたタスクを作成順に返す
+// ゴミ箱にない系列のタスクは繰り返しのルールがなくなるため、版数を増やす（既に終了していれば何もしない）
+func (r *sqlTaskRepository) EndTaskSeries(seriesID string, endedAt time.Time) ([]models.Task, error) {
+	var ended []models.Task
+	err := r.db.withTx(func(tx *Tx) error {
+		query := `UPDATE task_series SET ended_at = ?, updated_at = ? WHERE id = ? AND ended_at IS NULL`
+		result, err := tx.Exec(query, endedAt, endedAt, seriesID)
+		if err != nil {
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE tasks SET version = version + 1 WHERE series_id = ? AND deleted_at IS NULL`, seriesID); err != nil {
+			return err
+		}
+		repo := &sqlTaskRepository{db: tx, dialect: r.dialect}
+		query = `SELECT ` + taskColumns + ` FROM tasks WHERE series_id = ? AND deleted_at IS NULL
+				 ORDER BY ` + r.dialect.timeExpr("created_at") + `, id`
+		ended, err = repo.queryTasks(query, seriesID)
+		return err
+	})
+	return ended, err
+}
+
+// CreateOccurrence 繰り返しの次の回を作成する
+// 完了を取り消して再度完了にした場合など、同じ回が既にあれば作成せず false を返す
+func (r *sqlTaskRepository) CreateOccurrence(task *models.Task) (bool, error) {
+	created := false
+	err := r.db.withTx(func(tx *Tx) error {
+		var err error
+		created, err = createOccurrence(tx, r.dialect, task)
+		return err
+	})
+	return created, err
+}
+
+// SkipOccurrence この回をスキップする（この回のタスクを子孫ごとゴミ箱に移し、next を作成する）
+// next が nil の場合はゴミ箱に移すのみ行う。version の版数から変更されていれば ErrTaskVersionConflict を返す
+func (r *sqlTaskRepository) SkipOccurrence(taskID string, version int64, next *models.Task, skippedAt time.Time) error {
+	return r.db.withTx(func(tx *Tx) error {
+		result, err := tx.Exec(`UPDATE tasks SET deleted_at = ?, version = version + 1 WHERE id = ? AND version = ? AND deleted_at IS NULL`,
+			skippedAt, taskID, version)
+		if err != nil {
+			return err
+		}
+		if err := checkTaskVersion(tx, result, taskID); err != nil {
+			return err
+		}
+		if err := trashDescendants(tx, taskID, skippedAt); err != nil {
+			return err
+		}
+		if next != nil {
+			if _, err := createOccurrence(tx, r.dialect, next); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// createOccurrence ゴミ箱にある回も (series_id, occurrence_at) の一意制約に含まれるため、同じ回として数える
+func createOccurrence(q querier, d *dialect, task *models.Task) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM tasks WHERE series_id = ? AND ` + d.timeExpr("occurrence_at") + ` = ` + d.timeExpr("?")
+	if err := q.QueryRow(query, task.SeriesID, task.OccurrenceAt).Scan(&count); err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	return true, insertTask(q, task)
+}
+
+// createTaskSeries 繰り返しのルールが設定された系列のないタスクに、タスクを最初の回とする系列を作成する
+func createTaskSeries(q querier, task *models.Task) error {
+	if task.RRule == nil || task.SeriesID != nil {
+		return nil
+	}
+
+	series := models.NewTaskSeries(utils.GenerateID(), task)
+	query := `INSERT INTO task_series (` + taskSeriesColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := q.Exec(query, series.ID, series.UserID, series.RRule, series.Timezone, series.DTStart, series.Title,
+		series.Description, series.Priority, series.EndedAt, series.CreatedAt, series.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	task.SeriesID = &series.ID
+	return nil
+}
+
+// loadTaskSeries タスクの一覧に、終了していない系列の繰り返しのルールを設定する
+func loadTaskSeries(q querier, tasks []models.Task) error {
+	index := map[string][]int{}
+	var args []interface{}
+	for i := range tasks {
+		tasks[i].RRule, tasks[i].Timezone = nil, nil
+		if id := tasks[i].SeriesID; id != nil {
+			if _, ok := index[*id]; !ok {
+				args = append(args, *id)
+			}
+			index[*id] = append(index[*id], i)
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	query := `SELECT id, rrule, timezone FROM task_series WHERE ended_at IS NULL AND id IN (` + placeholders(len(args)) + `)`
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, rule, timezone string
+		if err := rows.Scan(&id, &rule, &timezone); err != nil {
+			return err
+		}
+		for _, i := range index[id] {
+			tasks[i].RRule, tasks[i].Timezone = &rule, &timezone
+		}
+	}
+	return rows.Err()
+}
+
+// loadTaskDetails タスクの一覧にタグと繰り返しのルールを設定する
+func loadTaskDetails(q querier, tasks []models.Task) error {
+	if err := loadTaskTags(q, tasks); err != nil {
+		return err
+	}
+	return loadTaskSeries(q, tasks)
+}
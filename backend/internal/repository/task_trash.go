@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// GetDeletedTasks ユーザーのゴミ箱のタスクを、削除日時の新しい順に取得する
+// 親と一緒に削除した子孫は親を元に戻すと戻るため、一覧には含めない
+func (r *sqlTaskRepository) GetDeletedTasks(userID string) ([]models.Task, error) {
+	deletedAt := r.dialect.timeExpr("deleted_at")
+	query := `SELECT ` + taskColumns + ` FROM tasks t
+			  WHERE user_id = ? AND deleted_at IS NOT NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM tasks p WHERE p.id = t.parent_id AND p.deleted_at IS NOT NULL
+					  AND ` + r.dialect.timeExpr("p.deleted_at") + ` = ` + r.dialect.timeExpr("t.deleted_at") + `
+				)
+			  ORDER BY ` + deletedAt + ` DESC, id DESC`
+	return r.queryTasks(query, userID)
+}
+
+// GetDeletedTaskByID ゴミ箱のタスクを取得する（ゴミ箱にない場合は sql.ErrNoRows）
+func (r *sqlTaskRepository) GetDeletedTaskByID(taskID string) (*models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = ? AND deleted_at IS NOT NULL`
+	task, err := scanTask(r.db.QueryRow(query, taskID))
+	if err != nil {
+		return nil, err
+	}
+	tasks := []models.Task{*task}
+	if err := loadTaskDetails(r.db, tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// RestoreTask ゴミ箱のタスクを、同じ日時に削除した子孫とともに元に戻す
+// 親タスクがゴミ箱にある（または完全に削除された）場合はトップレベルのタスクとして戻す
+// プロジェクトが削除されていた場合は、親タスクのプロジェクト（トップレベルなら Inbox）に戻す
+func (r *sqlTaskRepository) RestoreTask(taskID string, restoredAt time.Time) error {
+	return r.db.withTx(func(tx *Tx) error {
+		var deletedAt time.Time
+		var parentID *string
+		var userID, projectID string
+		err := tx.QueryRow(`SELECT deleted_at, parent_id, user_id, project_id FROM tasks WHERE id = ? AND deleted_at IS NOT NULL`, taskID).
+			Scan(&deletedAt, &parentID, &userID, &projectID)
+		if err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			var count int
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM tasks WHERE id = ? AND deleted_at IS NULL`, *parentID).Scan(&count); err != nil {
+				return err
+			}
+			if count == 0 {
+				if _, err := tx.Exec(`UPDATE tasks SET parent_id = NULL WHERE id = ?`, taskID); err != nil {
+					return err
+				}
+				parentID = nil
+			}
+		}
+
+		// restoredProject が nil なら元のプロジェクトのまま戻す
+		var restoredProject *string
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM projects WHERE id = ?`, projectID).Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			if parentID != nil {
+				err = tx.QueryRow(`SELECT project_id FROM tasks WHERE id = ?`, *parentID).Scan(&projectID)
+			} else {
+				projectID, err = ensureInbox(tx, userID, restoredAt)
+			}
+			if err != nil {
+				return err
+			}
+			restoredProject = &projectID
+		}
+
+		sameDeletion := r.dialect.timeExpr("t.deleted_at") + ` = ` + r.dialect.timeExpr("?")
+		query := `WITH RECURSIVE restored (id) AS (
+					  SELECT id FROM tasks WHERE id = ?
+					  UNION
+					  SELECT t.id FROM tasks t JOIN restored s ON t.parent_id = s.id
+					  WHERE t.deleted_at IS NOT NULL AND ` + sameDeletion + `
+				  )
+				  UPDATE tasks SET deleted_at = NULL, project_id = COALESCE(?, project_id), updated_at = ?, version = version + 1
+				  WHERE id IN (SELECT id FROM restored)`
+		_, err = tx.Exec(query, taskID, deletedAt, restoredProject, restoredAt)
+		return err
+	})
+}
+
+// PurgeTask ゴミ箱のタスクを完全に削除する（子孫・タグ・リマインダーは外部キーの ON DELETE CASCADE で削除される）
+func (r *sqlTaskRepository) PurgeTask(taskID string) error {
+	result, err := r.db.Exec(`DELETE FROM tasks WHERE id = ? AND deleted_at IS NOT NULL`, taskID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PurgeDeletedTasks before より前にゴミ箱に移したタスクを完全に削除し、削除件数を返す
+func (r *sqlTaskRepository) PurgeDeletedTasks(before time.Time) (int64, error) {
+	query := `DELETE FROM tasks WHERE deleted_at IS NOT NULL AND ` + r.dialect.timeExpr("deleted_at") + ` < ` + r.dialect.timeExpr("?")
+	result, err := r.db.Exec(query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// ErrParentNotFound 親タスクが存在しない、または別のユーザーのタスク
+var ErrParentNotFound = errors.New("parent task not found")
+
+// ErrTaskCycle 親タスクの指定によって階層が循環する
+var ErrTaskCycle = errors.New("task hierarchy would contain a cycle")
+
+// checkTaskParent 親タスクの存在・所有者と、階層が循環しないことを確認する
+func checkTaskParent(q querier, task *models.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+	if *task.ParentID == task.ID {
+		return ErrTaskCycle
+	}
+
+	var ownerID string
+	err := q.QueryRow(`SELECT user_id FROM tasks WHERE id = ? AND deleted_at IS NULL`, *task.ParentID).Scan(&ownerID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && ownerID != task.UserID) {
+		return ErrParentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// 新しい親の祖先にこのタスクが含まれていれば循環する
+	// UNION で重複を除くため、既存のデータが循環していても再帰は停止する
+	query := `WITH RECURSIVE ancestors (id, parent_id) AS (
+				  SELECT id, parent_id FROM tasks WHERE id = ?
+				  UNION
+				  SELECT t.id, t.parent_id FROM tasks t JOIN ancestors a ON t.id = a.parent_id
+			  )
+			  SELECT COUNT(*) FROM ancestors WHERE id = ?`
+	var count int
+	if err := q.QueryRow(query, *task.ParentID, task.ID).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrTaskCycle
+	}
+	return nil
+}
+
+func (r *sqlTaskRepository) GetChildTasks(parentID string) ([]models.Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE parent_id = ? AND deleted_at IS NULL
+			  ORDER BY ` + r.dialect.timeExpr("created_at") + `, id`
+	return r.queryTasks(query, parentID)
+}
+
+func (r *sqlTaskRepository) GetTaskSubtree(rootID string) ([]models.Task, error) {
+	query := `WITH RECURSIVE subtree (id) AS (
+				  SELECT id FROM tasks WHERE id = ? AND deleted_at IS NULL
+				  UNION
+				  SELECT t.id FROM tasks t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+			  )
+			  SELECT ` + taskColumns + ` FROM tasks WHERE id IN (SELECT id FROM subtree)
+			  ORDER BY ` + r.dialect.timeExpr("created_at") + `, id`
+	return r.queryTasks(query, rootID)
+}
+
+func (r *sqlTaskRepository) CompleteSubtasks(parentID string, updatedAt time.Time) ([]models.Task, error) {
+	var completed []models.Task
+	err := r.db.withTx(func(tx *Tx) error {
+		query := `WITH RECURSIVE descendants (id) AS (
+					  SELECT id FROM tasks WHERE parent_id = ? AND deleted_at IS NULL
+					  UNION
+					  SELECT t.id FROM tasks t JOIN descendants d ON t.parent_id = d.id WHERE t.deleted_at IS NULL
+				  )
+				  SELECT id FROM tasks WHERE id IN (SELECT id FROM descendants) AND status <> 'completed'`
+		rows, err := tx.Query(query, parentID)
+		if err != nil {
+			return err
+		}
+		var ids []interface{}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil || len(ids) == 0 {
+			return err
+		}
+
+		query = `UPDATE tasks SET status = 'completed', updated_at = ?, version = version + 1 WHERE id IN (` + placeholders(len(ids)) + `)`
+		if _, err := tx.Exec(query, append([]interface{}{updatedAt}, ids...)...); err != nil {
+			return err
+		}
+		repo := &sqlTaskRepository{db: tx, dialect: r.dialect}
+		query = `SELECT ` + taskColumns + ` FROM tasks WHERE id IN (` + placeholders(len(ids)) + `)
+				 ORDER BY ` + r.dialect.timeExpr("created_at") + `, id`
+		completed, err = repo.queryTasks(query, ids...)
+		return err
+	})
+	return completed, err
+}
+
+func (r *sqlTaskRepository) queryTasks(query string, args ...interface{}) ([]models.Task, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if err := loadTaskDetails(r.db, tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
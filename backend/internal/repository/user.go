@@ -1,28 +1,28 @@
 package repository
 
 import (
-	"database/sql"
 	"todo-app-backend/internal/models"
 )
 
-type UserRepository struct {
-	db *sql.DB
+// sqlUserRepository SQLite / PostgreSQL 共通の実装（方言の違いは DB が吸収する）
+type sqlUserRepository struct {
+	db *DB
 }
 
-func NewUserRepository() *UserRepository {
-	return &UserRepository{
-		db: GetDB(),
+func NewUserRepository(db *DB) UserRepository {
+	return &sqlUserRepository{
+		db: db,
 	}
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
+func (r *sqlUserRepository) CreateUser(user *models.User) error {
 	query := `INSERT INTO users (id, email, password_hash, name, created_at, updated_at) 
 			  VALUES (?, ?, ?, ?, ?, ?)`
 	_, err := r.db.Exec(query, user.ID, user.Email, user.Password, user.Name, user.CreatedAt, user.UpdatedAt)
 	return err
 }
 
-func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+func (r *sqlUserRepository) GetUserByEmail(email string) (*models.User, error) {
 	query := `SELECT id, email, password_hash, name, created_at, updated_at FROM users WHERE email = ?`
 	row := r.db.QueryRow(query, email)
 
@@ -35,7 +35,20 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (r *UserRepository) UserExists(email string) (bool, error) {
+func (r *sqlUserRepository) GetUserByID(userID string) (*models.User, error) {
+	query := `SELECT id, email, password_hash, name, created_at, updated_at FROM users WHERE id = ?`
+	row := r.db.QueryRow(query, userID)
+
+	user := &models.User{}
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (r *sqlUserRepository) UserExists(email string) (bool, error) {
 	query := `SELECT COUNT(*) FROM users WHERE email = ?`
 	var count int
 	err := r.db.QueryRow(query, email).Scan(&count)
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"encoding/json"
+
+	"todo-app-backend/internal/models"
+)
+
+// SELECT対象のカラム（scanView と順序を合わせる）
+const viewColumns = `id, user_id, name, filters, display, created_at, updated_at`
+
+type sqlViewRepository struct {
+	db *DB
+}
+
+func NewViewRepository(db *DB) ViewRepository {
+	return &sqlViewRepository{
+		db: db,
+	}
+}
+
+func (r *sqlViewRepository) CreateView(view *models.View) error {
+	filters, display, err := marshalView(view)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO views (` + viewColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err = r.db.Exec(query, view.ID, view.UserID, view.Name, filters, display, view.CreatedAt, view.UpdatedAt)
+	return err
+}
+
+// GetViewsByUserID ユーザーのビューを作成順に取得する
+func (r *sqlViewRepository) GetViewsByUserID(userID string) ([]models.View, error) {
+	query := `SELECT ` + viewColumns + ` FROM views WHERE user_id = ?
+			  ORDER BY ` + r.db.dialect.timeExpr("created_at") + `, id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []models.View
+	for rows.Next() {
+		view, err := scanView(rows)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, rows.Err()
+}
+
+func (r *sqlViewRepository) GetViewByID(viewID string) (*models.View, error) {
+	query := `SELECT ` + viewColumns + ` FROM views WHERE id = ?`
+	return scanView(r.db.QueryRow(query, viewID))
+}
+
+func (r *sqlViewRepository) UpdateView(view *models.View) error {
+	filters, display, err := marshalView(view)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE views SET name = ?, filters = ?, display = ?, updated_at = ? WHERE id = ?`
+	_, err = r.db.Exec(query, view.Name, filters, display, view.UpdatedAt, view.ID)
+	return err
+}
+
+func (r *sqlViewRepository) DeleteView(viewID string) error {
+	_, err := r.db.Exec(`DELETE FROM views WHERE id = ?`, viewID)
+	return err
+}
+
+// marshalView 条件と表示方法をJSONの文字列にする
+func marshalView(view *models.View) (string, string, error) {
+	filters, err := json.Marshal(view.Filters)
+	if err != nil {
+		return "", "", err
+	}
+	display, err := json.Marshal(view.Display)
+	if err != nil {
+		return "", "", err
+	}
+	return string(filters), string(display), nil
+}
+
+func scanView(row rowScanner) (*models.View, error) {
+	view := &models.View{}
+	var filters, display string
+	err := row.Scan(&view.ID, &view.UserID, &view.Name, &filters, &display, &view.CreatedAt, &view.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(filters), &view.Filters); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(display), &view.Display); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
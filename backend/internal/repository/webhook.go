@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// SELECT対象のカラム（scanWebhook / scanWebhookDelivery と順序を合わせる）
+const (
+	webhookColumns         = `id, user_id, url, events, secret, active, created_at, updated_at`
+	webhookDeliveryColumns = `d.id, d.webhook_id, d.event_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+		d.response_status, d.last_error, d.delivered_at, d.created_at, d.updated_at`
+)
+
+type sqlWebhookRepository struct {
+	db *DB
+}
+
+func NewWebhookRepository(db *DB) WebhookRepository {
+	return &sqlWebhookRepository{
+		db: db,
+	}
+}
+
+func (r *sqlWebhookRepository) CreateWebhook(webhook *models.Webhook) error {
+	query := `INSERT INTO webhooks (` + webhookColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, webhook.ID, webhook.UserID, webhook.URL, strings.Join(webhook.Events, ","),
+		webhook.Secret, webhook.Active, webhook.CreatedAt, webhook.UpdatedAt)
+	return err
+}
+
+func (r *sqlWebhookRepository) GetWebhooksByUserID(userID string) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = ?
+			  ORDER BY ` + r.db.dialect.timeExpr("created_at") + `, id`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []models.Webhook
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *sqlWebhookRepository) GetWebhookByID(webhookID string) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = ?`
+	return scanWebhook(r.db.QueryRow(query, webhookID))
+}
+
+func (r *sqlWebhookRepository) UpdateWebhook(webhook *models.Webhook) error {
+	query := `UPDATE webhooks SET url = ?, events = ?, active = ?, updated_at = ? WHERE id = ?`
+	_, err := r.db.Exec(query, webhook.URL, strings.Join(webhook.Events, ","), webhook.Active, webhook.UpdatedAt, webhook.ID)
+	return err
+}
+
+// DeleteWebhook Webhook を削除する（配信ログは外部キーの ON DELETE CASCADE で削除される）
+func (r *sqlWebhookRepository) DeleteWebhook(webhookID string) error {
+	_, err := r.db.Exec(`DELETE FROM webhooks WHERE id = ?`, webhookID)
+	return err
+}
+
+func (r *sqlWebhookRepository) CreateWebhookDelivery(delivery *models.WebhookDelivery) error {
+	query := `INSERT INTO webhook_deliveries (id, webhook_id, event_id, event, payload, status, attempts,
+				  next_attempt_at, created_at, updated_at)
+			  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.Exec(query, delivery.ID, delivery.WebhookID, delivery.EventID, delivery.Event, string(delivery.Payload),
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.CreatedAt, delivery.UpdatedAt)
+	return err
+}
+
+func (r *sqlWebhookRepository) GetWebhookDeliveries(webhookID, status string, limit int) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d WHERE d.webhook_id = ?`
+	args := []interface{}{webhookID}
+	if status != "" {
+		query += ` AND d.status = ?`
+		args = append(args, status)
+	}
+	query += ` ORDER BY ` + r.db.dialect.timeExpr("d.created_at") + ` DESC, d.id DESC LIMIT ?`
+	args = append(args, limit)
+	return r.queryDeliveries(query, args...)
+}
+
+func (r *sqlWebhookRepository) GetWebhookDeliveryByID(deliveryID string) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d WHERE d.id = ?`
+	return scanWebhookDelivery(r.db.QueryRow(query, deliveryID))
+}
+
+// GetDueWebhookDeliveries 有効な Webhook の送信待ちのうち、送信時刻を迎えた配信を古い順に取得する
+func (r *sqlWebhookRepository) GetDueWebhookDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	nextAttemptAt := r.db.dialect.timeExpr("d.next_attempt_at")
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d
+			  JOIN webhooks w ON w.id = d.webhook_id
+			  WHERE w.active = ? AND d.status = ? AND ` + nextAttemptAt + ` <= ` + r.db.dialect.timeExpr("?") + `
+			  ORDER BY ` + nextAttemptAt + `, d.id LIMIT ?`
+	return r.queryDeliveries(query, true, models.WebhookDeliveryPending, now.UTC(), limit)
+}
+
+// ClaimWebhookDelivery 送信待ちで送信時刻を迎えた配信のみ取得できる
+// 送信中に停止した場合は leaseUntil を過ぎると再び取得できるようになる
+func (r *sqlWebhookRepository) ClaimWebhookDelivery(deliveryID string, now, leaseUntil time.Time) (bool, error) {
+	query := `UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+			  WHERE id = ? AND status = ? AND ` + r.db.dialect.timeExpr("next_attempt_at") + ` <= ` + r.db.dialect.timeExpr("?")
+	result, err := r.db.Exec(query, leaseUntil.UTC(), now, deliveryID, models.WebhookDeliveryPending, now.UTC())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (r *sqlWebhookRepository) FinishWebhookDelivery(delivery *models.WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries SET status = ?, next_attempt_at = ?, response_status = ?, last_error = ?,
+				  delivered_at = ?, updated_at = ?
+			  WHERE id = ?`
+	_, err := r.db.Exec(query, delivery.Status, delivery.NextAttemptAt, delivery.ResponseStatus, delivery.LastError,
+		delivery.DeliveredAt, delivery.UpdatedAt, delivery.ID)
+	return err
+}
+
+func (r *sqlWebhookRepository) queryDeliveries(query string, args ...interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	var events string
+	err := row.Scan(&webhook.ID, &webhook.UserID, &webhook.URL, &events, &webhook.Secret, &webhook.Active,
+		&webhook.CreatedAt, &webhook.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Events = strings.Split(events, ",")
+	return webhook, nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload string
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.Event, &payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.ResponseStatus, &delivery.LastError, &delivery.DeliveredAt,
+		&delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = []byte(payload)
+	return delivery, nil
+}
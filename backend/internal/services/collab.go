@@ -0,0 +1,287 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/utils"
+)
+
+const (
+	// 接続ごとに溜めておける送信待ちのメッセージ数（溢れた接続は切断する）
+	collabSendBufferSize = 64
+	// 1つの接続で購読できるトピックの上限
+	collabMaxTopics = 100
+)
+
+var (
+	ErrCollabTooManyTopics = errors.New("too many subscribed topics")
+	ErrCollabNotSubscribed = errors.New("not subscribed to the topic")
+)
+
+// CollabSession WebSocket の1つの接続
+// Send はハブの停止時や、送信が追いつかずに切断した場合に閉じられる
+type CollabSession struct {
+	ID     string
+	UserID string
+	Name   string
+	send   chan []byte
+	// topics 購読中のトピックと、そのトピックでのプレゼンスの状態
+	topics map[string]string
+	closed bool
+}
+
+// Send 送信するメッセージ（エンコード済みのJSON）
+func (s *CollabSession) Send() <-chan []byte {
+	return s.send
+}
+
+// CollabHub プロジェクト・タスクのトピックごとに、接続とプレゼンスを管理する
+// タスクの変更イベントを、タスクまたはそのプロジェクトを購読している接続に配信する
+// 状態はプロセス内に保持するため、同じサーバーに接続したクライアントの間でのみ共有される
+type CollabHub struct {
+	mu     sync.Mutex
+	topics map[string]map[*CollabSession]struct{}
+	// sessions 切断していない接続（停止時に閉じる）
+	sessions map[*CollabSession]struct{}
+	closed   bool
+}
+
+func NewCollabHub() *CollabHub {
+	return &CollabHub{
+		topics:   map[string]map[*CollabSession]struct{}{},
+		sessions: map[*CollabSession]struct{}{},
+	}
+}
+
+// Connect 接続を登録する（ハブが停止している場合は閉じた接続を返す）
+func (h *CollabHub) Connect(userID, name string) *CollabSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	session := &CollabSession{
+		ID:     utils.GenerateID(),
+		UserID: userID,
+		Name:   name,
+		send:   make(chan []byte, collabSendBufferSize),
+		topics: map[string]string{},
+	}
+	if h.closed {
+		session.closed = true
+		close(session.send)
+		return session
+	}
+	h.sessions[session] = struct{}{}
+	return session
+}
+
+// Disconnect 接続を削除し、参加していたトピックのプレゼンスを更新する
+func (h *CollabHub) Disconnect(session *CollabSession) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.drop(session)
+}
+
+// Join トピックを購読し、閲覧中としてプレゼンスに参加する（購読済みの場合は何もしない）
+// 呼び出し側でトピックへのアクセス権を確認しておく
+func (h *CollabHub) Join(session *CollabSession, topic string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if session.closed {
+		return nil
+	}
+	if _, ok := session.topics[topic]; ok {
+		return nil
+	}
+	if len(session.topics) >= collabMaxTopics {
+		return ErrCollabTooManyTopics
+	}
+
+	session.topics[topic] = models.PresenceViewing
+	members, ok := h.topics[topic]
+	if !ok {
+		members = map[*CollabSession]struct{}{}
+		h.topics[topic] = members
+	}
+	members[session] = struct{}{}
+	h.broadcastPresence(topic)
+	return nil
+}
+
+// Leave トピックの購読をやめ、プレゼンスから外れる
+func (h *CollabHub) Leave(session *CollabSession, topic string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := session.topics[topic]; !ok {
+		return ErrCollabNotSubscribed
+	}
+	h.leave(session, topic)
+	return nil
+}
+
+// SetState 購読中のトピックでのプレゼンスの状態を変更する
+func (h *CollabHub) SetState(session *CollabSession, topic, state string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	current, ok := session.topics[topic]
+	if !ok {
+		return ErrCollabNotSubscribed
+	}
+	if current != state {
+		session.topics[topic] = state
+		h.broadcastPresence(topic)
+	}
+	return nil
+}
+
+// Reply 接続にメッセージを送る
+func (h *CollabHub) Reply(session *CollabSession, message *models.CollabServerMessage) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Failed to encode %s message: %v", message.Type, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.deliver(session, data) {
+		h.drop(session)
+	}
+}
+
+// PublishTaskEvent タスクまたはそのプロジェクトを購読している、タスクの所有者の接続にイベントを配信する
+func (h *CollabHub) PublishTaskEvent(event *models.TaskEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, topic := range []string{
+		models.CollabTopicTask + ":" + event.Task.ID,
+		models.CollabTopicProject + ":" + event.Task.ProjectID,
+	} {
+		members := h.topics[topic]
+		if len(members) == 0 {
+			continue
+		}
+		data, err := json.Marshal(&models.CollabServerMessage{
+			Type:  event.Type,
+			Topic: topic,
+			Data:  event.Task,
+		})
+		if err != nil {
+			log.Printf("Failed to encode %s event: %v", event.Type, err)
+			return
+		}
+		var slow []*CollabSession
+		for session := range members {
+			if session.UserID == event.UserID && !h.deliver(session, data) {
+				slow = append(slow, session)
+			}
+		}
+		for _, session := range slow {
+			h.drop(session)
+		}
+	}
+}
+
+// Close すべての接続を閉じる（サーバーの停止時に WebSocket の接続を閉じるために使う）
+func (h *CollabHub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for session := range h.sessions {
+		h.drop(session)
+	}
+}
+
+// deliver 送信待ちに積む（溢れた場合は false を返すので、呼び出し側で切断する）
+// 参加者への送信の途中で切断するとプレゼンスの送信が入れ子になるため、切断は送信し終えてから行う
+func (h *CollabHub) deliver(session *CollabSession, data []byte) bool {
+	if session.closed {
+		return true
+	}
+	select {
+	case session.send <- data:
+		return true
+	default:
+		log.Printf("Dropping slow collaboration session %s", session.ID)
+		return false
+	}
+}
+
+// drop 接続を閉じ、すべてのトピックから外す
+func (h *CollabHub) drop(session *CollabSession) {
+	if session.closed {
+		return
+	}
+	session.closed = true
+	close(session.send)
+	delete(h.sessions, session)
+	for topic := range session.topics {
+		h.leave(session, topic)
+	}
+}
+
+func (h *CollabHub) leave(session *CollabSession, topic string) {
+	delete(session.topics, topic)
+	members := h.topics[topic]
+	delete(members, session)
+	if len(members) == 0 {
+		delete(h.topics, topic)
+		return
+	}
+	h.broadcastPresence(topic)
+}
+
+// broadcastPresence トピックの参加者全員に、現在の参加者の一覧を送る
+func (h *CollabHub) broadcastPresence(topic string) {
+	sessions := sortedSessions(h.topics[topic])
+	presence := &models.CollabServerMessage{
+		Type:    models.CollabPresence,
+		Topic:   topic,
+		Members: make([]models.PresenceMember, 0, len(sessions)),
+	}
+	for _, session := range sessions {
+		presence.Members = append(presence.Members, models.PresenceMember{
+			SessionID: session.ID,
+			UserID:    session.UserID,
+			Name:      session.Name,
+			State:     session.topics[topic],
+		})
+	}
+	data, err := json.Marshal(presence)
+	if err != nil {
+		log.Printf("Failed to encode presence for %s: %v", topic, err)
+		return
+	}
+	var slow []*CollabSession
+	for _, session := range sessions {
+		if !h.deliver(session, data) {
+			slow = append(slow, session)
+		}
+	}
+	for _, session := range slow {
+		h.drop(session)
+	}
+}
+
+// sortedSessions 接続した順（セッションIDは ULID）に並べる
+func sortedSessions(members map[*CollabSession]struct{}) []*CollabSession {
+	sessions := make([]*CollabSession, 0, len(members))
+	for session := range members {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions
+}
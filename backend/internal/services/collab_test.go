@@ -0,0 +1,153 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// drainCollab 接続に送信待ちのメッセージを、閉じているかどうかとともに返す
+func drainCollab(t *testing.T, session *CollabSession) ([]models.CollabServerMessage, bool) {
+	t.Helper()
+
+	var messages []models.CollabServerMessage
+	for {
+		select {
+		case data, ok := <-session.Send():
+			if !ok {
+				return messages, true
+			}
+			var message models.CollabServerMessage
+			if err := json.Unmarshal(data, &message); err != nil {
+				t.Fatalf("decode %s: %v", data, err)
+			}
+			messages = append(messages, message)
+		default:
+			return messages, false
+		}
+	}
+}
+
+// memberNames プレゼンスの参加者の名前と状態
+func memberNames(message models.CollabServerMessage) []string {
+	names := make([]string, len(message.Members))
+	for i, member := range message.Members {
+		names[i] = member.Name + ":" + member.State
+	}
+	return names
+}
+
+func TestCollabHubPresence(t *testing.T) {
+	hub := NewCollabHub()
+	alice := hub.Connect("u1", "Alice")
+	tablet := hub.Connect("u1", "Alice (tablet)")
+	const topic = models.CollabTopicProject + ":p1"
+
+	if err := hub.Join(alice, topic); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+	if err := hub.Join(tablet, topic); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+
+	// 参加するたびに、参加者全員に一覧を送る
+	messages, _ := drainCollab(t, alice)
+	if len(messages) != 2 || messages[1].Type != models.CollabPresence || messages[1].Topic != topic || len(messages[1].Members) != 2 {
+		t.Fatalf("alice received %+v, want presence with 1 and then 2 members", messages)
+	}
+	if messages, _ := drainCollab(t, tablet); len(messages) != 1 || len(messages[0].Members) != 2 {
+		t.Errorf("tablet received %+v, want presence with 2 members", messages)
+	}
+
+	if err := hub.SetState(tablet, topic, models.PresenceEditing); err != nil {
+		t.Fatalf("SetState: %v", err)
+	}
+	if messages, _ := drainCollab(t, alice); len(messages) != 1 || memberNames(messages[0])[1] != "Alice (tablet):editing" {
+		t.Errorf("alice received %+v, want the tablet editing", messages)
+	}
+	drainCollab(t, tablet)
+
+	// 抜けると残った参加者に一覧を送り、購読していないトピックからは抜けられない
+	if err := hub.Leave(tablet, topic); err != nil {
+		t.Fatalf("Leave: %v", err)
+	}
+	if messages, _ := drainCollab(t, alice); len(messages) != 1 || len(messages[0].Members) != 1 || messages[0].Members[0].SessionID != alice.ID {
+		t.Errorf("alice received %+v after the tablet left, want only alice", messages)
+	}
+	if messages, _ := drainCollab(t, tablet); len(messages) != 0 {
+		t.Errorf("tablet received %+v after leaving, want nothing", messages)
+	}
+	if err := hub.Leave(tablet, topic); !errors.Is(err, ErrCollabNotSubscribed) {
+		t.Errorf("Leave again = %v, want ErrCollabNotSubscribed", err)
+	}
+
+	// 切断すると接続を閉じ、トピックも空になる
+	hub.Disconnect(alice)
+	if _, closed := drainCollab(t, alice); !closed {
+		t.Error("alice is open after Disconnect")
+	}
+	if len(hub.topics) != 0 || len(hub.sessions) != 1 {
+		t.Errorf("%d topics and %d sessions after disconnecting, want 0 and 1", len(hub.topics), len(hub.sessions))
+	}
+}
+
+func TestCollabHubPublishTaskEvent(t *testing.T) {
+	hub := NewCollabHub()
+	project := hub.Connect("u1", "Alice")
+	task := hub.Connect("u1", "Alice (tablet)")
+	other := hub.Connect("u2", "Bob")
+	elsewhere := hub.Connect("u1", "Alice (phone)")
+	hub.Join(project, models.CollabTopicProject+":p1")
+	hub.Join(task, models.CollabTopicTask+":t1")
+	hub.Join(other, models.CollabTopicProject+":p1")
+	hub.Join(elsewhere, models.CollabTopicProject+":p2")
+	for _, session := range []*CollabSession{project, task, other, elsewhere} {
+		drainCollab(t, session)
+	}
+
+	hub.PublishTaskEvent(&models.TaskEvent{ID: "e1", Type: models.EventTaskUpdated, UserID: "u1", Task: &models.Task{ID: "t1", ProjectID: "p1", UserID: "u1"}, OccurredAt: time.Now()})
+
+	// タスクとそのプロジェクトを購読している、所有者の接続にだけ届く
+	for session, topic := range map[*CollabSession]string{project: models.CollabTopicProject + ":p1", task: models.CollabTopicTask + ":t1"} {
+		if messages, _ := drainCollab(t, session); len(messages) != 1 || messages[0].Type != models.EventTaskUpdated || messages[0].Topic != topic {
+			t.Errorf("%s received %+v, want task.updated on %s", session.Name, messages, topic)
+		}
+	}
+	for _, session := range []*CollabSession{other, elsewhere} {
+		if messages, _ := drainCollab(t, session); len(messages) != 0 {
+			t.Errorf("%s received %+v, want nothing", session.Name, messages)
+		}
+	}
+}
+
+func TestCollabHubDropsSlowSessions(t *testing.T) {
+	hub := NewCollabHub()
+	slow := hub.Connect("u1", "Slow")
+	fast := hub.Connect("u1", "Fast")
+	const topic = models.CollabTopicTask + ":t1"
+	hub.Join(slow, topic)
+	hub.Join(fast, topic)
+	drainCollab(t, fast)
+
+	var received []models.CollabServerMessage
+	for i := 0; i < collabSendBufferSize; i++ {
+		hub.PublishTaskEvent(&models.TaskEvent{ID: "e", Type: models.EventTaskUpdated, UserID: "u1", Task: &models.Task{ID: "t1"}, OccurredAt: time.Now()})
+		messages, _ := drainCollab(t, fast)
+		received = append(received, messages...)
+	}
+
+	// 溢れた接続は切断し、残った参加者に一覧を送る
+	if messages, closed := drainCollab(t, slow); !closed || len(messages) != collabSendBufferSize {
+		t.Errorf("slow session received %d messages (closed %t), want %d and closed", len(messages), closed, collabSendBufferSize)
+	}
+	if len(received) != collabSendBufferSize+1 {
+		t.Fatalf("fast session received %d messages, want every event and one presence", len(received))
+	}
+	presence := received[len(received)-2]
+	if presence.Type != models.CollabPresence || len(presence.Members) != 1 || presence.Members[0].SessionID != fast.ID {
+		t.Errorf("presence after dropping the slow session = %+v, want only the fast session", presence)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+// 購読者ごとに溜めておけるイベント数（溢れた購読者は切断し、再接続時にイベントログから再送する）
+const subscriberBufferSize = 64
+
+// StreamEvent 購読者に配信するイベント
+type StreamEvent struct {
+	// ID Last-Event-ID として再接続時に送られる "<起動ごとのID>-<連番>"
+	ID     string
+	seq    uint64
+	UserID string
+	Type   string
+	Data   []byte
+}
+
+// EventSubscription ユーザーのイベントの購読
+// C はブローカーの停止時や、受信が追いつかずに切断した場合に閉じられる
+type EventSubscription struct {
+	userID string
+	C      chan StreamEvent
+}
+
+// EventResume 購読開始時に送るイベント
+type EventResume struct {
+	// Events Last-Event-ID より後の、ユーザーのイベント
+	Events []StreamEvent
+	// Reset Last-Event-ID 以降のイベントをすべては再送できない（古すぎる・再起動した）ため、クライアントは取得し直す
+	Reset bool
+	// LastID 現時点で最新のイベントID（イベントがなければ連番 0 のID）
+	LastID string
+}
+
+// EventBroker タスクの変更イベントを同じプロセス内の購読者（SSE の接続）に配信する
+// 直近のイベントを一定件数保持し、Last-Event-ID を指定した再接続で取りこぼした分を再送する
+type EventBroker struct {
+	mu sync.Mutex
+	// epoch 起動ごとに変わるID。再起動前のイベントIDでの再開を検出する
+	epoch string
+	seq   uint64
+	// ring 直近のイベントのリングバッファ（start が最も古いイベント）
+	ring        []StreamEvent
+	start       int
+	count       int
+	subscribers map[*EventSubscription]struct{}
+	closed      bool
+}
+
+func NewEventBroker(logSize int) *EventBroker {
+	if logSize < 1 {
+		logSize = 1
+	}
+	return &EventBroker{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		ring:        make([]StreamEvent, logSize),
+		subscribers: map[*EventSubscription]struct{}{},
+	}
+}
+
+// PublishTaskEvent イベントをログに追加し、ユーザーの購読者に配信する
+func (b *EventBroker) PublishTaskEvent(event *models.TaskEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event: %v", event.Type, err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.seq++
+	streamEvent := StreamEvent{
+		ID:     b.eventID(b.seq),
+		seq:    b.seq,
+		UserID: event.UserID,
+		Type:   event.Type,
+		Data:   data,
+	}
+	b.append(streamEvent)
+
+	for sub := range b.subscribers {
+		if sub.userID != event.UserID {
+			continue
+		}
+		select {
+		case sub.C <- streamEvent:
+		default:
+			// 受信が追いつかない購読者は切断する（クライアントは Last-Event-ID で再開する）
+			delete(b.subscribers, sub)
+			close(sub.C)
+		}
+	}
+}
+
+// Subscribe ユーザーのイベントを購読する
+// lastEventID を指定した場合は、それより後のイベントを EventResume.Events で返す
+func (b *EventBroker) Subscribe(userID, lastEventID string) (*EventSubscription, *EventResume) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &EventSubscription{
+		userID: userID,
+		C:      make(chan StreamEvent, subscriberBufferSize),
+	}
+	resume := &EventResume{LastID: b.eventID(b.seq)}
+
+	if lastEventID != "" {
+		lastSeq, ok := b.parseEventID(lastEventID)
+		oldest := b.seq + 1
+		if b.count > 0 {
+			oldest = b.ring[b.start].seq
+		}
+		switch {
+		case !ok || lastSeq > b.seq || lastSeq+1 < oldest:
+			resume.Reset = true
+		default:
+			for i := 0; i < b.count; i++ {
+				event := b.ring[(b.start+i)%len(b.ring)]
+				if event.seq > lastSeq && event.UserID == userID {
+					resume.Events = append(resume.Events, event)
+				}
+			}
+		}
+	}
+
+	if b.closed {
+		close(sub.C)
+	} else {
+		b.subscribers[sub] = struct{}{}
+	}
+	return sub, resume
+}
+
+// Unsubscribe 購読をやめる（既に切断されている場合は何もしない）
+func (b *EventBroker) Unsubscribe(sub *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.C)
+	}
+}
+
+// Close すべての購読を終了する（サーバーの停止時に SSE の接続を閉じるために使う）
+func (b *EventBroker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		close(sub.C)
+	}
+}
+
+// append リングバッファにイベントを追加する（満杯なら最も古いイベントを捨てる）
+func (b *EventBroker) append(event StreamEvent) {
+	if b.count < len(b.ring) {
+		b.ring[(b.start+b.count)%len(b.ring)] = event
+		b.count++
+		return
+	}
+	b.ring[b.start] = event
+	b.start = (b.start + 1) % len(b.ring)
+}
+
+func (b *EventBroker) eventID(seq uint64) string {
+	return fmt.Sprintf("%s-%d", b.epoch, seq)
+}
+
+// parseEventID このプロセスが発行したイベントIDの連番を返す
+func (b *EventBroker) parseEventID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
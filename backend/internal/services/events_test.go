@@ -0,0 +1,135 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"todo-app-backend/internal/models"
+)
+
+func publishTo(b *EventBroker, userID, taskID string) {
+	b.PublishTaskEvent(&models.TaskEvent{ID: taskID, Type: models.EventTaskUpdated, UserID: userID, Task: &models.Task{ID: taskID}, OccurredAt: time.Now()})
+}
+
+// receive 購読に届いているイベントを、閉じているかどうかとともに返す
+func receive(sub *EventSubscription) ([]StreamEvent, bool) {
+	var events []StreamEvent
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				return events, true
+			}
+			events = append(events, event)
+		default:
+			return events, false
+		}
+	}
+}
+
+func TestEventBrokerFansOutPerUser(t *testing.T) {
+	b := NewEventBroker(10)
+	first, _ := b.Subscribe("u1", "")
+	second, _ := b.Subscribe("u1", "")
+	other, _ := b.Subscribe("u2", "")
+
+	publishTo(b, "u1", "t1")
+
+	for name, sub := range map[string]*EventSubscription{"first": first, "second": second} {
+		if events, closed := receive(sub); len(events) != 1 || closed || events[0].Type != models.EventTaskUpdated || events[0].UserID != "u1" {
+			t.Errorf("%s subscriber of u1 received %+v (closed %t), want the event", name, events, closed)
+		}
+	}
+	if events, closed := receive(other); len(events) != 0 || closed {
+		t.Errorf("subscriber of u2 received %+v (closed %t), want nothing", events, closed)
+	}
+}
+
+func TestEventBrokerUnsubscribe(t *testing.T) {
+	b := NewEventBroker(10)
+	sub, _ := b.Subscribe("u1", "")
+
+	b.Unsubscribe(sub)
+	if _, closed := receive(sub); !closed {
+		t.Error("channel is open after Unsubscribe")
+	}
+	if len(b.subscribers) != 0 {
+		t.Errorf("%d subscribers after Unsubscribe, want 0", len(b.subscribers))
+	}
+
+	// 切断後の配信と二重の Unsubscribe で panic しない
+	publishTo(b, "u1", "t1")
+	b.Unsubscribe(sub)
+}
+
+func TestEventBrokerDisconnectsSlowSubscribers(t *testing.T) {
+	b := NewEventBroker(subscriberBufferSize * 2)
+	slow, _ := b.Subscribe("u1", "")
+	fast, _ := b.Subscribe("u1", "")
+
+	// 受信しない購読者がいても、配信は待たずに終わる
+	var fastEvents []StreamEvent
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := 0; i <= subscriberBufferSize; i++ {
+			publishTo(b, "u1", "t1")
+			events, _ := receive(fast)
+			fastEvents = append(fastEvents, events...)
+		}
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("PublishTaskEvent blocked on a slow subscriber")
+	}
+
+	if len(fastEvents) != subscriberBufferSize+1 {
+		t.Errorf("fast subscriber received %d events, want %d", len(fastEvents), subscriberBufferSize+1)
+	}
+	received, closed := receive(slow)
+	if len(received) != subscriberBufferSize || !closed {
+		t.Fatalf("slow subscriber received %d events (closed %t), want %d and disconnected", len(received), closed, subscriberBufferSize)
+	}
+
+	// 切断された購読者は最後に受け取ったIDから再開し、溢れたイベントを取りこぼさない
+	_, resume := b.Subscribe("u1", received[len(received)-1].ID)
+	if resume.Reset || len(resume.Events) != 1 || resume.Events[0].ID != fastEvents[len(fastEvents)-1].ID {
+		t.Errorf("resume = %+v, want the overflowed event", resume)
+	}
+}
+
+func TestEventBrokerResume(t *testing.T) {
+	b := NewEventBroker(2)
+	_, ready := b.Subscribe("u1", "")
+	publishTo(b, "u1", "t1")
+	publishTo(b, "u2", "t2")
+
+	_, resume := b.Subscribe("u1", ready.LastID)
+	if resume.Reset || len(resume.Events) != 1 || resume.Events[0].UserID != "u1" {
+		t.Errorf("resume from the ready ID = %+v, want only the event of u1", resume)
+	}
+
+	// ログから溢れた・再起動前のIDでは再開できない
+	publishTo(b, "u1", "t3")
+	if _, resume := b.Subscribe("u1", ready.LastID); !resume.Reset {
+		t.Errorf("resume from an evicted ID = %+v, want reset", resume)
+	}
+	if _, resume := b.Subscribe("u1", "previous-1"); !resume.Reset {
+		t.Errorf("resume from another epoch = %+v, want reset", resume)
+	}
+}
+
+func TestEventBrokerClose(t *testing.T) {
+	b := NewEventBroker(10)
+	sub, _ := b.Subscribe("u1", "")
+
+	b.Close()
+	if _, closed := receive(sub); !closed {
+		t.Error("channel is open after Close")
+	}
+	late, _ := b.Subscribe("u1", "")
+	if _, closed := receive(late); !closed {
+		t.Error("subscription after Close is open")
+	}
+}
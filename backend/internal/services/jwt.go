@@ -1,17 +1,38 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"todo-app-backend/internal/config"
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// リフレッシュトークンの有効期間
+const refreshTokenTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidRefreshToken 署名不正・失効済み・未登録などで使用できないリフレッシュトークン
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused 使用済みのリフレッシュトークンが再提示された（ファミリーごと失効済み）
+	ErrRefreshTokenReused = fmt.Errorf("%w: token reuse detected", ErrInvalidRefreshToken)
+)
+
 type JWTService struct {
-	secretKey string
-	expiryHours int
+	secretKey        string
+	expiryHours      int
+	refreshTokenRepo repository.RefreshTokenRepository
 }
 
 type Claims struct {
@@ -20,22 +41,32 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-func NewJWTService(cfg *config.Config) *JWTService {
+func NewJWTService(cfg *config.Config, refreshTokenRepo repository.RefreshTokenRepository) *JWTService {
 	return &JWTService{
-		secretKey: cfg.JWTSecret,
-		expiryHours: cfg.JWTExpiryHours,
+		secretKey:        cfg.JWTSecret,
+		expiryHours:      cfg.JWTExpiryHours,
+		refreshTokenRepo: refreshTokenRepo,
 	}
 }
 
+// GenerateTokens 新しいトークンファミリーを開始してトークンペアを発行する
 func (s *JWTService) GenerateTokens(userID string) (string, string, error) {
+	familyID, err := newTokenID()
+	if err != nil {
+		return "", "", err
+	}
+	return s.generateTokenPair(userID, familyID)
+}
+
+func (s *JWTService) generateTokenPair(userID, familyID string) (string, string, error) {
 	// アクセストークンを生成
-	accessToken, err := s.generateToken(userID, "access", time.Duration(s.expiryHours)*time.Hour)
+	accessToken, err := s.generateToken(userID, "access", "", time.Duration(s.expiryHours)*time.Hour)
 	if err != nil {
 		return "", "", err
 	}
 
-	// リフレッシュトークンを生成（7日間有効）
-	refreshToken, err := s.generateToken(userID, "refresh", 7*24*time.Hour)
+	// リフレッシュトークンを生成し、サーバー側に登録
+	refreshToken, err := s.issueRefreshToken(userID, familyID)
 	if err != nil {
 		return "", "", err
 	}
@@ -43,13 +74,15 @@ func (s *JWTService) GenerateTokens(userID string) (string, string, error) {
 	return accessToken, refreshToken, nil
 }
 
-func (s *JWTService) generateToken(userID, tokenType string, duration time.Duration) (string, error) {
+func (s *JWTService) generateToken(userID, tokenType, tokenID string, duration time.Duration) (string, error) {
+	now := time.Now()
 	claims := &Claims{
 		UserID: userID,
 		Type:   tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -57,6 +90,34 @@ func (s *JWTService) generateToken(userID, tokenType string, duration time.Durat
 	return token.SignedString([]byte(s.secretKey))
 }
 
+// issueRefreshToken jti付きのリフレッシュトークンを発行し、ハッシュをDBに保存する
+func (s *JWTService) issueRefreshToken(userID, familyID string) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	refreshToken, err := s.generateToken(userID, "refresh", tokenID, refreshTokenTTL)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	record := &models.RefreshToken{
+		ID:        tokenID,
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(refreshTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.refreshTokenRepo.CreateRefreshToken(record); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
 func (s *JWTService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -76,30 +137,130 @@ func (s *JWTService) ValidateToken(tokenString string) (jwt.MapClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// RefreshAccessToken リフレッシュトークンをローテーションして新しいトークンペアを発行する
+// 提示されたトークンは使用済みとなり、再度提示された場合はファミリー全体を失効させる
 func (s *JWTService) RefreshAccessToken(refreshToken string) (string, string, error) {
-	claims, err := s.ValidateToken(refreshToken)
+	record, err := s.lookupRefreshToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	// 使用済みトークンの再提示は漏洩とみなす
+	if record.UsedAt != nil {
+		return "", "", s.handleRefreshTokenReuse(record)
+	}
+
+	// 失効済み・期限切れのトークンは使用不可
+	now := time.Now().UTC()
+	if record.RevokedAt != nil || now.After(record.ExpiresAt) {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	// 同時に同じトークンが使われた場合は一方だけが成功する
+	marked, err := s.refreshTokenRepo.MarkRefreshTokenUsed(record.ID, now)
 	if err != nil {
 		return "", "", err
 	}
+	if !marked {
+		return "", "", s.handleRefreshTokenReuse(record)
+	}
+
+	// 同じファミリーで新しいトークンペアを生成
+	return s.generateTokenPair(record.UserID, record.FamilyID)
+}
+
+// RevokeRefreshToken リフレッシュトークンのファミリーを失効させる（失効済みの場合も成功扱い）
+func (s *JWTService) RevokeRefreshToken(refreshToken string) error {
+	record, err := s.lookupRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.refreshTokenRepo.RevokeRefreshTokenFamily(record.FamilyID, time.Now().UTC())
+	return err
+}
+
+// handleRefreshTokenReuse 再利用を記録し、ファミリー全体を失効させる
+func (s *JWTService) handleRefreshTokenReuse(record *models.RefreshToken) error {
+	log.Printf("SECURITY: refresh token reuse detected (user_id=%s, family_id=%s, jti=%s)",
+		record.UserID, record.FamilyID, record.ID)
+
+	revoked, err := s.refreshTokenRepo.RevokeRefreshTokenFamily(record.FamilyID, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	log.Printf("SECURITY: revoked %d refresh tokens in family %s", revoked, record.FamilyID)
+
+	return ErrRefreshTokenReused
+}
+
+// lookupRefreshToken トークンを検証し、対応するDBのレコードを返す
+func (s *JWTService) lookupRefreshToken(refreshToken string) (*models.RefreshToken, error) {
+	claims, err := s.ValidateToken(refreshToken)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
 
 	// リフレッシュトークンかチェック
 	tokenType, ok := claims["type"].(string)
 	if !ok || tokenType != "refresh" {
-		return "", "", errors.New("invalid token type")
+		return nil, ErrInvalidRefreshToken
+	}
+
+	tokenID, ok := claims["jti"].(string)
+	if !ok || tokenID == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	record, err := s.refreshTokenRepo.GetRefreshTokenByID(tokenID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	userID, ok := claims["user_id"].(string)
-	if !ok {
-		return "", "", errors.New("invalid user ID")
+	// 保存済みのハッシュと一致するか確認
+	if subtle.ConstantTimeCompare([]byte(record.TokenHash), []byte(hashToken(refreshToken))) != 1 {
+		return nil, ErrInvalidRefreshToken
 	}
 
-	// 新しいトークンペアを生成
-	return s.GenerateTokens(userID)
+	return record, nil
 }
 
-func (s *JWTService) RevokeRefreshToken(refreshToken string) error {
-	// 実際の実装では、リフレッシュトークンのブラックリストを管理する
-	// ここでは簡単な実装として、トークンの有効性をチェックするだけ
-	_, err := s.ValidateToken(refreshToken)
-	return err
+// StartRefreshTokenCleanup 期限切れのリフレッシュトークンを定期的に削除する
+// ctxがキャンセルされるまでバックグラウンドで動作する
+func (s *JWTService) StartRefreshTokenCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			deleted, err := s.refreshTokenRepo.DeleteExpiredRefreshTokens(time.Now().UTC())
+			if err != nil {
+				log.Printf("Failed to purge expired refresh tokens: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Purged %d expired refresh tokens", deleted)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func newTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
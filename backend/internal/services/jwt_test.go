@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"todo-app-backend/internal/config"
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+)
+
+func newTestJWTService(t *testing.T) *JWTService {
+	t.Helper()
+
+	repos := repository.NewMemoryRepositories()
+	now := time.Now()
+	if err := repos.Users.CreateUser(&models.User{ID: "u1", Email: "u1@example.com", Password: "hash", Name: "User", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return NewJWTService(&config.Config{JWTSecret: "test-secret", JWTExpiryHours: 1}, repos.RefreshTokens)
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	s := newTestJWTService(t)
+	_, first, err := s.GenerateTokens("u1")
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+	// 別のログイン（別のファミリー）は巻き込まない
+	_, otherLogin, err := s.GenerateTokens("u1")
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	access, second, err := s.RefreshAccessToken(first)
+	if err != nil || access == "" || second == "" || second == first {
+		t.Fatalf("RefreshAccessToken = %q, %q, %v; want a rotated pair", access, second, err)
+	}
+
+	// 使用済みのトークンの再提示は、ローテーション後のトークンも含めてファミリーごと失効させる
+	if _, _, err := s.RefreshAccessToken(first); !errors.Is(err, ErrRefreshTokenReused) || !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reusing a refresh token = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := s.RefreshAccessToken(second); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refresh after reuse = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	if _, _, err := s.RefreshAccessToken(otherLogin); err != nil {
+		t.Errorf("refresh in another family = %v, want nil", err)
+	}
+}
+
+func TestRefreshAccessTokenRejectsInvalidTokens(t *testing.T) {
+	s := newTestJWTService(t)
+	access, refresh, err := s.GenerateTokens("u1")
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	for name, token := range map[string]string{
+		"access token": access,
+		"tampered":     refresh[:len(refresh)-2] + "xx",
+		"garbage":      "not-a-token",
+	} {
+		if _, _, err := s.RefreshAccessToken(token); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("RefreshAccessToken(%s) = %v, want ErrInvalidRefreshToken", name, err)
+		}
+	}
+
+	// ログアウトで失効したトークンは使えない
+	if err := s.RevokeRefreshToken(refresh); err != nil {
+		t.Fatalf("RevokeRefreshToken: %v", err)
+	}
+	if _, _, err := s.RefreshAccessToken(refresh); !errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("refresh after revoking = %v, want ErrInvalidRefreshToken", err)
+	}
+}
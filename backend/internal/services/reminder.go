@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/notify"
+	"todo-app-backend/internal/repository"
+)
+
+const (
+	// 1つの回・チャネルに配信を試みる最大回数
+	reminderMaxAttempts = 5
+	// 送信中のまま止まった配信（送信中の再起動など）をやり直すまでの時間
+	reminderStaleAfter = 5 * time.Minute
+	// 通知時刻からこれ以上遅れた配信は遅延として通知する
+	reminderLateAfter = 5 * time.Minute
+	// 期限日のリマインダーは期限の前後にずれるため、期限の検索範囲を広げる
+	dueDayMargin = 48 * time.Hour
+)
+
+// ReminderScheduler タスクのリマインダーを評価し、通知チャネルに配信する
+// 配信記録により、再起動をまたいでも同じ回を二重に配信しない
+type ReminderScheduler struct {
+	reminderRepo repository.ReminderRepository
+	notifiers    []notify.Notifier
+	// grace 停止中などで通知時刻を過ぎた回を、どれだけ遅れても配信するか（過ぎたものはスキップする）
+	grace time.Duration
+}
+
+func NewReminderScheduler(reminderRepo repository.ReminderRepository, notifiers []notify.Notifier, grace time.Duration) *ReminderScheduler {
+	return &ReminderScheduler{
+		reminderRepo: reminderRepo,
+		notifiers:    notifiers,
+		grace:        grace,
+	}
+}
+
+// Start 定期的にリマインダーを評価する
+// 起動直後にも評価するため、停止中に通知時刻を迎えた回は猶予の範囲内で配信される
+// ctxがキャンセルされるまでバックグラウンドで動作する
+func (s *ReminderScheduler) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.RunOnce(ctx, time.Now().UTC())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce now までに通知時刻を迎えたリマインダーを配信する
+// 配信済み・スキップ済みなど、既に片付いた回・チャネルには書き込まない
+func (s *ReminderScheduler) RunOnce(ctx context.Context, now time.Time) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+
+	reminders, err := s.reminderRepo.GetDueReminders(now.Add(-s.grace-dueDayMargin), now.Add(models.MaxReminderOffset+dueDayMargin))
+	if err != nil {
+		log.Printf("Failed to load due reminders: %v", err)
+		return
+	}
+
+	// 通知時刻を迎えた回のみを対象にする
+	var due []dueFire
+	var ids []string
+	for i := range reminders {
+		fireAt, err := reminders[i].FireAt(reminders[i].Deadline)
+		if err != nil {
+			log.Printf("Failed to evaluate reminder %s: %v", reminders[i].ID, err)
+			continue
+		}
+		if fireAt.After(now) {
+			continue
+		}
+		due = append(due, dueFire{reminder: &reminders[i], fireAt: fireAt})
+		ids = append(ids, reminders[i].ID)
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	settled, err := s.reminderRepo.GetSettledReminderDeliveries(ids, now.Add(-reminderStaleAfter), reminderMaxAttempts)
+	if err != nil {
+		log.Printf("Failed to load reminder deliveries: %v", err)
+		return
+	}
+	done := make(map[deliveryKey]bool, len(settled))
+	for i := range settled {
+		done[deliveryKeyOf(&settled[i])] = true
+	}
+
+	sent, failed, skipped := 0, 0, 0
+	for _, fire := range due {
+		for _, notifier := range s.notifiers {
+			if ctx.Err() != nil {
+				return
+			}
+			delivery := &models.ReminderDelivery{
+				ReminderID: fire.reminder.ID,
+				FireAt:     fire.fireAt,
+				Channel:    notifier.Name(),
+				UpdatedAt:  now,
+			}
+			if done[deliveryKeyOf(delivery)] {
+				continue
+			}
+
+			if fire.fireAt.Before(now.Add(-s.grace)) {
+				recorded, err := s.reminderRepo.SkipReminderDelivery(delivery)
+				if err != nil {
+					log.Printf("Failed to record skipped reminder %s (%s): %v", fire.reminder.ID, notifier.Name(), err)
+				} else if recorded {
+					skipped++
+				}
+				continue
+			}
+
+			switch s.deliver(ctx, notifier, fire.reminder, delivery, now) {
+			case models.DeliverySent:
+				sent++
+			case models.DeliveryFailed:
+				failed++
+			}
+		}
+	}
+
+	if sent > 0 || failed > 0 || skipped > 0 {
+		log.Printf("Reminders: %d sent, %d failed, %d skipped", sent, failed, skipped)
+	}
+}
+
+// dueFire 通知時刻を迎えたリマインダーの回
+type dueFire struct {
+	reminder *models.DueReminder
+	fireAt   time.Time
+}
+
+// deliveryKey 配信記録の主キー（通知時刻は秒単位で比較する）
+type deliveryKey struct {
+	reminderID string
+	fireAt     int64
+	channel    string
+}
+
+func deliveryKeyOf(delivery *models.ReminderDelivery) deliveryKey {
+	return deliveryKey{delivery.ReminderID, delivery.FireAt.Unix(), delivery.Channel}
+}
+
+// deliver 配信する権利を取得できた場合のみ通知し、結果の状態を返す（配信しなかった場合は空文字列）
+func (s *ReminderScheduler) deliver(ctx context.Context, notifier notify.Notifier, due *models.DueReminder, delivery *models.ReminderDelivery, now time.Time) string {
+	claimed, err := s.reminderRepo.ClaimReminderDelivery(delivery, now.Add(-reminderStaleAfter), reminderMaxAttempts)
+	if err != nil {
+		log.Printf("Failed to claim reminder %s (%s): %v", due.ID, notifier.Name(), err)
+		return ""
+	}
+	if !claimed {
+		return ""
+	}
+
+	err = notifier.Notify(ctx, &notify.Notification{
+		ReminderID: due.ID,
+		TaskID:     due.TaskID,
+		TaskTitle:  due.TaskTitle,
+		Deadline:   due.Deadline,
+		FireAt:     delivery.FireAt,
+		UserID:     due.UserID,
+		UserEmail:  due.UserEmail,
+		Late:       now.Sub(delivery.FireAt) > reminderLateAfter,
+	})
+
+	delivery.Status = models.DeliverySent
+	delivery.LastError = nil
+	if err != nil {
+		log.Printf("Failed to deliver reminder %s (%s): %v", due.ID, notifier.Name(), err)
+		message := err.Error()
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = &message
+	}
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := s.reminderRepo.FinishReminderDelivery(delivery); err != nil {
+		log.Printf("Failed to record reminder delivery %s (%s): %v", due.ID, notifier.Name(), err)
+	}
+	return delivery.Status
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/notify"
+	"todo-app-backend/internal/repository"
+)
+
+// countingReminders 配信記録への書き込みを数える
+type countingReminders struct {
+	repository.ReminderRepository
+	writes int
+}
+
+func (r *countingReminders) ClaimReminderDelivery(delivery *models.ReminderDelivery, staleBefore time.Time, maxAttempts int) (bool, error) {
+	r.writes++
+	return r.ReminderRepository.ClaimReminderDelivery(delivery, staleBefore, maxAttempts)
+}
+
+func (r *countingReminders) SkipReminderDelivery(delivery *models.ReminderDelivery) (bool, error) {
+	r.writes++
+	return r.ReminderRepository.SkipReminderDelivery(delivery)
+}
+
+// stubNotifier 通知を記録し、err を返す
+type stubNotifier struct {
+	name string
+	err  error
+	sent []*notify.Notification
+}
+
+func (n *stubNotifier) Name() string {
+	return n.name
+}
+
+func (n *stubNotifier) Notify(ctx context.Context, notification *notify.Notification) error {
+	n.sent = append(n.sent, notification)
+	return n.err
+}
+
+func TestReminderSchedulerWritesOnlyUnsettledDeliveries(t *testing.T) {
+	repos := repository.NewMemoryRepositories()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := repos.Users.CreateUser(&models.User{ID: "u1", Email: "u1@example.com", Password: "hash", Name: "User", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	newTask := func(id string, deadline time.Time) {
+		t.Helper()
+		task := &models.Task{ID: id, UserID: "u1", Title: id, Status: "pending", Priority: "low", Deadline: &deadline, CreatedAt: now, UpdatedAt: now}
+		if err := repos.Tasks.CreateTask(task); err != nil {
+			t.Fatalf("CreateTask(%s): %v", id, err)
+		}
+		reminder := &models.Reminder{ID: "r-" + id, TaskID: id, Kind: models.ReminderBeforeDeadline, CreatedAt: now}
+		if err := repos.Reminders.CreateReminder(reminder); err != nil {
+			t.Fatalf("CreateReminder(%s): %v", id, err)
+		}
+	}
+	newTask("due", now.Add(-time.Minute))
+	newTask("missed", now.Add(-2*time.Hour)) // 猶予を過ぎている
+	newTask("future", now.Add(time.Hour))
+
+	reminders := &countingReminders{ReminderRepository: repos.Reminders}
+	ok := &stubNotifier{name: "log"}
+	broken := &stubNotifier{name: "webhook", err: errors.New("boom")}
+	scheduler := NewReminderScheduler(reminders, []notify.Notifier{ok, broken}, time.Hour)
+
+	// 1回目: due は両方のチャネルに配信し、missed は両方のチャネルでスキップする
+	scheduler.RunOnce(context.Background(), now)
+	if reminders.writes != 4 || len(ok.sent) != 1 || len(broken.sent) != 1 || ok.sent[0].TaskID != "due" {
+		t.Fatalf("first run: %d writes, sent %d/%d; want 4 writes and one notification per channel", reminders.writes, len(ok.sent), len(broken.sent))
+	}
+
+	// 2回目: 失敗したチャネルだけを再試行し、配信済み・スキップ済みの回には書き込まない
+	reminders.writes = 0
+	scheduler.RunOnce(context.Background(), now.Add(time.Minute))
+	if reminders.writes != 1 || len(ok.sent) != 1 || len(broken.sent) != 2 {
+		t.Errorf("second run: %d writes, sent %d/%d; want only the failed delivery retried", reminders.writes, len(ok.sent), len(broken.sent))
+	}
+
+	// 試行回数の上限に達すると書き込みもしない
+	for i := 2; i < reminderMaxAttempts; i++ {
+		scheduler.RunOnce(context.Background(), now.Add(time.Duration(i)*time.Minute))
+	}
+	reminders.writes = 0
+	scheduler.RunOnce(context.Background(), now.Add(30*time.Minute))
+	if reminders.writes != 0 || len(broken.sent) != reminderMaxAttempts {
+		t.Errorf("after max attempts: %d writes, %d attempts; want 0 writes and %d attempts", reminders.writes, len(broken.sent), reminderMaxAttempts)
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"todo-app-backend/internal/repository"
+)
+
+// TrashPurger 保持期間を過ぎたゴミ箱のタスクを完全に削除する
+type TrashPurger struct {
+	taskRepo  repository.TaskRepository
+	retention time.Duration
+}
+
+func NewTrashPurger(taskRepo repository.TaskRepository, retention time.Duration) *TrashPurger {
+	return &TrashPurger{
+		taskRepo:  taskRepo,
+		retention: retention,
+	}
+}
+
+// Start 定期的にゴミ箱を確認する（保持期間が 0 以下なら何もしない）
+// ctxがキャンセルされるまでバックグラウンドで動作する
+func (p *TrashPurger) Start(ctx context.Context, interval time.Duration) {
+	if p.retention <= 0 {
+		log.Println("Trash retention is disabled; deleted tasks are kept until purged")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			p.RunOnce(time.Now().UTC())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// RunOnce now から保持期間より前にゴミ箱に移したタスクを削除する
+func (p *TrashPurger) RunOnce(now time.Time) {
+	purged, err := p.taskRepo.PurgeDeletedTasks(now.Add(-p.retention))
+	if err != nil {
+		log.Printf("Failed to purge deleted tasks: %v", err)
+	} else if purged > 0 {
+		log.Printf("Purged %d deleted tasks", purged)
+	}
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+	"todo-app-backend/internal/utils"
+)
+
+// 送信時に付けるヘッダー
+const (
+	// WebhookSignatureHeader "sha256=" に続けて、"<タイムスタンプ>.<本文>" の HMAC-SHA256 を16進数で表したもの
+	WebhookSignatureHeader = "X-Todo-Signature"
+	// WebhookTimestampHeader 署名したUNIX時刻（秒）。受信側は古すぎるものを拒否して再送攻撃を防ぐ
+	WebhookTimestampHeader = "X-Todo-Timestamp"
+	WebhookEventHeader     = "X-Todo-Event"
+	WebhookDeliveryHeader  = "X-Todo-Delivery"
+)
+
+const (
+	// 1つの配信を試みる最大回数（30秒から倍々に間隔を空け、約1時間で諦める）
+	webhookMaxAttempts = 8
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+	// 1回の送信のタイムアウト
+	webhookTimeout = 10 * time.Second
+	// 送信中に停止した配信を再送するまでの時間（タイムアウトより長くする）
+	webhookLease = time.Minute
+	// 1回の処理で送信する配信の上限
+	webhookBatchSize = 50
+	// 同時に送信する配信の数（応答の遅い送信先が他の配信を待たせないようにする）
+	webhookWorkers = 8
+	// 接続を再利用するために読み捨てるレスポンス本文の上限
+	webhookDrainLimit = 4096
+)
+
+// WebhookDispatcher タスクの変更イベントを Webhook の配信ログに積み、バックグラウンドで送信する
+// 配信ログをキューとして使うため、再起動しても送信待ちの配信は失われない
+type WebhookDispatcher struct {
+	webhookRepo repository.WebhookRepository
+	client      *http.Client
+	// wake 新しい配信を積んだときに、次の定期実行を待たずに送信させる
+	wake chan struct{}
+}
+
+func NewWebhookDispatcher(webhookRepo repository.WebhookRepository) *WebhookDispatcher {
+	// 接続する直前に送信先のアドレスを確認する（名前解決の結果を差し替える DNS リバインディングも防ぐ）
+	return newWebhookDispatcher(webhookRepo, webhookDialControl)
+}
+
+// newWebhookDispatcher 接続先の確認を差し替えて作成する（テストでループバックの送信先に接続するため）
+func newWebhookDispatcher(webhookRepo repository.WebhookRepository, dialControl func(network, address string, c syscall.RawConn) error) *WebhookDispatcher {
+	// プロキシを経由すると接続先を確認できないため、環境変数のプロキシは使わない
+	dialer := &net.Dialer{Timeout: webhookTimeout, Control: dialControl}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		webhookRepo: webhookRepo,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   webhookTimeout,
+			// リダイレクト先には送信しない（3xx は失敗として扱う）
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// PublishTaskEvent イベントを購読している有効な Webhook ごとに配信を積む
+// 配信の登録に失敗してもタスクの変更は取り消さず、ログに残す
+func (d *WebhookDispatcher) PublishTaskEvent(event *models.TaskEvent) {
+	webhooks, err := d.webhookRepo.GetWebhooksByUserID(event.UserID)
+	if err != nil {
+		log.Printf("Failed to load webhooks for %s: %v", event.Type, err)
+		return
+	}
+
+	var payload []byte
+	queued := 0
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if !webhook.Active || !webhook.Subscribes(event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				log.Printf("Failed to encode %s event: %v", event.Type, err)
+				return
+			}
+		}
+
+		nextAttemptAt := event.OccurredAt.UTC()
+		delivery := &models.WebhookDelivery{
+			ID:            utils.GenerateID(),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			Event:         event.Type,
+			Payload:       payload,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &nextAttemptAt,
+			CreatedAt:     event.OccurredAt,
+			UpdatedAt:     event.OccurredAt,
+		}
+		if err := d.webhookRepo.CreateWebhookDelivery(delivery); err != nil {
+			log.Printf("Failed to queue %s for webhook %s: %v", event.Type, webhook.ID, err)
+			continue
+		}
+		queued++
+	}
+	if queued > 0 {
+		d.notify()
+	}
+}
+
+// Replay 配信済み・失敗した配信と同じイベントを、新しい配信としてもう一度送信する
+// イベントのID（event_id）と本文は元の配信と同じになる
+func (d *WebhookDispatcher) Replay(original *models.WebhookDelivery, now time.Time) (*models.WebhookDelivery, error) {
+	nextAttemptAt := now.UTC()
+	delivery := &models.WebhookDelivery{
+		ID:            utils.GenerateID(),
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &nextAttemptAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := d.webhookRepo.CreateWebhookDelivery(delivery); err != nil {
+		return nil, err
+	}
+	d.notify()
+	return delivery, nil
+}
+
+func (d *WebhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start 送信時刻を迎えた配信を定期的に送信する
+// 新しい配信が積まれた場合は定期実行を待たずに送信する
+// ctxがキャンセルされるまでバックグラウンドで動作する
+func (d *WebhookDispatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			d.RunOnce(ctx, time.Now().UTC())
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-d.wake:
+			}
+		}
+	}()
+}
+
+// RunOnce now までに送信時刻を迎えた配信を、最大 webhookWorkers 件ずつ並行して送信する
+// すべての送信が終わるまで戻らない
+func (d *WebhookDispatcher) RunOnce(ctx context.Context, now time.Time) {
+	deliveries, err := d.webhookRepo.GetDueWebhookDeliveries(now, webhookBatchSize)
+	if err != nil {
+		log.Printf("Failed to load webhook deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	workers := make(chan struct{}, webhookWorkers)
+	webhooks := map[string]*models.Webhook{}
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		delivery := &deliveries[i]
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			if webhook, err = d.webhookRepo.GetWebhookByID(delivery.WebhookID); err != nil {
+				log.Printf("Failed to load webhook %s: %v", delivery.WebhookID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		// 空きを待ってから確保し、送信を待つ間にリースが切れないようにする
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		claimed, err := d.webhookRepo.ClaimWebhookDelivery(delivery.ID, now, now.Add(webhookLease))
+		if err != nil || !claimed {
+			if err != nil {
+				log.Printf("Failed to claim webhook delivery %s: %v", delivery.ID, err)
+			}
+			<-workers
+			continue
+		}
+		delivery.Attempts++
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			d.deliver(ctx, webhook, delivery)
+		}()
+	}
+}
+
+// deliver 1回送信し、結果に応じて成功・再送待ち・失敗を記録する
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	statusCode, err := d.send(ctx, webhook, delivery)
+	finishedAt := time.Now().UTC()
+
+	delivery.ResponseStatus = statusCode
+	delivery.UpdatedAt = finishedAt
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.LastError = nil
+		delivery.DeliveredAt = &finishedAt
+	case delivery.Attempts >= webhookMaxAttempts:
+		message := err.Error()
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = &message
+		log.Printf("Webhook delivery %s to %s failed after %d attempts: %v", delivery.ID, webhook.URL, delivery.Attempts, err)
+	default:
+		message := err.Error()
+		nextAttemptAt := finishedAt.Add(webhookBackoff(delivery.Attempts))
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.NextAttemptAt = &nextAttemptAt
+		delivery.LastError = &message
+	}
+
+	if err := d.webhookRepo.FinishWebhookDelivery(delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send 署名を付けて POST し、レスポンスのステータスコードを返す（2xx 以外はエラー）
+func (d *WebhookDispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (*int, error) {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// レスポンスの本文は配信ログに残さない（送信先の内容を利用者に返さない）
+	io.Copy(io.Discard, io.LimitReader(resp.Body, webhookDrainLimit))
+	statusCode := resp.StatusCode
+	if statusCode < 200 || statusCode >= 300 {
+		return &statusCode, fmt.Errorf("unexpected status %d", statusCode)
+	}
+	return &statusCode, nil
+}
+
+// webhookDialControl 接続先がループバック・プライベートなど内部のアドレスなら接続しない
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !models.IsPublicWebhookAddr(addrPort.Addr()) {
+		return fmt.Errorf("destination %s is not allowed", addrPort.Addr())
+	}
+	return nil
+}
+
+// SignWebhookPayload 送信する本文の署名ヘッダーの値を計算する
+// 受信側は同じ計算をして hmac.Equal で比較する
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff attempts 回目の送信に失敗した後、次に送信するまでの間隔
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"todo-app-backend/internal/models"
+	"todo-app-backend/internal/repository"
+)
+
+const testWebhookSecret = "whsec_test"
+
+func TestSignWebhookPayload(t *testing.T) {
+	// HMAC-SHA256("whsec_test", `1700000000.{"id":"evt_1"}`)
+	want := "sha256=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+	if got := SignWebhookPayload(testWebhookSecret, 1700000000, []byte(`{"id":"evt_1"}`)); got != want {
+		t.Errorf("SignWebhookPayload = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// webhookReceiver 受け取った配信を記録し、status で応答する送信先
+type webhookReceiver struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	deliveryID string
+	body       string
+}
+
+func newWebhookReceiver(t *testing.T, status int) *webhookReceiver {
+	r := &webhookReceiver{t: t, status: status}
+	r.server = httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *webhookReceiver) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	timestamp, err := strconv.ParseInt(req.Header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil || req.Header.Get(WebhookSignatureHeader) != SignWebhookPayload(testWebhookSecret, timestamp, body) {
+		r.t.Errorf("request %s has an invalid signature", req.Header.Get(WebhookDeliveryHeader))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, receivedWebhook{deliveryID: req.Header.Get(WebhookDeliveryHeader), body: string(body)})
+	w.WriteHeader(r.status)
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+// newWebhookTest 送信先を登録し、task.created のイベントを1件積む
+func newWebhookTest(t *testing.T, url string, dispatcher func(repository.WebhookRepository) *WebhookDispatcher) (*WebhookDispatcher, repository.WebhookRepository) {
+	t.Helper()
+
+	repos := repository.NewMemoryRepositories()
+	now := time.Now().UTC()
+	if err := repos.Users.CreateUser(&models.User{ID: "u1", Email: "u1@example.com", Password: "hash", Name: "User", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	webhook := &models.Webhook{ID: "w1", UserID: "u1", URL: url, Events: []string{models.EventTaskCreated}, Secret: testWebhookSecret, Active: true, CreatedAt: now, UpdatedAt: now}
+	if err := repos.Webhooks.CreateWebhook(webhook); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	d := dispatcher(repos.Webhooks)
+	d.PublishTaskEvent(&models.TaskEvent{ID: "evt_1", Type: models.EventTaskCreated, UserID: "u1", Task: &models.Task{ID: "t1", Title: "t"}, OccurredAt: now})
+	return d, repos.Webhooks
+}
+
+// allowLoopback テストの送信先（httptest のループバック）に接続できるよう、接続先を確認しない
+func allowLoopback(repo repository.WebhookRepository) *WebhookDispatcher {
+	return newWebhookDispatcher(repo, nil)
+}
+
+// onlyDelivery 配信ログが1件であることを確認して返す
+func onlyDelivery(t *testing.T, repo repository.WebhookRepository) *models.WebhookDelivery {
+	t.Helper()
+
+	deliveries, err := repo.GetWebhookDeliveries("w1", "", 10)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("GetWebhookDeliveries = %+v, %v; want one delivery", deliveries, err)
+	}
+	return &deliveries[0]
+}
+
+func TestWebhookDispatcherRetriesUntilSuccess(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	d, repo := newWebhookTest(t, receiver.server.URL, allowLoopback)
+
+	before := time.Now().UTC()
+	d.RunOnce(context.Background(), time.Now().UTC())
+	delivery := onlyDelivery(t, repo)
+	if delivery.Status != models.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("after a 500 = %+v, want pending after 1 attempt with status 500", delivery)
+	}
+	if delivery.LastError == nil || !strings.Contains(*delivery.LastError, "500") {
+		t.Errorf("last error = %v, want the unexpected status", delivery.LastError)
+	}
+	if delivery.NextAttemptAt == nil || delivery.NextAttemptAt.Before(before.Add(webhookBaseBackoff)) {
+		t.Errorf("next attempt at %v, want at least %v after the attempt", delivery.NextAttemptAt, webhookBaseBackoff)
+	}
+
+	// 送信時刻を迎えるまでは再送しない
+	d.RunOnce(context.Background(), time.Now().UTC())
+	if got := len(receiver.received()); got != 1 {
+		t.Fatalf("requests before the next attempt = %d, want 1", got)
+	}
+
+	receiver.setStatus(http.StatusNoContent)
+	d.RunOnce(context.Background(), delivery.NextAttemptAt.Add(time.Second))
+	delivery = onlyDelivery(t, repo)
+	if delivery.Status != models.WebhookDeliverySucceeded || delivery.Attempts != 2 || delivery.NextAttemptAt != nil || delivery.LastError != nil || delivery.DeliveredAt == nil {
+		t.Errorf("after a 204 = %+v, want succeeded after 2 attempts", delivery)
+	}
+	requests := receiver.received()
+	if len(requests) != 2 || requests[0].deliveryID != delivery.ID || requests[1].body != requests[0].body {
+		t.Errorf("requests = %+v, want the same delivery sent twice", requests)
+	}
+}
+
+func TestWebhookDispatcherFailsAfterMaxAttempts(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusBadGateway)
+	d, repo := newWebhookTest(t, receiver.server.URL, allowLoopback)
+
+	at := time.Now().UTC()
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		d.RunOnce(context.Background(), at)
+		delivery := onlyDelivery(t, repo)
+		if delivery.Attempts != attempt {
+			t.Fatalf("attempts = %d, want %d", delivery.Attempts, attempt)
+		}
+		if attempt < webhookMaxAttempts {
+			if delivery.Status != models.WebhookDeliveryPending || delivery.NextAttemptAt == nil {
+				t.Fatalf("after attempt %d = %+v, want pending", attempt, delivery)
+			}
+			at = delivery.NextAttemptAt.Add(time.Second)
+		}
+	}
+
+	delivery := onlyDelivery(t, repo)
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.NextAttemptAt != nil || delivery.LastError == nil {
+		t.Errorf("after %d attempts = %+v, want failed", webhookMaxAttempts, delivery)
+	}
+	d.RunOnce(context.Background(), at.Add(24*time.Hour))
+	if got := len(receiver.received()); got != webhookMaxAttempts {
+		t.Errorf("requests = %d, want %d", got, webhookMaxAttempts)
+	}
+}
+
+func TestWebhookDispatcherRejectsLoopback(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	d, repo := newWebhookTest(t, receiver.server.URL, NewWebhookDispatcher)
+
+	d.RunOnce(context.Background(), time.Now().UTC())
+	delivery := onlyDelivery(t, repo)
+	if delivery.Status != models.WebhookDeliveryPending || delivery.ResponseStatus != nil || delivery.LastError == nil || !strings.Contains(*delivery.LastError, "not allowed") {
+		t.Errorf("delivery to a loopback address = %+v, want a failed attempt without a response", delivery)
+	}
+	if got := len(receiver.received()); got != 0 {
+		t.Errorf("requests = %d, want 0", got)
+	}
+}
+
+func TestWebhookDispatcherReplayKeepsEventID(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	d, repo := newWebhookTest(t, receiver.server.URL, allowLoopback)
+	d.RunOnce(context.Background(), time.Now().UTC())
+	original := onlyDelivery(t, repo)
+
+	replay, err := d.Replay(original, time.Now())
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if replay.ID == original.ID || replay.EventID != "evt_1" || replay.EventID != original.EventID || string(replay.Payload) != string(original.Payload) {
+		t.Errorf("replay = %+v, want a new delivery of event evt_1 with the same payload", replay)
+	}
+
+	d.RunOnce(context.Background(), time.Now().UTC())
+	requests := receiver.received()
+	if len(requests) != 2 || requests[1].deliveryID != replay.ID || requests[1].body != requests[0].body || !strings.Contains(requests[1].body, `"id":"evt_1"`) {
+		t.Errorf("requests = %+v, want the replay to resend event evt_1 as a new delivery", requests)
+	}
+}
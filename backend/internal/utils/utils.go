@@ -1,8 +1,8 @@
 package utils
 
-import "time"
+import "github.com/oklog/ulid/v2"
 
-// GenerateID generates a unique ID based on current timestamp
+// GenerateID generates a unique, time-sortable ID (ULID, monotonic within the same millisecond)
 func GenerateID() string {
-	return time.Now().Format("20060102150405")
+	return ulid.Make().String()
 }
@@ -1,6 +1,6 @@
 'use client';
 
-import { useState, useEffect } from 'react';
+import { useState, useEffect, useRef } from 'react';
 import { useRouter } from 'next/navigation';
 import { 
   Card, 
@@ -14,7 +14,8 @@ import {
   message, 
   Space, 
   Tag,
-  Popconfirm 
+  Popconfirm,
+  Alert
 } from 'antd';
 import { 
   PlusOutlined, 
@@ -22,22 +23,78 @@ import {
   DeleteOutlined, 
   LogoutOutlined,
   CheckOutlined,
-  UndoOutlined
+  UndoOutlined,
+  RestOutlined,
+  HistoryOutlined
 } from '@ant-design/icons';
 import { apiClient } from '@/lib/api';
 import { useAuth } from '@/lib/auth';
-import { Task, CreateTaskRequest, UpdateTaskRequest } from '@/types';
+import { CollabClient } from '@/lib/collab';
+import { Task, CreateTaskRequest, UpdateTaskRequest, TaskMergePatch, TaskStreamEvent, PresenceMember, TaskHistoryEntry, TaskFieldChange, TaskSearchResult, View } from '@/types';
 import dayjs from 'dayjs';
 
 const { TextArea } = Input;
 const { Option } = Select;
 
+// 変更履歴の表示名
+const historyActionLabels: Record<TaskHistoryEntry['action'], string> = {
+  created: '作成',
+  updated: '更新',
+  deleted: 'ゴミ箱に移動',
+  restored: 'ゴミ箱から復元',
+  reverted: '以前の版に戻す',
+};
+
+const historyFieldLabels: Record<string, string> = {
+  title: 'タイトル',
+  description: '説明',
+  deadline: '期限',
+  priority: '優先度',
+  status: 'ステータス',
+  parent_id: '親タスク',
+  tags: 'タグ',
+  rrule: '繰り返し',
+  timezone: 'タイムゾーン',
+  project_id: 'プロジェクト',
+  deleted_at: '削除日時',
+};
+
+// 変更履歴の値を表示用の文字列にする
+const formatHistoryValue = (field: string, value: unknown) => {
+  if (value === null || value === undefined) return 'なし';
+  if (Array.isArray(value)) return value.length > 0 ? value.join(', ') : 'なし';
+  if ((field === 'deadline' || field === 'deleted_at') && typeof value === 'string') {
+    return dayjs(value).format('YYYY/MM/DD HH:mm');
+  }
+  return String(value);
+};
+
+// ほかの変更で更新されていたため、If-Match の版数が一致せずに 412 になったか
+const isConflict = (error: unknown) =>
+  error instanceof Error && error.message === 'Task has been modified';
+
 export default function TasksPage() {
   const [tasks, setTasks] = useState<Task[]>([]);
   const [loading, setLoading] = useState(false);
   const [modalVisible, setModalVisible] = useState(false);
   const [editingTask, setEditingTask] = useState<Task | null>(null);
   const [showCompleted, setShowCompleted] = useState(false); // 完了タスクの表示制御
+  const [selectedTaskIds, setSelectedTaskIds] = useState<string[]>([]); // まとめて操作する未完了タスク
+  const [trashVisible, setTrashVisible] = useState(false);
+  const [trashTasks, setTrashTasks] = useState<Task[]>([]);
+  const [historyTask, setHistoryTask] = useState<Task | null>(null); // 変更履歴を表示しているタスク
+  const [historyEntries, setHistoryEntries] = useState<TaskHistoryEntry[]>([]);
+  const filterQueryRef = useRef(''); // 一覧に適用しているフィルター式（イベントの購読からも参照する）
+  const [views, setViews] = useState<View[]>([]);
+  const [activeView, setActiveView] = useState<View | null>(null); // 一覧に適用している保存したビュー
+  const activeViewRef = useRef<View | null>(null);
+  const [saveViewVisible, setSaveViewVisible] = useState(false);
+  const [viewName, setViewName] = useState('');
+  const [searchQuery, setSearchQuery] = useState(''); // 検索中の文字列（空なら検索結果を表示しない）
+  const [searchResults, setSearchResults] = useState<TaskSearchResult[]>([]);
+  const [searching, setSearching] = useState(false);
+  const [otherEditors, setOtherEditors] = useState<PresenceMember[]>([]); // 編集中のタスクを他のタブ・端末で編集している接続
+  const collabRef = useRef<CollabClient | null>(null);
   const [form] = Form.useForm();
   const router = useRouter();
   const { user, logout, loading: authLoading } = useAuth();
@@ -46,13 +103,15 @@ export default function TasksPage() {
   const fetchTasks = async () => {
     setLoading(true);
     try {
-      const response = await apiClient.getTasks();
-      if (response.success) {
-        // データがnullの場合は空配列を設定
-        setTasks(response.data || []);
+      const query = filterQueryRef.current;
+      if (activeViewRef.current) {
+        setTasks(await apiClient.getAllViewTasks(activeViewRef.current.id));
+      } else {
+        setTasks(await apiClient.getAllTasks(query ? { query } : {}));
       }
     } catch (error) {
-      message.error('タスクの取得に失敗しました');
+      // フィルター式の構文エラーは位置を含むメッセージをそのまま表示する
+      message.error(filterQueryRef.current && error instanceof Error ? error.message : 'タスクの取得に失敗しました');
       console.error('Fetch tasks error:', error);
       setTasks([]); // エラー時も空配列を設定
     } finally {
@@ -88,15 +147,16 @@ export default function TasksPage() {
     if (!editingTask) return;
 
     try {
-      const updateData: UpdateTaskRequest = {
+      // 空にした説明・期限は null で消す
+      const patch: TaskMergePatch = {
         title: values.title,
-        description: values.description || undefined,
-        deadline: values.deadline ? values.deadline.toDate() : undefined,
+        description: values.description || null,
+        deadline: values.deadline ? values.deadline.toDate() : null,
         priority: values.priority,
         status: values.status,
       };
 
-      const response = await apiClient.updateTask(editingTask.id, updateData);
+      const response = await apiClient.patchTask(editingTask.id, editingTask.version, patch);
       if (response.success && response.data) {
         message.success('タスクを更新しました');
         setModalVisible(false);
@@ -105,25 +165,215 @@ export default function TasksPage() {
         fetchTasks();
       }
     } catch (error) {
+      if (isConflict(error)) {
+        message.warning('ほかの変更でタスクが更新されていたため、最新の内容を読み込みました');
+        fetchTasks();
+        return;
+      }
       message.error('タスクの更新に失敗しました');
       console.error('Update task error:', error);
     }
   };
 
   // タスクを削除
-  const handleDeleteTask = async (taskId: string) => {
+  const handleDeleteTask = async (task: Task) => {
     try {
-      const response = await apiClient.deleteTask(taskId);
+      const response = await apiClient.deleteTask(task.id, task.version);
       if (response.success) {
-        message.success('タスクを削除しました');
+        message.success('タスクをゴミ箱に移しました');
         fetchTasks();
       }
     } catch (error) {
+      if (isConflict(error)) {
+        message.warning('ほかの変更でタスクが更新されていたため、最新の内容を読み込みました');
+        fetchTasks();
+        return;
+      }
       message.error('タスクの削除に失敗しました');
       console.error('Delete task error:', error);
     }
   };
 
+  // 選択したタスクをまとめて完了にする・ゴミ箱に移す（失敗したタスクのみ選択したまま残す）
+  const handleBatch = async (op: 'complete' | 'delete') => {
+    const selected = tasks.filter((task) => selectedTaskIds.includes(task.id));
+    try {
+      const response = await apiClient.batchTasks(
+        selected.map((task) => ({ op, id: task.id, version: task.version }))
+      );
+      const failed = response.data.filter((result) => result.error);
+      setSelectedTaskIds(failed.map((result) => result.id!));
+      const label = op === 'complete' ? '完了にしました' : 'ゴミ箱に移しました';
+      if (failed.length > 0) {
+        message.warning(`${selected.length - failed.length}件を${label}（${failed.length}件は失敗しました）`);
+      } else {
+        message.success(`${selected.length}件を${label}`);
+      }
+      fetchTasks();
+    } catch (error) {
+      message.error('タスクの一括操作に失敗しました');
+      console.error('Batch tasks error:', error);
+    }
+  };
+
+  // 一覧にフィルター式を適用する（空なら解除）
+  const handleFilter = (value: string) => {
+    filterQueryRef.current = value.trim();
+    fetchTasks();
+  };
+
+  // 保存したビューを取得
+  const fetchViews = async () => {
+    try {
+      const response = await apiClient.getViews();
+      setViews(response.data ?? []);
+    } catch (error) {
+      console.error('Fetch views error:', error);
+    }
+  };
+
+  // 保存したビューを一覧に適用する（未選択なら解除）
+  const selectView = (viewId?: string) => {
+    const view = views.find((v) => v.id === viewId) ?? null;
+    activeViewRef.current = view;
+    setActiveView(view);
+    if (view) {
+      setShowCompleted(view.display.show_completed);
+    }
+    fetchTasks();
+  };
+
+  // 現在のフィルター式と表示方法をビューとして保存する
+  const handleSaveView = async () => {
+    try {
+      const query = filterQueryRef.current;
+      const response = await apiClient.createView({
+        name: viewName,
+        filters: query ? { query } : {},
+        display: { show_completed: showCompleted },
+      });
+      message.success('ビューを保存しました');
+      setSaveViewVisible(false);
+      setViewName('');
+      await fetchViews();
+      if (response.data) {
+        activeViewRef.current = response.data;
+        setActiveView(response.data);
+      }
+    } catch (error) {
+      message.error(error instanceof Error ? error.message : 'ビューを保存できませんでした');
+      console.error('Save view error:', error);
+    }
+  };
+
+  // 選択中のビューを削除する
+  const handleDeleteView = async () => {
+    if (!activeView) return;
+    try {
+      await apiClient.deleteView(activeView.id);
+      message.success('ビューを削除しました');
+      activeViewRef.current = null;
+      setActiveView(null);
+      fetchViews();
+      fetchTasks();
+    } catch (error) {
+      message.error('ビューを削除できませんでした');
+      console.error('Delete view error:', error);
+    }
+  };
+
+  // タスクを全文検索する
+  const handleSearch = async (value: string) => {
+    const q = value.trim();
+    setSearchQuery(q);
+    if (!q) {
+      setSearchResults([]);
+      return;
+    }
+    setSearching(true);
+    try {
+      const response = await apiClient.searchTasks(q);
+      setSearchResults(response.data ?? []);
+    } catch (error) {
+      message.error('タスクの検索に失敗しました');
+      console.error('Search tasks error:', error);
+    } finally {
+      setSearching(false);
+    }
+  };
+
+  // ゴミ箱を開く
+  const openTrash = async () => {
+    setTrashVisible(true);
+    try {
+      const response = await apiClient.getTrash();
+      setTrashTasks(response.data ?? []);
+    } catch (error) {
+      message.error('ゴミ箱の取得に失敗しました');
+      console.error('Fetch trash error:', error);
+    }
+  };
+
+  // ゴミ箱のタスクを元に戻す
+  const handleRestoreTask = async (taskId: string) => {
+    try {
+      await apiClient.restoreTask(taskId);
+      message.success('タスクを元に戻しました');
+      setTrashTasks((current) => current.filter((t) => t.id !== taskId));
+      fetchTasks();
+    } catch (error) {
+      message.error('タスクを元に戻せませんでした');
+      console.error('Restore task error:', error);
+    }
+  };
+
+  // ゴミ箱のタスクを完全に削除する
+  const handlePurgeTask = async (taskId: string) => {
+    try {
+      await apiClient.purgeTask(taskId);
+      message.success('タスクを完全に削除しました');
+      setTrashTasks((current) => current.filter((t) => t.id !== taskId));
+    } catch (error) {
+      message.error('タスクの削除に失敗しました');
+      console.error('Purge task error:', error);
+    }
+  };
+
+  // タスクの変更履歴を開く
+  const openHistory = async (task: Task) => {
+    setHistoryTask(task);
+    setHistoryEntries([]);
+    try {
+      const response = await apiClient.getTaskHistory(task.id);
+      setHistoryEntries(response.data ?? []);
+    } catch (error) {
+      message.error('変更履歴の取得に失敗しました');
+      console.error('Fetch task history error:', error);
+    }
+  };
+
+  // タスクを履歴の版の内容に戻す
+  const handleRevertTask = async (entry: TaskHistoryEntry) => {
+    if (!historyTask) return;
+    try {
+      const response = await apiClient.revertTask(historyTask.id, historyTask.version, entry.version);
+      message.success(`版 ${entry.version} の内容に戻しました`);
+      if (response.data) {
+        openHistory(response.data);
+      }
+      fetchTasks();
+    } catch (error) {
+      if (isConflict(error)) {
+        message.warning('ほかの変更でタスクが更新されています。最新の状態を読み込みました');
+        fetchTasks();
+        setHistoryTask(null);
+      } else {
+        message.error('タスクを戻せませんでした');
+      }
+      console.error('Revert task error:', error);
+    }
+  };
+
   // タスクのステータスを切り替え
   const handleToggleTaskStatus = async (task: Task) => {
     try {
@@ -136,12 +386,17 @@ export default function TasksPage() {
         status: newStatus,
       };
 
-      const response = await apiClient.updateTask(task.id, updateData);
+      const response = await apiClient.updateTask(task.id, task.version, updateData);
       if (response.success) {
         message.success(`タスクを${newStatus === 'completed' ? '完了' : '未完了'}に変更しました`);
         fetchTasks();
       }
     } catch (error) {
+      if (isConflict(error)) {
+        message.warning('ほかの変更でタスクが更新されていたため、最新の内容を読み込みました');
+        fetchTasks();
+        return;
+      }
       message.error('タスクのステータス変更に失敗しました');
       console.error('Toggle task status error:', error);
     }
@@ -260,9 +515,16 @@ export default function TasksPage() {
           >
             編集
           </Button>
+          <Button
+            type="link"
+            icon={<HistoryOutlined />}
+            onClick={() => openHistory(record)}
+          >
+            履歴
+          </Button>
           <Popconfirm
-            title="このタスクを削除しますか？"
-            onConfirm={() => handleDeleteTask(record.id)}
+            title="このタスクをゴミ箱に移しますか？"
+            onConfirm={() => handleDeleteTask(record)}
             okText="削除"
             cancelText="キャンセル"
           >
@@ -291,8 +553,72 @@ export default function TasksPage() {
     }
     
     fetchTasks();
+    fetchViews();
   }, [user, router, authLoading]);
 
+  // 他のタブや端末での変更を一覧に反映する
+  useEffect(() => {
+    if (authLoading || !user) {
+      return;
+    }
+
+    const applyEvent = (event: TaskStreamEvent) => {
+      // フィルター式・ビューで絞り込んでいる場合、一致するかはサーバーで判定する
+      if (filterQueryRef.current || activeViewRef.current) {
+        fetchTasks();
+        return;
+      }
+      const task = event.data;
+      setTasks((current) => {
+        switch (event.type) {
+          case 'task.created':
+          case 'task.restored':
+            return current.some((t) => t.id === task.id) ? current : [...current, task];
+          case 'task.deleted':
+            return current.filter((t) => t.id !== task.id);
+          default:
+            return current.map((t) => (t.id === task.id ? task : t));
+        }
+      });
+    };
+
+    return apiClient.subscribeEvents(applyEvent, fetchTasks);
+  }, [user, authLoading]);
+
+  // 共同編集の接続（編集中のタスクのプレゼンスに使う）
+  useEffect(() => {
+    if (authLoading || !user) {
+      return;
+    }
+
+    const collab = new CollabClient();
+    collab.connect();
+    collabRef.current = collab;
+    return () => {
+      collab.close();
+      collabRef.current = null;
+    };
+  }, [user, authLoading]);
+
+  // 編集モーダルを開いている間はタスクを編集中として参加し、他の接続の編集状況を表示する
+  const editingTaskId = modalVisible ? editingTask?.id : undefined;
+  useEffect(() => {
+    const collab = collabRef.current;
+    if (!collab || !editingTaskId) {
+      return;
+    }
+
+    const topic = `task:${editingTaskId}`;
+    collab.subscribe(topic, (members) => {
+      setOtherEditors(members.filter((m) => m.state === 'editing' && m.session_id !== collab.sessionId));
+    });
+    collab.setPresence(topic, 'editing');
+    return () => {
+      collab.unsubscribe(topic);
+      setOtherEditors([]);
+    };
+  }, [editingTaskId]);
+
   // 認証のローディング中は何も表示しない
   if (authLoading) {
     return (
@@ -333,6 +659,12 @@ export default function TasksPage() {
              >
                {showCompleted ? '未完了タスクを表示' : '完了済みタスクを表示'}
              </Button>
+             <Button
+               icon={<RestOutlined />}
+               onClick={openTrash}
+             >
+               ゴミ箱
+             </Button>
              <Button
                icon={<LogoutOutlined />}
                onClick={handleLogout}
@@ -344,17 +676,116 @@ export default function TasksPage() {
 
         {/* タスク一覧 */}
         <div className="space-y-6">
+          {/* フィルター式・全文検索 */}
+          <Card>
+            <Space className="mb-4" wrap>
+              <Select
+                style={{ width: 240 }}
+                placeholder="保存したビュー"
+                allowClear
+                value={activeView?.id}
+                onChange={(value?: string) => selectView(value)}
+                options={views.map((view) => ({ value: view.id, label: view.name }))}
+              />
+              <Button onClick={() => setSaveViewVisible(true)} disabled={activeView !== null}>
+                ビューとして保存
+              </Button>
+              {activeView && (
+                <Popconfirm
+                  title="このビューを削除しますか？（タスクは削除されません）"
+                  onConfirm={handleDeleteView}
+                  okText="削除"
+                  cancelText="キャンセル"
+                >
+                  <Button danger>ビューを削除</Button>
+                </Popconfirm>
+              )}
+            </Space>
+            <Input.Search
+              className="mb-4"
+              placeholder="フィルター式（例: priority:high status:pending due:<7d tag:backend -tag:blocked）"
+              allowClear
+              enterButton="絞り込み"
+              disabled={activeView !== null}
+              onSearch={handleFilter}
+            />
+            <Input.Search
+              placeholder='タイトル・説明を検索（"..." でフレーズ、末尾の * で前方一致）'
+              allowClear
+              enterButton
+              loading={searching}
+              onSearch={handleSearch}
+            />
+            {searchQuery && (
+              <Table
+                className="mt-4"
+                dataSource={searchResults}
+                rowKey={(record: TaskSearchResult) => record.task.id}
+                loading={searching}
+                pagination={false}
+                locale={{ emptyText: '一致するタスクはありません' }}
+                columns={[
+                  {
+                    title: 'タイトル',
+                    key: 'title',
+                    // ハイライトはサーバーで <mark> 以外をエスケープ済み
+                    render: (_, record: TaskSearchResult) => (
+                      <a onClick={() => openEditModal(record.task)} dangerouslySetInnerHTML={{ __html: record.title_highlight }} />
+                    ),
+                  },
+                  {
+                    title: '説明',
+                    key: 'snippet',
+                    render: (_, record: TaskSearchResult) => (
+                      <span className="text-gray-600" dangerouslySetInnerHTML={{ __html: record.snippet }} />
+                    ),
+                  },
+                  {
+                    title: 'ステータス',
+                    key: 'status',
+                    render: (_, record: TaskSearchResult) => (
+                      <Tag color={getStatusColor(record.task.status)}>
+                        {record.task.status === 'completed' ? '完了' : '未完了'}
+                      </Tag>
+                    ),
+                  },
+                ]}
+              />
+            )}
+          </Card>
+
           {/* 未完了タスク */}
           <Card>
             <div className="mb-4">
               <h2 className="text-xl font-semibold text-gray-800">
                 未完了タスク ({pendingTasks.length}件)
               </h2>
+              {selectedTaskIds.length > 0 && (
+                <Space className="mt-2">
+                  <Button icon={<CheckOutlined />} onClick={() => handleBatch('complete')}>
+                    選択した{selectedTaskIds.length}件を完了
+                  </Button>
+                  <Popconfirm
+                    title={`選択した${selectedTaskIds.length}件をゴミ箱に移しますか？`}
+                    onConfirm={() => handleBatch('delete')}
+                    okText="移動"
+                    cancelText="キャンセル"
+                  >
+                    <Button danger icon={<DeleteOutlined />}>
+                      ゴミ箱に移す
+                    </Button>
+                  </Popconfirm>
+                </Space>
+              )}
             </div>
             <Table
               columns={columns}
               dataSource={pendingTasks}
               rowKey="id"
+              rowSelection={{
+                selectedRowKeys: selectedTaskIds,
+                onChange: (keys) => setSelectedTaskIds(keys as string[]),
+              }}
               loading={loading}
               pagination={{
                 pageSize: 10,
@@ -411,6 +842,14 @@ export default function TasksPage() {
           footer={null}
           width={600}
         >
+          {editingTask && otherEditors.length > 0 && (
+            <Alert
+              className="mb-4"
+              type="warning"
+              showIcon
+              message={`${otherEditors.map((m) => m.name).join('、')} が別のタブ・端末でこのタスクを編集中です`}
+            />
+          )}
           <Form
             form={form}
             layout="vertical"
@@ -486,6 +925,134 @@ export default function TasksPage() {
             </Form.Item>
           </Form>
         </Modal>
+
+        {/* ビューの保存 */}
+        <Modal
+          title="ビューとして保存"
+          open={saveViewVisible}
+          onOk={handleSaveView}
+          onCancel={() => setSaveViewVisible(false)}
+          okText="保存"
+          cancelText="キャンセル"
+          okButtonProps={{ disabled: !viewName.trim() }}
+        >
+          <p className="mb-2 text-gray-600">現在のフィルター式と完了済みタスクの表示設定を保存します</p>
+          <Input placeholder="ビューの名前（例: Overdue high priority）" value={viewName} onChange={(e) => setViewName(e.target.value)} />
+        </Modal>
+
+        {/* ゴミ箱 */}
+        <Modal
+          title="ゴミ箱"
+          open={trashVisible}
+          onCancel={() => setTrashVisible(false)}
+          footer={null}
+          width={700}
+        >
+          <Table
+            dataSource={trashTasks}
+            rowKey="id"
+            pagination={false}
+            locale={{ emptyText: 'ゴミ箱は空です' }}
+            columns={[
+              { title: 'タイトル', dataIndex: 'title', key: 'title' },
+              {
+                title: '削除日時',
+                dataIndex: 'deleted_at',
+                key: 'deleted_at',
+                render: (deletedAt: string) => dayjs(deletedAt).format('YYYY/MM/DD HH:mm'),
+              },
+              {
+                title: '操作',
+                key: 'actions',
+                render: (_, record: Task) => (
+                  <Space>
+                    <Button type="link" icon={<UndoOutlined />} onClick={() => handleRestoreTask(record.id)}>
+                      元に戻す
+                    </Button>
+                    <Popconfirm
+                      title="完全に削除すると元に戻せません。削除しますか？"
+                      onConfirm={() => handlePurgeTask(record.id)}
+                      okText="削除"
+                      cancelText="キャンセル"
+                    >
+                      <Button type="link" danger icon={<DeleteOutlined />}>
+                        完全に削除
+                      </Button>
+                    </Popconfirm>
+                  </Space>
+                ),
+              },
+            ]}
+          />
+        </Modal>
+
+        {/* 変更履歴 */}
+        <Modal
+          title={historyTask ? `変更履歴: ${historyTask.title}` : '変更履歴'}
+          open={historyTask !== null}
+          onCancel={() => setHistoryTask(null)}
+          footer={null}
+          width={800}
+        >
+          <Table
+            dataSource={historyEntries}
+            rowKey="id"
+            pagination={false}
+            locale={{ emptyText: '変更履歴はありません' }}
+            columns={[
+              { title: '版', dataIndex: 'version', key: 'version', width: 60 },
+              {
+                title: '日時',
+                dataIndex: 'created_at',
+                key: 'created_at',
+                render: (createdAt: string) => dayjs(createdAt).format('YYYY/MM/DD HH:mm'),
+              },
+              {
+                title: '操作',
+                key: 'action',
+                render: (_, record: TaskHistoryEntry) =>
+                  record.reverted_from
+                    ? `版 ${record.reverted_from} に戻す`
+                    : historyActionLabels[record.action],
+              },
+              {
+                title: '変更内容',
+                dataIndex: 'changes',
+                key: 'changes',
+                render: (changes: TaskFieldChange[]) => (
+                  <ul className="list-none p-0 m-0">
+                    {changes.map((change) => (
+                      <li key={change.field}>
+                        <span className="font-medium">{historyFieldLabels[change.field] ?? change.field}</span>
+                        {': '}
+                        <span className="line-through text-red-500">{formatHistoryValue(change.field, change.old)}</span>
+                        {' → '}
+                        <span className="text-green-600">{formatHistoryValue(change.field, change.new)}</span>
+                      </li>
+                    ))}
+                  </ul>
+                ),
+              },
+              {
+                title: '',
+                key: 'revert',
+                render: (_, record: TaskHistoryEntry) =>
+                  historyTask && record.version !== historyTask.version && (
+                    <Popconfirm
+                      title={`版 ${record.version} の内容に戻しますか？`}
+                      onConfirm={() => handleRevertTask(record)}
+                      okText="戻す"
+                      cancelText="キャンセル"
+                    >
+                      <Button type="link" icon={<UndoOutlined />}>
+                        この版に戻す
+                      </Button>
+                    </Popconfirm>
+                  ),
+              },
+            ]}
+          />
+        </Modal>
       </div>
     </div>
   );
@@ -3,8 +3,32 @@ import {
   LoginRequest, 
   RegisterRequest, 
   Task, 
+  TaskTree,
+  TaskHistoryEntry,
+  TaskSearchResult,
+  TaskBatchOperation,
+  TaskBatchResult,
+  Project,
+  CreateProjectRequest,
+  UpdateProjectRequest,
+  DeleteProjectMode,
+  View,
+  CreateViewRequest,
+  UpdateViewRequest,
+  Tag,
+  Reminder,
+  CreateReminderRequest,
+  ReminderDelivery,
+  Webhook,
+  CreateWebhookRequest,
+  UpdateWebhookRequest,
+  WebhookDelivery,
+  TaskStreamEvent,
   CreateTaskRequest, 
   UpdateTaskRequest,
+  TaskMergePatch,
+  JsonPatchOperation,
+  TaskFilters,
   ApiResponse 
 } from '@/types';
 
@@ -94,8 +118,44 @@ class ApiClient {
   }
 
   // タスク関連
-  async getTasks(): Promise<ApiResponse<Task[]>> {
-    return this.request<Task[]>('/tasks');
+  async getTasks(filters: TaskFilters = {}): Promise<ApiResponse<Task[]>> {
+    const params = new URLSearchParams();
+    if (filters.projectId) params.set('project_id', filters.projectId);
+    if (filters.status) params.set('status', filters.status);
+    if (filters.priority) params.set('priority', filters.priority);
+    if (filters.sortBy) params.set('sort_by', filters.sortBy);
+    if (filters.sortOrder) params.set('sort_order', filters.sortOrder);
+    if (filters.deadlineFrom) params.set('deadline_from', filters.deadlineFrom);
+    if (filters.deadlineTo) params.set('deadline_to', filters.deadlineTo);
+    if (filters.overdue) params.set('overdue', 'true');
+    if (filters.noDeadline) params.set('no_deadline', 'true');
+    filters.tagsAny?.forEach((tag) => params.append('tags_any', tag));
+    filters.tagsAll?.forEach((tag) => params.append('tags_all', tag));
+    if (filters.query) params.set('query', filters.query);
+    if (filters.limit) params.set('limit', String(filters.limit));
+    if (filters.cursor) params.set('cursor', filters.cursor);
+
+    const query = params.toString();
+    return this.request<Task[]>(query ? `/tasks?${query}` : '/tasks');
+  }
+
+  // next_cursor をたどって全ページのタスクを取得
+  async getAllTasks(filters: TaskFilters = {}): Promise<Task[]> {
+    const tasks: Task[] = [];
+    let cursor: string | undefined;
+    do {
+      const response = await this.getTasks({ ...filters, cursor });
+      tasks.push(...(response.data || []));
+      cursor = response.has_more ? response.next_cursor : undefined;
+    } while (cursor);
+    return tasks;
+  }
+
+  // タスクのタイトル・説明を全文検索（"..." でフレーズ、末尾の * で前方一致）
+  async searchTasks(q: string, limit?: number): Promise<ApiResponse<TaskSearchResult[]>> {
+    const params = new URLSearchParams({ q });
+    if (limit) params.set('limit', String(limit));
+    return this.request<TaskSearchResult[]>(`/tasks/search?${params.toString()}`);
   }
 
   async createTask(data: CreateTaskRequest): Promise<ApiResponse<Task>> {
@@ -105,16 +165,345 @@ class ApiClient {
     });
   }
 
-  async updateTask(id: string, data: UpdateTaskRequest): Promise<ApiResponse<Task>> {
+  // version は取得したタスクの版数。ほかの変更で更新されていれば 412 になる
+  async updateTask(id: string, version: number, data: UpdateTaskRequest): Promise<ApiResponse<Task>> {
     return this.request<Task>(`/tasks/${id}`, {
       method: 'PUT',
+      headers: { 'If-Match': `"${version}"` },
       body: JSON.stringify(data),
     });
   }
 
-  async deleteTask(id: string): Promise<ApiResponse<void>> {
+  // 部分更新。JSON Merge Patch（オブジェクト）または JSON Patch（操作の配列）を送る
+  async patchTask(
+    id: string,
+    version: number,
+    patch: TaskMergePatch | JsonPatchOperation[],
+    options: { scope?: 'this' | 'series'; cascade?: boolean } = {}
+  ): Promise<ApiResponse<Task>> {
+    const params = new URLSearchParams();
+    if (options.scope) params.set('scope', options.scope);
+    if (options.cascade) params.set('cascade', 'true');
+    const query = params.toString();
+    return this.request<Task>(`/tasks/${id}${query ? `?${query}` : ''}`, {
+      method: 'PATCH',
+      headers: {
+        'Content-Type': Array.isArray(patch) ? 'application/json-patch+json' : 'application/merge-patch+json',
+        'If-Match': `"${version}"`,
+      },
+      body: JSON.stringify(patch),
+    });
+  }
+
+  async deleteTask(id: string, version: number): Promise<ApiResponse<void>> {
     return this.request<void>(`/tasks/${id}`, {
       method: 'DELETE',
+      headers: { 'If-Match': `"${version}"` },
+    });
+  }
+
+  // 複数のタスクをまとめて作成・更新・完了・削除する（1つのトランザクションで実行し、操作ごとの結果を返す）
+  // atomic なら1つでも失敗すればすべて取り消し、エラーになる
+  async batchTasks(operations: TaskBatchOperation[], atomic = false): Promise<ApiResponse<TaskBatchResult[]>> {
+    return this.request<TaskBatchResult[]>('/tasks/batch', {
+      method: 'POST',
+      body: JSON.stringify({ atomic, operations }),
+    });
+  }
+
+  // タスクの変更履歴を新しい順に取得
+  async getTaskHistory(id: string): Promise<ApiResponse<TaskHistoryEntry[]>> {
+    return this.request<TaskHistoryEntry[]>(`/tasks/${id}/history`);
+  }
+
+  // タスクの内容を履歴の版の時点に戻す（繰り返しのルールとプロジェクトは戻らない）
+  async revertTask(id: string, version: number, revision: number): Promise<ApiResponse<Task>> {
+    return this.request<Task>(`/tasks/${id}/history/${revision}/revert`, {
+      method: 'POST',
+      headers: { 'If-Match': `"${version}"` },
+    });
+  }
+
+  // ゴミ箱のタスクを取得（親と一緒に削除したサブタスクは含まれない）
+  async getTrash(): Promise<ApiResponse<Task[]>> {
+    return this.request<Task[]>('/trash');
+  }
+
+  // ゴミ箱のタスクを一緒に削除したサブタスクとともに元に戻す
+  async restoreTask(id: string): Promise<ApiResponse<Task>> {
+    return this.request<Task>(`/tasks/${id}/restore`, {
+      method: 'POST',
+    });
+  }
+
+  // ゴミ箱のタスクを完全に削除する（元に戻せない）
+  async purgeTask(id: string): Promise<ApiResponse<void>> {
+    return this.request<void>(`/trash/${id}`, {
+      method: 'DELETE',
+    });
+  }
+
+  // 直下のサブタスクを取得
+  async getTaskChildren(id: string): Promise<ApiResponse<Task[]>> {
+    return this.request<Task[]>(`/tasks/${id}/children`);
+  }
+
+  // タスクとサブタスクを木構造で取得
+  async getTaskTree(id: string): Promise<ApiResponse<TaskTree>> {
+    return this.request<TaskTree>(`/tasks/${id}/tree`);
+  }
+
+  // 繰り返しタスクのこの回をスキップ（次の回を返す。最後の回なら data は null）
+  async skipOccurrence(id: string, version: number): Promise<ApiResponse<Task | null>> {
+    return this.request<Task | null>(`/tasks/${id}/skip`, {
+      method: 'POST',
+      headers: { 'If-Match': `"${version}"` },
+    });
+  }
+
+  // 繰り返しを終了（このタスクは残る）
+  async endTaskSeries(id: string, version: number): Promise<ApiResponse<Task>> {
+    return this.request<Task>(`/tasks/${id}/end-series`, {
+      method: 'POST',
+      headers: { 'If-Match': `"${version}"` },
+    });
+  }
+
+  // タスクをサブタスクごと別のプロジェクトに移動
+  async moveTask(id: string, version: number, projectId: string): Promise<ApiResponse<Task>> {
+    return this.request<Task>(`/tasks/${id}/move`, {
+      method: 'POST',
+      headers: { 'If-Match': `"${version}"` },
+      body: JSON.stringify({ project_id: projectId }),
+    });
+  }
+
+  // リマインダー関連
+  async getReminders(taskId: string): Promise<ApiResponse<Reminder[]>> {
+    return this.request<Reminder[]>(`/tasks/${taskId}/reminders`);
+  }
+
+  async createReminder(taskId: string, data: CreateReminderRequest): Promise<ApiResponse<Reminder>> {
+    return this.request<Reminder>(`/tasks/${taskId}/reminders`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async deleteReminder(taskId: string, reminderId: string): Promise<ApiResponse<void>> {
+    return this.request<void>(`/tasks/${taskId}/reminders/${reminderId}`, {
+      method: 'DELETE',
+    });
+  }
+
+  // チャネルごとの配信記録
+  async getReminderDeliveries(taskId: string, reminderId: string): Promise<ApiResponse<ReminderDelivery[]>> {
+    return this.request<ReminderDelivery[]>(`/tasks/${taskId}/reminders/${reminderId}/deliveries`);
+  }
+
+  // Webhook関連
+  async getWebhooks(): Promise<ApiResponse<Webhook[]>> {
+    return this.request<Webhook[]>('/webhooks');
+  }
+
+  // レスポンスの secret は作成時にのみ返される
+  async createWebhook(data: CreateWebhookRequest): Promise<ApiResponse<Webhook>> {
+    return this.request<Webhook>('/webhooks', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async updateWebhook(id: string, data: UpdateWebhookRequest): Promise<ApiResponse<Webhook>> {
+    return this.request<Webhook>(`/webhooks/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async deleteWebhook(id: string): Promise<ApiResponse<void>> {
+    return this.request<void>(`/webhooks/${id}`, {
+      method: 'DELETE',
+    });
+  }
+
+  async getWebhookDeliveries(id: string, status?: WebhookDelivery['status']): Promise<ApiResponse<WebhookDelivery[]>> {
+    return this.request<WebhookDelivery[]>(`/webhooks/${id}/deliveries${status ? `?status=${status}` : ''}`);
+  }
+
+  // 同じイベントを新しい配信として再送
+  async replayWebhookDelivery(id: string, deliveryId: string): Promise<ApiResponse<WebhookDelivery>> {
+    return this.request<WebhookDelivery>(`/webhooks/${id}/deliveries/${deliveryId}/replay`, {
+      method: 'POST',
+    });
+  }
+
+  // タスクの変更イベントを購読（切断時は Last-Event-ID を付けて再接続する）
+  // EventSource はヘッダーを付けられないため、トークンを URL に含めないよう fetch で受信する
+  // onReset は取りこぼしを再送できなかった場合に呼ばれるため、一覧を取得し直す
+  // 戻り値の関数を呼ぶと購読をやめる
+  subscribeEvents(onEvent: (event: TaskStreamEvent) => void, onReset: () => void): () => void {
+    const controller = new AbortController();
+    let lastEventId = '';
+    let retryMs = 3000;
+
+    const connect = async () => {
+      while (!controller.signal.aborted) {
+        try {
+          const headers: Record<string, string> = { Accept: 'text/event-stream' };
+          const token = localStorage.getItem('access_token');
+          if (token) headers.Authorization = `Bearer ${token}`;
+          if (lastEventId) headers['Last-Event-ID'] = lastEventId;
+
+          const response = await fetch(`${this.baseURL}/events`, { headers, signal: controller.signal });
+          if (!response.ok || !response.body) {
+            throw new Error(`HTTP ${response.status}: ${response.statusText}`);
+          }
+
+          const reader = response.body.getReader();
+          const decoder = new TextDecoder();
+          let buffer = '';
+          let id = '';
+          let type = '';
+          let data = '';
+          for (;;) {
+            const { done, value } = await reader.read();
+            if (done) break;
+            buffer += decoder.decode(value, { stream: true });
+
+            let newline;
+            while ((newline = buffer.indexOf('\n')) >= 0) {
+              const line = buffer.slice(0, newline).replace(/\r$/, '');
+              buffer = buffer.slice(newline + 1);
+
+              if (line === '') {
+                // 空行でイベントの終わり
+                if (id) lastEventId = id;
+                if (type === 'reset') {
+                  onReset();
+                } else if (type.startsWith('task.') && data) {
+                  onEvent(JSON.parse(data) as TaskStreamEvent);
+                }
+                id = type = data = '';
+              } else if (line.startsWith('id:')) {
+                id = line.slice(3).trim();
+              } else if (line.startsWith('event:')) {
+                type = line.slice(6).trim();
+              } else if (line.startsWith('data:')) {
+                data += (data ? '\n' : '') + line.slice(5).trim();
+              } else if (line.startsWith('retry:')) {
+                retryMs = Number(line.slice(6).trim()) || retryMs;
+              }
+            }
+          }
+        } catch (error) {
+          if (controller.signal.aborted) return;
+          console.warn('Event stream disconnected:', error);
+        }
+        await new Promise((resolve) => setTimeout(resolve, retryMs));
+      }
+    };
+    connect();
+
+    return () => controller.abort();
+  }
+
+  // プロジェクト関連
+  async getProjects(includeArchived = false): Promise<ApiResponse<Project[]>> {
+    return this.request<Project[]>(`/projects${includeArchived ? '?include_archived=true' : ''}`);
+  }
+
+  async createProject(data: CreateProjectRequest): Promise<ApiResponse<Project>> {
+    return this.request<Project>('/projects', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async updateProject(id: string, data: UpdateProjectRequest): Promise<ApiResponse<Project>> {
+    return this.request<Project>(`/projects/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  // 指定した順に並べ替える（省略したプロジェクトは後ろに並ぶ）
+  async reorderProjects(projectIds: string[]): Promise<ApiResponse<Project[]>> {
+    return this.request<Project[]>('/projects/order', {
+      method: 'PUT',
+      body: JSON.stringify({ project_ids: projectIds }),
+    });
+  }
+
+  async deleteProject(id: string, options: DeleteProjectMode): Promise<ApiResponse<void>> {
+    const params = new URLSearchParams({ mode: options.mode });
+    if (options.mode === 'reassign' && options.target) params.set('target', options.target);
+    return this.request<void>(`/projects/${id}?${params.toString()}`, {
+      method: 'DELETE',
+    });
+  }
+
+  // 保存したビュー関連
+  async getViews(): Promise<ApiResponse<View[]>> {
+    return this.request<View[]>('/views');
+  }
+
+  async createView(data: CreateViewRequest): Promise<ApiResponse<View>> {
+    return this.request<View>('/views', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async updateView(id: string, data: UpdateViewRequest): Promise<ApiResponse<View>> {
+    return this.request<View>(`/views/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async deleteView(id: string): Promise<ApiResponse<void>> {
+    return this.request<void>(`/views/${id}`, {
+      method: 'DELETE',
+    });
+  }
+
+  // ビューの条件で全ページのタスクを取得（GET /api/tasks と同じ絞り込み・並び替え）
+  async getAllViewTasks(id: string): Promise<Task[]> {
+    const tasks: Task[] = [];
+    let cursor: string | undefined;
+    do {
+      const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : '';
+      const response = await this.request<Task[]>(`/views/${id}/tasks${query}`);
+      tasks.push(...(response.data || []));
+      cursor = response.has_more ? response.next_cursor : undefined;
+    } while (cursor);
+    return tasks;
+  }
+
+  // タグ関連
+  async getTags(): Promise<ApiResponse<Tag[]>> {
+    return this.request<Tag[]>('/tags');
+  }
+
+  async createTag(name: string): Promise<ApiResponse<Tag>> {
+    return this.request<Tag>('/tags', {
+      method: 'POST',
+      body: JSON.stringify({ name }),
+    });
+  }
+
+  // タグ名を変更（タグが付いたすべてのタスクに反映される）
+  async renameTag(id: string, name: string): Promise<ApiResponse<Tag>> {
+    return this.request<Tag>(`/tags/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify({ name }),
+    });
+  }
+
+  async deleteTag(id: string): Promise<ApiResponse<void>> {
+    return this.request<void>(`/tags/${id}`, {
+      method: 'DELETE',
     });
   }
 }
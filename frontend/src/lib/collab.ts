@@ -0,0 +1,123 @@
+import { CollabServerMessage, PresenceMember, PresenceState, Task, UpdateTaskRequest } from '@/types';
+
+const API_BASE_URL = process.env.NEXT_PUBLIC_API_URL || 'http://localhost:8080';
+
+// ブラウザの WebSocket はヘッダーを付けられないため、アクセストークンはサブプロトコルで送る
+const COLLAB_PROTOCOL = 'todo.collab.v1';
+const RECONNECT_DELAY_MS = 3000;
+
+type PresenceListener = (members: PresenceMember[]) => void;
+type TaskListener = (type: string, task: Task) => void;
+
+// /api/collab の WebSocket クライアント
+// 切断時は再接続し、購読していたトピックとプレゼンスの状態を送り直す
+export class CollabClient {
+  private socket: WebSocket | null = null;
+  // sessionId この接続のID（プレゼンスの参加者から自分を見分ける）
+  sessionId = '';
+  private closed = false;
+  private nextRef = 1;
+  private pending = new Map<string, { resolve: (message: CollabServerMessage) => void; reject: (error: Error) => void }>();
+  private topics = new Map<string, PresenceState>();
+  private presenceListeners = new Map<string, PresenceListener>();
+  private taskListener: TaskListener | null = null;
+
+  constructor(private baseURL: string = API_BASE_URL) {}
+
+  connect() {
+    this.closed = false;
+    const token = localStorage.getItem('access_token');
+    const url = `${this.baseURL.replace(/^http/, 'ws')}/collab`;
+    const socket = new WebSocket(url, token ? [COLLAB_PROTOCOL, `bearer.${token}`] : [COLLAB_PROTOCOL]);
+    this.socket = socket;
+
+    socket.onopen = () => {
+      this.topics.forEach((state, topic) => {
+        this.send({ type: 'subscribe', topic });
+        if (state !== 'viewing') this.send({ type: 'presence', topic, state });
+      });
+    };
+    socket.onmessage = (event) => this.handleMessage(JSON.parse(event.data) as CollabServerMessage);
+    socket.onclose = () => {
+      this.pending.forEach(({ reject }) => reject(new Error('Connection closed')));
+      this.pending.clear();
+      if (!this.closed) setTimeout(() => this.connect(), RECONNECT_DELAY_MS);
+    };
+  }
+
+  close() {
+    this.closed = true;
+    this.socket?.close();
+  }
+
+  // project:<ID> または task:<ID> を購読し、参加者の一覧を受け取る
+  subscribe(topic: string, onPresence?: PresenceListener) {
+    this.topics.set(topic, 'viewing');
+    if (onPresence) this.presenceListeners.set(topic, onPresence);
+    this.send({ type: 'subscribe', topic });
+  }
+
+  unsubscribe(topic: string) {
+    this.topics.delete(topic);
+    this.presenceListeners.delete(topic);
+    this.send({ type: 'unsubscribe', topic });
+  }
+
+  setPresence(topic: string, state: PresenceState) {
+    if (!this.topics.has(topic)) return;
+    this.topics.set(topic, state);
+    this.send({ type: 'presence', topic, state });
+  }
+
+  // 購読中のトピックのタスクの変更を受け取る
+  onTaskEvent(listener: TaskListener | null) {
+    this.taskListener = listener;
+  }
+
+  // PUT /api/tasks/:id と同じ検証でタスクを更新する
+  // version は変更の元にしたタスクの版数
+  async updateTask(taskId: string, version: number, changes: UpdateTaskRequest): Promise<Task> {
+    const response = await this.request({ type: 'update', task_id: taskId, version, changes });
+    return response.data as Task;
+  }
+
+  private request(message: Record<string, unknown>): Promise<CollabServerMessage> {
+    return new Promise((resolve, reject) => {
+      const ref = String(this.nextRef++);
+      if (!this.send({ ...message, ref })) {
+        reject(new Error('Not connected'));
+        return;
+      }
+      this.pending.set(ref, { resolve, reject });
+    });
+  }
+
+  private send(message: Record<string, unknown>): boolean {
+    if (this.socket?.readyState !== WebSocket.OPEN) return false;
+    this.socket.send(JSON.stringify(message));
+    return true;
+  }
+
+  private handleMessage(message: CollabServerMessage) {
+    if (message.ref && this.pending.has(message.ref)) {
+      const { resolve, reject } = this.pending.get(message.ref)!;
+      this.pending.delete(message.ref);
+      if (message.type === 'error') {
+        reject(new Error(message.error));
+      } else {
+        resolve(message);
+      }
+      return;
+    }
+
+    if (message.type === 'hello') {
+      this.sessionId = message.session_id ?? '';
+    } else if (message.type === 'presence' && message.topic) {
+      this.presenceListeners.get(message.topic)?.(message.members ?? []);
+    } else if (message.type.startsWith('task.') && message.data) {
+      this.taskListener?.(message.type, message.data);
+    } else if (message.type === 'error') {
+      console.warn('Collaboration error:', message.error);
+    }
+  }
+}
@@ -27,11 +27,217 @@ export interface RegisterRequest {
 export interface Task {
   id: string;
   user_id: string;
+  project_id: string;
+  parent_id?: string;
   title: string;
   description?: string;
   deadline?: string;
   priority: 'high' | 'medium' | 'low';
   status: 'pending' | 'completed';
+  tags: string[];
+  rrule?: string; // iCalendar RRULE（例: FREQ=WEEKLY;BYDAY=MO）。系列が終了すると省略される
+  timezone?: string; // 繰り返しを計算する IANA タイムゾーン
+  series_id?: string;
+  occurrence_at?: string; // ルールから計算したこの回の本来の日時
+  version: number; // 更新のたびに増える版数。更新・削除の If-Match に使う
+  deleted_at?: string; // ゴミ箱に移した日時（ゴミ箱のタスクのみ）
+  created_at: string;
+  updated_at: string;
+}
+
+// プロジェクト関連の型定義
+export interface Project {
+  id: string;
+  user_id: string;
+  name: string;
+  color: string; // #rrggbb
+  position: number;
+  archived: boolean;
+  is_inbox: boolean; // 登録時に作成される既定のプロジェクト
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateProjectRequest {
+  name: string;
+  color?: string;
+}
+
+export interface UpdateProjectRequest {
+  name?: string;
+  color?: string;
+  archived?: boolean;
+}
+
+// プロジェクト削除時のタスクの扱い
+export type DeleteProjectMode =
+  | { mode: 'cascade' }
+  | { mode: 'reassign'; target?: string }; // target 省略時は Inbox
+
+// 保存したビュー（スマートリスト）
+export interface View {
+  id: string;
+  user_id: string;
+  name: string;
+  filters: ViewFilters;
+  display: ViewDisplay;
+  created_at: string;
+  updated_at: string;
+}
+
+// ビューの条件（GET /api/tasks のクエリパラメータと同じ名前）
+export interface ViewFilters {
+  status?: 'pending' | 'completed' | 'all';
+  priority?: 'high' | 'medium' | 'low' | 'all';
+  sort_by?: 'deadline' | 'priority' | 'created_at';
+  sort_order?: 'asc' | 'desc';
+  deadline_from?: string; // RFC 3339
+  deadline_to?: string; // RFC 3339
+  overdue?: boolean;
+  no_deadline?: boolean;
+  project_id?: string;
+  tags_any?: string[];
+  tags_all?: string[];
+  query?: string; // フィルター式
+}
+
+// ビューの表示方法（サーバーは保存するのみ）
+export interface ViewDisplay {
+  show_completed: boolean;
+  group_by?: 'project' | 'priority' | 'status' | 'deadline';
+  columns?: ViewColumn[]; // 表示する列と順序（省略時は既定の列）
+}
+
+export type ViewColumn =
+  | 'title'
+  | 'description'
+  | 'priority'
+  | 'status'
+  | 'deadline'
+  | 'tags'
+  | 'project'
+  | 'created_at'
+  | 'updated_at';
+
+export interface CreateViewRequest {
+  name: string;
+  filters: ViewFilters;
+  display?: ViewDisplay;
+}
+
+// filters / display は全体を置き換える
+export interface UpdateViewRequest {
+  name?: string;
+  filters?: ViewFilters;
+  display?: ViewDisplay;
+}
+
+export interface Tag {
+  id: string;
+  user_id: string;
+  name: string;
+  task_count: number;
+  created_at: string;
+  updated_at: string;
+}
+
+// タスクのリマインダー（期限の offset_minutes 分前、または期限日の time_of_day に通知）
+export interface Reminder {
+  id: string;
+  task_id: string;
+  kind: 'before_deadline' | 'due_day';
+  offset_minutes?: number;
+  time_of_day?: string; // HH:MM
+  timezone?: string;
+  created_at: string;
+}
+
+export interface CreateReminderRequest {
+  kind: 'before_deadline' | 'due_day';
+  offset_minutes?: number;
+  time_of_day?: string;
+  timezone?: string; // 省略時は UTC
+}
+
+export interface ReminderDelivery {
+  reminder_id: string;
+  fire_at: string;
+  channel: string;
+  status: 'sending' | 'sent' | 'failed' | 'skipped';
+  attempts: number;
+  last_error?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export type WebhookEvent = 'task.created' | 'task.updated' | 'task.completed' | 'task.deleted' | 'task.restored';
+
+export interface Webhook {
+  id: string;
+  user_id: string;
+  url: string;
+  events: WebhookEvent[];
+  secret?: string; // 作成時のレスポンスのみ
+  active: boolean;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface CreateWebhookRequest {
+  url: string;
+  events: WebhookEvent[];
+  secret?: string; // 省略時はサーバーで生成
+}
+
+// GET /api/events で受信するタスクの変更イベント（Webhook の本文と同じ形式）
+export interface TaskStreamEvent {
+  id: string;
+  type: WebhookEvent;
+  data: Task;
+  created_at: string;
+}
+
+// /api/collab（WebSocket）のプレゼンス
+export type PresenceState = 'viewing' | 'editing';
+
+export interface PresenceMember {
+  session_id: string; // 同じユーザーの別のタブ・端末を区別する
+  user_id: string;
+  name: string;
+  state: PresenceState;
+}
+
+// /api/collab でサーバーから受信するメッセージ
+export interface CollabServerMessage {
+  type: 'hello' | 'ack' | 'error' | 'pong' | 'presence' | WebhookEvent;
+  ref?: string;
+  topic?: string; // project:<ID> または task:<ID>
+  session_id?: string; // hello のみ
+  status?: number;
+  error?: string;
+  data?: Task;
+  next_occurrence?: Task;
+  members?: PresenceMember[];
+}
+
+export interface UpdateWebhookRequest {
+  url?: string;
+  events?: WebhookEvent[];
+  active?: boolean;
+}
+
+export interface WebhookDelivery {
+  id: string;
+  webhook_id: string;
+  event_id: string;
+  event: WebhookEvent;
+  payload: unknown;
+  status: 'pending' | 'succeeded' | 'failed';
+  attempts: number;
+  next_attempt_at?: string;
+  response_status?: number;
+  last_error?: string;
+  delivered_at?: string;
   created_at: string;
   updated_at: string;
 }
@@ -41,6 +247,11 @@ export interface CreateTaskRequest {
   description?: string;
   deadline?: Date;
   priority: 'high' | 'medium' | 'low';
+  project_id?: string; // 省略時は Inbox
+  parent_id?: string;
+  tags?: string[];
+  rrule?: string; // 指定すると期限を最初の回とする繰り返しタスクになる（deadline 必須）
+  timezone?: string; // 省略時は UTC
 }
 
 export interface UpdateTaskRequest {
@@ -49,6 +260,87 @@ export interface UpdateTaskRequest {
   deadline?: Date;
   priority?: 'high' | 'medium' | 'low';
   status?: 'pending' | 'completed';
+  parent_id?: string; // 空文字列でトップレベルに戻す
+  tags?: string[]; // 指定するとタグを置き換える
+  cascade?: boolean; // 完了にする際にサブタスクもまとめて完了にする
+  rrule?: string;
+  timezone?: string;
+  scope?: 'this' | 'series'; // 繰り返しタスクの変更範囲（省略時は this）
+}
+
+// POST /api/tasks/batch の操作。version は取得したタスクの版数（If-Match に当たる）
+export type TaskBatchOperation =
+  | { op: 'create'; task: CreateTaskRequest }
+  | { op: 'update'; id: string; version: number; changes: UpdateTaskRequest }
+  | { op: 'complete'; id: string; version: number; cascade?: boolean }
+  | { op: 'delete'; id: string; version: number };
+
+// 一括操作の1件の結果（status は単独の API と同じステータスコード。atomic で取り消した操作は 424）
+export interface TaskBatchResult {
+  index: number;
+  op: TaskBatchOperation['op'];
+  id?: string;
+  status: number;
+  error?: string;
+  data?: Task; // 作成・更新後のタスク（412 の場合は現在のタスク）
+  next_occurrence?: Task;
+}
+
+// PATCH /api/tasks/:id の JSON Merge Patch。null で説明・期限・親タスクを消す
+export interface TaskMergePatch {
+  title?: string;
+  description?: string | null;
+  deadline?: Date | null;
+  priority?: 'high' | 'medium' | 'low';
+  status?: 'pending' | 'completed';
+  parent_id?: string | null;
+  tags?: string[];
+  rrule?: string;
+  timezone?: string;
+}
+
+// PATCH /api/tasks/:id の JSON Patch の操作。test が失敗するとパッチ全体が適用されない
+export interface JsonPatchOperation {
+  op: 'add' | 'remove' | 'replace' | 'move' | 'copy' | 'test';
+  path: string;
+  from?: string;
+  value?: unknown;
+}
+
+// タスクの変更履歴
+export type TaskHistoryAction = 'created' | 'updated' | 'deleted' | 'restored' | 'reverted';
+
+// 変更した項目の変更前後の値（値がない項目は null）
+export interface TaskFieldChange {
+  field: string;
+  old: unknown;
+  new: unknown;
+}
+
+export interface TaskHistoryEntry {
+  id: string;
+  task_id: string;
+  actor_id: string; // 変更したユーザー
+  action: TaskHistoryAction;
+  version: number; // 変更後の版数。この版に戻す際に指定する
+  changes: TaskFieldChange[];
+  snapshot: TaskMergePatch; // 変更後のタスクの内容
+  reverted_from?: number; // 以前の版に戻した場合の戻した版数
+  created_at: string;
+}
+
+// 全文検索に一致したタスク
+export interface TaskSearchResult {
+  task: Task;
+  score: number; // 大きいほど上位
+  title_highlight: string; // 一致した語を <mark> で囲んだタイトル（HTML。それ以外はエスケープ済み）
+  snippet: string; // 一致した語を <mark> で囲んだ説明の抜粋（同上）
+}
+
+// サブタスクを含むタスクの木構造
+export interface TaskTree extends Task {
+  completion_percent: number;
+  children: TaskTree[];
 }
 
 // APIレスポンスの型定義
@@ -57,12 +349,27 @@ export interface ApiResponse<T> {
   data?: T;
   message?: string;
   error?: string;
+  // ページングされた一覧のみ
+  next_cursor?: string;
+  has_more?: boolean;
+  // 繰り返しタスクを完了にした場合のみ
+  next_occurrence?: Task;
 }
 
 // フィルター・ソート関連
 export interface TaskFilters {
+  projectId?: string;
   status?: 'pending' | 'completed' | 'all';
   priority?: 'high' | 'medium' | 'low' | 'all';
   sortBy?: 'deadline' | 'priority' | 'created_at';
   sortOrder?: 'asc' | 'desc';
+  deadlineFrom?: string; // RFC 3339
+  deadlineTo?: string; // RFC 3339
+  overdue?: boolean;
+  noDeadline?: boolean;
+  tagsAny?: string[]; // いずれかのタグが付いたタスク
+  tagsAll?: string[]; // すべてのタグが付いたタスク
+  query?: string; // フィルター式（例: priority:high due:<7d -tag:blocked "release notes"）
+  limit?: number;
+  cursor?: string;
 }